@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// AllocatedResourcesAnnotationKey carries the resources the kubelet actually
+// allocated to the pod's containers, as comma-separated
+// container=resource:quantity[+resource:quantity] entries, e.g.
+// "app=cpu:500m+memory:1Gi,sidecar=cpu:100m". During an in-flight resize the
+// allocation lags or leads the spec; the cache folds the difference into the
+// node's accounting so capacity math matches reality mid-transition.
+const AllocatedResourcesAnnotationKey = "scheduler.alpha.kubernetes.io/allocated-resources"
+
+// parseAllocatedResources parses the allocated resources annotation value
+// into per-container Resources.
+func parseAllocatedResources(value string) (map[string]*Resource, error) {
+	allocated := make(map[string]*Resource)
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("malformed container entry %q", entry)
+		}
+		res := &Resource{}
+		for _, pair := range strings.Split(parts[1], "+") {
+			kv := strings.SplitN(pair, ":", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("malformed resource pair %q for container %q", pair, parts[0])
+			}
+			quantity, err := resource.ParseQuantity(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quantity %q for resource %q of container %q: %v", kv[1], kv[0], parts[0], err)
+			}
+			switch v1.ResourceName(kv[0]) {
+			case v1.ResourceCPU:
+				res.MilliCPU = quantity.MilliValue()
+			case v1.ResourceMemory:
+				res.Memory = quantity.Value()
+			case v1.ResourceEphemeralStorage:
+				res.EphemeralStorage = quantity.Value()
+			default:
+				return nil, fmt.Errorf("unsupported resource %q for container %q", kv[0], parts[0])
+			}
+		}
+		allocated[parts[0]] = res
+	}
+	return allocated, nil
+}
+
+// allocatedDeltaOf returns the pod's reported allocation minus its spec
+// requests, or nil if the pod carries no valid allocation annotation or the
+// two agree. Containers absent from the annotation count as allocated what
+// they request.
+func allocatedDeltaOf(pod *v1.Pod) *Resource {
+	value, ok := pod.Annotations[AllocatedResourcesAnnotationKey]
+	if !ok {
+		return nil
+	}
+	allocated, err := parseAllocatedResources(value)
+	if err != nil {
+		glog.Errorf("Pod %v/%v has an invalid allocated resources annotation: %v", pod.Namespace, pod.Name, err)
+		return nil
+	}
+	delta := &Resource{}
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		res, ok := allocated[c.Name]
+		if !ok {
+			continue
+		}
+		requested := &Resource{}
+		requested.Add(c.Resources.Requests)
+		delta.MilliCPU += res.MilliCPU - requested.MilliCPU
+		delta.Memory += res.Memory - requested.Memory
+		delta.EphemeralStorage += res.EphemeralStorage - requested.EphemeralStorage
+	}
+	if delta.MilliCPU == 0 && delta.Memory == 0 && delta.EphemeralStorage == 0 {
+		return nil
+	}
+	return delta
+}
+
+// accountAllocated folds the pod's allocation delta into the node's aggregate
+// with the given sign. The aggregate is nil while spec and allocation agree
+// for every pod on the node.
+func (n *NodeInfo) accountAllocated(pod *v1.Pod, sign int64) {
+	delta := allocatedDeltaOf(pod)
+	if delta == nil {
+		return
+	}
+	if n.allocatedDelta == nil {
+		n.allocatedDelta = &Resource{}
+	}
+	n.allocatedDelta.MilliCPU += sign * delta.MilliCPU
+	n.allocatedDelta.Memory += sign * delta.Memory
+	n.allocatedDelta.EphemeralStorage += sign * delta.EphemeralStorage
+	if n.allocatedDelta.MilliCPU == 0 && n.allocatedDelta.Memory == 0 && n.allocatedDelta.EphemeralStorage == 0 {
+		n.allocatedDelta = nil
+	}
+}
+
+// AllocatedDelta returns the summed difference between what the kubelet
+// reported as allocated and what the pods' specs request, zero while they
+// agree.
+func (n *NodeInfo) AllocatedDelta() Resource {
+	if n.allocatedDelta == nil {
+		return Resource{}
+	}
+	return *n.allocatedDelta
+}
+
+// PodAllocatedResources returns the pod's spec-requested and kubelet-reported
+// allocated resources per container. Containers without a report appear only
+// in the requested map.
+func (cache *schedulerCache) PodAllocatedResources(podKey string) (requested, allocated map[string]Resource, err error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	ps, ok := cache.podStates[podKey]
+	if !ok {
+		return nil, nil, newError(ReasonPodNotFound, "pod %v is not in the scheduler cache, so its allocation can't be reported", podKey)
+	}
+	requested = make(map[string]Resource)
+	for i := range ps.pod.Spec.Containers {
+		c := &ps.pod.Spec.Containers[i]
+		res := &Resource{}
+		res.Add(c.Resources.Requests)
+		requested[c.Name] = *res
+	}
+	allocated = make(map[string]Resource)
+	if value, ok := ps.pod.Annotations[AllocatedResourcesAnnotationKey]; ok {
+		parsed, err := parseAllocatedResources(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("pod %v has an invalid allocated resources annotation: %v", podKey, err)
+		}
+		for name, res := range parsed {
+			allocated[name] = *res
+		}
+	}
+	return requested, allocated, nil
+}
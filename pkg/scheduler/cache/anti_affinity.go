@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// MatchingAntiAffinityTerm is one required anti-affinity term of an existing
+// pod that matches a prospective pod, i.e. the existing pod repels it. The
+// predicate still has to evaluate the term's topology key against the nodes
+// involved.
+type MatchingAntiAffinityTerm struct {
+	// PodKey and NodeName identify the existing pod the term belongs to.
+	PodKey   string
+	NodeName string
+	Term     v1.PodAffinityTerm
+}
+
+// antiAffinityTerm is one indexed required anti-affinity term of a cached
+// pod, with its selector parsed once at index time.
+type antiAffinityTerm struct {
+	nodeName string
+	// namespaces the term applies to; a term without explicit namespaces
+	// applies to its own pod's namespace.
+	namespaces []string
+	selector   labels.Selector
+	term       v1.PodAffinityTerm
+}
+
+// requiredAntiAffinityTerms returns the pod's required anti-affinity terms in
+// indexed form, or nil if it has none.
+func requiredAntiAffinityTerms(pod *v1.Pod) []antiAffinityTerm {
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.PodAntiAffinity == nil {
+		return nil
+	}
+	var terms []antiAffinityTerm
+	for _, term := range pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+		if err != nil {
+			glog.Errorf("Pod %s/%s has an invalid anti-affinity selector: %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		namespaces := term.Namespaces
+		if len(namespaces) == 0 {
+			namespaces = []string{pod.Namespace}
+		}
+		terms = append(terms, antiAffinityTerm{
+			nodeName:   pod.Spec.NodeName,
+			namespaces: namespaces,
+			selector:   selector,
+			term:       term,
+		})
+	}
+	return terms
+}
+
+// indexAntiAffinity records the pod's required anti-affinity terms so the
+// symmetry check can find repelling pods without walking the whole cache.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) indexAntiAffinity(pod *v1.Pod) {
+	terms := requiredAntiAffinityTerms(pod)
+	if terms == nil {
+		return
+	}
+	key, err := getPodKey(pod)
+	if err != nil {
+		return
+	}
+	cache.antiAffinityTerms[key] = terms
+}
+
+// unindexAntiAffinity drops the pod's indexed anti-affinity terms.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) unindexAntiAffinity(pod *v1.Pod) {
+	key, err := getPodKey(pod)
+	if err != nil {
+		return
+	}
+	delete(cache.antiAffinityTerms, key)
+}
+
+// MatchingAntiAffinityTerms returns the required anti-affinity terms of cached
+// pods that select the given prospective pod. This is the symmetry half of the
+// anti-affinity predicate: an existing pod's term forbids the new pod from its
+// topology domain even though the new pod declares no anti-affinity itself.
+func (cache *schedulerCache) MatchingAntiAffinityTerms(pod *v1.Pod) []MatchingAntiAffinityTerm {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	podLabels := labels.Set(pod.Labels)
+	var matching []MatchingAntiAffinityTerm
+	for key, terms := range cache.antiAffinityTerms {
+		for _, t := range terms {
+			if !containsString(t.namespaces, pod.Namespace) {
+				continue
+			}
+			if !t.selector.Matches(podLabels) {
+				continue
+			}
+			matching = append(matching, MatchingAntiAffinityTerm{
+				PodKey:   key,
+				NodeName: t.nodeName,
+				Term:     t.term,
+			})
+		}
+	}
+	return matching
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/kubernetes/pkg/scheduler/metrics"
+)
+
+// AssumeSource says why a pod is in the assumed state.
+type AssumeSource string
+
+const (
+	// AssumeSourceBind is a pod assumed for a normal bind in flight.
+	AssumeSourceBind AssumeSource = "Bind"
+	// AssumeSourceReservation is a held pod: assumed with a
+	// scheduling-gate annotation and not yet released.
+	AssumeSourceReservation AssumeSource = "Reservation"
+	// AssumeSourceResize is an assumed pod that also has a resize in
+	// flight.
+	AssumeSourceResize AssumeSource = "Resize"
+	// AssumeSourcePin is a pod pinned by an external binding controller
+	// (see PinPod).
+	AssumeSourcePin AssumeSource = "Pin"
+)
+
+// AssumedPodInfo describes one assumed pod: why it is assumed, when it was,
+// and when the cache will expire it. A nil deadline means the pod doesn't
+// expire yet, either because its binding still runs or because it is held.
+type AssumedPodInfo struct {
+	PodKey    string
+	Source    AssumeSource
+	AssumedAt time.Time
+	Deadline  *time.Time
+}
+
+// assumedPodAgeBuckets are the upper bounds of the age buckets the assumed
+// pod gauge is broken down by; ages past the last bound land in the overflow
+// bucket.
+var assumedPodAgeBuckets = []struct {
+	bound time.Duration
+	label string
+}{
+	{30 * time.Second, "30s"},
+	{time.Minute, "1m"},
+	{5 * time.Minute, "5m"},
+}
+
+// assumedPodAgeOverflowBucket labels ages past the last bucket bound.
+const assumedPodAgeOverflowBucket = "5m+"
+
+// assumedPodAgeBucket returns the label of the age bucket the duration falls
+// into.
+func assumedPodAgeBucket(age time.Duration) string {
+	for _, bucket := range assumedPodAgeBuckets {
+		if age < bucket.bound {
+			return bucket.label
+		}
+	}
+	return assumedPodAgeOverflowBucket
+}
+
+// assumeSourceOf classifies why the pod is assumed.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) assumeSourceOf(key string, ps *podState) AssumeSource {
+	if ps.held {
+		return AssumeSourceReservation
+	}
+	if cache.pinnedPods[key] {
+		return AssumeSourcePin
+	}
+	if cache.resizingPods[key] {
+		return AssumeSourceResize
+	}
+	return AssumeSourceBind
+}
+
+// GetAssumedPods returns every assumed pod with its source, assume time and
+// expiry deadline, sorted by pod key. Operators use it to spot binds stuck
+// long enough that expiry would silently drop them.
+func (cache *schedulerCache) GetAssumedPods() []AssumedPodInfo {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	var infos []AssumedPodInfo
+	for key, ps := range cache.podStates {
+		if !cache.assumedPods[key] && !ps.held {
+			continue
+		}
+		info := AssumedPodInfo{
+			PodKey:    key,
+			Source:    cache.assumeSourceOf(key, ps),
+			AssumedAt: ps.assumedAt,
+		}
+		if ps.deadline != nil {
+			deadline := *ps.deadline
+			info.Deadline = &deadline
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].PodKey < infos[j].PodKey })
+	return infos
+}
+
+// updateAssumedPodMetrics recomputes the per-source, per-age-bucket assumed
+// pod gauge, run from the cleanup loop.
+func (cache *schedulerCache) updateAssumedPodMetrics() {
+	now := time.Now()
+
+	cache.mu.RLock()
+	counts := make(map[AssumeSource]map[string]int)
+	for key, ps := range cache.podStates {
+		if !cache.assumedPods[key] && !ps.held {
+			continue
+		}
+		source := cache.assumeSourceOf(key, ps)
+		if counts[source] == nil {
+			counts[source] = make(map[string]int)
+		}
+		counts[source][assumedPodAgeBucket(now.Sub(ps.assumedAt))]++
+	}
+	cache.mu.RUnlock()
+
+	metrics.CacheAssumedPods.Reset()
+	for source, buckets := range counts {
+		for bucket, count := range buckets {
+			metrics.CacheAssumedPods.WithLabelValues(string(source), bucket).Set(float64(count))
+		}
+	}
+}
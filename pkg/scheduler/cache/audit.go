@@ -0,0 +1,164 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+)
+
+// AuditEntry records one cache mutation together with its input object and
+// the resulting state of the affected node, so that incidents like "the
+// scheduler placed a pod on a full node" can be reconstructed after the fact.
+type AuditEntry struct {
+	// Seq is a per-cache, strictly increasing sequence number.
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	// Op is the mutation that was applied.
+	Op        ExportEventType `json:"op"`
+	Namespace string          `json:"namespace,omitempty"`
+	Name      string          `json:"name"`
+	// NodeName is the node affected by the mutation.
+	NodeName string `json:"nodeName,omitempty"`
+	// Pod and Node are the mutation's input object; exactly one is set.
+	Pod  *v1.Pod  `json:"pod,omitempty"`
+	Node *v1.Node `json:"node,omitempty"`
+	// The node's aggregates after the mutation was applied.
+	RequestedMilliCPU int64 `json:"requestedMilliCPU"`
+	RequestedMemory   int64 `json:"requestedMemory"`
+	NumPods           int   `json:"numPods"`
+}
+
+// auditLog is an append-only log of cache mutations, capped by entry count
+// and age, optionally mirrored to a file as JSON lines.
+type auditLog struct {
+	entries    []AuditEntry
+	nextSeq    int64
+	maxEntries int
+	maxAge     time.Duration
+	file       *os.File
+}
+
+// EnableAuditLog turns on the mutation audit log, keeping up to maxEntries
+// entries no older than maxAge (zero means no age limit). If path is
+// non-empty, every entry is also appended to the file as one JSON line.
+func (cache *schedulerCache) EnableAuditLog(maxEntries int, maxAge time.Duration, path string) error {
+	var file *os.File
+	if path != "" {
+		var err error
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.audit = &auditLog{
+		nextSeq:    1,
+		maxEntries: maxEntries,
+		maxAge:     maxAge,
+		file:       file,
+	}
+	return nil
+}
+
+// AuditEntries returns a copy of the retained audit entries in order.
+func (cache *schedulerCache) AuditEntries() []AuditEntry {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	if cache.audit == nil {
+		return nil
+	}
+	entries := make([]AuditEntry, len(cache.audit.entries))
+	copy(entries, cache.audit.entries)
+	return entries
+}
+
+// record appends one entry, pruning by count and age, and mirrors it to the
+// log file if one is configured. This function assumes the lock to the
+// scheduler cache has been acquired.
+func (log *auditLog) record(entry AuditEntry) {
+	entry.Seq = log.nextSeq
+	log.nextSeq++
+	log.entries = append(log.entries, entry)
+
+	if log.maxEntries > 0 && len(log.entries) > log.maxEntries {
+		log.entries = log.entries[len(log.entries)-log.maxEntries:]
+	}
+	if log.maxAge > 0 {
+		cutoff := entry.Time.Add(-log.maxAge)
+		for len(log.entries) > 0 && log.entries[0].Time.Before(cutoff) {
+			log.entries = log.entries[1:]
+		}
+	}
+	if log.file != nil {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			line = append(line, '\n')
+			_, err = log.file.Write(line)
+		}
+		if err != nil {
+			glog.Errorf("Failed to write cache audit entry %d: %v", entry.Seq, err)
+		}
+	}
+}
+
+// replayTo is a test helper: it builds a fresh cache by re-applying the
+// logged mutations up to and including the given sequence number, so a
+// problematic cache state can be reconstructed step by step.
+func (cache *schedulerCache) replayTo(seq int64) (*schedulerCache, error) {
+	entries := cache.AuditEntries()
+	replayed := newSchedulerCache(cache.ttl, cache.period, nil)
+	for _, entry := range entries {
+		if entry.Seq > seq {
+			break
+		}
+		var err error
+		switch entry.Op {
+		case ExportEventAssumePod:
+			_, err = replayed.AssumePod(entry.Pod)
+		case ExportEventForgetPod:
+			err = replayed.ForgetPod(entry.Pod)
+		case ExportEventAddPod:
+			err = replayed.AddPod(entry.Pod)
+		case ExportEventUpdatePod, ExportEventMovePod:
+			oldPod, getErr := replayed.GetPod(entry.Pod)
+			if getErr != nil {
+				return nil, getErr
+			}
+			err = replayed.UpdatePod(oldPod, entry.Pod)
+		case ExportEventRemovePod:
+			err = replayed.RemovePod(entry.Pod)
+		case ExportEventAddNode:
+			err = replayed.AddNode(entry.Node)
+		case ExportEventUpdateNode:
+			err = replayed.UpdateNode(nil, entry.Node)
+		case ExportEventRemoveNode:
+			err = replayed.RemoveNode(entry.Node)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return replayed, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ApplyBatch applies a batch of pod and node events under one lock
+// acquisition, for informer adapters that coalesce event storms during a
+// relist. Readers never observe a partially applied batch, so downstream
+// snapshots see at most one state change per touched node no matter how many
+// events touched it. Events that fail to apply are skipped and their errors
+// returned aggregated, like Replay does; the events use the same CacheEvent
+// type the audit log records.
+func (cache *schedulerCache) ApplyBatch(events []CacheEvent) error {
+	defer cache.lockFor("ApplyBatch")()
+
+	var errs []error
+	for i, event := range events {
+		var err error
+		switch event.Op {
+		case ExportEventAssumePod:
+			_, err = cache.assumePodLocked(event.Pod)
+		case ExportEventForgetPod:
+			err = cache.forgetPodLocked(event.Pod)
+		case ExportEventAddPod:
+			err = cache.addPodLocked(event.Pod)
+		case ExportEventUpdatePod, ExportEventMovePod:
+			var key string
+			if key, err = getPodKey(event.Pod); err == nil {
+				if ps, ok := cache.podStates[key]; ok {
+					err = cache.updatePodLocked(ps.pod, event.Pod)
+				} else {
+					err = newError(ReasonPodNotFound, "pod %v does not exist in scheduler cache", key)
+				}
+			}
+		case ExportEventRemovePod:
+			err = cache.removePodLocked(event.Pod)
+		case ExportEventAddNode:
+			err = cache.addNodeLocked(event.Node)
+		case ExportEventUpdateNode:
+			err = cache.updateNodeLocked(nil, event.Node)
+		case ExportEventRemoveNode:
+			err = cache.removeNodeLocked(event.Node)
+		default:
+			err = fmt.Errorf("unknown operation %q", event.Op)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("event %d (%v): %v", i, event.Op, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
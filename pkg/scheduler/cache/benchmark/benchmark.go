@@ -0,0 +1,242 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package benchmark drives the scheduler cache with synthetic clusters and
+// reports throughput and operation latencies. Unlike the uniform-pod
+// benchmarks in the cache package itself, the profiles here vary node sizes,
+// pod density, and churn, which is where cache performance problems have
+// historically surfaced.
+package benchmark
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+)
+
+// ClusterProfile describes a synthetic cluster. The generator is
+// deterministic for a given profile and seed.
+type ClusterProfile struct {
+	Name string
+	// Nodes is the number of nodes.
+	Nodes int
+	// NodeMilliCPU and NodeMemory are the mean allocatable resources per
+	// node; individual nodes vary up to +/-50%.
+	NodeMilliCPU int64
+	NodeMemory   int64
+	// PodsPerNode is the mean pod density; individual nodes vary up to
+	// +/-50%.
+	PodsPerNode int
+	// ChurnFraction is the fraction of pods removed and replaced during a
+	// churn pass.
+	ChurnFraction float64
+	// Seed seeds the generator.
+	Seed int64
+}
+
+// Cluster is a generated synthetic cluster.
+type Cluster struct {
+	Nodes []*v1.Node
+	Pods  []*v1.Pod
+}
+
+// vary returns a value in [base/2, 3*base/2), deterministic per rng.
+func vary(rng *rand.Rand, base int64) int64 {
+	if base <= 1 {
+		return base
+	}
+	return base/2 + rng.Int63n(base)
+}
+
+// Generate builds the nodes and pods of the profile's synthetic cluster.
+func Generate(profile ClusterProfile) *Cluster {
+	rng := rand.New(rand.NewSource(profile.Seed))
+	cluster := &Cluster{}
+
+	for i := 0; i < profile.Nodes; i++ {
+		nodeName := fmt.Sprintf("%s-node-%d", profile.Name, i)
+		milliCPU := vary(rng, profile.NodeMilliCPU)
+		memory := vary(rng, profile.NodeMemory)
+		cluster.Nodes = append(cluster.Nodes, &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    *resource.NewMilliQuantity(milliCPU, resource.DecimalSI),
+					v1.ResourceMemory: *resource.NewQuantity(memory, resource.BinarySI),
+				},
+			},
+		})
+
+		pods := int(vary(rng, int64(profile.PodsPerNode)))
+		for j := 0; j < pods; j++ {
+			podName := fmt.Sprintf("%s-pod-%d-%d", profile.Name, i, j)
+			podMilliCPU := milliCPU / int64(profile.PodsPerNode*2)
+			podMemory := memory / int64(profile.PodsPerNode*2)
+			cluster.Pods = append(cluster.Pods, &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "benchmark",
+					Name:      podName,
+					UID:       types.UID(podName),
+				},
+				Spec: v1.PodSpec{
+					NodeName: nodeName,
+					Containers: []v1.Container{{
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{
+								v1.ResourceCPU:    *resource.NewMilliQuantity(podMilliCPU, resource.DecimalSI),
+								v1.ResourceMemory: *resource.NewQuantity(podMemory, resource.BinarySI),
+							},
+						},
+					}},
+				},
+			})
+		}
+	}
+	return cluster
+}
+
+// Result aggregates the measurements of one driver run.
+type Result struct {
+	// Operations is the total number of cache operations performed.
+	Operations int
+	// Duration is the wall time of the run.
+	Duration time.Duration
+	// Throughput is operations per second.
+	Throughput float64
+	// P50, P99, and Max are operation latency percentiles.
+	P50 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// String formats the result for benchmark logs.
+func (r Result) String() string {
+	return fmt.Sprintf("%d ops in %v (%.0f ops/s), p50 %v, p99 %v, max %v",
+		r.Operations, r.Duration, r.Throughput, r.P50, r.P99, r.Max)
+}
+
+// latencyRecorder collects per-operation latencies from concurrent workers.
+type latencyRecorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+}
+
+func (r *latencyRecorder) observe(d time.Duration) {
+	r.mu.Lock()
+	r.latencies = append(r.latencies, d)
+	r.mu.Unlock()
+}
+
+func (r *latencyRecorder) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(r.latencies)-1))
+	return r.latencies[i]
+}
+
+// Run populates the cache from the profile's cluster with the given number of
+// concurrent workers, then performs one churn pass removing and re-adding
+// ChurnFraction of the pods, and reports throughput and latencies.
+func Run(cache schedulercache.Cache, profile ClusterProfile, workers int) (Result, error) {
+	cluster := Generate(profile)
+	recorder := &latencyRecorder{}
+
+	var firstErr error
+	var errOnce sync.Once
+	fail := func(err error) {
+		errOnce.Do(func() { firstErr = err })
+	}
+
+	start := time.Now()
+	for _, node := range cluster.Nodes {
+		if err := cache.AddNode(node); err != nil {
+			return Result{}, err
+		}
+	}
+
+	work := make(chan *v1.Pod, len(cluster.Pods))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pod := range work {
+				opStart := time.Now()
+				if err := cache.AddPod(pod); err != nil {
+					fail(err)
+				}
+				recorder.observe(time.Since(opStart))
+			}
+		}()
+	}
+	for _, pod := range cluster.Pods {
+		work <- pod
+	}
+	close(work)
+	wg.Wait()
+
+	// Churn pass: remove and re-add a fraction of the pods.
+	churned := cluster.Pods[:int(float64(len(cluster.Pods))*profile.ChurnFraction)]
+	churn := make(chan *v1.Pod, len(churned))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pod := range churn {
+				opStart := time.Now()
+				if err := cache.RemovePod(pod); err != nil {
+					fail(err)
+				}
+				if err := cache.AddPod(pod); err != nil {
+					fail(err)
+				}
+				recorder.observe(time.Since(opStart))
+			}
+		}()
+	}
+	for _, pod := range churned {
+		churn <- pod
+	}
+	close(churn)
+	wg.Wait()
+
+	if firstErr != nil {
+		return Result{}, firstErr
+	}
+
+	duration := time.Since(start)
+	sort.Slice(recorder.latencies, func(i, j int) bool {
+		return recorder.latencies[i] < recorder.latencies[j]
+	})
+	operations := len(cluster.Nodes) + len(recorder.latencies)
+	return Result{
+		Operations: operations,
+		Duration:   duration,
+		Throughput: float64(operations) / duration.Seconds(),
+		P50:        recorder.percentile(0.50),
+		P99:        recorder.percentile(0.99),
+		Max:        recorder.percentile(1.0),
+	}, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package benchmark
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+)
+
+var profiles = []ClusterProfile{
+	{
+		Name:          "small-dense",
+		Nodes:         100,
+		NodeMilliCPU:  4000,
+		NodeMemory:    8 * 1024 * 1024 * 1024,
+		PodsPerNode:   60,
+		ChurnFraction: 0.1,
+		Seed:          42,
+	},
+	{
+		Name:          "large-sparse",
+		Nodes:         1000,
+		NodeMilliCPU:  32000,
+		NodeMemory:    128 * 1024 * 1024 * 1024,
+		PodsPerNode:   10,
+		ChurnFraction: 0.05,
+		Seed:          42,
+	},
+	{
+		Name:          "high-churn",
+		Nodes:         500,
+		NodeMilliCPU:  8000,
+		NodeMemory:    16 * 1024 * 1024 * 1024,
+		PodsPerNode:   30,
+		ChurnFraction: 0.5,
+		Seed:          42,
+	},
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	first := Generate(profiles[0])
+	second := Generate(profiles[0])
+	if len(first.Nodes) != profiles[0].Nodes {
+		t.Errorf("expected %d nodes, got: %d", profiles[0].Nodes, len(first.Nodes))
+	}
+	if len(first.Pods) != len(second.Pods) {
+		t.Errorf("expected the generator to be deterministic, got %d and %d pods", len(first.Pods), len(second.Pods))
+	}
+	if len(first.Pods) == 0 {
+		t.Error("expected the generator to produce pods")
+	}
+}
+
+func TestRun(t *testing.T) {
+	cache := schedulercache.New(time.Second, wait.NeverStop)
+	profile := profiles[0]
+	profile.Nodes = 10
+	result, err := Run(cache, profile, 4)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Operations == 0 {
+		t.Error("expected a non-zero operation count")
+	}
+	if result.P99 < result.P50 {
+		t.Errorf("expected p99 >= p50, got p50 %v, p99 %v", result.P50, result.P99)
+	}
+}
+
+func BenchmarkProfiles(b *testing.B) {
+	for _, profile := range profiles {
+		b.Run(profile.Name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				cache := schedulercache.New(time.Second, wait.NeverStop)
+				result, err := Run(cache, profile, 8)
+				if err != nil {
+					b.Fatalf("Run failed: %v", err)
+				}
+				if n == 0 {
+					b.Log(result)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// BindingHandle controls the lifecycle of one assumed pod's binding. It makes
+// the binding transaction explicit where callers previously juggled
+// FinishBinding, ForgetPod, and the expiry timer.
+type BindingHandle interface {
+	// Confirm marks the binding as finished. The assumed pod then expires
+	// after the assume TTL unless the add event confirms it first.
+	Confirm() error
+
+	// Abort forgets the assumed pod, releasing its resources immediately.
+	Abort() error
+
+	// Extend postpones expiry: the pod is kept for ttl from now even if the
+	// binding hasn't been confirmed. Asynchronous bind plugins use this to
+	// hold the assume beyond the default TTL while staying bounded — a
+	// plugin that dies after Extend leaks the pod only until the extended
+	// deadline.
+	Extend(ttl time.Duration) error
+}
+
+// bindingHandle is the BindingHandle returned by schedulerCache.AssumePod.
+type bindingHandle struct {
+	cache *schedulerCache
+	pod   *v1.Pod
+}
+
+var _ BindingHandle = &bindingHandle{}
+
+func (h *bindingHandle) Confirm() error {
+	return h.cache.FinishBinding(h.pod)
+}
+
+func (h *bindingHandle) Abort() error {
+	return h.cache.ForgetPod(h.pod)
+}
+
+func (h *bindingHandle) Extend(ttl time.Duration) error {
+	return h.cache.extendBinding(h.pod, ttl, time.Now())
+}
+
+// extendBinding moves the pod's expiry deadline to now+ttl and makes the pod
+// expirable even if its binding hasn't finished, bounding the hold.
+func (cache *schedulerCache) extendBinding(pod *v1.Pod, ttl time.Duration, now time.Time) error {
+	key, err := getPodKey(pod)
+	if err != nil {
+		return err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	currState, ok := cache.podStates[key]
+	if !ok || !cache.assumedPods[key] {
+		return newError(ReasonPodNotAssumed, "pod %v is not assumed, so its binding can't be extended", key)
+	}
+	dl := now.Add(ttl)
+	currState.bindingFinished = true
+	currState.deadline = &dl
+	return nil
+}
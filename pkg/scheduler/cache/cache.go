@@ -17,16 +17,21 @@ limitations under the License.
 package cache
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/kubernetes/pkg/features"
+	"k8s.io/kubernetes/pkg/scheduler/metrics"
 
 	"github.com/golang/glog"
 	policy "k8s.io/api/policy/v1beta1"
@@ -34,34 +39,355 @@ import (
 
 var (
 	cleanAssumedPeriod = 1 * time.Second
+	compactionPeriod   = 10 * time.Minute
 )
 
+// compactionSizeThreshold is the minimum peak map size before compaction
+// considers rebuilding a map to release memory.
+const compactionSizeThreshold = 1024
+
+// AnnotationPodSchedulingGate marks a pod as held: the pod is tracked by the
+// cache but doesn't consume node capacity or count as assumed until the gate
+// clears and ReleasePod is called.
+const AnnotationPodSchedulingGate = "scheduler.alpha.kubernetes.io/scheduling-gate"
+
+// isPodHeld returns true if the pod carries a non-empty scheduling-gate
+// annotation.
+func isPodHeld(pod *v1.Pod) bool {
+	return pod.Annotations[AnnotationPodSchedulingGate] != ""
+}
+
+// PodGetter returns the full pod object for the given namespace and name,
+// typically backed by the scheduler's pod informer.
+type PodGetter func(namespace, name string) (*v1.Pod, error)
+
+// AssumeAdmitFunc is called inside AssumePod with the pod and the NodeInfo of
+// its target node (nil if the node isn't in the cache yet). Returning an
+// error vetoes the assume before the cache commits it.
+type AssumeAdmitFunc func(pod *v1.Pod, nodeInfo *NodeInfo) error
+
+// SetAssumeAdmitFunc installs an admission hook that can veto assumes, e.g.
+// to enforce tenant capacity guards.
+func (cache *schedulerCache) SetAssumeAdmitFunc(fn AssumeAdmitFunc) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.admitAssume = fn
+}
+
+// EnablePodTrimming makes the cache store trimmed pod copies instead of the
+// full informer objects. getter, if non-nil, is used by GetFullPod to
+// reconstruct full objects on demand.
+func (cache *schedulerCache) EnablePodTrimming(getter PodGetter) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.trimPods = true
+	cache.podGetter = getter
+}
+
+// GetFullPod returns the untrimmed pod object. If trimming is off or no pod
+// getter is configured, it returns the cached pod.
+func (cache *schedulerCache) GetFullPod(pod *v1.Pod) (*v1.Pod, error) {
+	cache.mu.RLock()
+	trimmed, getter := cache.trimPods, cache.podGetter
+	cache.mu.RUnlock()
+
+	if trimmed && getter != nil {
+		return getter(pod.Namespace, pod.Name)
+	}
+	return cache.GetPod(pod)
+}
+
+// trimPod returns a copy of the pod with the fields no predicate or priority
+// reads removed, to bound cache memory on large clusters. The original object
+// owned by the informer is left untouched.
+func trimPod(pod *v1.Pod) *v1.Pod {
+	trimmed := pod.DeepCopy()
+	trimContainers(trimmed.Spec.InitContainers)
+	trimContainers(trimmed.Spec.Containers)
+	trimmed.Spec.ImagePullSecrets = nil
+	trimmed.Status = v1.PodStatus{Phase: pod.Status.Phase}
+	return trimmed
+}
+
+func trimContainers(containers []v1.Container) {
+	for i := range containers {
+		c := &containers[i]
+		c.Command = nil
+		c.Args = nil
+		c.Env = nil
+		c.EnvFrom = nil
+		c.VolumeMounts = nil
+		c.VolumeDevices = nil
+		c.LivenessProbe = nil
+		c.ReadinessProbe = nil
+		c.Lifecycle = nil
+	}
+}
+
+// isStalePod returns true if newPod carries an older resourceVersion than the
+// cached pod. ResourceVersions are compared numerically, which holds for
+// etcd-backed API servers; pods whose versions don't parse are never
+// considered stale.
+func isStalePod(cachedPod, newPod *v1.Pod) bool {
+	cachedRV, err := strconv.ParseUint(cachedPod.ResourceVersion, 10, 64)
+	if err != nil {
+		return false
+	}
+	newRV, err := strconv.ParseUint(newPod.ResourceVersion, 10, 64)
+	if err != nil {
+		return false
+	}
+	return newRV < cachedRV
+}
+
+// CacheOptions configures a Cache built by NewWithOptions.
+type CacheOptions struct {
+	// TTL is how long an assumed pod stays before expiring once its binding
+	// finished.
+	TTL time.Duration
+	// CleanupPeriod is the interval between expiry scans. Zero means the
+	// default of 1 second.
+	CleanupPeriod time.Duration
+	// CleanupJitter, when positive, spreads the scans by sleeping up to
+	// CleanupJitter*CleanupPeriod longer between them. This keeps several
+	// schedulers sharing a store from scanning in lockstep.
+	CleanupJitter float64
+	// FeatureGate is the gate reader the cache consults (e.g. for
+	// BalanceAttachedNodeVolumes) instead of the process-global
+	// DefaultFeatureGate, so caches with different behavior can coexist in
+	// one process. Nil means the global gate.
+	FeatureGate utilfeature.FeatureGate
+}
+
 // New returns a Cache implementation.
 // It automatically starts a go routine that manages expiration of assumed pods.
 // "ttl" is how long the assumed pod will get expired.
 // "stop" is the channel that would close the background goroutine.
 func New(ttl time.Duration, stop <-chan struct{}) Cache {
-	cache := newSchedulerCache(ttl, cleanAssumedPeriod, stop)
+	return NewWithOptions(CacheOptions{TTL: ttl}, stop)
+}
+
+// NewWithOptions returns a Cache implementation configured by opts. Very large
+// clusters use it to tune the expiry-scan cost; New covers everyone else.
+func NewWithOptions(opts CacheOptions, stop <-chan struct{}) Cache {
+	period := opts.CleanupPeriod
+	if period <= 0 {
+		period = cleanAssumedPeriod
+	}
+	cache := newSchedulerCache(opts.TTL, period, stop)
+	cache.periodJitter = opts.CleanupJitter
+	if opts.FeatureGate != nil {
+		cache.featureGate = opts.FeatureGate
+	}
 	cache.run()
 	return cache
 }
 
 type schedulerCache struct {
-	stop   <-chan struct{}
-	ttl    time.Duration
-	period time.Duration
+	stop <-chan struct{}
+	ttl  time.Duration
+	// period and periodJitter pace the cleanup loop; both are guarded by mu
+	// so they can be adjusted at runtime.
+	period       time.Duration
+	periodJitter float64
+	// featureGate is the gate reader consulted for feature checks. It is
+	// set at construction and never mutated, so caches with different
+	// gates can coexist in one process.
+	featureGate utilfeature.FeatureGate
 
 	// This mutex guards all fields within this cache struct.
 	mu sync.RWMutex
 	// a set of assumed pod keys.
 	// The key could further be used to get an entry in podStates.
 	assumedPods map[string]bool
+	// the subset of assumed pods reserved via PinPod on behalf of an
+	// external binding controller (see pin.go).
+	pinnedPods map[string]bool
+	// gang reservations: a map from group name to its member pod keys and
+	// the reverse index (see reservation_group.go).
+	reservationGroups map[string][]string
+	podGroups         map[string]string
 	// a map from pod key to podState.
 	podStates map[string]*podState
-	nodes     map[string]*NodeInfo
-	pdbs      map[string]*policy.PodDisruptionBudget
+	// holds the NodeInfos and pod objects; pluggable so an external backend
+	// can be shared by multiple schedulers.
+	store Store
+	// a map from PDB namespace to a map from PDB UID to its pdbInfo.
+	pdbs map[string]map[string]*pdbInfo
+	// a map from scheduler profile name to the assume TTL used for pods of
+	// that profile. Profiles without an entry use the cache-wide ttl.
+	profileTTLs map[string]time.Duration
+	// If true, pods are stored in trimmed form to bound cache memory.
+	trimPods bool
+	// Used to reconstruct full pod objects on demand when trimming is on.
+	podGetter PodGetter
+	// If true, pods of the same controller share one canonical container
+	// spec slice, keyed by the owner's UID (see pod_dedup.go).
+	dedupPodSpecs  bool
+	dedupTemplates map[types.UID]*podTemplate
+	// If true, assumes on unknown or cordoned nodes are rejected instead
+	// of tolerated (see strict_assume.go).
+	strictAssume bool
+	// If true, resize fit checks count the requests of terminating pods as
+	// free capacity (see terminating.go).
+	optimisticTerminating bool
+	// Cache-wide and per-namespace overrides of the requests assumed for
+	// zero-request containers; both nil means the compiled-in 100m/200Mi
+	// (see zero_request.go).
+	zeroRequestDefaults *ZeroRequestDefaults
+	zeroRequestPolicy   ZeroRequestPolicy
+	// If set, called inside AssumePod and able to veto the assume before
+	// the cache commits it.
+	admitAssume AssumeAdmitFunc
+	// The largest size podStates has reached since the last compaction. Go
+	// maps never shrink, so this drives rebuilding oversized maps.
+	podStatesPeak int
+	// an index from topology label key to label value to the names of nodes
+	// carrying that label.
+	topology map[string]map[string]sets.String
+	// per-constraint pod counts by topology domain, maintained incrementally
+	// for the even-pod-spread logic.
+	spreadCounts map[string]*spreadCounts
+	// subscribers to NodeInfo field change events.
+	invalidationListeners []InvalidationListener
+	// If non-nil, cache mutations are streamed to external consumers via
+	// the export gRPC service.
+	export *exportServer
+	// If non-nil, cache mutations are recorded for post-incident analysis.
+	audit *auditLog
+	// When not VerificationOff, every pod mutation is checked against a
+	// from-scratch rebuild of the node's accounting.
+	verification VerificationMode
+	// Selects Capacity or Allocatable, per resource, for fit checks.
+	fitPolicy FitResourcePolicy
+	// Over-commit multipliers applied to nodes by label; first match wins.
+	overCommitRules []OverCommitRule
+	// Guaranteed capacity pools; their reservations are subtracted from the
+	// effective allocatable seen by non-member pods in fit checks.
+	capacityPools []CapacityPool
+	// a map from pod key to when the pod was first seen orphaned, i.e. its
+	// node's NodeInfo missing or its node object deleted.
+	orphanedPods map[string]time.Time
+	// If positive, pods orphaned for longer than this are purged.
+	orphanGracePeriod time.Duration
+	// If non-nil, cache operations are wrapped in tracing spans.
+	tracer Tracer
+	// Lock contention profiling: when lockProfileRate is positive, one in
+	// that many lock acquisitions per operation is timed into lockProfiles.
+	// The rate and sequence are atomics, the profiles have their own mutex -
+	// the profiler can't take the lock it is measuring (see lock_profile.go).
+	lockProfileRate int64
+	lockProfileSeq  int64
+	lockProfileMu   sync.Mutex
+	lockProfiles    map[string]*lockOpProfile
+	// a map from pod key to the growth headroom currently reserved for it.
+	headroomByPod map[string]*Resource
+	// a map from pod key to the resources recommended for it, fed in by an
+	// external recommender via SetRecommendedResources.
+	recommendations map[string]*Resource
+	// Granularity recommendations are rounded to as they are ingested;
+	// non-positive values leave the resource unrounded.
+	resizeCPUGranularity    int64
+	resizeMemoryGranularity int64
+	// If set, called inside PlanResize and able to veto an in-place resize
+	// that would violate a scheduling predicate.
+	resizeFitPredicate FitPredicate
+	// a map from pod key to when the pod's last resize completed, and the
+	// minimum time PlanResize keeps between resizes of the same pod.
+	lastResize     map[string]time.Time
+	resizeCooldown time.Duration
+	// a map from pod key to the content hash of the last ingested resize
+	// request annotation, for deduplicating controller re-syncs.
+	resizeRequestHashes map[string]uint64
+	// the same, for the kubelet's resize status annotation.
+	resizeStatusHashes map[string]uint64
+	// Per-node resize rate budget and the starts charged against it, a map
+	// from node name to the start times still inside the sliding window.
+	// A non-positive limit disables the cap (see resize_priority.go).
+	resizeRateLimit  int
+	resizeRateWindow time.Duration
+	resizeStarts     map[string][]time.Time
+	// How long after a completed resize its annotations are kept, and the
+	// injected patch used to clear them. Non-positive disables the sweep.
+	resizeGCRetention time.Duration
+	podPatch          PodPatchFunc
+	// Pods with a resize in flight and pods marked as preemption victims;
+	// each set defers the opposite action (see resize_interlock.go).
+	resizingPods       map[string]bool
+	pendingPreemptions map[string]bool
+	// Periodic checkpointing of failover-relevant state through the
+	// injected writer (see checkpoint.go). Zero interval disables it.
+	checkpointInterval time.Duration
+	checkpointWrite    CheckpointWriteFunc
+	lastCheckpoint     time.Time
+	// If non-nil, feature vectors for pending pods are periodically
+	// appended to the configured writer (see score_export.go).
+	scoreExport *scoreDataExporter
+	// If non-nil, maps PVCs to their CSI driver so pods' volumes are
+	// charged against per-driver attach slots (see csi_node.go).
+	pvcDriverResolver PVCDriverResolver
+	// a set of "namespace/claim" keys for PVCs bound to node-local PVs;
+	// pods using them can't be deleted for resizing (see local_volumes.go).
+	nodeLocalPVCs map[string]bool
+	// Cache-wide cap on how many pods from one namespace may land on one
+	// node; non-positive disables it (see namespace_limit.go).
+	namespacePodLimit int
+	// Memoized admission results per node and pod template hash, tagged
+	// with the node generation they were computed at (see fit_cache.go).
+	fitResults map[string]map[string]fitResult
+	// a map from controller UID to the pods it owns, keyed by pod key
+	// (see owner_index.go).
+	podsByOwner map[types.UID]map[string]*v1.Pod
+	// Queued write-behind annotation mutations per pod key and the
+	// injected updater that applies them (see mutation_queue.go).
+	podMutations map[string]*podMutation
+	podUpdate    PodUpdateFunc
+	// Bounded journal of disruption-budget decisions, self-locked so
+	// read-locked paths can record into it (see pdb_journal.go).
+	pdbJournal pdbJournal
+	// Stuck-resize watchdog config and state: the in-flight age threshold,
+	// the injected handler, and per pod when the resize started and how
+	// often it already timed out (see resize_watchdog.go).
+	resizeWatchdogThreshold time.Duration
+	resizeStuck             ResizeStuckFunc
+	resizeStartedAt         map[string]time.Time
+	resizeTimeouts          map[string]int
+	// Maximum heartbeat age up to which nodes accept new assumes and
+	// in-place resizes; zero disables the gate (see heartbeat.go).
+	heartbeatThreshold time.Duration
+	// a map from node name to the deadline of its registered maintenance
+	// intent; such nodes don't accept new assumes or in-place resizes.
+	maintenance map[string]time.Time
+	// a map from pod key to the pod's indexed required anti-affinity terms,
+	// maintained incrementally for the symmetry check.
+	antiAffinityTerms map[string][]antiAffinityTerm
+	// a map from pod key to the pod's last reported ephemeral storage
+	// usage, in bytes.
+	podUsage map[string]int64
+	// Right-sizing analyzer config and state: the over-provisioning factor
+	// and how long it must be sustained, the injected suggestion sink, and
+	// per pod when the current streak started and whether it was already
+	// reported (see downsize.go). A non-positive factor or duration
+	// disables the analyzer.
+	downsizeFactor       float64
+	downsizeWindow       time.Duration
+	downsizeEmit         DownsizeSuggestFunc
+	overProvisionedSince map[string]time.Time
+	downsizeEmitted      map[string]bool
 	// A map from image name to its imageState.
 	imageStates map[string]*imageState
+	// Registry bandwidth, in bytes per second, assumed when estimating
+	// image pull times. Non-positive means the default.
+	pullBandwidth int64
+
+	// Serializes snapshot builds. Separate from mu so a build competes with
+	// writers only while copying, not while assembling the snapshot.
+	snapshotMu sync.Mutex
+	// The last published snapshot and the node generations it was built
+	// from; the next build clones only nodes whose generation moved and
+	// reuses the rest. Guarded by snapshotMu.
+	lastSnapshot     *Snapshot
+	lastSnapshotGens map[string]int64
 }
 
 type podState struct {
@@ -70,6 +396,16 @@ type podState struct {
 	deadline *time.Time
 	// Used to block cache from expiring assumedPod if binding still runs
 	bindingFinished bool
+	// The scheduler profile (scheduler name) the pod was assumed under.
+	profile string
+	// Set for pods carrying a scheduling-gate annotation. Held pods are
+	// tracked but don't consume node capacity until released.
+	held bool
+	// Timestamps of the binding cycle: when the pod was assumed, when its
+	// binding finished, and when the add event confirmed the binding.
+	assumedAt         time.Time
+	bindingFinishedAt time.Time
+	confirmedAt       time.Time
 }
 
 type imageState struct {
@@ -85,40 +421,87 @@ type ImageStateSummary struct {
 	Size int64
 	// Used to track how many nodes have this image
 	NumNodes int
+	// Estimated time to pull the image at the configured registry
+	// bandwidth (see SetImagePullBandwidth).
+	EstimatedPullTime time.Duration
 }
 
 // createImageStateSummary returns a summarizing snapshot of the given image's state.
 func (cache *schedulerCache) createImageStateSummary(state *imageState) *ImageStateSummary {
 	return &ImageStateSummary{
-		Size:     state.size,
-		NumNodes: len(state.nodes),
+		Size:              state.size,
+		NumNodes:          len(state.nodes),
+		EstimatedPullTime: estimatePullDuration(state.size, cache.imagePullBandwidth()),
 	}
 }
 
 func newSchedulerCache(ttl, period time.Duration, stop <-chan struct{}) *schedulerCache {
 	return &schedulerCache{
-		ttl:    ttl,
-		period: period,
-		stop:   stop,
-
-		nodes:       make(map[string]*NodeInfo),
-		assumedPods: make(map[string]bool),
-		podStates:   make(map[string]*podState),
-		pdbs:        make(map[string]*policy.PodDisruptionBudget),
-		imageStates: make(map[string]*imageState),
+		ttl:         ttl,
+		period:      period,
+		stop:        stop,
+		featureGate: utilfeature.DefaultFeatureGate,
+
+		store:                NewMapStore(),
+		assumedPods:          make(map[string]bool),
+		pinnedPods:           make(map[string]bool),
+		reservationGroups:    make(map[string][]string),
+		podGroups:            make(map[string]string),
+		podStates:            make(map[string]*podState),
+		pdbs:                 make(map[string]map[string]*pdbInfo),
+		imageStates:          make(map[string]*imageState),
+		profileTTLs:          make(map[string]time.Duration),
+		topology:             make(map[string]map[string]sets.String),
+		spreadCounts:         make(map[string]*spreadCounts),
+		orphanedPods:         make(map[string]time.Time),
+		headroomByPod:        make(map[string]*Resource),
+		recommendations:      make(map[string]*Resource),
+		maintenance:          make(map[string]time.Time),
+		antiAffinityTerms:    make(map[string][]antiAffinityTerm),
+		podUsage:             make(map[string]int64),
+		lastResize:           make(map[string]time.Time),
+		resizeRequestHashes:  make(map[string]uint64),
+		resizeStatusHashes:   make(map[string]uint64),
+		resizingPods:         make(map[string]bool),
+		pendingPreemptions:   make(map[string]bool),
+		resizeStarts:         make(map[string][]time.Time),
+		overProvisionedSince: make(map[string]time.Time),
+		downsizeEmitted:      make(map[string]bool),
+		nodeLocalPVCs:        make(map[string]bool),
+		fitResults:           make(map[string]map[string]fitResult),
+		podsByOwner:          make(map[types.UID]map[string]*v1.Pod),
+		podMutations:         make(map[string]*podMutation),
+		resizeStartedAt:      make(map[string]time.Time),
+		resizeTimeouts:       make(map[string]int),
+		dedupTemplates:       make(map[types.UID]*podTemplate),
 	}
 }
 
-// Snapshot takes a snapshot of the current schedulerCache. The method has performance impact,
-// and should be only used in non-critical path.
+// Snapshot takes a snapshot of the current schedulerCache. Builds are
+// incremental: only nodes changed since the previous snapshot are cloned, the
+// rest are shared with it, so the cache lock is held in proportion to the
+// churn rather than the cluster size. The returned snapshot must therefore be
+// treated as read-only.
 func (cache *schedulerCache) Snapshot() *Snapshot {
-	cache.mu.RLock()
-	defer cache.mu.RUnlock()
+	s, _ := cache.SnapshotWithContext(context.Background())
+	return s
+}
 
+// snapshotLocked builds the snapshot to publish, cloning only the nodes whose
+// generation moved since the last published snapshot and reusing the rest.
+// Assumes that both snapshotMu and the cache lock are already acquired.
+func (cache *schedulerCache) snapshotLocked() *Snapshot {
+	gens := make(map[string]int64)
 	nodes := make(map[string]*NodeInfo)
-	for k, v := range cache.nodes {
+	cache.store.RangeNodeInfos(func(k string, v *NodeInfo) bool {
+		gens[k] = v.generation
+		if prev, ok := cache.lastSnapshotGens[k]; ok && prev == v.generation {
+			nodes[k] = cache.lastSnapshot.Nodes[k]
+			return true
+		}
 		nodes[k] = v.Clone()
-	}
+		return true
+	})
 
 	assumedPods := make(map[string]bool)
 	for k, v := range cache.assumedPods {
@@ -126,35 +509,171 @@ func (cache *schedulerCache) Snapshot() *Snapshot {
 	}
 
 	pdbs := make(map[string]*policy.PodDisruptionBudget)
-	for k, v := range cache.pdbs {
-		pdbs[k] = v.DeepCopy()
+	for _, nsPDBs := range cache.pdbs {
+		for k, v := range nsPDBs {
+			if v.v1beta1 != nil {
+				pdbs[k] = v.v1beta1.DeepCopy()
+			}
+		}
+	}
+
+	var maintenance map[string]time.Time
+	if len(cache.maintenance) > 0 {
+		now := time.Now()
+		maintenance = make(map[string]time.Time, len(cache.maintenance))
+		for nodeName, deadline := range cache.maintenance {
+			if now.Before(deadline) {
+				maintenance[nodeName] = deadline
+			}
+		}
 	}
 
-	return &Snapshot{
-		Nodes:       nodes,
-		AssumedPods: assumedPods,
-		Pdbs:        pdbs,
+	s := &Snapshot{
+		Nodes:                nodes,
+		AssumedPods:          assumedPods,
+		Pdbs:                 pdbs,
+		MaintenanceDeadlines: maintenance,
 	}
+	cache.lastSnapshot = s
+	cache.lastSnapshotGens = gens
+	return s
 }
 
-func (cache *schedulerCache) UpdateNodeNameToInfoMap(nodeNameToInfo map[string]*NodeInfo) error {
+func (cache *schedulerCache) UpdateNodeNameToInfoMap(nodeNameToInfo map[string]*NodeInfo) ([]string, error) {
 	cache.mu.Lock()
 	defer cache.mu.Unlock()
 
-	for name, info := range cache.nodes {
-		if utilfeature.DefaultFeatureGate.Enabled(features.BalanceAttachedNodeVolumes) && info.TransientInfo != nil {
+	var changed []string
+	cache.store.RangeNodeInfos(func(name string, info *NodeInfo) bool {
+		if cache.featureGate.Enabled(features.BalanceAttachedNodeVolumes) && info.TransientInfo != nil {
 			// Transient scheduler info is reset here.
 			info.TransientInfo.resetTransientSchedulerInfo()
 		}
 		if current, ok := nodeNameToInfo[name]; !ok || current.generation != info.generation {
 			nodeNameToInfo[name] = info.Clone()
+			changed = append(changed, name)
 		}
-	}
+		return true
+	})
 	for name := range nodeNameToInfo {
-		if _, ok := cache.nodes[name]; !ok {
+		if _, ok := cache.store.GetNodeInfo(name); !ok {
 			delete(nodeNameToInfo, name)
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// ClusterCapacitySummary aggregates the capacity view of the whole cluster.
+type ClusterCapacitySummary struct {
+	// Allocatable is the sum of the allocatable resources of all nodes.
+	Allocatable Resource
+	// Requested is the sum of the requested resources of all pods, including
+	// assumed ones.
+	Requested Resource
+	// Headroom is Allocatable minus Requested, clamped at zero per resource.
+	Headroom Resource
+	// Nodes is the total number of nodes, ReadyNodes the subset reporting a
+	// true Ready condition, and DeletingNodes the ones whose node object is
+	// deleted but still hold pods.
+	Nodes         int
+	ReadyNodes    int
+	DeletingNodes int
+}
+
+// ClusterCapacity returns a summary of total allocatable versus requested
+// resources and node counts by readiness. It sums the per-node aggregates the
+// cache already maintains incrementally, so its cost is proportional to the
+// number of nodes, not pods.
+func (cache *schedulerCache) ClusterCapacity() *ClusterCapacitySummary {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	summary := &ClusterCapacitySummary{}
+	cache.store.RangeNodeInfos(func(_ string, n *NodeInfo) bool {
+		if n.state == NodeDeleting {
+			summary.DeletingNodes++
+			return true
+		}
+		summary.Nodes++
+		if nodeReady(n.node) {
+			summary.ReadyNodes++
+		}
+		summary.Allocatable.addResource(n.allocatableResource)
+		summary.Requested.addResource(n.requestedResource)
+		return true
+	})
+	summary.Headroom = *summary.Allocatable.Clone()
+	summary.Headroom.subResource(&summary.Requested)
+	return summary
+}
+
+// nodeReady returns true if the node reports a true Ready condition.
+func nodeReady(node *v1.Node) bool {
+	if node == nil {
+		return false
+	}
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == v1.NodeReady {
+			return node.Status.Conditions[i].Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Resync replaces the cache's nodes, pods, and PDBs with the given
+// authoritative lists, preserving assumed and held pods that have not been
+// confirmed yet. The new state is built before the cache lock is taken, so
+// readers are only blocked for the duration of the final swap.
+func (cache *schedulerCache) Resync(nodes []*v1.Node, pods []*v1.Pod, pdbs []*policy.PodDisruptionBudget) error {
+	shadow := newSchedulerCache(cache.ttl, cache.period, nil)
+	shadow.featureGate = cache.featureGate
+	for _, node := range nodes {
+		if err := shadow.AddNode(node); err != nil {
+			return err
+		}
+	}
+	for _, pod := range pods {
+		if err := shadow.AddPod(pod); err != nil {
+			return err
+		}
+	}
+	for _, pdb := range pdbs {
+		if err := shadow.AddPDB(pdb); err != nil {
+			return err
+		}
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Carry over assumed and held pods the authoritative lists don't know
+	// about yet; pods present in the lists have been confirmed meanwhile and
+	// the entries built from the lists win.
+	for key, ps := range cache.podStates {
+		if !cache.assumedPods[key] && !ps.held {
+			continue
+		}
+		if _, ok := shadow.podStates[key]; ok {
+			continue
+		}
+		if !ps.held {
+			shadow.addPod(ps.pod)
+			shadow.assumedPods[key] = true
 		}
+		shadow.podStates[key] = ps
+		shadow.store.SetPod(key, ps.pod)
 	}
+
+	cache.store = shadow.store
+	cache.podStates = shadow.podStates
+	cache.assumedPods = shadow.assumedPods
+	cache.pdbs = shadow.pdbs
+	cache.imageStates = shadow.imageStates
+	cache.topology = shadow.topology
+	cache.spreadCounts = shadow.spreadCounts
+	cache.podStatesPeak = len(cache.podStates)
 	return nil
 }
 
@@ -166,45 +685,148 @@ func (cache *schedulerCache) List(selector labels.Selector) ([]*v1.Pod, error) {
 func (cache *schedulerCache) FilteredList(podFilter PodFilter, selector labels.Selector) ([]*v1.Pod, error) {
 	cache.mu.RLock()
 	defer cache.mu.RUnlock()
+	return cache.filteredListLocked(podFilter, selector)
+}
+
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) filteredListLocked(podFilter PodFilter, selector labels.Selector) ([]*v1.Pod, error) {
 	// podFilter is expected to return true for most or all of the pods. We
 	// can avoid expensive array growth without wasting too much memory by
 	// pre-allocating capacity.
 	maxSize := 0
-	for _, info := range cache.nodes {
+	cache.store.RangeNodeInfos(func(_ string, info *NodeInfo) bool {
 		maxSize += len(info.pods)
-	}
+		return true
+	})
 	pods := make([]*v1.Pod, 0, maxSize)
-	for _, info := range cache.nodes {
+	cache.store.RangeNodeInfos(func(_ string, info *NodeInfo) bool {
 		for _, pod := range info.pods {
 			if podFilter(pod) && selector.Matches(labels.Set(pod.Labels)) {
 				pods = append(pods, pod)
 			}
 		}
-	}
+		return true
+	})
 	return pods, nil
 }
 
-func (cache *schedulerCache) AssumePod(pod *v1.Pod) error {
+func (cache *schedulerCache) AssumePod(pod *v1.Pod) (BindingHandle, error) {
+	return cache.AssumePodWithContext(context.Background(), pod)
+}
+
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) assumePodLocked(pod *v1.Pod) (BindingHandle, error) {
 	key, err := getPodKey(pod)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
 	if _, ok := cache.podStates[key]; ok {
-		return fmt.Errorf("pod %v is in the cache, so can't be assumed", key)
+		return nil, newError(ReasonPodAlreadyAssumed, "pod %v is in the cache, so can't be assumed", key)
+	}
+	if n, ok := cache.store.GetNodeInfo(pod.Spec.NodeName); ok && n.state == NodeDeleting {
+		return nil, newError(ReasonNodeDeleting, "node %v is being deleted, so pod %v can't be assumed on it", pod.Spec.NodeName, key)
+	}
+	if err := cache.checkStrictAssume(key, pod); err != nil {
+		return nil, err
+	}
+	if cache.nodeUnderMaintenance(pod.Spec.NodeName, time.Now()) {
+		return nil, newError(ReasonNodeUnderMaintenance, "node %v has maintenance scheduled, so pod %v can't be assumed on it", pod.Spec.NodeName, key)
+	}
+	if n, ok := cache.store.GetNodeInfo(pod.Spec.NodeName); ok && cache.nodeHeartbeatStale(n, time.Now()) {
+		return nil, newError(ReasonNodeHeartbeatStale, "node %v stopped renewing its lease, so pod %v can't be assumed on it", pod.Spec.NodeName, key)
+	}
+	if n, ok := cache.store.GetNodeInfo(pod.Spec.NodeName); ok {
+		if limit, count, exceeded := cache.namespaceAtPodLimit(pod, n); exceeded {
+			return nil, newError(ReasonNamespacePodLimit, "namespace %v already has %v of at most %v pods on node %v, so pod %v can't be assumed on it", pod.Namespace, count, limit, pod.Spec.NodeName, key)
+		}
+	}
+	if n, ok := cache.store.GetNodeInfo(pod.Spec.NodeName); ok {
+		if err := n.validateCPUPool(pod); err != nil {
+			return nil, err
+		}
+	}
+	if cache.admitAssume != nil {
+		nodeInfo, _ := cache.store.GetNodeInfo(pod.Spec.NodeName)
+		if err := cache.admitAssume(pod, nodeInfo); err != nil {
+			return nil, newError(ReasonAssumeRejected, "pod %v can't be assumed on node %v: %v", key, pod.Spec.NodeName, err)
+		}
 	}
 
-	cache.addPod(pod)
+	if cache.trimPods {
+		pod = trimPod(pod)
+	}
+	if cache.dedupPodSpecs {
+		pod = cache.dedupPodSpec(pod)
+	}
 	ps := &podState{
-		pod: pod,
+		pod:       pod,
+		profile:   pod.Spec.SchedulerName,
+		assumedAt: time.Now(),
+	}
+	handle := &bindingHandle{cache: cache, pod: pod}
+	if isPodHeld(pod) {
+		// Held pods are tracked but don't consume node capacity or count as
+		// assumed until ReleasePod is called.
+		ps.held = true
+		cache.podStates[key] = ps
+		cache.store.SetPod(key, pod)
+		cache.recordPodStatesPeak()
+		cache.recordMutation(ExportEventAssumePod, pod, nil)
+		return handle, nil
 	}
+	cache.addPod(pod)
 	cache.podStates[key] = ps
+	cache.store.SetPod(key, pod)
 	cache.assumedPods[key] = true
+	cache.recordPodStatesPeak()
+	cache.recordMutation(ExportEventAssumePod, pod, nil)
+	return handle, nil
+}
+
+// ReleasePod clears the hold on a pod that was assumed while carrying a
+// scheduling-gate annotation, committing its resources to its node and
+// starting the normal assumed-pod lifecycle.
+func (cache *schedulerCache) ReleasePod(podKey string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	ps, ok := cache.podStates[podKey]
+	if !ok {
+		return newError(ReasonPodNotFound, "pod %v is not in the cache, so can't be released", podKey)
+	}
+	if !ps.held {
+		return newError(ReasonPodNotHeld, "pod %v is not held, so can't be released", podKey)
+	}
+	ps.held = false
+	cache.addPod(ps.pod)
+	cache.assumedPods[podKey] = true
 	return nil
 }
 
+// SetProfileTTL sets the assume TTL for pods scheduled by the given profile
+// (scheduler name). Pods of profiles without an explicit TTL use the
+// cache-wide one.
+func (cache *schedulerCache) SetProfileTTL(profile string, ttl time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.profileTTLs[profile] = ttl
+}
+
+// AssumedPodsByProfile returns the number of assumed pods per scheduler
+// profile (scheduler name).
+func (cache *schedulerCache) AssumedPodsByProfile() map[string]int {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for key := range cache.assumedPods {
+		if ps, ok := cache.podStates[key]; ok {
+			counts[ps.profile]++
+		}
+	}
+	return counts
+}
+
 func (cache *schedulerCache) FinishBinding(pod *v1.Pod) error {
 	return cache.finishBinding(pod, time.Now())
 }
@@ -222,25 +844,56 @@ func (cache *schedulerCache) finishBinding(pod *v1.Pod, now time.Time) error {
 	glog.V(5).Infof("Finished binding for pod %v. Can be expired.", key)
 	currState, ok := cache.podStates[key]
 	if ok && cache.assumedPods[key] {
-		dl := now.Add(cache.ttl)
+		ttl := cache.ttl
+		if profileTTL, ok := cache.profileTTLs[currState.profile]; ok {
+			ttl = profileTTL
+		}
+		dl := now.Add(ttl)
 		currState.bindingFinished = true
+		currState.bindingFinishedAt = now
 		currState.deadline = &dl
+		metrics.BindingFinishLatency.Observe(float64(now.Sub(currState.assumedAt).Nanoseconds() / time.Microsecond.Nanoseconds()))
 	}
 	return nil
 }
 
+// BindingLatency returns the time between the pod being assumed and its add
+// event confirming the binding. It returns an error if the pod hasn't gone
+// through a complete binding cycle in this cache.
+func (cache *schedulerCache) BindingLatency(pod *v1.Pod) (time.Duration, error) {
+	key, err := getPodKey(pod)
+	if err != nil {
+		return 0, err
+	}
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	ps, ok := cache.podStates[key]
+	if !ok {
+		return 0, newError(ReasonPodNotFound, "pod %v does not exist in scheduler cache", key)
+	}
+	if ps.assumedAt.IsZero() || ps.confirmedAt.IsZero() {
+		return 0, fmt.Errorf("pod %v hasn't completed a binding cycle", key)
+	}
+	return ps.confirmedAt.Sub(ps.assumedAt), nil
+}
+
 func (cache *schedulerCache) ForgetPod(pod *v1.Pod) error {
+	defer cache.lockFor("ForgetPod")()
+	return cache.forgetPodLocked(pod)
+}
+
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) forgetPodLocked(pod *v1.Pod) error {
 	key, err := getPodKey(pod)
 	if err != nil {
 		return err
 	}
 
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
-
 	currState, ok := cache.podStates[key]
 	if ok && currState.pod.Spec.NodeName != pod.Spec.NodeName {
-		return fmt.Errorf("pod %v was assumed on %v but assigned to %v", key, pod.Spec.NodeName, currState.pod.Spec.NodeName)
+		return newError(ReasonNodeMismatch, "pod %v was assumed on %v but assigned to %v", key, pod.Spec.NodeName, currState.pod.Spec.NodeName)
 	}
 
 	switch {
@@ -251,21 +904,40 @@ func (cache *schedulerCache) ForgetPod(pod *v1.Pod) error {
 			return err
 		}
 		delete(cache.assumedPods, key)
+		delete(cache.pinnedPods, key)
+		cache.dropFromGroup(key)
+		delete(cache.podStates, key)
+		cache.store.DeletePod(key)
+	// Held pods never committed resources, so there is nothing to subtract.
+	case ok && currState.held:
 		delete(cache.podStates, key)
+		cache.store.DeletePod(key)
 	default:
-		return fmt.Errorf("pod %v wasn't assumed so cannot be forgotten", key)
+		return newError(ReasonPodNotAssumed, "pod %v wasn't assumed so cannot be forgotten", key)
 	}
+	cache.recordMutation(ExportEventForgetPod, pod, nil)
 	return nil
 }
 
 // Assumes that lock is already acquired.
 func (cache *schedulerCache) addPod(pod *v1.Pod) {
-	n, ok := cache.nodes[pod.Spec.NodeName]
+	n, ok := cache.store.GetNodeInfo(pod.Spec.NodeName)
 	if !ok {
-		n = NewNodeInfo()
-		cache.nodes[pod.Spec.NodeName] = n
+		n = cache.newNodeInfo()
+		cache.store.SetNodeInfo(pod.Spec.NodeName, n)
 	}
 	n.AddPod(pod)
+	cache.reserveHeadroom(pod, n)
+	cache.indexAntiAffinity(pod)
+	cache.updateSpreadCounts(pod, 1)
+	cache.chargeAttachSlots(pod, n, 1)
+	cache.indexPodOwner(pod)
+	if podUsesHostPorts(pod) {
+		cache.publishInvalidation(pod.Spec.NodeName, InvalidationPods, InvalidationUsedPorts)
+	} else {
+		cache.publishInvalidation(pod.Spec.NodeName, InvalidationPods)
+	}
+	cache.verifyNodeInfo(pod.Spec.NodeName)
 }
 
 // Assumes that lock is already acquired.
@@ -279,13 +951,26 @@ func (cache *schedulerCache) updatePod(oldPod, newPod *v1.Pod) error {
 
 // Assumes that lock is already acquired.
 func (cache *schedulerCache) removePod(pod *v1.Pod) error {
-	n := cache.nodes[pod.Spec.NodeName]
+	n, _ := cache.store.GetNodeInfo(pod.Spec.NodeName)
 	if err := n.RemovePod(pod); err != nil {
 		return err
 	}
+	cache.releaseHeadroom(pod, n)
+	cache.unindexAntiAffinity(pod)
+	cache.dropPodUsage(pod, n)
+	cache.clearResizeInterlock(pod)
+	cache.updateSpreadCounts(pod, -1)
+	cache.chargeAttachSlots(pod, n, -1)
+	cache.unindexPodOwner(pod)
+	if podUsesHostPorts(pod) {
+		cache.publishInvalidation(pod.Spec.NodeName, InvalidationPods, InvalidationUsedPorts)
+	} else {
+		cache.publishInvalidation(pod.Spec.NodeName, InvalidationPods)
+	}
 	if len(n.pods) == 0 && n.node == nil {
-		delete(cache.nodes, pod.Spec.NodeName)
+		cache.store.DeleteNodeInfo(pod.Spec.NodeName)
 	}
+	cache.verifyNodeInfo(pod.Spec.NodeName)
 	return nil
 }
 
@@ -295,9 +980,30 @@ func (cache *schedulerCache) AddPod(pod *v1.Pod) error {
 		return err
 	}
 
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
+	_, span := cache.startSpan(context.Background(), "AddPod")
+	span.SetAttribute("pod", key)
+	span.SetAttribute("node", pod.Spec.NodeName)
+	defer span.End()
+
+	defer cache.lockFor("AddPod")()
+	return cache.addPodLocked(pod)
+}
+
+// addPodLocked is the body of AddPod; it handles the confirmed/held/expired
+// pod states, unlike the lower-level addPod which only charges the node.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) addPodLocked(pod *v1.Pod) error {
+	key, err := getPodKey(pod)
+	if err != nil {
+		return err
+	}
 
+	if cache.trimPods {
+		pod = trimPod(pod)
+	}
+	if cache.dedupPodSpecs {
+		pod = cache.dedupPodSpec(pod)
+	}
 	currState, ok := cache.podStates[key]
 	switch {
 	case ok && cache.assumedPods[key]:
@@ -309,8 +1015,20 @@ func (cache *schedulerCache) AddPod(pod *v1.Pod) error {
 			cache.addPod(pod)
 		}
 		delete(cache.assumedPods, key)
+		delete(cache.pinnedPods, key)
+		cache.dropFromGroup(key)
 		cache.podStates[key].deadline = nil
 		cache.podStates[key].pod = pod
+		cache.store.SetPod(key, pod)
+		currState.confirmedAt = time.Now()
+		metrics.BindingConfirmationLatency.Observe(float64(currState.confirmedAt.Sub(currState.assumedAt).Nanoseconds() / time.Microsecond.Nanoseconds()))
+	case ok && currState.held:
+		// The gate cleared out-of-band and the pod got scheduled; start
+		// accounting for it now.
+		currState.held = false
+		cache.addPod(pod)
+		currState.pod = pod
+		cache.store.SetPod(key, pod)
 	case !ok:
 		// Pod was expired. We should add it back.
 		cache.addPod(pod)
@@ -318,51 +1036,93 @@ func (cache *schedulerCache) AddPod(pod *v1.Pod) error {
 			pod: pod,
 		}
 		cache.podStates[key] = ps
+		cache.store.SetPod(key, pod)
+		cache.recordPodStatesPeak()
 	default:
-		return fmt.Errorf("pod %v was already in added state", key)
+		return newError(ReasonPodAlreadyAdded, "pod %v was already in added state", key)
 	}
+	cache.recordMutation(ExportEventAddPod, pod, nil)
 	return nil
 }
 
 func (cache *schedulerCache) UpdatePod(oldPod, newPod *v1.Pod) error {
+	return cache.UpdatePodWithContext(context.Background(), oldPod, newPod)
+}
+
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) updatePodLocked(oldPod, newPod *v1.Pod) error {
 	key, err := getPodKey(oldPod)
 	if err != nil {
 		return err
 	}
-
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
-
+	if cache.trimPods {
+		newPod = trimPod(newPod)
+	}
+	if cache.dedupPodSpecs {
+		newPod = cache.dedupPodSpec(newPod)
+	}
 	currState, ok := cache.podStates[key]
 	switch {
 	// An assumed pod won't have Update/Remove event. It needs to have Add event
 	// before Update event, in which case the state would change from Assumed to Added.
 	case ok && !cache.assumedPods[key]:
+		if isStalePod(currState.pod, newPod) {
+			// Out-of-order informer events would otherwise overwrite the
+			// cached pod with an older version and corrupt the node's
+			// requested resources.
+			return newError(ReasonStaleUpdate, "pod %v update with resourceVersion %v is older than cached resourceVersion %v",
+				key, newPod.ResourceVersion, currState.pod.ResourceVersion)
+		}
 		if currState.pod.Spec.NodeName != newPod.Spec.NodeName {
-			glog.Errorf("Pod %v updated on a different node than previously added to.", key)
-			glog.Fatalf("Schedulercache is corrupted and can badly affect scheduling decisions")
+			// The pod moved to a different node, e.g. after forced rebinding.
+			// Both halves happen under the lock, so its accounting moves from
+			// the old node to the new one atomically.
+			glog.V(2).Infof("Pod %v moved from node %v to node %v", key, currState.pod.Spec.NodeName, newPod.Spec.NodeName)
+			if err := cache.removePod(currState.pod); err != nil {
+				return err
+			}
+			cache.addPod(newPod)
+			currState.pod = newPod
+			cache.store.SetPod(key, newPod)
+			cache.recordMutation(ExportEventMovePod, newPod, nil)
+			return nil
 		}
+		oldReserved := cache.headroomByPod[key]
 		if err := cache.updatePod(oldPod, newPod); err != nil {
 			return err
 		}
+		cache.settleHeadroom(key, oldReserved, oldPod, newPod)
 		currState.pod = newPod
+		cache.store.SetPod(key, newPod)
+		cache.ingestResizeRequest(key, newPod)
+		cache.processResizeStatus(key, newPod)
 	default:
-		return fmt.Errorf("pod %v is not added to scheduler cache, so cannot be updated", key)
+		return newError(ReasonPodNotFound, "pod %v is not added to scheduler cache, so cannot be updated", key)
 	}
+	cache.recordMutation(ExportEventUpdatePod, newPod, nil)
 	return nil
 }
 
 func (cache *schedulerCache) RemovePod(pod *v1.Pod) error {
+	defer cache.lockFor("RemovePod")()
+	return cache.removePodLocked(pod)
+}
+
+// removePodLocked is the body of RemovePod; unlike the lower-level removePod
+// it also retires the pod's state tracking.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) removePodLocked(pod *v1.Pod) error {
 	key, err := getPodKey(pod)
 	if err != nil {
 		return err
 	}
 
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
-
 	currState, ok := cache.podStates[key]
 	switch {
+	// Held pods never committed resources, so there is nothing to subtract.
+	case ok && currState.held:
+		delete(cache.podStates, key)
+		cache.store.DeletePod(key)
 	// An assumed pod won't have Delete/Remove event. It needs to have Add event
 	// before Remove event, in which case the state would change from Assumed to Added.
 	case ok && !cache.assumedPods[key]:
@@ -375,9 +1135,11 @@ func (cache *schedulerCache) RemovePod(pod *v1.Pod) error {
 			return err
 		}
 		delete(cache.podStates, key)
+		cache.store.DeletePod(key)
 	default:
-		return fmt.Errorf("pod %v is not found in scheduler cache, so cannot be removed from it", key)
+		return newError(ReasonPodNotFound, "pod %v is not found in scheduler cache, so cannot be removed from it", key)
 	}
+	cache.recordMutation(ExportEventRemovePod, pod, nil)
 	return nil
 }
 
@@ -408,49 +1170,98 @@ func (cache *schedulerCache) GetPod(pod *v1.Pod) (*v1.Pod, error) {
 
 	podState, ok := cache.podStates[key]
 	if !ok {
-		return nil, fmt.Errorf("pod %v does not exist in scheduler cache", key)
+		return nil, newError(ReasonPodNotFound, "pod %v does not exist in scheduler cache", key)
 	}
 
 	return podState.pod, nil
 }
 
+// GetPodNode resolves the node the pod is placed on, including assumed pods
+// whose binding hasn't been confirmed yet. Controllers coordinating with the
+// scheduler use it instead of listing pods just to look up one placement.
+func (cache *schedulerCache) GetPodNode(podKey string) (string, bool, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	ps, ok := cache.podStates[podKey]
+	if !ok {
+		return "", false, newError(ReasonPodNotFound, "pod %v does not exist in scheduler cache", podKey)
+	}
+	return ps.pod.Spec.NodeName, cache.assumedPods[podKey], nil
+}
+
 func (cache *schedulerCache) AddNode(node *v1.Node) error {
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
+	defer cache.lockFor("AddNode")()
+	return cache.addNodeLocked(node)
+}
 
-	n, ok := cache.nodes[node.Name]
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) addNodeLocked(node *v1.Node) error {
+	n, ok := cache.store.GetNodeInfo(node.Name)
 	if !ok {
-		n = NewNodeInfo()
-		cache.nodes[node.Name] = n
+		n = cache.newNodeInfo()
+		cache.store.SetNodeInfo(node.Name, n)
 	} else {
 		cache.removeNodeImageStates(n.node)
+		cache.removeNodeTopology(n.node)
 	}
 
+	changed := nodeChangedFields(n.node, node)
 	cache.addNodeImageStates(node, n)
-	return n.SetNode(node)
+	cache.addNodeTopology(node)
+	if err := n.SetNode(node); err != nil {
+		return err
+	}
+	cache.applyOverCommit(n)
+	cache.publishInvalidation(node.Name, changed...)
+	cache.recordMutation(ExportEventAddNode, nil, node)
+	// Pods observed before their node couldn't be counted by topology domain;
+	// recount now that the node's labels are known.
+	cache.rebuildSpreadCounts()
+	return nil
 }
 
 func (cache *schedulerCache) UpdateNode(oldNode, newNode *v1.Node) error {
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
+	defer cache.lockFor("UpdateNode")()
+	return cache.updateNodeLocked(oldNode, newNode)
+}
 
-	n, ok := cache.nodes[newNode.Name]
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) updateNodeLocked(oldNode, newNode *v1.Node) error {
+	n, ok := cache.store.GetNodeInfo(newNode.Name)
 	if !ok {
-		n = NewNodeInfo()
-		cache.nodes[newNode.Name] = n
+		n = cache.newNodeInfo()
+		cache.store.SetNodeInfo(newNode.Name, n)
 	} else {
 		cache.removeNodeImageStates(n.node)
+		cache.removeNodeTopology(n.node)
 	}
 
 	cache.addNodeImageStates(newNode, n)
-	return n.SetNode(newNode)
+	cache.addNodeTopology(newNode)
+	if err := n.SetNode(newNode); err != nil {
+		return err
+	}
+	cache.applyOverCommit(n)
+	if oldNode == nil || !labels.Equals(labels.Set(oldNode.Labels), labels.Set(newNode.Labels)) {
+		cache.rebuildSpreadCounts()
+	}
+	cache.publishInvalidation(newNode.Name, nodeChangedFields(oldNode, newNode)...)
+	cache.recordMutation(ExportEventUpdateNode, nil, newNode)
+	return nil
 }
 
 func (cache *schedulerCache) RemoveNode(node *v1.Node) error {
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
+	defer cache.lockFor("RemoveNode")()
+	return cache.removeNodeLocked(node)
+}
 
-	n := cache.nodes[node.Name]
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) removeNodeLocked(node *v1.Node) error {
+	n, ok := cache.store.GetNodeInfo(node.Name)
+	if !ok {
+		return newError(ReasonNodeNotFound, "node %v does not exist in scheduler cache", node.Name)
+	}
 	if err := n.RemoveNode(node); err != nil {
 		return err
 	}
@@ -459,13 +1270,33 @@ func (cache *schedulerCache) RemoveNode(node *v1.Node) error {
 	// in a different watch, and thus can potentially be observed later, even though
 	// they happened before node removal.
 	if len(n.pods) == 0 && n.node == nil {
-		delete(cache.nodes, node.Name)
+		cache.store.DeleteNodeInfo(node.Name)
 	}
+	delete(cache.fitResults, node.Name)
 
 	cache.removeNodeImageStates(node)
+	cache.removeNodeTopology(node)
+	cache.publishInvalidation(node.Name, InvalidationAllocatable, InvalidationTaints)
+	cache.recordMutation(ExportEventRemoveNode, nil, node)
 	return nil
 }
 
+// DeletedNodes returns the names of nodes whose node objects are deleted but
+// whose NodeInfos are kept in the cache because pods still remain on them.
+func (cache *schedulerCache) DeletedNodes() []string {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	var deleted []string
+	cache.store.RangeNodeInfos(func(name string, n *NodeInfo) bool {
+		if n.state == NodeDeleting {
+			deleted = append(deleted, name)
+		}
+		return true
+	})
+	return deleted
+}
+
 // addNodeImageStates adds states of the images on given node to the given nodeInfo and update the imageStates in
 // scheduler cache. This function assumes the lock to scheduler cache has been acquired.
 func (cache *schedulerCache) addNodeImageStates(node *v1.Node, nodeInfo *NodeInfo) {
@@ -517,48 +1348,153 @@ func (cache *schedulerCache) removeNodeImageStates(node *v1.Node) {
 	}
 }
 
-func (cache *schedulerCache) AddPDB(pdb *policy.PodDisruptionBudget) error {
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
+func (cache *schedulerCache) IsUpToDate(n *NodeInfo) bool {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	node, ok := cache.store.GetNodeInfo(n.Node().Name)
+	return ok && n.generation == node.generation
+}
 
-	// Unconditionally update cache.
-	cache.pdbs[string(pdb.UID)] = pdb
-	return nil
+func (cache *schedulerCache) run() {
+	go cache.cleanupLoop()
+	go wait.Until(cache.compact, compactionPeriod, cache.stop)
 }
 
-func (cache *schedulerCache) UpdatePDB(oldPDB, newPDB *policy.PodDisruptionBudget) error {
-	return cache.AddPDB(newPDB)
+// cleanupLoop drives the expiry and orphan scans. It re-reads the period each
+// round so SetCleanupPeriod takes effect without restarting the scheduler, and
+// applies the configured jitter.
+func (cache *schedulerCache) cleanupLoop() {
+	for {
+		cache.mu.RLock()
+		period := cache.period
+		jitter := cache.periodJitter
+		cache.mu.RUnlock()
+
+		if jitter > 0 {
+			period = wait.Jitter(period, jitter)
+		}
+		select {
+		case <-cache.stop:
+			return
+		case <-time.After(period):
+		}
+		cache.cleanupExpiredAssumedPods()
+		cache.cleanupOrphanedPods()
+		cache.cleanupResizeAnnotations()
+		cache.analyzeDownsizes()
+		cache.checkpointIfDue()
+		cache.exportScoreDataIfDue()
+		cache.processPodMutations()
+		cache.updateAssumedPodMetrics()
+		cache.watchResizes()
+		cache.cleanupDedupTemplates()
+	}
 }
 
-func (cache *schedulerCache) RemovePDB(pdb *policy.PodDisruptionBudget) error {
+// SetCleanupPeriod adjusts the interval between expiry scans and the jitter
+// factor applied to it. A non-positive period or a negative jitter leaves the
+// corresponding setting unchanged.
+func (cache *schedulerCache) SetCleanupPeriod(period time.Duration, jitter float64) {
 	cache.mu.Lock()
 	defer cache.mu.Unlock()
-
-	delete(cache.pdbs, string(pdb.UID))
-	return nil
+	if period > 0 {
+		cache.period = period
+	}
+	if jitter >= 0 {
+		cache.periodJitter = jitter
+	}
 }
 
-func (cache *schedulerCache) ListPDBs(selector labels.Selector) ([]*policy.PodDisruptionBudget, error) {
-	cache.mu.RLock()
-	defer cache.mu.RUnlock()
-	var pdbs []*policy.PodDisruptionBudget
-	for _, pdb := range cache.pdbs {
-		if selector.Matches(labels.Set(pdb.Labels)) {
-			pdbs = append(pdbs, pdb)
-		}
+// recordPodStatesPeak tracks the high-water mark of podStates.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) recordPodStatesPeak() {
+	if len(cache.podStates) > cache.podStatesPeak {
+		cache.podStatesPeak = len(cache.podStates)
 	}
-	return pdbs, nil
 }
 
-func (cache *schedulerCache) IsUpToDate(n *NodeInfo) bool {
-	cache.mu.RLock()
-	defer cache.mu.RUnlock()
-	node, ok := cache.nodes[n.Node().Name]
-	return ok && n.generation == node.generation
+// recordMutation forwards one applied mutation to the optional export and
+// audit sinks. Exactly one of pod and node is non-nil. This function assumes
+// the lock to the scheduler cache has been acquired.
+func (cache *schedulerCache) recordMutation(eventType ExportEventType, pod *v1.Pod, node *v1.Node) {
+	if cache.export == nil && cache.audit == nil {
+		return
+	}
+	var namespace, name, nodeName string
+	if pod != nil {
+		namespace, name, nodeName = pod.Namespace, pod.Name, pod.Spec.NodeName
+	} else {
+		name, nodeName = node.Name, node.Name
+	}
+	if cache.export != nil {
+		cache.export.record(ExportEvent{
+			Seq:       nextGeneration(),
+			Type:      eventType,
+			Namespace: namespace,
+			Name:      name,
+		})
+	}
+	if cache.audit == nil {
+		return
+	}
+	entry := AuditEntry{
+		Time:      time.Now(),
+		Op:        eventType,
+		Namespace: namespace,
+		Name:      name,
+		NodeName:  nodeName,
+		Pod:       pod,
+		Node:      node,
+	}
+	if n, ok := cache.store.GetNodeInfo(nodeName); ok {
+		entry.RequestedMilliCPU = n.requestedResource.MilliCPU
+		entry.RequestedMemory = n.requestedResource.Memory
+		entry.NumPods = len(n.pods)
+	}
+	cache.audit.record(entry)
 }
 
-func (cache *schedulerCache) run() {
-	go wait.Until(cache.cleanupExpiredAssumedPods, cache.period, cache.stop)
+// compact reclaims memory held by stale cache entries: NodeInfos of deleted
+// nodes whose last pod is gone, and map buckets left behind after churn. It
+// runs periodically alongside cleanupAssumedPods.
+func (cache *schedulerCache) compact() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	reclaimed := 0
+	var staleNodes []string
+	cache.store.RangeNodeInfos(func(name string, n *NodeInfo) bool {
+		if n.state == NodeDeleting && len(n.pods) == 0 {
+			staleNodes = append(staleNodes, name)
+		}
+		return true
+	})
+	for _, name := range staleNodes {
+		cache.store.DeleteNodeInfo(name)
+		reclaimed++
+	}
+
+	// Go maps never shrink, so rebuild podStates once it has drained far
+	// below its peak.
+	if cache.podStatesPeak > compactionSizeThreshold && cache.podStatesPeak > 2*len(cache.podStates) {
+		podStates := make(map[string]*podState, len(cache.podStates))
+		for k, v := range cache.podStates {
+			podStates[k] = v
+		}
+		cache.podStates = podStates
+		assumedPods := make(map[string]bool, len(cache.assumedPods))
+		for k, v := range cache.assumedPods {
+			assumedPods[k] = v
+		}
+		cache.assumedPods = assumedPods
+		reclaimed += cache.podStatesPeak - len(cache.podStates)
+		cache.podStatesPeak = len(cache.podStates)
+	}
+
+	if reclaimed > 0 {
+		glog.V(3).Infof("Cache compaction reclaimed %d entries", reclaimed)
+		metrics.CacheCompactionReclaimedItems.Add(float64(reclaimed))
+	}
 }
 
 func (cache *schedulerCache) cleanupExpiredAssumedPods() {
@@ -591,10 +1527,18 @@ func (cache *schedulerCache) cleanupAssumedPods(now time.Time) {
 }
 
 func (cache *schedulerCache) expirePod(key string, ps *podState) error {
+	// A member expiring drags its whole reservation group with it, so
+	// gangs never keep a partial reservation.
+	group, inGroup := cache.podGroups[key]
 	if err := cache.removePod(ps.pod); err != nil {
 		return err
 	}
 	delete(cache.assumedPods, key)
+	delete(cache.pinnedPods, key)
 	delete(cache.podStates, key)
+	cache.dropFromGroup(key)
+	if inGroup {
+		cache.expireGroup(group)
+	}
 	return nil
 }
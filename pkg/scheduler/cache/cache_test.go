@@ -17,7 +17,12 @@ limitations under the License.
 package cache
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"strings"
 	"testing"
@@ -32,11 +37,19 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/kubernetes/pkg/apis/scheduling"
 	"k8s.io/kubernetes/pkg/features"
+	kubeletapis "k8s.io/kubernetes/pkg/kubelet/apis"
 	priorityutil "k8s.io/kubernetes/pkg/scheduler/algorithm/priorities/util"
 	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
 )
 
+// nodeInfoOf returns the NodeInfo the cache stores for the node, or nil.
+func nodeInfoOf(cache *schedulerCache, nodeName string) *NodeInfo {
+	n, _ := cache.store.GetNodeInfo(nodeName)
+	return n
+}
+
 func deepEqualWithoutGeneration(t *testing.T, testcase int, actual, expected *NodeInfo) {
 	// Ignore generation field.
 	if actual != nil {
@@ -104,8 +117,15 @@ func TestAssumePodScheduled(t *testing.T) {
 				MilliCPU: 100,
 				Memory:   500,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 100,
+					Memory:   500,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[0]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -121,8 +141,15 @@ func TestAssumePodScheduled(t *testing.T) {
 				MilliCPU: 300,
 				Memory:   1524,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 300,
+					Memory:   1524,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[1], testPods[2]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).add("TCP", "127.0.0.1", 8080).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -140,6 +167,7 @@ func TestAssumePodScheduled(t *testing.T) {
 			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[3]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -156,8 +184,16 @@ func TestAssumePodScheduled(t *testing.T) {
 				MilliCPU: 100,
 				Memory:   500,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU:        100,
+					Memory:          500,
+					ScalarResources: map[v1.ResourceName]int64{"example.com/foo": 3},
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[4]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -174,8 +210,16 @@ func TestAssumePodScheduled(t *testing.T) {
 				MilliCPU: 300,
 				Memory:   1524,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU:        300,
+					Memory:          1524,
+					ScalarResources: map[v1.ResourceName]int64{"example.com/foo": 8},
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[4], testPods[5]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).add("TCP", "127.0.0.1", 8080).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -191,8 +235,15 @@ func TestAssumePodScheduled(t *testing.T) {
 				MilliCPU: 100,
 				Memory:   500,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 100,
+					Memory:   500,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[6]},
 			usedPorts:           newHostPortInfoBuilder().build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -203,11 +254,11 @@ func TestAssumePodScheduled(t *testing.T) {
 	for i, tt := range tests {
 		cache := newSchedulerCache(time.Second, time.Second, nil)
 		for _, pod := range tt.pods {
-			if err := cache.AssumePod(pod); err != nil {
+			if _, err := cache.AssumePod(pod); err != nil {
 				t.Fatalf("AssumePod failed: %v", err)
 			}
 		}
-		n := cache.nodes[nodeName]
+		n := nodeInfoOf(cache, nodeName)
 		deepEqualWithoutGeneration(t, i, n, tt.wNodeInfo)
 
 		for _, pod := range tt.pods {
@@ -215,7 +266,7 @@ func TestAssumePodScheduled(t *testing.T) {
 				t.Fatalf("ForgetPod failed: %v", err)
 			}
 		}
-		if cache.nodes[nodeName] != nil {
+		if nodeInfoOf(cache, nodeName) != nil {
 			t.Errorf("NodeInfo should be cleaned for %s", nodeName)
 		}
 	}
@@ -227,7 +278,7 @@ type testExpirePodStruct struct {
 }
 
 func assumeAndFinishBinding(cache *schedulerCache, pod *v1.Pod, assumedTime time.Time) error {
-	if err := cache.AssumePod(pod); err != nil {
+	if _, err := cache.AssumePod(pod); err != nil {
 		return err
 	}
 	return cache.finishBinding(pod, assumedTime)
@@ -271,8 +322,15 @@ func TestExpirePod(t *testing.T) {
 				MilliCPU: 200,
 				Memory:   1024,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 200,
+					Memory:   1024,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[1]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 8080).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -289,7 +347,7 @@ func TestExpirePod(t *testing.T) {
 		}
 		// pods that have assumedTime + ttl < cleanupTime will get expired and removed
 		cache.cleanupAssumedPods(tt.cleanupTime)
-		n := cache.nodes[nodeName]
+		n := nodeInfoOf(cache, nodeName)
 		deepEqualWithoutGeneration(t, i, n, tt.wNodeInfo)
 	}
 }
@@ -324,8 +382,15 @@ func TestAddPodWillConfirm(t *testing.T) {
 				MilliCPU: 100,
 				Memory:   500,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 100,
+					Memory:   500,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[0]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -346,7 +411,7 @@ func TestAddPodWillConfirm(t *testing.T) {
 		}
 		cache.cleanupAssumedPods(now.Add(2 * ttl))
 		// check after expiration. confirmed pods shouldn't be expired.
-		n := cache.nodes[nodeName]
+		n := nodeInfoOf(cache, nodeName)
 		deepEqualWithoutGeneration(t, i, n, tt.wNodeInfo)
 	}
 }
@@ -382,8 +447,8 @@ func TestSnapshot(t *testing.T) {
 		}
 
 		snapshot := cache.Snapshot()
-		if !reflect.DeepEqual(snapshot.Nodes, cache.nodes) {
-			t.Fatalf("expect \n%+v; got \n%+v", cache.nodes, snapshot.Nodes)
+		if !reflect.DeepEqual(snapshot.Nodes, cache.store.(*mapStore).nodes) {
+			t.Fatalf("expect \n%+v; got \n%+v", cache.store.(*mapStore).nodes, snapshot.Nodes)
 		}
 		if !reflect.DeepEqual(snapshot.AssumedPods, cache.assumedPods) {
 			t.Fatalf("expect \n%+v; got \n%+v", cache.assumedPods, snapshot.AssumedPods)
@@ -421,8 +486,15 @@ func TestAddPodWillReplaceAssumed(t *testing.T) {
 					MilliCPU: 200,
 					Memory:   500,
 				},
+				requestedByOrigin: map[PodOrigin]*Resource{
+					PodOriginNormal: {
+						MilliCPU: 200,
+						Memory:   500,
+					},
+				},
 				TransientInfo:       newTransientSchedulerInfo(),
 				allocatableResource: &Resource{},
+				capacityResource:    &Resource{},
 				pods:                []*v1.Pod{updatedPod.DeepCopy()},
 				usedPorts:           newHostPortInfoBuilder().add("TCP", "0.0.0.0", 90).build(),
 				imageStates:         make(map[string]*ImageStateSummary),
@@ -449,7 +521,7 @@ func TestAddPodWillReplaceAssumed(t *testing.T) {
 		}
 		for nodeName, expected := range tt.wNodeInfo {
 			t.Log(nodeName)
-			n := cache.nodes[nodeName]
+			n := nodeInfoOf(cache, nodeName)
 			deepEqualWithoutGeneration(t, i, n, expected)
 		}
 	}
@@ -477,8 +549,15 @@ func TestAddPodAfterExpiration(t *testing.T) {
 				MilliCPU: 100,
 				Memory:   500,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 100,
+					Memory:   500,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{basePod},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -493,7 +572,7 @@ func TestAddPodAfterExpiration(t *testing.T) {
 		}
 		cache.cleanupAssumedPods(now.Add(2 * ttl))
 		// It should be expired and removed.
-		n := cache.nodes[nodeName]
+		n := nodeInfoOf(cache, nodeName)
 		if n != nil {
 			t.Errorf("#%d: expecting nil node info, but get=%v", i, n)
 		}
@@ -501,7 +580,7 @@ func TestAddPodAfterExpiration(t *testing.T) {
 			t.Fatalf("AddPod failed: %v", err)
 		}
 		// check after expiration. confirmed pods shouldn't be expired.
-		n = cache.nodes[nodeName]
+		n = nodeInfoOf(cache, nodeName)
 		deepEqualWithoutGeneration(t, i, n, tt.wNodeInfo)
 	}
 }
@@ -533,8 +612,15 @@ func TestUpdatePod(t *testing.T) {
 				MilliCPU: 200,
 				Memory:   1024,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 200,
+					Memory:   1024,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[1]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 8080).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -547,8 +633,15 @@ func TestUpdatePod(t *testing.T) {
 				MilliCPU: 100,
 				Memory:   500,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 100,
+					Memory:   500,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[0]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -571,7 +664,7 @@ func TestUpdatePod(t *testing.T) {
 				t.Fatalf("UpdatePod failed: %v", err)
 			}
 			// check after expiration. confirmed pods shouldn't be expired.
-			n := cache.nodes[nodeName]
+			n := nodeInfoOf(cache, nodeName)
 			deepEqualWithoutGeneration(t, i, n, tt.wNodeInfo[i-1])
 		}
 	}
@@ -598,7 +691,8 @@ func TestUpdatePodAndGet(t *testing.T) {
 
 			podToUpdate: testPods[0],
 			handler: func(cache Cache, pod *v1.Pod) error {
-				return cache.AssumePod(pod)
+				_, err := cache.AssumePod(pod)
+				return err
 			},
 			assumePod: true,
 		},
@@ -665,8 +759,15 @@ func TestExpireAddUpdatePod(t *testing.T) {
 				MilliCPU: 200,
 				Memory:   1024,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 200,
+					Memory:   1024,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[1]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 8080).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -679,8 +780,15 @@ func TestExpireAddUpdatePod(t *testing.T) {
 				MilliCPU: 100,
 				Memory:   500,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 100,
+					Memory:   500,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{testPods[0]},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -711,7 +819,7 @@ func TestExpireAddUpdatePod(t *testing.T) {
 				t.Fatalf("UpdatePod failed: %v", err)
 			}
 			// check after expiration. confirmed pods shouldn't be expired.
-			n := cache.nodes[nodeName]
+			n := nodeInfoOf(cache, nodeName)
 			deepEqualWithoutGeneration(t, i, n, tt.wNodeInfo[i-1])
 		}
 	}
@@ -757,8 +865,14 @@ func TestEphemeralStorageResource(t *testing.T) {
 					MilliCPU: priorityutil.DefaultMilliCPURequest,
 					Memory:   priorityutil.DefaultMemoryRequest,
 				},
+				requestedByOrigin: map[PodOrigin]*Resource{
+					PodOriginNormal: {
+						EphemeralStorage: 500,
+					},
+				},
 				TransientInfo:       newTransientSchedulerInfo(),
 				allocatableResource: &Resource{},
+				capacityResource:    &Resource{},
 				pods:                []*v1.Pod{podE},
 				usedPorts:           schedutil.HostPortInfo{},
 				imageStates:         make(map[string]*ImageStateSummary),
@@ -770,14 +884,14 @@ func TestEphemeralStorageResource(t *testing.T) {
 		if err := cache.AddPod(tt.pod); err != nil {
 			t.Fatalf("AddPod failed: %v", err)
 		}
-		n := cache.nodes[nodeName]
+		n := nodeInfoOf(cache, nodeName)
 		deepEqualWithoutGeneration(t, i, n, tt.wNodeInfo)
 
 		if err := cache.RemovePod(tt.pod); err != nil {
 			t.Fatalf("RemovePod failed: %v", err)
 		}
 
-		n = cache.nodes[nodeName]
+		n = nodeInfoOf(cache, nodeName)
 		if n != nil {
 			t.Errorf("#%d: expecting pod deleted and nil node info, get=%s", i, n)
 		}
@@ -804,8 +918,15 @@ func TestRemovePod(t *testing.T) {
 				MilliCPU: 100,
 				Memory:   500,
 			},
+			requestedByOrigin: map[PodOrigin]*Resource{
+				PodOriginNormal: {
+					MilliCPU: 100,
+					Memory:   500,
+				},
+			},
 			TransientInfo:       newTransientSchedulerInfo(),
 			allocatableResource: &Resource{},
+			capacityResource:    &Resource{},
 			pods:                []*v1.Pod{basePod},
 			usedPorts:           newHostPortInfoBuilder().add("TCP", "127.0.0.1", 80).build(),
 			imageStates:         make(map[string]*ImageStateSummary),
@@ -817,14 +938,14 @@ func TestRemovePod(t *testing.T) {
 		if err := cache.AddPod(tt.pod); err != nil {
 			t.Fatalf("AddPod failed: %v", err)
 		}
-		n := cache.nodes[nodeName]
+		n := nodeInfoOf(cache, nodeName)
 		deepEqualWithoutGeneration(t, i, n, tt.wNodeInfo)
 
 		if err := cache.RemovePod(tt.pod); err != nil {
 			t.Fatalf("RemovePod failed: %v", err)
 		}
 
-		n = cache.nodes[nodeName]
+		n = nodeInfoOf(cache, nodeName)
 		if n != nil {
 			t.Errorf("#%d: expecting pod deleted and nil node info, get=%s", i, n)
 		}
@@ -879,7 +1000,7 @@ func TestForgetPod(t *testing.T) {
 			}
 		}
 		cache.cleanupAssumedPods(now.Add(2 * ttl))
-		if n := cache.nodes[nodeName]; n != nil {
+		if n := nodeInfoOf(cache, nodeName); n != nil {
 			t.Errorf("#%d: expecting pod deleted and nil node info, get=%s", i, n)
 		}
 	}
@@ -903,7 +1024,9 @@ func buildNodeInfo(node *v1.Node, pods []*v1.Pod) *NodeInfo {
 	// Simulate SetNode.
 	expected.node = node
 	expected.allocatableResource = NewResource(node.Status.Allocatable)
+	expected.capacityResource = NewResource(node.Status.Capacity)
 	expected.taints = node.Spec.Taints
+	expected.inPlaceResizeSupported = inPlaceResizeSupported(node)
 	expected.generation++
 
 	for _, pod := range pods {
@@ -911,6 +1034,7 @@ func buildNodeInfo(node *v1.Node, pods []*v1.Pod) *NodeInfo {
 		expected.pods = append(expected.pods, pod)
 		expected.requestedResource.Add(getResourceRequest(pod))
 		expected.nonzeroRequest.Add(getResourceRequest(pod))
+		expected.accountOrigin(pod, NewResource(getResourceRequest(pod)), 1)
 		expected.updateUsedPorts(pod, true)
 		expected.generation++
 	}
@@ -1061,7 +1185,7 @@ func TestNodeOperators(t *testing.T) {
 		}
 
 		// Case 1: the node was added into cache successfully.
-		got, found := cache.nodes[node.Name]
+		got, found := cache.store.GetNodeInfo(node.Name)
 		if !found {
 			t.Errorf("Failed to find node %v in schedulercache.", node.Name)
 		}
@@ -1077,7 +1201,7 @@ func TestNodeOperators(t *testing.T) {
 		cache.UpdateNodeNameToInfoMap(cachedNodes)
 		newNode, found := cachedNodes[node.Name]
 		if !found || len(cachedNodes) != 1 {
-			t.Errorf("failed to dump cached nodes:\n got: %v \nexpected: %v", cachedNodes, cache.nodes)
+			t.Errorf("failed to dump cached nodes:\n got: %v \nexpected: %v", cachedNodes, cache.store.(*mapStore).nodes)
 		}
 		expected.generation = newNode.generation
 		if !reflect.DeepEqual(newNode, expected) {
@@ -1088,7 +1212,7 @@ func TestNodeOperators(t *testing.T) {
 		node.Status.Allocatable[v1.ResourceMemory] = mem50m
 		expected.allocatableResource.Memory = mem50m.Value()
 		cache.UpdateNode(nil, node)
-		got, found = cache.nodes[node.Name]
+		got, found = cache.store.GetNodeInfo(node.Name)
 		if !found {
 			t.Errorf("Failed to find node %v in schedulercache after UpdateNode.", node.Name)
 		}
@@ -1103,12 +1227,4869 @@ func TestNodeOperators(t *testing.T) {
 
 		// Case 4: the node can not be removed if pods is not empty.
 		cache.RemoveNode(node)
-		if _, found := cache.nodes[node.Name]; !found {
+		if _, found := cache.store.GetNodeInfo(node.Name); !found {
 			t.Errorf("The node %v should not be removed if pods is not empty.", node.Name)
 		}
 	}
 }
 
+// TestDeletedNodes tests that nodes removed while pods remain are reported as
+// deleted and refuse new assumed pods until the last pod is removed.
+func TestDeletedNodes(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+	assumedPod := makeBasePod(t, "node-1", "assumed-pod", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if deleted := cache.DeletedNodes(); len(deleted) != 0 {
+		t.Errorf("expected no deleted nodes, got: %v", deleted)
+	}
+	if err := cache.RemoveNode(node); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if got := nodeInfoOf(cache, node.Name).State(); got != NodeDeleting {
+		t.Errorf("expected node state %v, got: %v", NodeDeleting, got)
+	}
+	if deleted := cache.DeletedNodes(); len(deleted) != 1 || deleted[0] != node.Name {
+		t.Errorf("expected deleted nodes [%v], got: %v", node.Name, deleted)
+	}
+	if _, err := cache.AssumePod(assumedPod); err == nil {
+		t.Error("expected AssumePod to fail on a deleting node")
+	}
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	// The NodeInfo is dropped once the last pod is gone.
+	if deleted := cache.DeletedNodes(); len(deleted) != 0 {
+		t.Errorf("expected no deleted nodes, got: %v", deleted)
+	}
+}
+
+// TestHeldPods tests that pods carrying a scheduling-gate annotation don't
+// consume node capacity until they are released.
+func TestHeldPods(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "held-pod", "100m", "500", "", nil)
+	pod.Annotations = map[string]string{AnnotationPodSchedulingGate: "tenant-quota"}
+	key, _ := getPodKey(pod)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if _, ok := cache.store.GetNodeInfo(pod.Spec.NodeName); ok {
+		t.Error("held pod should not commit resources on its node")
+	}
+	if n := len(cache.AssumedPodsByProfile()); n != 0 {
+		t.Errorf("held pod should not count as assumed, got %d profiles", n)
+	}
+
+	if err := cache.ReleasePod(key); err != nil {
+		t.Fatalf("ReleasePod failed: %v", err)
+	}
+	if _, ok := cache.store.GetNodeInfo(pod.Spec.NodeName); !ok {
+		t.Error("released pod should commit resources on its node")
+	}
+	if assumed, err := cache.IsAssumedPod(pod); err != nil || !assumed {
+		t.Errorf("released pod should be assumed, got %v, %v", assumed, err)
+	}
+	if err := cache.ReleasePod(key); err == nil {
+		t.Error("expected ReleasePod to fail for a pod that isn't held")
+	}
+}
+
+// TestCacheErrorReasons tests that cache operations return typed errors that
+// callers can branch on.
+func TestCacheErrorReasons(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+	otherPod := makeBasePod(t, "node-1", "other-pod", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if _, err := cache.AssumePod(pod); !IsPodAlreadyAssumed(err) {
+		t.Errorf("expected PodAlreadyAssumed error, got: %v", err)
+	}
+	if err := cache.RemovePod(otherPod); !IsPodNotFound(err) {
+		t.Errorf("expected PodNotFound error, got: %v", err)
+	}
+	if err := cache.ForgetPod(otherPod); ReasonForError(err) != ReasonPodNotAssumed {
+		t.Errorf("expected PodNotAssumed error, got: %v", err)
+	}
+	if _, err := cache.GetPod(otherPod); !IsPodNotFound(err) {
+		t.Errorf("expected PodNotFound error, got: %v", err)
+	}
+}
+
+// TestStalePodUpdate tests that updates carrying an older resourceVersion
+// than the cached pod are rejected.
+func TestStalePodUpdate(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+	pod.ResourceVersion = "2"
+	stalePod := pod.DeepCopy()
+	stalePod.ResourceVersion = "1"
+	newPod := pod.DeepCopy()
+	newPod.ResourceVersion = "3"
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if err := cache.UpdatePod(pod, stalePod); !IsStaleUpdate(err) {
+		t.Errorf("expected StaleUpdate error, got: %v", err)
+	}
+	if err := cache.UpdatePod(pod, newPod); err != nil {
+		t.Errorf("UpdatePod failed: %v", err)
+	}
+	if cached, err := cache.GetPod(pod); err != nil || cached.ResourceVersion != "3" {
+		t.Errorf("expected cached resourceVersion 3, got: %v, %v", cached.ResourceVersion, err)
+	}
+}
+
+// TestResync tests that Resync replaces the cache contents while preserving
+// assumed pods that aren't in the authoritative lists yet.
+func TestResync(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	stalePod := makeBasePod(t, "node-1", "stale-pod", "100m", "500", "", nil)
+	listedPod := makeBasePod(t, "node-1", "listed-pod", "100m", "500", "", nil)
+	assumedPod := makeBasePod(t, "node-1", "assumed-pod", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(stalePod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if _, err := cache.AssumePod(assumedPod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+
+	if err := cache.Resync([]*v1.Node{node}, []*v1.Pod{listedPod}, nil); err != nil {
+		t.Fatalf("Resync failed: %v", err)
+	}
+
+	// The stale pod is gone, the listed pod is added, and the assumed pod
+	// survives the resync.
+	if _, err := cache.GetPod(stalePod); !IsPodNotFound(err) {
+		t.Errorf("expected stale pod to be dropped, got: %v", err)
+	}
+	if _, err := cache.GetPod(listedPod); err != nil {
+		t.Errorf("expected listed pod in cache, got: %v", err)
+	}
+	if assumed, err := cache.IsAssumedPod(assumedPod); err != nil || !assumed {
+		t.Errorf("expected assumed pod to survive resync, got: %v, %v", assumed, err)
+	}
+	if n := len(nodeInfoOf(cache, node.Name).Pods()); n != 2 {
+		t.Errorf("expected 2 pods on node after resync, got %d", n)
+	}
+}
+
+// TestPodTrimming tests that trimmed pods keep the fields predicates need
+// while dropping bulky ones, and that resource accounting is unaffected.
+func TestPodTrimming(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+	pod.Spec.Containers[0].Env = []v1.EnvVar{{Name: "FOO", Value: "bar"}}
+	pod.Spec.Containers[0].Command = []string{"sleep", "1d"}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	cache.EnablePodTrimming(nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	cached, err := cache.GetPod(pod)
+	if err != nil {
+		t.Fatalf("GetPod failed: %v", err)
+	}
+	if cached.Spec.Containers[0].Env != nil || cached.Spec.Containers[0].Command != nil {
+		t.Error("expected env and command to be trimmed from the cached pod")
+	}
+	if len(pod.Spec.Containers[0].Env) != 1 {
+		t.Error("trimming must not mutate the informer's pod object")
+	}
+	if cpu := nodeInfoOf(cache, pod.Spec.NodeName).RequestedResource().MilliCPU; cpu != 100 {
+		t.Errorf("expected 100 millicpu requested, got %d", cpu)
+	}
+}
+
+// TestAssumeAdmit tests that an installed admission hook can veto assumes.
+func TestAssumeAdmit(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	cache.SetAssumeAdmitFunc(func(pod *v1.Pod, nodeInfo *NodeInfo) error {
+		return fmt.Errorf("tenant out of capacity")
+	})
+	if _, err := cache.AssumePod(pod); !IsAssumeRejected(err) {
+		t.Errorf("expected AssumeRejected error, got: %v", err)
+	}
+
+	cache.SetAssumeAdmitFunc(nil)
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Errorf("AssumePod failed: %v", err)
+	}
+}
+
+// TestClusterCapacity tests summing allocatable, requested and headroom over
+// the cached nodes.
+func TestClusterCapacity(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test-pod", "400m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	summary := cache.ClusterCapacity()
+	if summary.Nodes != 1 || summary.ReadyNodes != 1 || summary.DeletingNodes != 0 {
+		t.Errorf("unexpected node counts: %+v", summary)
+	}
+	if summary.Allocatable.MilliCPU != 1000 || summary.Requested.MilliCPU != 400 || summary.Headroom.MilliCPU != 600 {
+		t.Errorf("unexpected CPU summary: %+v", summary)
+	}
+}
+
+func TestNodesInTopologyDomain(t *testing.T) {
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: "zone-a", kubeletapis.LabelZoneRegion: "region-1"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-2",
+			Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: "zone-a", kubeletapis.LabelZoneRegion: "region-1"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-3",
+			Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: "zone-b", kubeletapis.LabelZoneRegion: "region-1"},
+		}},
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, node := range nodes {
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+
+	if got := cache.NodesInTopologyDomain(kubeletapis.LabelZoneFailureDomain, "zone-a"); !reflect.DeepEqual(got, []string{"node-1", "node-2"}) {
+		t.Errorf("expected nodes [node-1 node-2] in zone-a, got: %v", got)
+	}
+	if got := cache.NodesInTopologyDomain(kubeletapis.LabelZoneRegion, "region-1"); len(got) != 3 {
+		t.Errorf("expected 3 nodes in region-1, got: %v", got)
+	}
+	if got := cache.NodesInTopologyDomain(kubeletapis.LabelZoneFailureDomain, "zone-c"); got != nil {
+		t.Errorf("expected no nodes in zone-c, got: %v", got)
+	}
+
+	// Moving a node to another zone updates both domains.
+	moved := nodes[0].DeepCopy()
+	moved.Labels[kubeletapis.LabelZoneFailureDomain] = "zone-b"
+	if err := cache.UpdateNode(nodes[0], moved); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	if got := cache.NodesInTopologyDomain(kubeletapis.LabelZoneFailureDomain, "zone-b"); !reflect.DeepEqual(got, []string{"node-1", "node-3"}) {
+		t.Errorf("expected nodes [node-1 node-3] in zone-b, got: %v", got)
+	}
+
+	// Removing a node drops it from the index; empty domains are deleted.
+	if err := cache.RemoveNode(nodes[1]); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if got := cache.NodesInTopologyDomain(kubeletapis.LabelZoneFailureDomain, "zone-a"); got != nil {
+		t.Errorf("expected no nodes left in zone-a, got: %v", got)
+	}
+}
+
+func TestTopologySpreadCounts(t *testing.T) {
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: "zone-a"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-2",
+			Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: "zone-b"},
+		}},
+	}
+	makeSpreadPod := func(name, nodeName string) *v1.Pod {
+		pod := makeBasePod(t, nodeName, name, "100m", "500", "", nil)
+		pod.Labels = map[string]string{"app": "web"}
+		return pod
+	}
+	constraint := TopologySpreadConstraint{
+		Namespace:   "node_info_cache_test",
+		TopologyKey: kubeletapis.LabelZoneFailureDomain,
+		Selector:    labels.SelectorFromSet(labels.Set{"app": "web"}),
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, node := range nodes {
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	for _, pod := range []*v1.Pod{
+		makeSpreadPod("web-1", "node-1"),
+		makeSpreadPod("web-2", "node-1"),
+		makeSpreadPod("web-3", "node-2"),
+	} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	// A pod not matching the selector must not be counted.
+	if err := cache.AddPod(makeBasePod(t, "node-2", "other", "100m", "500", "", nil)); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	counts := cache.TopologySpreadCounts(constraint)
+	if expected := map[string]int32{"zone-a": 2, "zone-b": 1}; !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected counts %v, got: %v", expected, counts)
+	}
+
+	// Counts are maintained incrementally once the constraint is tracked.
+	extra := makeSpreadPod("web-4", "node-2")
+	if err := cache.AddPod(extra); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if err := cache.RemovePod(makeSpreadPod("web-1", "node-1")); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	counts = cache.TopologySpreadCounts(constraint)
+	if expected := map[string]int32{"zone-a": 1, "zone-b": 2}; !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected counts %v, got: %v", expected, counts)
+	}
+}
+
+func TestInvalidationEvents(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("4"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+	portPod := makeBasePod(t, "node-1", "port-pod", "100m", "500", "", []v1.ContainerPort{{HostIP: "127.0.0.1", HostPort: 80, Protocol: "TCP"}})
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	var events []InvalidationEvent
+	cache.AddInvalidationListener(func(event InvalidationEvent) {
+		events = append(events, event)
+	})
+
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if err := cache.AddPod(portPod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	updatedNode := node.DeepCopy()
+	updatedNode.Spec.Taints = []v1.Taint{{Key: "dedicated", Value: "batch", Effect: v1.TaintEffectNoSchedule}}
+	if err := cache.UpdateNode(node, updatedNode); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+
+	expected := []InvalidationEvent{
+		{NodeName: "node-1", Field: InvalidationAllocatable},
+		{NodeName: "node-1", Field: InvalidationTaints},
+		{NodeName: "node-1", Field: InvalidationPods},
+		{NodeName: "node-1", Field: InvalidationPods},
+		{NodeName: "node-1", Field: InvalidationUsedPorts},
+		{NodeName: "node-1", Field: InvalidationTaints},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got: %v", expected, events)
+	}
+}
+
+func TestSnapshotForExtender(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec: v1.NodeSpec{
+			Taints: []v1.Taint{{Key: "dedicated", Value: "batch", Effect: v1.TaintEffectNoSchedule}},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+			Images: []v1.ContainerImage{{Names: []string{"gcr.io/test:latest"}, SizeBytes: 100}},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	payload, err := cache.SnapshotForExtender(nil)
+	if err != nil {
+		t.Fatalf("SnapshotForExtender failed: %v", err)
+	}
+	var infos []ExtenderNodeInfo
+	if err := json.Unmarshal(payload, &infos); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "node-1" {
+		t.Fatalf("expected one entry for node-1, got: %v", infos)
+	}
+	if len(infos[0].Taints) != 1 || infos[0].Taints[0].Key != "dedicated" {
+		t.Errorf("expected the node's taints, got: %v", infos[0].Taints)
+	}
+	if !reflect.DeepEqual(infos[0].Images, []string{"gcr.io/test:latest"}) {
+		t.Errorf("expected the node's images, got: %v", infos[0].Images)
+	}
+	if infos[0].Requested.Cpu().MilliValue() != 100 {
+		t.Errorf("expected 100m cpu requested, got: %v", infos[0].Requested.Cpu())
+	}
+
+	// Field selection drops everything but the requested fields.
+	payload, err = cache.SnapshotForExtender(&ExtenderSnapshotFilter{Fields: []string{ExtenderFieldAllocatable}})
+	if err != nil {
+		t.Fatalf("SnapshotForExtender failed: %v", err)
+	}
+	// Decode into a fresh slice: the omitted fields wouldn't overwrite the
+	// values left over from the unfiltered decode above.
+	infos = nil
+	if err := json.Unmarshal(payload, &infos); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	if infos[0].Allocatable == nil || infos[0].Taints != nil || infos[0].Images != nil {
+		t.Errorf("expected only allocatable to be set, got: %+v", infos[0])
+	}
+
+	// The gzip payload decompresses to the same JSON.
+	compressed, err := cache.SnapshotForExtender(&ExtenderSnapshotFilter{Fields: []string{ExtenderFieldAllocatable}, Gzip: true})
+	if err != nil {
+		t.Fatalf("SnapshotForExtender failed: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("failed to open gzip payload: %v", err)
+	}
+	decompressed, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress payload: %v", err)
+	}
+	if !bytes.Equal(decompressed, payload) {
+		t.Errorf("expected gzip payload to decompress to the plain payload")
+	}
+}
+
+func TestExportEvents(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	cache.export = newExportServer()
+
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+
+	events := cache.export.eventsAfter(0)
+	var types []ExportEventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	expected := []ExportEventType{ExportEventAddNode, ExportEventAssumePod, ExportEventAddPod, ExportEventRemovePod}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatalf("expected events %v, got: %v", expected, types)
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Seq <= events[i-1].Seq {
+			t.Errorf("expected strictly increasing sequence numbers, got: %v", events)
+		}
+	}
+	// Resuming from a sequence number replays only later events.
+	if resumed := cache.export.eventsAfter(events[1].Seq); len(resumed) != 2 {
+		t.Errorf("expected 2 events after seq %v, got: %v", events[1].Seq, resumed)
+	}
+}
+
+func TestStoreKeptInSync(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	store := NewMapStore()
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	cache.store = store
+
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if _, ok := store.GetNodeInfo(node.Name); !ok {
+		t.Errorf("expected node %v in the store", node.Name)
+	}
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if got, ok := store.GetPod(key); !ok || got.Name != pod.Name {
+		t.Errorf("expected pod %v in the store, got: %v", key, got)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if _, ok := store.GetPod(key); ok {
+		t.Errorf("expected pod %v to be deleted from the store", key)
+	}
+	if store.NumPods() != 0 {
+		t.Errorf("expected an empty pod store, got %v pods", store.NumPods())
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	pod := makeBasePod(t, "node-1", "test-pod", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.EnableAuditLog(10, 0, ""); err != nil {
+		t.Fatalf("EnableAuditLog failed: %v", err)
+	}
+
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	entries := cache.AuditEntries()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got: %v", entries)
+	}
+	for i, op := range []ExportEventType{ExportEventAddNode, ExportEventAssumePod, ExportEventAddPod} {
+		if entries[i].Op != op {
+			t.Errorf("expected entry %d to be %v, got: %v", i, op, entries[i].Op)
+		}
+		if entries[i].Seq != int64(i+1) {
+			t.Errorf("expected entry %d to have seq %d, got: %v", i, i+1, entries[i].Seq)
+		}
+	}
+	if entries[1].RequestedMilliCPU != 100 {
+		t.Errorf("expected 100m requested cpu after assume, got: %v", entries[1].RequestedMilliCPU)
+	}
+
+	// Replaying up to the assume reconstructs the intermediate state.
+	replayed, err := cache.replayTo(2)
+	if err != nil {
+		t.Fatalf("replayTo failed: %v", err)
+	}
+	if assumed, err := replayed.IsAssumedPod(pod); err != nil || !assumed {
+		t.Errorf("expected the pod to be assumed in the replayed cache, got: %v, %v", assumed, err)
+	}
+	if cpu := nodeInfoOf(replayed, "node-1").RequestedResource().MilliCPU; cpu != 100 {
+		t.Errorf("expected 100m requested cpu in the replayed cache, got: %v", cpu)
+	}
+
+	// The log is capped at maxEntries.
+	for i := 0; i < 10; i++ {
+		cache.UpdateNode(node, node)
+	}
+	if entries := cache.AuditEntries(); len(entries) != 10 || entries[0].Seq != 4 {
+		t.Errorf("expected the log to be capped at 10 entries starting at seq 4, got %d entries", len(entries))
+	}
+}
+
+func TestVerificationMode(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", []v1.ContainerPort{{HostIP: "127.0.0.1", HostPort: 80, Protocol: "TCP"}})
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	cache.SetVerificationMode(VerificationPanic)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	// A consistent sequence of mutations must not trip verification.
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+
+	// Corrupt the incremental accounting and check that the next mutation
+	// panics.
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	n, _ := cache.store.GetNodeInfo("node-1")
+	n.requestedResource.MilliCPU += 100
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected verification to panic on divergence")
+		}
+	}()
+	secondPod := makeBasePod(t, "node-1", "test-2", "200m", "1Ki", "", nil)
+	cache.AddPod(secondPod)
+}
+
+func TestOverCommitRules(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{"pool": "burstable"},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	other := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-2",
+			Labels: map[string]string{"pool": "guaranteed"},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse("2"),
+			},
+		},
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddNode(other); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	cache.SetOverCommitRules([]OverCommitRule{{
+		Selector: labels.SelectorFromSet(labels.Set{"pool": "burstable"}),
+		Ratios:   map[v1.ResourceName]float64{v1.ResourceCPU: 1.5},
+	}})
+
+	effective := nodeInfoOf(cache, "node-1").EffectiveAllocatable()
+	if effective.MilliCPU != 3000 {
+		t.Errorf("expected over-committed CPU 3000m, got %d", effective.MilliCPU)
+	}
+	if effective.Memory != 1024*1024*1024 {
+		t.Errorf("expected memory unchanged, got %d", effective.Memory)
+	}
+	// The raw allocatable stays available for reporting.
+	if raw := nodeInfoOf(cache, "node-1").AllocatableResource(); raw.MilliCPU != 2000 {
+		t.Errorf("expected raw allocatable CPU 2000m, got %d", raw.MilliCPU)
+	}
+	// Non-matching nodes are unaffected.
+	if effective := nodeInfoOf(cache, "node-2").EffectiveAllocatable(); effective.MilliCPU != 2000 {
+		t.Errorf("expected CPU 2000m on the non-matching node, got %d", effective.MilliCPU)
+	}
+	// Fit checks see the over-committed allocatable, but capacity is never
+	// over-committed.
+	if fit := nodeInfoOf(cache, "node-1").FitResource(FitResourcePolicy{}); fit.MilliCPU != 3000 {
+		t.Errorf("expected fit CPU 3000m, got %d", fit.MilliCPU)
+	}
+	if fit := nodeInfoOf(cache, "node-1").FitResource(FitResourcePolicy{UseCapacity: true}); fit.MilliCPU != 0 {
+		t.Errorf("expected capacity fit CPU 0, got %d", fit.MilliCPU)
+	}
+
+	// Nodes added after the rules are set get the ratios as well.
+	node3 := node.DeepCopy()
+	node3.Name = "node-3"
+	if err := cache.AddNode(node3); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if effective := nodeInfoOf(cache, "node-3").EffectiveAllocatable(); effective.MilliCPU != 3000 {
+		t.Errorf("expected over-committed CPU 3000m, got %d", effective.MilliCPU)
+	}
+
+	// A rules change bumps the nodes' generations, so incremental snapshot
+	// consumers re-clone them instead of serving the old ratios.
+	cachedNodes := map[string]*NodeInfo{}
+	if _, err := cache.UpdateNodeNameToInfoMap(cachedNodes); err != nil {
+		t.Fatalf("UpdateNodeNameToInfoMap failed: %v", err)
+	}
+	cache.SetOverCommitRules(nil)
+	changed, err := cache.UpdateNodeNameToInfoMap(cachedNodes)
+	if err != nil {
+		t.Fatalf("UpdateNodeNameToInfoMap failed: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"node-1", "node-2", "node-3"}) {
+		t.Errorf("expected all nodes re-cloned after the rules change, got %v", changed)
+	}
+	if effective := cachedNodes["node-1"].EffectiveAllocatable(); effective.MilliCPU != 2000 {
+		t.Errorf("expected the cleared ratios in the re-cloned NodeInfo, got %d", effective.MilliCPU)
+	}
+}
+
+func TestSelectVictims(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	lowPriority, midPriority, highPriority := int32(0), int32(5), int32(10)
+
+	lowPod := makeBasePod(t, "node-1", "low-pod", "1500m", "1Ki", "", nil)
+	lowPod.Spec.Priority = &lowPriority
+	lowPod.Labels = map[string]string{"app": "protected"}
+	midPod := makeBasePod(t, "node-1", "mid-pod", "400m", "1Ki", "", nil)
+	midPod.Spec.Priority = &midPriority
+
+	preemptor := makeBasePod(t, "node-1", "preemptor", "1600m", "1Ki", "", nil)
+	preemptor.Spec.Priority = &highPriority
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, pod := range []*v1.Pod{lowPod, midPod} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	// Evicting the low-priority pod alone frees enough CPU.
+	victims, err := cache.SelectVictims("node-1", preemptor, false)
+	if err != nil {
+		t.Fatalf("SelectVictims failed: %v", err)
+	}
+	if len(victims) != 1 || victims[0] != lowPod {
+		t.Errorf("expected the low-priority pod as the only victim, got: %v", victims)
+	}
+
+	// A pod that already fits needs no victims.
+	smallPreemptor := makeBasePod(t, "node-1", "small-preemptor", "50m", "1Ki", "", nil)
+	smallPreemptor.Spec.Priority = &highPriority
+	if victims, err := cache.SelectVictims("node-1", smallPreemptor, false); err != nil || len(victims) != 0 {
+		t.Errorf("expected no victims for a fitting pod, got: %v, %v", victims, err)
+	}
+
+	// With an exhausted disruption budget the low-priority pod is not
+	// eligible, and the mid-priority pod alone doesn't free enough.
+	minAvailable := intstr.FromInt(1)
+	if err := cache.AddPDB(&v1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "node_info_cache_test", Name: "pdb-1", UID: "pdb-1"},
+		Spec: v1beta1.PodDisruptionBudgetSpec{
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "protected"}},
+			MinAvailable: &minAvailable,
+		},
+	}); err != nil {
+		t.Fatalf("AddPDB failed: %v", err)
+	}
+	if _, err := cache.SelectVictims("node-1", preemptor, true); ReasonForError(err) != ReasonInsufficientVictims {
+		t.Errorf("expected an InsufficientVictims error, got: %v", err)
+	}
+	// Ignoring budgets still finds the victim set.
+	if victims, err := cache.SelectVictims("node-1", preemptor, false); err != nil || len(victims) != 1 {
+		t.Errorf("expected one victim ignoring budgets, got: %v, %v", victims, err)
+	}
+
+	if _, err := cache.SelectVictims("unknown-node", preemptor, false); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+}
+
+// TestLockProfiling tests that sampled operations show up in the contention
+// report attributed to their operation type and that disabling clears it.
+func TestLockProfiling(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "profiled", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	cache.EnableLockProfiling(1)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	cache.Snapshot()
+
+	report := cache.LockContentionReport()
+	stats := make(map[string]LockOpStats)
+	for _, opStats := range report {
+		stats[opStats.Op] = opStats
+	}
+	for _, op := range []string{"AddNode", "AddPod", "Snapshot"} {
+		opStats, ok := stats[op]
+		if !ok {
+			t.Errorf("expected %v in the report, got: %v", op, report)
+			continue
+		}
+		if opStats.Samples < 1 || opStats.TotalHold <= 0 || opStats.MaxHold > opStats.TotalHold {
+			t.Errorf("implausible stats for %v: %+v", op, opStats)
+		}
+	}
+
+	// Disabling clears the collected samples and stops collecting.
+	cache.EnableLockProfiling(0)
+	cache.Snapshot()
+	if report := cache.LockContentionReport(); len(report) != 0 {
+		t.Errorf("expected an empty report after disabling, got: %v", report)
+	}
+}
+
+// TestRankNodes tests the three ranking strategies over the cached
+// aggregates, the limit, and that cordoned nodes are skipped.
+func TestRankNodes(t *testing.T) {
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, nodeName := range []string{"node-a", "node-b", "node-c"} {
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("2"),
+					v1.ResourceMemory: resource.MustParse("4Gi"),
+				},
+			},
+		}
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	// node-a at 75% CPU / 25% memory, node-b at 25% / 25%, node-c full.
+	for _, pod := range []*v1.Pod{
+		makeBasePod(t, "node-a", "pod-a", "1500m", "1Gi", "", nil),
+		makeBasePod(t, "node-b", "pod-b", "500m", "1Gi", "", nil),
+		makeBasePod(t, "node-c", "pod-c", "2000m", "4Gi", "", nil),
+	} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	// A cordoned node never ranks.
+	cordoned := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-cordoned"},
+		Spec:       v1.NodeSpec{Unschedulable: true},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	if err := cache.AddNode(cordoned); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+
+	names := func(ranks []NodeRank) []string {
+		var result []string
+		for _, rank := range ranks {
+			result = append(result, rank.Name)
+		}
+		return result
+	}
+	tests := []struct {
+		strategy RankStrategy
+		limit    int
+		expected []string
+	}{
+		{strategy: RankLeastRequested, limit: 0, expected: []string{"node-b", "node-a", "node-c"}},
+		{strategy: RankLeastRequested, limit: 2, expected: []string{"node-b", "node-a"}},
+		{strategy: RankMostRequested, limit: 0, expected: []string{"node-c", "node-a", "node-b"}},
+		// node-b and node-c are both perfectly balanced; names break the tie.
+		{strategy: RankBalanced, limit: 1, expected: []string{"node-b"}},
+	}
+	for _, test := range tests {
+		ranks, err := cache.RankNodes(test.strategy, test.limit)
+		if err != nil {
+			t.Fatalf("RankNodes(%v) failed: %v", test.strategy, err)
+		}
+		if !reflect.DeepEqual(names(ranks), test.expected) {
+			t.Errorf("%v limit %d: expected %v, got: %v", test.strategy, test.limit, test.expected, names(ranks))
+		}
+	}
+
+	if _, err := cache.RankNodes(RankStrategy("Random"), 0); err == nil {
+		t.Errorf("expected an error for an unknown strategy")
+	}
+}
+
+// TestApplyBatch tests that a batch of events applies in one shot, that a
+// failing event is skipped without aborting the rest, and that the errors
+// come back aggregated.
+func TestApplyBatch(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "batched", "100m", "500", "", nil)
+	stranger := makeBasePod(t, "node-1", "stranger", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	err := cache.ApplyBatch([]CacheEvent{
+		{Op: ExportEventAddNode, Node: node},
+		{Op: ExportEventAddPod, Pod: pod},
+		// Removing a pod the cache never saw fails without aborting the batch.
+		{Op: ExportEventRemovePod, Pod: stranger},
+		{Op: ExportEventAddPod, Pod: makeBasePod(t, "node-1", "batched-2", "200m", "500", "", nil)},
+		// Likewise removing a node the cache never saw.
+		{Op: ExportEventRemoveNode, Node: &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ghost"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "event 2 (RemovePod)") {
+		t.Fatalf("expected an aggregate holding the failed event's error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "event 4 (RemoveNode)") {
+		t.Fatalf("expected the unknown-node removal skipped with an error, got: %v", err)
+	}
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if len(n.pods) != 2 || n.requestedResource.MilliCPU != 300 {
+		t.Errorf("expected both adds applied around the failure, got %d pods requesting %v", len(n.pods), n.requestedResource.MilliCPU)
+	}
+
+	// An all-good batch returns nil rather than an empty aggregate.
+	if err := cache.ApplyBatch([]CacheEvent{{Op: ExportEventRemovePod, Pod: pod}}); err != nil {
+		t.Fatalf("ApplyBatch failed: %v", err)
+	}
+	if n.requestedResource.MilliCPU != 200 {
+		t.Errorf("expected the removal applied, got: %v", n.requestedResource.MilliCPU)
+	}
+}
+
+// TestZeroRequestDefaults tests that the requests assumed for zero-request
+// containers are configurable cache-wide and per namespace, and that changing
+// them recomputes the defaulted aggregate without touching the raw one.
+func TestZeroRequestDefaults(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "no-requests", "", "", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if n.nonzeroRequest.MilliCPU != priorityutil.DefaultMilliCPURequest ||
+		n.nonzeroRequest.Memory != priorityutil.DefaultMemoryRequest {
+		t.Errorf("expected the compiled-in defaults, got: %+v", n.nonzeroRequest)
+	}
+
+	// Cache-wide defaults recompute the aggregate of already-cached pods.
+	cache.SetZeroRequestDefaults(&ZeroRequestDefaults{MilliCPU: 50, Memory: 1024})
+	if n.nonzeroRequest.MilliCPU != 50 || n.nonzeroRequest.Memory != 1024 {
+		t.Errorf("expected the cache-wide defaults applied, got: %+v", n.nonzeroRequest)
+	}
+	if n.requestedResource.MilliCPU != 0 || n.requestedResource.Memory != 0 {
+		t.Errorf("expected the raw aggregate untouched, got: %+v", n.requestedResource)
+	}
+
+	// A namespace policy overrides the cache-wide defaults where it answers.
+	cache.SetZeroRequestPolicy(func(namespace string) *ZeroRequestDefaults {
+		if namespace == "node_info_cache_test" {
+			return &ZeroRequestDefaults{MilliCPU: 10, Memory: 512}
+		}
+		return nil
+	})
+	if n.nonzeroRequest.MilliCPU != 10 || n.nonzeroRequest.Memory != 512 {
+		t.Errorf("expected the namespace policy applied, got: %+v", n.nonzeroRequest)
+	}
+
+	// Nodes created after the configuration inherit it.
+	pod2 := makeBasePod(t, "node-2", "no-requests-2", "", "", "", nil)
+	if err := cache.AddPod(pod2); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	n2, ok := cache.store.GetNodeInfo("node-2")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-2")
+	}
+	if n2.nonzeroRequest.MilliCPU != 10 || n2.nonzeroRequest.Memory != 512 {
+		t.Errorf("expected the policy on the new node, got: %+v", n2.nonzeroRequest)
+	}
+
+	// Removal under the same configuration is symmetric.
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if n.nonzeroRequest.MilliCPU != 0 || n.nonzeroRequest.Memory != 0 {
+		t.Errorf("expected an empty aggregate after the removal, got: %+v", n.nonzeroRequest)
+	}
+}
+
+// TestReservationGroup tests that AssumeGroup reserves a gang of pods
+// all-or-nothing and that forgetting or expiring any member releases the
+// whole group.
+func TestReservationGroup(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	worker0 := makeBasePod(t, "node-1", "worker-0", "100m", "500", "", nil)
+	worker1 := makeBasePod(t, "node-1", "worker-1", "200m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AssumeGroup("gang", []*v1.Pod{worker0, worker1}, 30*time.Second); err != nil {
+		t.Fatalf("AssumeGroup failed: %v", err)
+	}
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if n.requestedResource.MilliCPU != 300 {
+		t.Errorf("expected both members charged to the node, got: %v", n.requestedResource.MilliCPU)
+	}
+	// Group names are unique while the reservation is live.
+	if err := cache.AssumeGroup("gang", nil, 0); ReasonForError(err) != ReasonGroupExists {
+		t.Errorf("expected a GroupExists error, got: %v", err)
+	}
+
+	// A group containing an already-reserved pod fails whole, rolling back
+	// the members assumed before the conflict.
+	other := makeBasePod(t, "node-1", "other", "400m", "500", "", nil)
+	if err := cache.AssumeGroup("gang-2", []*v1.Pod{other, worker0}, 0); ReasonForError(err) != ReasonPodAlreadyAssumed {
+		t.Errorf("expected a PodAlreadyAssumed error, got: %v", err)
+	}
+	if n.requestedResource.MilliCPU != 300 {
+		t.Errorf("expected the failed group fully rolled back, got: %v", n.requestedResource.MilliCPU)
+	}
+
+	// ForgetGroup releases every member at once.
+	if err := cache.ForgetGroup("gang"); err != nil {
+		t.Fatalf("ForgetGroup failed: %v", err)
+	}
+	if n.requestedResource.MilliCPU != 0 {
+		t.Errorf("expected the forgotten group released, got: %v", n.requestedResource.MilliCPU)
+	}
+	if err := cache.ForgetGroup("gang"); ReasonForError(err) != ReasonGroupNotFound {
+		t.Errorf("expected a GroupNotFound error, got: %v", err)
+	}
+
+	// One member expiring drags the rest of its group with it.
+	if err := cache.AssumeGroup("gang", []*v1.Pod{worker0, worker1}, 30*time.Second); err != nil {
+		t.Fatalf("AssumeGroup failed: %v", err)
+	}
+	cache.cleanupAssumedPods(time.Now().Add(time.Minute))
+	if n.requestedResource.MilliCPU != 0 {
+		t.Errorf("expected the expired group fully released, got: %v", n.requestedResource.MilliCPU)
+	}
+	if infos := cache.GetAssumedPods(); len(infos) != 0 {
+		t.Errorf("expected no assumed pods after the expiry, got: %v", infos)
+	}
+}
+
+// TestPinPod tests that external binds reserve capacity like assumes, show
+// up with the Pin source, and expire after their own TTL.
+func TestPinPod(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "", "pinned", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.PinPod(pod, "node-1", 30*time.Second); err != nil {
+		t.Fatalf("PinPod failed: %v", err)
+	}
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if n.requestedResource.MilliCPU != 100 {
+		t.Errorf("expected the pin charged to the node, got: %v", n.requestedResource.MilliCPU)
+	}
+	infos := cache.GetAssumedPods()
+	if len(infos) != 1 || infos[0].Source != AssumeSourcePin || infos[0].Deadline == nil {
+		t.Errorf("expected one pinned assumed pod with a deadline, got: %v", infos)
+	}
+	// A pinned pod can't be pinned or assumed twice.
+	if err := cache.PinPod(pod, "node-1", 30*time.Second); ReasonForError(err) != ReasonPodAlreadyAssumed {
+		t.Errorf("expected a PodAlreadyAssumed error, got: %v", err)
+	}
+
+	// An unconfirmed pin expires like any assumed pod.
+	cache.cleanupAssumedPods(time.Now().Add(time.Minute))
+	if n.requestedResource.MilliCPU != 0 {
+		t.Errorf("expected the expired pin released, got: %v", n.requestedResource.MilliCPU)
+	}
+	if infos := cache.GetAssumedPods(); len(infos) != 0 {
+		t.Errorf("expected no assumed pods after the expiry, got: %v", infos)
+	}
+}
+
+// TestAllocatedResources tests that kubelet-reported allocations diverging
+// from the spec are folded into the node's accounting and consulted by the
+// resize fit check.
+func TestAllocatedResources(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	// The kubelet reports 600m allocated against the 100m spec, e.g. a
+	// downsize it hasn't applied yet.
+	resizing := makeBasePod(t, "node-1", "resizing", "100m", "500", "", nil)
+	resizing.Spec.Containers[0].Name = "app"
+	resizing.Annotations = map[string]string{AllocatedResourcesAnnotationKey: "app=cpu:600m"}
+	resizingKey, err := getPodKey(resizing)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	pod := makeBasePod(t, "node-1", "growing", "100m", "500", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, p := range []*v1.Pod{resizing, pod} {
+		if err := cache.AddPod(p); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if delta := n.AllocatedDelta(); delta.MilliCPU != 500 {
+		t.Errorf("expected a 500 milliCPU allocation delta, got: %v", delta)
+	}
+	requested, allocated, err := cache.PodAllocatedResources(resizingKey)
+	if err != nil {
+		t.Fatalf("PodAllocatedResources failed: %v", err)
+	}
+	if requested["app"].MilliCPU != 100 || allocated["app"].MilliCPU != 600 {
+		t.Errorf("expected 100 requested and 600 allocated, got: %v, %v", requested, allocated)
+	}
+
+	// Against the allocation only 1300m is free, so the 1400m growth
+	// reschedules although the specs alone would let it fit.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1500, Memory: 500})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected DeletePodForResizing against the allocation, got: %v, %v", action, err)
+	}
+	// Once allocation and spec agree again the growth fits in place.
+	converged := resizing.DeepCopy()
+	converged.Annotations = nil
+	if err := cache.UpdatePod(resizing, converged); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	if delta := n.AllocatedDelta(); delta.MilliCPU != 0 {
+		t.Errorf("expected no allocation delta after convergence, got: %v", delta)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected InPlacePreferred after convergence, got: %v, %v", action, err)
+	}
+}
+
+// TestTerminatingPodAccounting tests that terminating pods are aggregated
+// separately and that the optimistic view counts their requests as free in
+// the resize fit check.
+func TestTerminatingPodAccounting(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	terminating := makeBasePod(t, "node-1", "leaving", "1500m", "500", "", nil)
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	pod := makeBasePod(t, "node-1", "growing", "100m", "500", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, p := range []*v1.Pod{terminating, pod} {
+		if err := cache.AddPod(p); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if got := n.TerminatingResource(); got.MilliCPU != 1500 || got.Memory != 500 {
+		t.Errorf("expected the terminating pod aggregated, got: %v", got)
+	}
+	if got := n.RequestedExcludingTerminating(); got.MilliCPU != 100 {
+		t.Errorf("expected 100 milliCPU requested excluding terminating, got: %v", got)
+	}
+
+	// Pessimistically only 400m is free, so the 900m growth reschedules.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1000, Memory: 500})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected DeletePodForResizing with terminating pods counted, got: %v, %v", action, err)
+	}
+	// Optimistically the terminating 1500m is free and the growth fits.
+	cache.SetOptimisticTerminating(true)
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected InPlacePreferred with terminating counted as free, got: %v, %v", action, err)
+	}
+
+	// The aggregate empties with the pod's removal.
+	if err := cache.RemovePod(terminating); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if got := n.TerminatingResource(); got.MilliCPU != 0 || got.Memory != 0 {
+		t.Errorf("expected no terminating requests after the removal, got: %v", got)
+	}
+}
+
+// TestStrictAssume tests that strict mode rejects assumes on unknown or
+// cordoned nodes, while the default keeps tolerating them.
+func TestStrictAssume(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	// By default assumes on a node the cache hasn't seen are tolerated,
+	// because pod and node watches are delivered independently.
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if err := cache.ForgetPod(pod); err != nil {
+		t.Fatalf("ForgetPod failed: %v", err)
+	}
+
+	cache.SetStrictAssume(true)
+	if _, err := cache.AssumePod(pod); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error for an unknown node, got: %v", err)
+	}
+	cordoned := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       v1.NodeSpec{Unschedulable: true},
+	}
+	if err := cache.AddNode(cordoned); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if _, err := cache.AssumePod(pod); ReasonForError(err) != ReasonNodeCordoned {
+		t.Errorf("expected a NodeCordoned error, got: %v", err)
+	}
+	uncordoned := cordoned.DeepCopy()
+	uncordoned.Spec.Unschedulable = false
+	if err := cache.UpdateNode(cordoned, uncordoned); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Errorf("AssumePod failed after the uncordon: %v", err)
+	}
+}
+
+// TestReadReplica tests that the replica serves a stable snapshot between
+// syncs and catches up with the primary on the next one.
+func TestReadReplica(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	pod := makeBasePod(t, "node-1", "test-1", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// The long period keeps the background loop out of the test; syncs
+	// happen explicitly.
+	replica := cache.NewReadReplica(time.Hour)
+	first := replica.Snapshot()
+	if len(first.Nodes) != 1 || len(first.Nodes["node-1"].Pods()) != 1 {
+		t.Fatalf("expected the first sync to see one pod on one node, got: %v", first.Nodes)
+	}
+	if replica.SyncedAt().IsZero() {
+		t.Errorf("expected a sync time on the fresh replica")
+	}
+
+	// Mutations on the primary aren't visible until the next sync.
+	if err := cache.AddPod(makeBasePod(t, "node-1", "test-2", "100m", "500", "", nil)); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if replica.Snapshot() != first {
+		t.Errorf("expected the published snapshot unchanged before a sync")
+	}
+	replica.sync()
+	if second := replica.Snapshot(); len(second.Nodes["node-1"].Pods()) != 2 {
+		t.Errorf("expected the second sync to see both pods, got: %v", second.Nodes["node-1"].Pods())
+	}
+	// The earlier snapshot stays what it was; changed nodes were cloned,
+	// not mutated.
+	if len(first.Nodes["node-1"].Pods()) != 1 {
+		t.Errorf("expected the first snapshot untouched by the sync")
+	}
+}
+
+// TestPodDeduplication tests that pods of the same controller share one
+// canonical container spec slice and that templates of gone owners are
+// cleaned up.
+func TestPodDeduplication(t *testing.T) {
+	controller := true
+	owner := metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       "rs-1",
+		UID:        types.UID("rs-1"),
+		Controller: &controller,
+	}
+	twin1 := makeBasePod(t, "node-1", "twin-1", "100m", "500", "", nil)
+	twin2 := makeBasePod(t, "node-1", "twin-2", "100m", "500", "", nil)
+	diverged := makeBasePod(t, "node-1", "diverged", "200m", "500", "", nil)
+	for _, pod := range []*v1.Pod{twin1, twin2, diverged} {
+		pod.OwnerReferences = []metav1.OwnerReference{owner}
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	cache.EnablePodDeduplication()
+	for _, pod := range []*v1.Pod{twin1, twin2, diverged} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	cached1, err := cache.GetPod(twin1)
+	if err != nil {
+		t.Fatalf("GetPod failed: %v", err)
+	}
+	cached2, err := cache.GetPod(twin2)
+	if err != nil {
+		t.Fatalf("GetPod failed: %v", err)
+	}
+	// The twins point at the same backing array; their metadata stays
+	// their own.
+	if &cached1.Spec.Containers[0] != &cached2.Spec.Containers[0] {
+		t.Errorf("expected the twins to share the owner's container template")
+	}
+	if cached1.Name != "twin-1" || cached2.Name != "twin-2" {
+		t.Errorf("expected per-pod metadata preserved, got: %v, %v", cached1.Name, cached2.Name)
+	}
+	// A pod whose containers diverge from the template keeps its own.
+	cachedDiverged, err := cache.GetPod(diverged)
+	if err != nil {
+		t.Fatalf("GetPod failed: %v", err)
+	}
+	if &cachedDiverged.Spec.Containers[0] == &cached1.Spec.Containers[0] {
+		t.Errorf("expected the diverged pod to keep its own containers")
+	}
+	// The node's accounting still counts every pod individually.
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if n.requestedResource.MilliCPU != 400 {
+		t.Errorf("expected 400 requested milliCPU, got: %v", n.requestedResource.MilliCPU)
+	}
+
+	// Once the owner's pods are gone the template is collected.
+	for _, pod := range []*v1.Pod{twin1, twin2, diverged} {
+		if err := cache.RemovePod(pod); err != nil {
+			t.Fatalf("RemovePod failed: %v", err)
+		}
+	}
+	cache.cleanupDedupTemplates()
+	cache.mu.RLock()
+	templates := len(cache.dedupTemplates)
+	cache.mu.RUnlock()
+	if templates != 0 {
+		t.Errorf("expected no templates for gone owners, got: %v", templates)
+	}
+}
+
+// TestNamespacePodLimit tests that the per-namespace pod cap, cache-wide or
+// advertised per node, rejects assumes beyond it and excludes full nodes from
+// resize placement hints.
+func TestNamespacePodLimit(t *testing.T) {
+	allocatable := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("4"),
+		v1.ResourceMemory: resource.MustParse("4Gi"),
+	}
+	node1 := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status:     v1.NodeStatus{Allocatable: allocatable},
+	}
+	node2 := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-2",
+			Annotations: map[string]string{NamespacePodLimitAnnotationKey: "1"},
+		},
+		Status: v1.NodeStatus{Allocatable: allocatable},
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node1); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddNode(node2); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	cache.SetNamespacePodLimit(2)
+
+	for _, name := range []string{"test-1", "test-2"} {
+		if err := cache.AddPod(makeBasePod(t, "node-1", name, "100m", "500", "", nil)); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	// The third pod of the namespace exceeds the cache-wide cap of two.
+	if _, err := cache.AssumePod(makeBasePod(t, "node-1", "test-3", "100m", "500", "", nil)); ReasonForError(err) != ReasonNamespacePodLimit {
+		t.Errorf("expected a NamespacePodLimit error, got: %v", err)
+	}
+	// A different namespace starts its own count.
+	other := makeBasePod(t, "node-1", "test-other", "100m", "500", "", nil)
+	other.Namespace = "other"
+	if _, err := cache.AssumePod(other); err != nil {
+		t.Errorf("AssumePod failed for a different namespace: %v", err)
+	}
+
+	// node-2's advertisement of one overrides the cache-wide cap of two.
+	if err := cache.AddPod(makeBasePod(t, "node-2", "test-4", "100m", "500", "", nil)); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if _, err := cache.AssumePod(makeBasePod(t, "node-2", "test-5", "100m", "500", "", nil)); ReasonForError(err) != ReasonNamespacePodLimit {
+		t.Errorf("expected a NamespacePodLimit error from the node override, got: %v", err)
+	}
+
+	// Nodes at the cap don't show up as resize placement hints either.
+	node3 := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-3"},
+		Status:     v1.NodeStatus{Allocatable: allocatable},
+	}
+	if err := cache.AddNode(node3); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	pod := makeBasePod(t, "node-3", "test-6", "100m", "500", "", nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 200, Memory: 500})
+	node4 := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-4"},
+		Status:     v1.NodeStatus{Allocatable: allocatable},
+	}
+	if err := cache.AddNode(node4); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if hints, err := cache.ResizePlacementHint(key); err != nil || !reflect.DeepEqual(hints, []string{"node-4"}) {
+		t.Errorf("expected only node-4 hinted, got: %v, %v", hints, err)
+	}
+}
+
+// TestEphemeralStorageInPlaceResize tests that ephemeral-storage growth is
+// applied in place only while the disk itself has room, even when the
+// requests-based fit accounting would still allow it.
+func TestEphemeralStorageInPlaceResize(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:              resource.MustParse("2"),
+				v1.ResourceMemory:           resource.MustParse("4Gi"),
+				v1.ResourceEphemeralStorage: resource.MustParse("10Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	pod.Spec.Containers[0].Resources.Requests[v1.ResourceEphemeralStorage] = resource.MustParse("2Gi")
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// A 2Gi storage bump fits: 8Gi of the disk is unrequested and nothing
+	// is measured as used yet.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 100, Memory: 500, EphemeralStorage: 4 << 30})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected InPlacePreferred with disk headroom, got: %v, %v", action, err)
+	}
+
+	// With 9Gi measured as used only 1Gi is actually free, so the same
+	// growth falls back to rescheduling although the requests still fit.
+	if err := cache.UpdatePodUsage(key, 9<<30); err != nil {
+		t.Fatalf("UpdatePodUsage failed: %v", err)
+	}
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if free := n.FreeLocalStorage(); free != 1<<30 {
+		t.Errorf("expected 1Gi free local storage, got: %v", free)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected DeletePodForResizing with the disk nearly full, got: %v, %v", action, err)
+	}
+
+	// The next usage report frees the disk and the in-place update is
+	// preferred again.
+	if err := cache.UpdatePodUsage(key, 1<<30); err != nil {
+		t.Fatalf("UpdatePodUsage failed: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected InPlacePreferred after the usage dropped, got: %v, %v", action, err)
+	}
+}
+
+// TestResizeWatchdog tests that resizes stuck in flight past the threshold
+// are re-emitted and eventually escalated to deletion, except for pods whose
+// data is pinned to the node.
+func TestResizeWatchdog(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	pinned := makeBasePod(t, "node-1", "pinned", "100m", "500", "", nil)
+	pinned.Spec.Volumes = []v1.Volume{
+		{Name: "data", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/data"}}},
+	}
+	pinnedKey, err := getPodKey(pinned)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, p := range []*v1.Pod{pod, pinned} {
+		if err := cache.AddPod(p); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	actions := make(map[string][]ResizeWatchdogAction)
+	cache.SetResizeWatchdog(time.Minute, func(podKey string, pod *v1.Pod, action ResizeWatchdogAction) {
+		actions[podKey] = append(actions[podKey], action)
+	})
+	for _, k := range []string{key, pinnedKey} {
+		if err := cache.MarkResizeStarted(k); err != nil {
+			t.Fatalf("MarkResizeStarted failed: %v", err)
+		}
+	}
+
+	// A fresh resize is not reported.
+	cache.watchResizes()
+	if len(actions) != 0 {
+		t.Fatalf("expected no actions below the threshold, got: %v", actions)
+	}
+
+	// Each timed-out round re-emits, until the escalation point.
+	backdate := func() {
+		cache.mu.Lock()
+		for k := range cache.resizeStartedAt {
+			cache.resizeStartedAt[k] = time.Now().Add(-2 * time.Minute)
+		}
+		cache.mu.Unlock()
+	}
+	for i := 0; i < 3; i++ {
+		backdate()
+		cache.watchResizes()
+	}
+	if got := actions[key]; len(got) != 3 || got[0] != ResizeWatchdogReemit || got[2] != ResizeWatchdogDelete {
+		t.Errorf("expected two re-emits then an escalation, got: %v", got)
+	}
+	// The hostPath pod is never escalated to deletion.
+	if got := actions[pinnedKey]; len(got) != 3 || got[2] != ResizeWatchdogReemit {
+		t.Errorf("expected only re-emits for the pinned pod, got: %v", got)
+	}
+
+	// Completing the resize stops the watchdog and resets the strikes.
+	cache.MarkResizeCompleted(key)
+	backdate()
+	cache.watchResizes()
+	if got := actions[key]; len(got) != 3 {
+		t.Errorf("expected no action after the resize completed, got: %v", got)
+	}
+}
+
+// TestPriorityBands tests that per-node requests are bucketed by priority
+// band and that reclaimable capacity sums the bands below a priority.
+func TestPriorityBands(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	systemPriority := scheduling.HighestUserDefinablePriority + 1
+	highPriority, defaultPriority, lowPriority := int32(10), int32(0), int32(-5)
+
+	pods := []struct {
+		name     string
+		priority *int32
+		cpu      string
+	}{
+		{"system", &systemPriority, "400m"},
+		{"high", &highPriority, "300m"},
+		{"default", &defaultPriority, "200m"},
+		{"low", &lowPriority, "100m"},
+	}
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, p := range pods {
+		pod := makeBasePod(t, "node-1", p.name, p.cpu, "500", "", nil)
+		pod.Spec.Priority = p.priority
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	bands := map[PriorityBand]int64{
+		PriorityBandSystem:  400,
+		PriorityBandHigh:    300,
+		PriorityBandDefault: 200,
+		PriorityBandLow:     100,
+	}
+	for band, milliCPU := range bands {
+		if requested := n.RequestedByBand(band); requested.MilliCPU != milliCPU {
+			t.Errorf("expected %vm requested in band %v, got: %v", milliCPU, band, requested.MilliCPU)
+		}
+	}
+
+	// Preempting at high priority can reclaim the default and low bands;
+	// pods inside the high band itself are not counted.
+	if reclaimable := n.ReclaimableBelow(highPriority); reclaimable.MilliCPU != 300 {
+		t.Errorf("expected 300m reclaimable below high priority, got: %v", reclaimable.MilliCPU)
+	}
+	if reclaimable := n.ReclaimableBelow(systemPriority); reclaimable.MilliCPU != 600 {
+		t.Errorf("expected 600m reclaimable below system priority, got: %v", reclaimable.MilliCPU)
+	}
+	if reclaimable := n.ReclaimableBelow(lowPriority); reclaimable.MilliCPU != 0 {
+		t.Errorf("expected nothing reclaimable below a low priority, got: %v", reclaimable.MilliCPU)
+	}
+}
+
+// TestGetPodNode tests that pod placement resolves through the cache,
+// including assumed pods whose binding isn't confirmed yet.
+func TestGetPodNode(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	added := makeBasePod(t, "node-1", "added", "100m", "500", "", nil)
+	addedKey, err := getPodKey(added)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	assumed := makeBasePod(t, "node-1", "assumed", "100m", "500", "", nil)
+	assumedKey, err := getPodKey(assumed)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(added); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if _, err := cache.AssumePod(assumed); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+
+	if nodeName, isAssumed, err := cache.GetPodNode(addedKey); err != nil || nodeName != "node-1" || isAssumed {
+		t.Errorf("expected node-1 and a confirmed placement, got: %v, %v, %v", nodeName, isAssumed, err)
+	}
+	if nodeName, isAssumed, err := cache.GetPodNode(assumedKey); err != nil || nodeName != "node-1" || !isAssumed {
+		t.Errorf("expected node-1 and an assumed placement, got: %v, %v, %v", nodeName, isAssumed, err)
+	}
+	if _, _, err := cache.GetPodNode("unknown"); ReasonForError(err) != ReasonPodNotFound {
+		t.Errorf("expected a PodNotFound error, got: %v", err)
+	}
+}
+
+// TestSnapshotMatching tests that scoped snapshots carry only the nodes
+// matching the selector, with the pods and bookkeeping on them.
+func TestSnapshotMatching(t *testing.T) {
+	poolNode := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"pool": "batch"}}}
+	otherNode := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"pool": "serving"}}}
+	poolPod := makeBasePod(t, "node-1", "pool-pod", "100m", "500", "", nil)
+	otherPod := makeBasePod(t, "node-2", "other-pod", "100m", "500", "", nil)
+	assumed := makeBasePod(t, "node-2", "assumed", "100m", "500", "", nil)
+	assumedKey, err := getPodKey(assumed)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, node := range []*v1.Node{poolNode, otherNode} {
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	for _, pod := range []*v1.Pod{poolPod, otherPod} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	if _, err := cache.AssumePod(assumed); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+
+	snapshot := cache.SnapshotMatching(labels.SelectorFromSet(labels.Set{"pool": "batch"}))
+	if len(snapshot.Nodes) != 1 || snapshot.Nodes["node-1"] == nil {
+		t.Fatalf("expected only node-1 in the scoped snapshot, got: %v", snapshot.Nodes)
+	}
+	if pods := snapshot.Nodes["node-1"].Pods(); len(pods) != 1 || pods[0].Name != "pool-pod" {
+		t.Errorf("expected only the pool pod, got: %v", pods)
+	}
+	if len(snapshot.AssumedPods) != 0 {
+		t.Errorf("expected no assumed pods outside the pool, got: %v", snapshot.AssumedPods)
+	}
+
+	// The assumed pod on node-2 shows up when its pool is selected.
+	snapshot = cache.SnapshotMatching(labels.SelectorFromSet(labels.Set{"pool": "serving"}))
+	if len(snapshot.Nodes) != 1 || snapshot.Nodes["node-2"] == nil {
+		t.Fatalf("expected only node-2 in the scoped snapshot, got: %v", snapshot.Nodes)
+	}
+	if !snapshot.AssumedPods[assumedKey] {
+		t.Errorf("expected the assumed pod included, got: %v", snapshot.AssumedPods)
+	}
+
+	if snapshot := cache.SnapshotMatching(labels.SelectorFromSet(labels.Set{"pool": "none"})); len(snapshot.Nodes) != 0 {
+		t.Errorf("expected an empty scoped snapshot, got: %v", snapshot.Nodes)
+	}
+}
+
+// TestCacheFeatureGate tests that a cache consults its injected feature-gate
+// reader, so caches with different gates can coexist in one process.
+func TestCacheFeatureGate(t *testing.T) {
+	enabled := utilfeature.NewFeatureGate()
+	if err := enabled.Add(map[utilfeature.Feature]utilfeature.FeatureSpec{
+		features.BalanceAttachedNodeVolumes: {Default: true, PreRelease: utilfeature.Alpha},
+	}); err != nil {
+		t.Fatalf("adding the feature failed: %v", err)
+	}
+	disabled := utilfeature.NewFeatureGate()
+	if err := disabled.Add(map[utilfeature.Feature]utilfeature.FeatureSpec{
+		features.BalanceAttachedNodeVolumes: {Default: false, PreRelease: utilfeature.Alpha},
+	}); err != nil {
+		t.Fatalf("adding the feature failed: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	first := NewWithOptions(CacheOptions{TTL: time.Second, FeatureGate: enabled}, stop).(*schedulerCache)
+	second := NewWithOptions(CacheOptions{TTL: time.Second, FeatureGate: disabled}, stop).(*schedulerCache)
+	if !first.featureGate.Enabled(features.BalanceAttachedNodeVolumes) {
+		t.Errorf("expected the feature enabled through the injected gate")
+	}
+	if second.featureGate.Enabled(features.BalanceAttachedNodeVolumes) {
+		t.Errorf("expected the feature disabled through the injected gate")
+	}
+
+	// Without an injected gate the cache falls back to the global one.
+	fallback := newSchedulerCache(time.Second, time.Second, nil)
+	if fallback.featureGate != utilfeature.DefaultFeatureGate {
+		t.Errorf("expected the process-global gate as the fallback")
+	}
+}
+
+// TestRecentPDBDecisions tests that disruption-budget check outcomes are
+// journaled with the matching PDBs and remaining budget.
+func TestRecentPDBDecisions(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	lowPriority, highPriority := int32(0), int32(10)
+	victim := makeBasePod(t, "node-1", "victim", "1500m", "1Ki", "", nil)
+	victim.Spec.Priority = &lowPriority
+	victim.Labels = map[string]string{"app": "protected"}
+	preemptor := makeBasePod(t, "node-1", "preemptor", "1600m", "1Ki", "", nil)
+	preemptor.Spec.Priority = &highPriority
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(victim); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	minAvailable := intstr.FromInt(0)
+	if err := cache.AddPDB(&v1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "node_info_cache_test", Name: "pdb-1", UID: "pdb-1"},
+		Spec: v1beta1.PodDisruptionBudgetSpec{
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "protected"}},
+			MinAvailable: &minAvailable,
+		},
+	}); err != nil {
+		t.Fatalf("AddPDB failed: %v", err)
+	}
+
+	// The budget permits one disruption, so the victim is eligible and the
+	// decision is journaled as permitted.
+	if victims, err := cache.SelectVictims("node-1", preemptor, true); err != nil || len(victims) != 1 {
+		t.Fatalf("expected one victim, got: %v, %v", victims, err)
+	}
+	decisions := cache.RecentPDBDecisions()
+	if len(decisions) != 1 {
+		t.Fatalf("expected one journaled decision, got: %v", decisions)
+	}
+	if d := decisions[0]; d.Name != "victim" || !d.Permitted || d.Allowed != 1 ||
+		len(d.PDBs) != 1 || d.PDBs[0] != "pdb-1" {
+		t.Errorf("expected a permitted decision against pdb-1 with budget 1, got: %+v", d)
+	}
+
+	// Once the disruption is marked, the budget is exhausted and the next
+	// check is journaled as blocked.
+	if err := cache.MarkPodDisruption(victim); err != nil {
+		t.Fatalf("MarkPodDisruption failed: %v", err)
+	}
+	if _, err := cache.SelectVictims("node-1", preemptor, true); ReasonForError(err) != ReasonInsufficientVictims {
+		t.Errorf("expected an InsufficientVictims error, got: %v", err)
+	}
+	decisions = cache.RecentPDBDecisions()
+	if len(decisions) != 2 {
+		t.Fatalf("expected two journaled decisions, got: %v", decisions)
+	}
+	if d := decisions[1]; d.Permitted || d.Allowed != 0 {
+		t.Errorf("expected a blocked decision with budget 0, got: %+v", d)
+	}
+}
+
+// TestGetAssumedPods tests that assumed pods are reported with their source,
+// assume time and expiry deadline.
+func TestGetAssumedPods(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	bound := makeBasePod(t, "node-1", "bound", "100m", "500", "", nil)
+	boundKey, err := getPodKey(bound)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	held := makeBasePod(t, "node-1", "held", "100m", "500", "", nil)
+	held.Annotations = map[string]string{AnnotationPodSchedulingGate: "quota"}
+	heldKey, err := getPodKey(held)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, pod := range []*v1.Pod{bound, held} {
+		if _, err := cache.AssumePod(pod); err != nil {
+			t.Fatalf("AssumePod failed: %v", err)
+		}
+	}
+	if err := cache.FinishBinding(bound); err != nil {
+		t.Fatalf("FinishBinding failed: %v", err)
+	}
+
+	infos := cache.GetAssumedPods()
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 assumed pods, got: %v", infos)
+	}
+	// Sorted by pod key: "bound" before "held" (the UID is the name).
+	if infos[0].PodKey != boundKey || infos[0].Source != AssumeSourceBind {
+		t.Errorf("expected the bound pod with source Bind first, got: %+v", infos[0])
+	}
+	if infos[0].Deadline == nil {
+		t.Errorf("expected a deadline once the binding finished")
+	}
+	if infos[1].PodKey != heldKey || infos[1].Source != AssumeSourceReservation {
+		t.Errorf("expected the held pod with source Reservation, got: %+v", infos[1])
+	}
+	if infos[1].Deadline != nil {
+		t.Errorf("expected no deadline for a held pod, got: %v", infos[1].Deadline)
+	}
+
+	if bucket := assumedPodAgeBucket(10 * time.Second); bucket != "30s" {
+		t.Errorf("expected the 30s bucket, got: %v", bucket)
+	}
+	if bucket := assumedPodAgeBucket(10 * time.Minute); bucket != "5m+" {
+		t.Errorf("expected the overflow bucket, got: %v", bucket)
+	}
+}
+
+// TestForEachPod tests that the chunked iterator visits every pod across
+// nodes and honors early termination.
+func TestForEachPod(t *testing.T) {
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, nodeName := range []string{"node-1", "node-2"} {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	pods := []*v1.Pod{
+		makeBasePod(t, "node-1", "test-1", "100m", "500", "", nil),
+		makeBasePod(t, "node-1", "test-2", "100m", "500", "", nil),
+		makeBasePod(t, "node-2", "test-3", "100m", "500", "", nil),
+	}
+	for _, pod := range pods {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	cache.ForEachPod(func(pod *v1.Pod) bool {
+		seen[pod.Name] = true
+		return true
+	})
+	if len(seen) != len(pods) {
+		t.Errorf("expected all %d pods visited, got: %v", len(pods), seen)
+	}
+
+	visited := 0
+	cache.ForEachPod(func(pod *v1.Pod) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("expected the iteration stopped after one pod, got: %v", visited)
+	}
+}
+
+// TestPodMutationQueue tests that queued annotation mutations are merged,
+// applied through the injected updater outside the lock, and retried on
+// failure.
+func TestPodMutationQueue(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	var applied []map[string]string
+	fail := true
+	cache.SetPodUpdater(func(pod *v1.Pod, annotations map[string]string) error {
+		if fail {
+			return fmt.Errorf("conflict")
+		}
+		applied = append(applied, annotations)
+		return nil
+	})
+
+	// Enqueues for the same pod merge, keeping the newest value per key.
+	cache.EnqueuePodMutation(key, map[string]string{"a": "1", "b": "1"})
+	cache.EnqueuePodMutation(key, map[string]string{"b": "2"})
+
+	// The first round fails and requeues; the second applies the merged
+	// mutation exactly once.
+	cache.processPodMutations()
+	if len(applied) != 0 {
+		t.Fatalf("expected no mutation applied while the updater fails, got: %v", applied)
+	}
+	fail = false
+	cache.processPodMutations()
+	if len(applied) != 1 || applied[0]["a"] != "1" || applied[0]["b"] != "2" {
+		t.Errorf("expected the merged mutation applied once, got: %v", applied)
+	}
+	cache.processPodMutations()
+	if len(applied) != 1 {
+		t.Errorf("expected no reapplication of a drained queue, got: %v", applied)
+	}
+
+	// Mutations for pods that left the cache are dropped.
+	cache.EnqueuePodMutation(key, map[string]string{"c": "3"})
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	cache.processPodMutations()
+	if len(applied) != 1 {
+		t.Errorf("expected the orphaned mutation dropped, got: %v", applied)
+	}
+}
+
+// TestPodsByOwner tests that pods are indexed by their controller UID and
+// that per-owner spread counts resolve through the nodes' topology labels.
+func TestPodsByOwner(t *testing.T) {
+	controller := true
+	owner := metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       "rs-1",
+		UID:        types.UID("rs-1"),
+		Controller: &controller,
+	}
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"zone": "zone-a"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"zone": "zone-b"}}},
+	}
+	owned := []*v1.Pod{
+		makeBasePod(t, "node-1", "owned-1", "100m", "500", "", nil),
+		makeBasePod(t, "node-1", "owned-2", "100m", "500", "", nil),
+		makeBasePod(t, "node-2", "owned-3", "100m", "500", "", nil),
+	}
+	for _, pod := range owned {
+		pod.OwnerReferences = []metav1.OwnerReference{owner}
+	}
+	loner := makeBasePod(t, "node-1", "loner", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, node := range nodes {
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	for _, pod := range append(owned, loner) {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	if pods := cache.PodsByOwner(owner.UID); len(pods) != 3 {
+		t.Errorf("expected 3 owned pods, got: %v", len(pods))
+	}
+	if pods := cache.PodsByOwner(types.UID("unknown")); len(pods) != 0 {
+		t.Errorf("expected no pods for an unknown owner, got: %v", len(pods))
+	}
+	counts := cache.OwnerSpreadCounts(owner.UID, "zone")
+	if counts["zone-a"] != 2 || counts["zone-b"] != 1 {
+		t.Errorf("expected 2 pods in zone-a and 1 in zone-b, got: %v", counts)
+	}
+
+	// Removing a pod updates the index incrementally.
+	if err := cache.RemovePod(owned[0]); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if pods := cache.PodsByOwner(owner.UID); len(pods) != 2 {
+		t.Errorf("expected 2 owned pods after the remove, got: %v", len(pods))
+	}
+	if counts := cache.OwnerSpreadCounts(owner.UID, "zone"); counts["zone-a"] != 1 {
+		t.Errorf("expected 1 pod left in zone-a, got: %v", counts)
+	}
+}
+
+// TestCachedFit tests that admission results memoized per pod template hash
+// stay valid exactly as long as the node's generation is unchanged.
+func TestCachedFit(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+
+	if err := cache.StoreFit("abcd1234", "node-1", true); err != nil {
+		t.Fatalf("StoreFit failed: %v", err)
+	}
+	if fit, ok := cache.CachedFit("abcd1234", "node-1"); !ok || !fit {
+		t.Errorf("expected a cached fit, got: %v, %v", fit, ok)
+	}
+	if _, ok := cache.CachedFit("other", "node-1"); ok {
+		t.Errorf("expected a miss for an unknown template hash")
+	}
+
+	// Any NodeInfo mutation bumps the generation and drops the entry.
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if _, ok := cache.CachedFit("abcd1234", "node-1"); ok {
+		t.Errorf("expected the cached fit invalidated by the pod add")
+	}
+	if err := cache.StoreFit("abcd1234", "node-1", false); err != nil {
+		t.Fatalf("StoreFit failed: %v", err)
+	}
+	if fit, ok := cache.CachedFit("abcd1234", "node-1"); !ok || fit {
+		t.Errorf("expected a cached non-fit, got: %v, %v", fit, ok)
+	}
+
+	if err := cache.StoreFit("abcd1234", "unknown-node", true); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+	if _, ok := cache.CachedFit("abcd1234", "unknown-node"); ok {
+		t.Errorf("expected a miss for an unknown node")
+	}
+}
+
+// TestNodeLocalVolumes tests that pods using node-local storage are never
+// planned for DeletePodForResizing, since rescheduling them would lose data.
+func TestNodeLocalVolumes(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	hostPathPod := makeBasePod(t, "node-1", "hostpath", "100m", "500", "", nil)
+	hostPathPod.Spec.Volumes = []v1.Volume{
+		{Name: "data", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/var/data"}}},
+	}
+	hostPathKey, err := getPodKey(hostPathPod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	pvcPod := makeBasePod(t, "node-1", "pvc", "100m", "500", "", nil)
+	pvcPod.Spec.Volumes = []v1.Volume{
+		{Name: "data", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data-1"}}},
+	}
+	pvcKey, err := getPodKey(pvcPod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, pod := range []*v1.Pod{hostPathPod, pvcPod} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	// The growth exceeds the node, so the fallback would be a delete; the
+	// hostPath volume pins the pod's data to the node instead.
+	cache.SetRecommendedResources(hostPathKey, &Resource{MilliCPU: 3000, Memory: 500})
+	if action, err := cache.PlanResize(hostPathKey); action != ResizeActionNone || ReasonForError(err) != ReasonNodeLocalData {
+		t.Errorf("expected a NodeLocalData error for the hostPath pod, got: %v, %v", action, err)
+	}
+
+	// The PVC pod is only pinned while its claim is in the node-local
+	// volume index.
+	cache.SetRecommendedResources(pvcKey, &Resource{MilliCPU: 3000, Memory: 500})
+	cache.AddNodeLocalPVC(pvcPod.Namespace, "data-1")
+	if action, err := cache.PlanResize(pvcKey); action != ResizeActionNone || ReasonForError(err) != ReasonNodeLocalData {
+		t.Errorf("expected a NodeLocalData error for the pinned PVC pod, got: %v, %v", action, err)
+	}
+	cache.RemoveNodeLocalPVC(pvcPod.Namespace, "data-1")
+	if action, err := cache.PlanResize(pvcKey); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected DeletePodForResizing once the claim is unpinned, got: %v, %v", action, err)
+	}
+}
+
+// TestCSINodeAttachSlots tests that per-driver allocatable attach counts are
+// ingested and the in-use side tracks pods' PVC volumes incrementally.
+func TestCSINodeAttachSlots(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	pod.Spec.Volumes = []v1.Volume{
+		{Name: "data", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data-1"}}},
+		{Name: "unbound", VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "unbound-1"}}},
+		{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	cache.SetPVCDriverResolver(func(namespace, claim string) (string, bool) {
+		if claim == "data-1" {
+			return "ebs.csi.example.com", true
+		}
+		return "", false
+	})
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddCSINode("node-1", map[string]int32{"ebs.csi.example.com": 3}); err != nil {
+		t.Fatalf("AddCSINode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	remaining, err := cache.RemainingAttachSlots("node-1")
+	if err != nil {
+		t.Fatalf("RemainingAttachSlots failed: %v", err)
+	}
+	if remaining["ebs.csi.example.com"] != 2 {
+		t.Errorf("expected 2 remaining attach slots, got: %v", remaining)
+	}
+
+	// Removing the pod releases its slot; an updated limit replaces the
+	// allocatable side without touching the in-use counts.
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if err := cache.UpdateCSINode("node-1", map[string]int32{"ebs.csi.example.com": 1}); err != nil {
+		t.Fatalf("UpdateCSINode failed: %v", err)
+	}
+	remaining, err = cache.RemainingAttachSlots("node-1")
+	if err != nil {
+		t.Fatalf("RemainingAttachSlots failed: %v", err)
+	}
+	if remaining["ebs.csi.example.com"] != 1 {
+		t.Errorf("expected 1 remaining attach slot, got: %v", remaining)
+	}
+
+	cache.RemoveCSINode("node-1")
+	remaining, err = cache.RemainingAttachSlots("node-1")
+	if err != nil || len(remaining) != 0 {
+		t.Errorf("expected no advertised drivers after removal, got: %v, %v", remaining, err)
+	}
+	if _, err := cache.RemainingAttachSlots("unknown-node"); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+	if err := cache.AddCSINode("unknown-node", nil); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+}
+
+// TestUnhealthyResources tests that advertised unhealthy extended resource
+// units are subtracted from the node's allocatable scalars.
+func TestUnhealthyResources(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-1",
+			Annotations: map[string]string{UnhealthyResourcesAnnotationKey: "nvidia.com/gpu=1"},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+				"nvidia.com/gpu":  resource.MustParse("4"),
+			},
+		},
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	n := nodeInfoOf(cache, "node-1")
+	if got := n.AllocatableResource().ScalarResources["nvidia.com/gpu"]; got != 3 {
+		t.Errorf("expected 3 healthy GPUs, got: %v", got)
+	}
+
+	// A recovery reported via node update restores the full count, and
+	// more unhealthy units than advertised clamp at zero.
+	healed := node.DeepCopy()
+	healed.Annotations = nil
+	if err := cache.UpdateNode(node, healed); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	if got := n.AllocatableResource().ScalarResources["nvidia.com/gpu"]; got != 4 {
+		t.Errorf("expected the full count restored, got: %v", got)
+	}
+	broken := node.DeepCopy()
+	broken.Annotations[UnhealthyResourcesAnnotationKey] = "nvidia.com/gpu=9"
+	if err := cache.UpdateNode(healed, broken); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	if got := n.AllocatableResource().ScalarResources["nvidia.com/gpu"]; got != 0 {
+		t.Errorf("expected the count clamped at zero, got: %v", got)
+	}
+
+	// Malformed annotations are ignored rather than zeroing devices out.
+	if _, err := parseUnhealthyResources("nvidia.com/gpu"); err == nil {
+		t.Errorf("expected an error for a count without a value")
+	}
+	if _, err := parseUnhealthyResources("nvidia.com/gpu=-1"); err == nil {
+		t.Errorf("expected an error for a negative count")
+	}
+}
+
+// TestStaticCPUPool tests that on static CPU manager nodes exclusive and
+// shared CPU are tracked separately and both assumes and in-place resizes
+// validate against the right pool.
+func TestStaticCPUPool(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{CPUManagerPolicyLabelKey: CPUManagerPolicyStatic},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		},
+	}
+	guaranteed := func(objName, cpu string) *v1.Pod {
+		pod := makeBasePod(t, "node-1", objName, cpu, "1Gi", "", nil)
+		pod.Spec.Containers[0].Resources.Limits = v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse("1Gi"),
+		}
+		return pod
+	}
+	excl := guaranteed("excl", "2")
+	shared := makeBasePod(t, "node-1", "shared", "500m", "1Gi", "", nil)
+	exclKey, err := getPodKey(excl)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	sharedKey, err := getPodKey(shared)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	if got := exclusiveCPURequest(excl); got != 2000 {
+		t.Errorf("expected a 2000m exclusive grant, got: %v", got)
+	}
+	if got := exclusiveCPURequest(shared); got != 0 {
+		t.Errorf("expected no exclusive grant for a burstable pod, got: %v", got)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, pod := range []*v1.Pod{excl, shared} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	n := nodeInfoOf(cache, "node-1")
+	if n.ExclusiveMilliCPU() != 2000 || n.SharedCPUPoolMilli() != 2000 {
+		t.Errorf("expected a 2000m/2000m exclusive/shared split, got: %v/%v", n.ExclusiveMilliCPU(), n.SharedCPUPoolMilli())
+	}
+
+	// An exclusive pod past the remaining whole CPUs, and a shared pod
+	// past the shared pool, are both rejected at assume time.
+	if _, err := cache.AssumePod(guaranteed("excl-2", "3")); ReasonForError(err) != ReasonCPUPoolExhausted {
+		t.Errorf("expected a CPUPoolExhausted error for the exclusive pod, got: %v", err)
+	}
+	big := makeBasePod(t, "node-1", "shared-2", "2500m", "1Gi", "", nil)
+	if _, err := cache.AssumePod(big); ReasonForError(err) != ReasonCPUPoolExhausted {
+		t.Errorf("expected a CPUPoolExhausted error for the shared pod, got: %v", err)
+	}
+
+	// Exclusive grants are pinned: the pod can't be resized in place.
+	cache.SetRecommendedResources(exclKey, &Resource{MilliCPU: 3000, Memory: 1 << 30})
+	if action, err := cache.PlanResize(exclKey); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected DeletePodForResizing for the exclusive pod, got: %v, %v", action, err)
+	}
+	cache.SetRecommendedResources(exclKey, nil)
+
+	// Shared growth is bounded by the pool, not the node.
+	cache.SetRecommendedResources(sharedKey, &Resource{MilliCPU: 2500, Memory: 1 << 30})
+	if action, err := cache.PlanResize(sharedKey); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected DeletePodForResizing past the shared pool, got: %v, %v", action, err)
+	}
+	cache.SetRecommendedResources(sharedKey, &Resource{MilliCPU: 1000, Memory: 1 << 30})
+	if action, err := cache.PlanResize(sharedKey); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected InPlacePreferred inside the shared pool, got: %v, %v", action, err)
+	}
+}
+
+// TestMaxSchedulableUnit tests that the largest still-schedulable pod tracks
+// the node's free capacity, counting pending resizes as occupied.
+func TestMaxSchedulableUnit(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Gi", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	unit, err := cache.MaxSchedulableUnit("node-1")
+	if err != nil {
+		t.Fatalf("MaxSchedulableUnit failed: %v", err)
+	}
+	if unit.MilliCPU != 1500 || unit.Memory != 3<<30 {
+		t.Errorf("expected a 1500m/3Gi unit, got: %v", unit)
+	}
+
+	// A pending resize shrinks the unit before the kubelet applies it.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1000, Memory: 1 << 30})
+	unit, err = cache.MaxSchedulableUnit("node-1")
+	if err != nil {
+		t.Fatalf("MaxSchedulableUnit failed: %v", err)
+	}
+	if unit.MilliCPU != 1000 || unit.Memory != 3<<30 {
+		t.Errorf("expected the pending 500m growth subtracted, got: %v", unit)
+	}
+
+	// Overcommitted components clamp at zero instead of going negative.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 5000, Memory: 1 << 30})
+	unit, err = cache.MaxSchedulableUnit("node-1")
+	if err != nil {
+		t.Fatalf("MaxSchedulableUnit failed: %v", err)
+	}
+	if unit.MilliCPU != 0 {
+		t.Errorf("expected the CPU component clamped at zero, got: %v", unit)
+	}
+
+	if _, err := cache.MaxSchedulableUnit("unknown-node"); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+}
+
+// TestScoreDataExport tests that one CSV feature vector per (pending pod,
+// candidate node) pair is exported, computed against the cached state.
+func TestScoreDataExport(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+			Images: []v1.ContainerImage{{Names: []string{"img:v1"}, SizeBytes: 1 << 20}},
+		},
+	}
+	controller := metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       "rs",
+		UID:        types.UID("rs-1"),
+		Controller: func() *bool { b := true; return &b }(),
+	}
+	existing := makeBasePod(t, "node-1", "existing", "100m", "500", "", []v1.ContainerPort{{HostPort: 80, Protocol: v1.ProtocolTCP}})
+	existing.OwnerReferences = []metav1.OwnerReference{controller}
+	pending := makeBasePod(t, "", "pending", "200m", "500", "", []v1.ContainerPort{{HostPort: 80, Protocol: v1.ProtocolTCP}})
+	pending.OwnerReferences = []metav1.OwnerReference{controller}
+	pending.Spec.Containers[0].Image = "img:v1"
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(existing); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	cache.SetScoreDataExport(time.Minute, func() []*v1.Pod { return []*v1.Pod{pending} }, &buf)
+	cache.exportScoreDataIfDue()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and one row, got: %q", lines)
+	}
+	if lines[0] != strings.Join(scoreDataHeader, ",") {
+		t.Errorf("expected the header first, got: %q", lines[0])
+	}
+	row := strings.Split(lines[1], ",")
+	if len(row) != len(scoreDataHeader) {
+		t.Fatalf("expected %v columns, got: %q", len(scoreDataHeader), row)
+	}
+	if row[0] != "node_info_cache_test/pending" || row[1] != "node-1" {
+		t.Errorf("expected the (pod, node) pair first, got: %q", row[:2])
+	}
+	if row[2] != "0.0500" || row[4] != "1900" {
+		t.Errorf("expected the utilization features, got: %q", row)
+	}
+	if row[6] != "1" || row[7] != "1" || row[8] != "1" {
+		t.Errorf("expected image presence, a port conflict and one colocated pod, got: %q", row)
+	}
+
+	// Within the interval nothing more is appended.
+	before := buf.Len()
+	cache.exportScoreDataIfDue()
+	if buf.Len() != before {
+		t.Errorf("expected no export before the interval elapses")
+	}
+}
+
+// TestReplay tests that a recorded event stream reconstructs cache state,
+// skipping events that fail to apply and reporting them alongside the
+// snapshot.
+func TestReplay(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	kept := makeBasePod(t, "node-1", "kept", "100m", "500", "", nil)
+	removed := makeBasePod(t, "node-1", "removed", "200m", "500", "", nil)
+
+	snapshot, err := Replay([]CacheEvent{
+		{Op: ExportEventAddNode, Node: node},
+		{Op: ExportEventAddPod, Pod: kept},
+		{Op: ExportEventAddPod, Pod: removed},
+		{Op: ExportEventRemovePod, Pod: removed},
+		// Removing it again can't apply; the rest of the stream still does.
+		{Op: ExportEventRemovePod, Pod: removed},
+	})
+	if err == nil {
+		t.Errorf("expected the double removal reported")
+	}
+	n, ok := snapshot.Nodes["node-1"]
+	if !ok {
+		t.Fatalf("expected node-1 in the replayed snapshot")
+	}
+	if len(n.Pods()) != 1 || n.Pods()[0].UID != kept.UID {
+		t.Errorf("expected only the kept pod replayed, got: %v", n.Pods())
+	}
+	if n.RequestedResource().MilliCPU != 100 {
+		t.Errorf("expected the replayed aggregates, got: %v", n.RequestedResource())
+	}
+
+	// Events round-trip from the audit log's retained entries.
+	events := EventsOf([]AuditEntry{{Op: ExportEventAddNode, Node: node}})
+	if len(events) != 1 || events[0].Op != ExportEventAddNode || events[0].Node != node {
+		t.Errorf("expected the audit entry's replayable subset, got: %v", events)
+	}
+
+	// Unknown operations are reported, not silently dropped.
+	if _, err := Replay([]CacheEvent{{Op: ExportEventType("Defragment")}}); err == nil {
+		t.Errorf("expected an error for an unknown operation")
+	}
+
+	// A log truncated by the audit retention can open with a RemoveNode whose
+	// AddNode fell out of the retained window; it is skipped like any other
+	// failing event rather than taking down the replay.
+	snapshot, err = Replay([]CacheEvent{
+		{Op: ExportEventRemoveNode, Node: &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "truncated"}}},
+		{Op: ExportEventAddNode, Node: node},
+		{Op: ExportEventAddPod, Pod: kept},
+	})
+	if err == nil {
+		t.Errorf("expected the truncated removal reported")
+	}
+	if n, ok := snapshot.Nodes["node-1"]; !ok || len(n.Pods()) != 1 {
+		t.Errorf("expected the events after the truncation replayed intact")
+	}
+}
+
+// TestNodeReservedResources tests that the node's advertised kube-reserved
+// plus system-reserved overhead is modeled and subtracted by the resize fit
+// check.
+func TestNodeReservedResources(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-1",
+			Annotations: map[string]string{NodeReservedAnnotationKey: "cpu=500m,memory=1Gi"},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "1", "500", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	n, ok := cache.store.GetNodeInfo("node-1")
+	if !ok {
+		t.Fatalf("expected a NodeInfo for node-1")
+	}
+	if reserved := n.ReservedResource(); reserved.MilliCPU != 500 || reserved.Memory != 1<<30 {
+		t.Errorf("expected the advertised overhead modeled, got: %v", reserved)
+	}
+
+	// The 800m growth doesn't fit once the 500m overhead is subtracted
+	// from the 1000m that would otherwise be free.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1800, Memory: 500})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected DeletePodForResizing with the overhead subtracted, got: %v, %v", action, err)
+	}
+
+	// Without the annotation the same growth fits in place.
+	unreserved := node.DeepCopy()
+	unreserved.Annotations = nil
+	if err := cache.UpdateNode(node, unreserved); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected InPlacePreferred without the overhead, got: %v, %v", action, err)
+	}
+
+	// A malformed annotation is ignored rather than blocking resizes.
+	if reserved, err := parseReservedResources("cpu=half"); err == nil {
+		t.Errorf("expected an error for a malformed quantity, got: %v", reserved)
+	}
+	if reserved, err := parseReservedResources("cpu"); err == nil {
+		t.Errorf("expected an error for a reservation without a quantity, got: %v", reserved)
+	}
+	bad := node.DeepCopy()
+	bad.Annotations[NodeReservedAnnotationKey] = "cpu"
+	if reservedResourcesOf(bad) != nil {
+		t.Errorf("expected no overhead from a malformed annotation")
+	}
+}
+
+// TestDownsizeAnalyzer tests that sustained over-provisioning is reported
+// once as a dry-run downsize suggestion and resets with the usage.
+func TestDownsizeAnalyzer(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	pod.Spec.Containers[0].Resources.Requests[v1.ResourceEphemeralStorage] = resource.MustParse("1Gi")
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	var suggestions []*Resource
+	cache.SetDownsizeAnalyzer(2.0, 10*time.Millisecond, func(p *v1.Pod, suggested *Resource) error {
+		if p.UID != pod.UID {
+			t.Errorf("expected a suggestion for %v, got one for %v", pod.UID, p.UID)
+		}
+		suggestions = append(suggestions, suggested)
+		return nil
+	})
+
+	// 1Gi requested against 100 bytes used starts a streak, but nothing is
+	// emitted before it outlasts the window.
+	if err := cache.UpdatePodUsage(key, 100); err != nil {
+		t.Fatalf("UpdatePodUsage failed: %v", err)
+	}
+	cache.analyzeDownsizes()
+	if len(suggestions) != 0 {
+		t.Errorf("expected no suggestion before the sustained window, got: %v", suggestions)
+	}
+
+	// After the window the suggestion comes exactly once, shrinking the
+	// ephemeral storage to the measured usage.
+	time.Sleep(20 * time.Millisecond)
+	cache.analyzeDownsizes()
+	cache.analyzeDownsizes()
+	if len(suggestions) != 1 {
+		t.Fatalf("expected exactly one suggestion, got: %v", suggestions)
+	}
+	if suggestions[0].EphemeralStorage != 100 || suggestions[0].MilliCPU != 100 {
+		t.Errorf("expected the current requests with ephemeral storage shrunk to 100, got: %v", suggestions[0])
+	}
+
+	// A report without over-provisioning resets the streak, so a relapse
+	// is reported again after a fresh sustained window.
+	if err := cache.UpdatePodUsage(key, 1<<30); err != nil {
+		t.Fatalf("UpdatePodUsage failed: %v", err)
+	}
+	cache.mu.RLock()
+	_, tracked := cache.overProvisionedSince[key]
+	cache.mu.RUnlock()
+	if tracked {
+		t.Errorf("expected the streak reset by a healthy usage report")
+	}
+	if err := cache.UpdatePodUsage(key, 100); err != nil {
+		t.Fatalf("UpdatePodUsage failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	cache.analyzeDownsizes()
+	if len(suggestions) != 2 {
+		t.Errorf("expected the relapse reported again, got %v suggestions", len(suggestions))
+	}
+}
+
+// TestResizePriority tests that pending resizes are ordered by priority and
+// that a contended rate budget is reserved for the higher-priority ones.
+func TestResizePriority(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		},
+	}
+	low := makeBasePod(t, "node-1", "low", "500m", "1Ki", "", nil)
+	high := makeBasePod(t, "node-1", "high", "500m", "1Ki", "", nil)
+	high.Annotations = map[string]string{ResizePriorityAnnotationKey: "100"}
+	lowKey, err := getPodKey(low)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	highKey, err := getPodKey(high)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, pod := range []*v1.Pod{low, high} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	cache.SetRecommendedResources(lowKey, &Resource{MilliCPU: 1000})
+	cache.SetRecommendedResources(highKey, &Resource{MilliCPU: 1000})
+
+	// The annotated pod's resize comes first despite the larger pod key.
+	pending := cache.PendingResizes("node-1")
+	if len(pending) != 2 || pending[0].PodKey != highKey || pending[0].Priority != 100 || pending[1].Priority != 0 {
+		t.Errorf("expected the high-priority resize first, got: %v", pending)
+	}
+
+	// With one slot per window, the slot is held back for the
+	// higher-priority resize until that one has started.
+	cache.SetResizeRateLimit(1, time.Minute)
+	if err := cache.MarkResizeStarted(lowKey); ReasonForError(err) != ReasonResizeRateLimited {
+		t.Errorf("expected the budget reserved for the higher priority, got: %v", err)
+	}
+	if err := cache.MarkResizeStarted(highKey); err != nil {
+		t.Fatalf("MarkResizeStarted failed: %v", err)
+	}
+	if err := cache.MarkResizeStarted(lowKey); ReasonForError(err) != ReasonResizeRateLimited {
+		t.Errorf("expected the window's budget spent, got: %v", err)
+	}
+
+	// Disabling the cap admits the remaining resize right away.
+	cache.SetResizeRateLimit(0, 0)
+	if err := cache.MarkResizeStarted(lowKey); err != nil {
+		t.Fatalf("MarkResizeStarted failed: %v", err)
+	}
+
+	// A malformed annotation falls back to the pod's scheduling priority.
+	bad := low.DeepCopy()
+	bad.Annotations = map[string]string{ResizePriorityAnnotationKey: "not-a-number"}
+	if got := resizePriorityOf(bad); got != 0 {
+		t.Errorf("expected fallback priority 0, got: %v", got)
+	}
+}
+
+// TestResizeStatus tests that per-container resize outcomes finish the
+// attempt, and that a partial failure keeps the resize pending instead of
+// dropping it with the successes.
+func TestResizeStatus(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1000})
+	if err := cache.MarkResizeStarted(key); err != nil {
+		t.Fatalf("MarkResizeStarted failed: %v", err)
+	}
+
+	// A partial failure ends the attempt but keeps the recommendation, so
+	// the remaining delta - the failed containers' portion - stays planned.
+	partial := pod.DeepCopy()
+	partial.Annotations = map[string]string{ResizeStatusAnnotationKey: "c1=ok,c2=failed:oom killed during restart"}
+	if err := cache.UpdatePod(pod, partial); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	if cache.IsResizing(key) {
+		t.Errorf("expected the in-flight mark released by the status")
+	}
+	cache.mu.RLock()
+	_, hasRec := cache.recommendations[key]
+	_, hasLast := cache.lastResize[key]
+	cache.mu.RUnlock()
+	if !hasRec {
+		t.Errorf("expected the recommendation kept after a partial failure")
+	}
+	if !hasLast {
+		t.Errorf("expected the attempt to start the cooldown window")
+	}
+
+	// A fully successful status completes the resize.
+	done := partial.DeepCopy()
+	done.Annotations[ResizeStatusAnnotationKey] = "c1=ok,c2=ok"
+	if err := cache.UpdatePod(partial, done); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	cache.mu.RLock()
+	_, hasRec = cache.recommendations[key]
+	cache.mu.RUnlock()
+	if hasRec {
+		t.Errorf("expected the recommendation dropped after full success")
+	}
+
+	// Malformed results are ignored.
+	if _, err := parseResizeStatus("c1"); err == nil {
+		t.Errorf("expected an error for a result without an outcome")
+	}
+	if _, err := parseResizeStatus("c1=exploded"); err == nil {
+		t.Errorf("expected an error for an unknown outcome")
+	}
+	if results, err := parseResizeStatus("c1=ok,c2=failed"); err != nil || len(results) != 2 || results[1].Succeeded {
+		t.Errorf("expected a parsed two-container status, got: %v, %v", results, err)
+	}
+}
+
+// TestResizeNoop tests that a resize request matching the pod's current
+// requests completes immediately without going through the kubelet.
+func TestResizeNoop(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// A recommendation equal to the current requests plans as a no-op; a
+	// partial one specifying only matching resources does too.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 500, Memory: 1024})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNoop {
+		t.Errorf("expected a no-op for a matching recommendation, got: %v, %v", action, err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 500})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNoop {
+		t.Errorf("expected a no-op for a matching partial recommendation, got: %v, %v", action, err)
+	}
+	// A shrink is still None, not a no-op.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 400})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNone {
+		t.Errorf("expected None for a shrink, got: %v, %v", action, err)
+	}
+
+	// A no-op resize annotation completes on ingestion: no recommendation
+	// is stored and an in-flight mark is released.
+	if err := cache.MarkResizeStarted(key); err != nil {
+		t.Fatalf("MarkResizeStarted failed: %v", err)
+	}
+	annotated := pod.DeepCopy()
+	annotated.Annotations = map[string]string{ResizeRequestAnnotationKey: "cpu=500m"}
+	if err := cache.UpdatePod(pod, annotated); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	cache.mu.RLock()
+	_, hasRec := cache.recommendations[key]
+	cache.mu.RUnlock()
+	if hasRec {
+		t.Errorf("expected no recommendation stored for a no-op request")
+	}
+	if cache.IsResizing(key) {
+		t.Errorf("expected the in-flight mark released by the no-op request")
+	}
+}
+
+// TestResizeGranularity tests that recommendations are rounded as they are
+// ingested, turning byte-level deltas into no-ops.
+func TestResizeGranularity(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Gi", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	cache.SetResizeGranularity(100, 1<<20)
+
+	// A recommendation within half a granule of the current requests
+	// rounds back onto them and becomes a no-op.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 520, Memory: (1 << 30) + 1000})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNoop {
+		t.Errorf("expected a sub-granularity delta to be a no-op, got: %v, %v", action, err)
+	}
+
+	// A real growth is rounded to the nearest granule and carried out.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 560, Memory: 1 << 30})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize for a rounded growth, got: %v, %v", action, err)
+	}
+	cache.mu.RLock()
+	rounded := cache.recommendations[key]
+	cache.mu.RUnlock()
+	if rounded.MilliCPU != 600 {
+		t.Errorf("expected the recommendation rounded to 600m, got %dm", rounded.MilliCPU)
+	}
+
+	// The annotation path is rounded the same way.
+	annotated := pod.DeepCopy()
+	annotated.Annotations = map[string]string{ResizeRequestAnnotationKey: "cpu=649m"}
+	if err := cache.UpdatePod(pod, annotated); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	cache.mu.RLock()
+	rounded = cache.recommendations[key]
+	cache.mu.RUnlock()
+	if rounded.MilliCPU != 600 {
+		t.Errorf("expected the annotated request rounded to 600m, got %dm", rounded.MilliCPU)
+	}
+
+	// Without a configured granularity, recommendations stay raw.
+	cache.SetResizeGranularity(0, 0)
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 501})
+	cache.mu.RLock()
+	raw := cache.recommendations[key]
+	cache.mu.RUnlock()
+	if raw.MilliCPU != 501 {
+		t.Errorf("expected the raw recommendation to be kept, got %dm", raw.MilliCPU)
+	}
+}
+
+// TestNodeHeartbeat tests lease ingestion, heartbeat ages, and the staleness
+// gate on assumes and resize planning.
+func TestNodeHeartbeat(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// Before any lease is ingested the heartbeat is unknown and the gate
+	// doesn't apply.
+	if _, err := cache.NodeHeartbeatAge("node-1"); ReasonForError(err) != ReasonHeartbeatUnknown {
+		t.Errorf("expected a HeartbeatUnknown error, got: %v", err)
+	}
+	cache.SetHeartbeatThreshold(time.Minute)
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1000, Memory: 1024})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize without a lease, got: %v, %v", action, err)
+	}
+
+	// A stale lease refuses assumes and in-place resizes.
+	if err := cache.UpdateNodeLease("node-1", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("UpdateNodeLease failed: %v", err)
+	}
+	if age, err := cache.NodeHeartbeatAge("node-1"); err != nil || age < time.Hour {
+		t.Errorf("expected a heartbeat age of at least an hour, got: %v, %v", age, err)
+	}
+	other := makeBasePod(t, "node-1", "other", "100m", "500", "", nil)
+	if _, err := cache.AssumePod(other); ReasonForError(err) != ReasonNodeHeartbeatStale {
+		t.Errorf("expected a NodeHeartbeatStale error, got: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected rescheduling off a stale node, got: %v, %v", action, err)
+	}
+
+	// A renewed lease reopens the node; older renew times are ignored.
+	if err := cache.UpdateNodeLease("node-1", time.Now()); err != nil {
+		t.Fatalf("UpdateNodeLease failed: %v", err)
+	}
+	if err := cache.UpdateNodeLease("node-1", time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatalf("UpdateNodeLease failed: %v", err)
+	}
+	if age, err := cache.NodeHeartbeatAge("node-1"); err != nil || age > time.Minute {
+		t.Errorf("expected a fresh heartbeat, got: %v, %v", age, err)
+	}
+	if _, err := cache.AssumePod(other); err != nil {
+		t.Errorf("expected the assume to pass with a fresh lease, got: %v", err)
+	}
+
+	if err := cache.UpdateNodeLease("unknown-node", time.Now()); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+}
+
+// TestCompositeCache tests cluster registration, the merged snapshot's
+// prefixed names, and per-cluster assume routing.
+func TestCompositeCache(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	east := newSchedulerCache(time.Second, time.Second, nil)
+	west := newSchedulerCache(time.Second, time.Second, nil)
+	for _, member := range []*schedulerCache{east, west} {
+		if err := member.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+
+	composite := NewCompositeCache()
+	if err := composite.AddCluster("east", east); err != nil {
+		t.Fatalf("AddCluster failed: %v", err)
+	}
+	if err := composite.AddCluster("west", west); err != nil {
+		t.Fatalf("AddCluster failed: %v", err)
+	}
+	if err := composite.AddCluster("east", east); err == nil {
+		t.Errorf("expected an error registering a duplicate cluster")
+	}
+	if err := composite.AddCluster("bad/name", east); err == nil {
+		t.Errorf("expected an error for a cluster name with a separator")
+	}
+
+	// The merged snapshot carries both clusters' nodes under prefixed names.
+	snapshot := composite.Snapshot()
+	if len(snapshot.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes in the merged snapshot, got: %v", snapshot.Nodes)
+	}
+	for _, name := range []string{"east/node-1", "west/node-1"} {
+		if snapshot.Nodes[name] == nil {
+			t.Errorf("expected node %v in the merged snapshot", name)
+		}
+	}
+	if cluster, nodeName, ok := SplitClusterNodeName("east/node-1"); !ok || cluster != "east" || nodeName != "node-1" {
+		t.Errorf("expected the prefixed name to split, got: %v, %v, %v", cluster, nodeName, ok)
+	}
+
+	// Assumes are routed to the owning cluster with the bare node name.
+	pod := makeBasePod(t, "east/node-1", "test", "100m", "500", "", nil)
+	if _, err := composite.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if assumed, err := east.IsAssumedPod(pod); err != nil || !assumed {
+		t.Errorf("expected the pod assumed in the east cluster, got: %v, %v", assumed, err)
+	}
+	if assumed, _ := west.IsAssumedPod(pod); assumed {
+		t.Errorf("expected the west cluster untouched")
+	}
+	if key, err := getPodKey(pod); err != nil || !composite.Snapshot().AssumedPods["east/"+key] {
+		t.Errorf("expected the assumed pod under a prefixed key in the merged snapshot")
+	}
+
+	// Pods without a cluster prefix or with an unknown cluster are refused.
+	bare := makeBasePod(t, "node-1", "bare", "100m", "500", "", nil)
+	if _, err := composite.AssumePod(bare); err == nil {
+		t.Errorf("expected an error for a node name without a cluster prefix")
+	}
+	unknown := makeBasePod(t, "north/node-1", "unknown", "100m", "500", "", nil)
+	if _, err := composite.AssumePod(unknown); err == nil {
+		t.Errorf("expected an error for an unregistered cluster")
+	}
+
+	composite.RemoveCluster("west")
+	if len(composite.Snapshot().Nodes) != 1 {
+		t.Errorf("expected only the east nodes after removing west")
+	}
+}
+
+// TestCheckpoint tests the content and pacing of periodic checkpoints and
+// that restoring one re-marks in-flight resizes.
+func TestCheckpoint(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	added := makeBasePod(t, "node-1", "added", "100m", "500", "", nil)
+	assumed := makeBasePod(t, "node-1", "assumed", "100m", "500", "", nil)
+	addedKey, err := getPodKey(added)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	assumedKey, err := getPodKey(assumed)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(added); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if _, err := cache.AssumePod(assumed); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if err := cache.MarkResizeStarted(addedKey); err != nil {
+		t.Fatalf("MarkResizeStarted failed: %v", err)
+	}
+
+	var written []*Checkpoint
+	cache.SetCheckpointing(time.Hour, func(cp *Checkpoint) error {
+		written = append(written, cp)
+		return nil
+	})
+
+	// The first round writes; another inside the interval doesn't.
+	cache.checkpointIfDue()
+	cache.checkpointIfDue()
+	if len(written) != 1 {
+		t.Fatalf("expected exactly one checkpoint, got %d", len(written))
+	}
+	cp := written[0]
+	if cp.Generation <= 0 {
+		t.Errorf("expected a positive generation, got %d", cp.Generation)
+	}
+	if !reflect.DeepEqual(cp.AssumedPods, []string{assumedKey}) {
+		t.Errorf("expected the assumed pod in the checkpoint, got: %v", cp.AssumedPods)
+	}
+	if !reflect.DeepEqual(cp.ResizingPods, []string{addedKey}) {
+		t.Errorf("expected the resizing pod in the checkpoint, got: %v", cp.ResizingPods)
+	}
+
+	// A standby restoring the checkpoint keeps the resize mark, so it won't
+	// re-issue the action, and sweeps marks whose pod never shows up.
+	standby := newSchedulerCache(time.Second, time.Second, nil)
+	if err := standby.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := standby.AddPod(added); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	standby.RestoreCheckpoint(cp)
+	if !standby.IsResizing(addedKey) {
+		t.Errorf("expected the restored pod to be marked as resizing")
+	}
+	standby.RestoreCheckpoint(&Checkpoint{ResizingPods: []string{"gone"}})
+	standby.cleanupResizeAnnotations()
+	if standby.IsResizing("gone") {
+		t.Errorf("expected the mark of an absent pod to be swept")
+	}
+	if !standby.IsResizing(addedKey) {
+		t.Errorf("expected the present pod's mark to survive the sweep")
+	}
+}
+
+// TestIsNodeDrainable tests the pod-level conditions that block a drain:
+// missing controllers, exhausted budgets, and pending resizes.
+func TestIsNodeDrainable(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	isController := true
+	replicated := makeBasePod(t, "node-1", "replicated", "100m", "500", "", nil)
+	replicated.OwnerReferences = []metav1.OwnerReference{
+		{Kind: "ReplicaSet", Name: "rs-1", Controller: &isController},
+	}
+	replicated.Labels = map[string]string{"app": "web"}
+	daemon := makeBasePod(t, "node-1", "daemon", "100m", "500", "", nil)
+	daemon.OwnerReferences = []metav1.OwnerReference{
+		{Kind: "DaemonSet", Name: "ds-1", Controller: &isController},
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, pod := range []*v1.Pod{replicated, daemon} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	// Replicated pods drain freely and DaemonSet pods don't count.
+	if drainable, reasons, err := cache.IsNodeDrainable("node-1"); err != nil || !drainable {
+		t.Errorf("expected the node to be drainable, got: %v, %v, %v", drainable, reasons, err)
+	}
+
+	// A pod without a controller blocks the drain.
+	bare := makeBasePod(t, "node-1", "bare", "100m", "500", "", nil)
+	if err := cache.AddPod(bare); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if drainable, reasons, _ := cache.IsNodeDrainable("node-1"); drainable || len(reasons) != 1 {
+		t.Errorf("expected one blocking reason for the bare pod, got: %v, %v", drainable, reasons)
+	}
+	if err := cache.RemovePod(bare); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+
+	// An exhausted disruption budget blocks the drain.
+	minAvailable := intstr.FromInt(1)
+	if err := cache.AddPDB(&v1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "node_info_cache_test", Name: "pdb-1", UID: "pdb-1"},
+		Spec: v1beta1.PodDisruptionBudgetSpec{
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			MinAvailable: &minAvailable,
+		},
+	}); err != nil {
+		t.Fatalf("AddPDB failed: %v", err)
+	}
+	if drainable, reasons, _ := cache.IsNodeDrainable("node-1"); drainable || len(reasons) != 1 {
+		t.Errorf("expected one blocking reason for the budget, got: %v, %v", drainable, reasons)
+	}
+	if err := cache.RemovePDB(&v1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "node_info_cache_test", Name: "pdb-1", UID: "pdb-1"},
+	}); err != nil {
+		t.Fatalf("RemovePDB failed: %v", err)
+	}
+
+	// An outstanding resize recommendation blocks the drain until cleared.
+	key, err := getPodKey(replicated)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 200, Memory: 1024})
+	if drainable, reasons, _ := cache.IsNodeDrainable("node-1"); drainable || len(reasons) != 1 {
+		t.Errorf("expected one blocking reason for the pending resize, got: %v, %v", drainable, reasons)
+	}
+	cache.SetRecommendedResources(key, nil)
+	if drainable, _, err := cache.IsNodeDrainable("node-1"); err != nil || !drainable {
+		t.Errorf("expected the node to be drainable again, got: %v, %v", drainable, err)
+	}
+
+	if _, _, err := cache.IsNodeDrainable("unknown-node"); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+}
+
+// TestNodeIterator tests that the iteration order round-robins across the
+// domains of the spread key.
+func TestNodeIterator(t *testing.T) {
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-1",
+			Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: "zone-a"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-2",
+			Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: "zone-b"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-3",
+			Labels: map[string]string{kubeletapis.LabelZoneFailureDomain: "zone-a"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-4"}},
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, node := range nodes {
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+
+	it := cache.NodeIterator(kubeletapis.LabelZoneFailureDomain)
+	if it.Len() != 4 {
+		t.Fatalf("expected the iterator to cover 4 nodes, got %d", it.Len())
+	}
+	var order []string
+	for {
+		name, ok := it.Next()
+		if !ok {
+			break
+		}
+		order = append(order, name)
+	}
+	// Zone-a, zone-b, and the unlabeled trailing domain are interleaved.
+	if !reflect.DeepEqual(order, []string{"node-1", "node-2", "node-4", "node-3"}) {
+		t.Errorf("expected a zone-interleaved order, got: %v", order)
+	}
+
+	// An unknown spread key degrades to one unlabeled domain with every node.
+	it = cache.NodeIterator("no-such-label")
+	if it.Len() != 4 {
+		t.Errorf("expected the iterator to cover 4 nodes for an unknown key, got %d", it.Len())
+	}
+	// The zero value is exhausted.
+	if _, ok := (&NodeIterator{}).Next(); ok {
+		t.Errorf("expected the zero iterator to be exhausted")
+	}
+}
+
+// TestSampleNodes tests the size, filtering, and exclusions of the random
+// node sample.
+func TestSampleNodes(t *testing.T) {
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	names := []string{"node-1", "node-2", "node-3", "node-4"}
+	for i, name := range names {
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse(fmt.Sprintf("%d", i+1)),
+					v1.ResourceMemory: resource.MustParse("4Gi"),
+				},
+			},
+		}
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+
+	// A sample larger than the cluster returns every node.
+	if sample := cache.SampleNodes(10, nil); !reflect.DeepEqual(sample, names) {
+		t.Errorf("expected all nodes in an oversized sample, got: %v", sample)
+	}
+	// A smaller sample is capped and drawn from the cluster.
+	sample := cache.SampleNodes(2, nil)
+	if len(sample) != 2 {
+		t.Fatalf("expected a sample of two nodes, got: %v", sample)
+	}
+	for _, name := range sample {
+		if !containsString(names, name) {
+			t.Errorf("sampled unknown node %v", name)
+		}
+	}
+	// The filter restricts the population.
+	bigNodes := func(n *NodeInfo) bool { return n.AllocatableResource().MilliCPU >= 3000 }
+	if sample := cache.SampleNodes(10, bigNodes); !reflect.DeepEqual(sample, []string{"node-3", "node-4"}) {
+		t.Errorf("expected only the filtered nodes, got: %v", sample)
+	}
+	// Non-positive sizes and deleted nodes yield nothing.
+	if sample := cache.SampleNodes(0, nil); sample != nil {
+		t.Errorf("expected no sample for size zero, got: %v", sample)
+	}
+	pod := makeBasePod(t, "node-4", "test", "100m", "500", "", nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if err := cache.RemoveNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-4"}}); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if sample := cache.SampleNodes(10, nil); containsString(sample, "node-4") {
+		t.Errorf("expected the deleted node to be excluded, got: %v", sample)
+	}
+}
+
+// TestUpdateNodeNameToInfoMapChanged tests that the returned change list
+// covers exactly the refreshed and removed entries.
+func TestUpdateNodeNameToInfoMapChanged(t *testing.T) {
+	nodes := []*v1.Node{}
+	for _, name := range []string{"node-1", "node-2"} {
+		nodes = append(nodes, &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("2"),
+					v1.ResourceMemory: resource.MustParse("4Gi"),
+				},
+			},
+		})
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, node := range nodes {
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+
+	// The first update refreshes every node, a repeated one none.
+	cachedNodes := map[string]*NodeInfo{}
+	changed, err := cache.UpdateNodeNameToInfoMap(cachedNodes)
+	if err != nil {
+		t.Fatalf("UpdateNodeNameToInfoMap failed: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"node-1", "node-2"}) {
+		t.Errorf("expected both nodes in the change list, got: %v", changed)
+	}
+	if changed, err := cache.UpdateNodeNameToInfoMap(cachedNodes); err != nil || len(changed) != 0 {
+		t.Errorf("expected an empty change list without churn, got: %v, %v", changed, err)
+	}
+
+	// A pod add bumps its node's generation; only that node is refreshed.
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if changed, err := cache.UpdateNodeNameToInfoMap(cachedNodes); err != nil || !reflect.DeepEqual(changed, []string{"node-1"}) {
+		t.Errorf("expected only the pod's node in the change list, got: %v, %v", changed, err)
+	}
+
+	// A removed node shows up in the change list as its entry is dropped.
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if err := cache.RemoveNode(nodes[0]); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	changed, err = cache.UpdateNodeNameToInfoMap(cachedNodes)
+	if err != nil {
+		t.Fatalf("UpdateNodeNameToInfoMap failed: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"node-1"}) {
+		t.Errorf("expected the removed node in the change list, got: %v", changed)
+	}
+	if _, ok := cachedNodes["node-1"]; ok {
+		t.Errorf("expected the removed node's entry to be dropped")
+	}
+}
+
+// TestResizeInterlock tests that a pod with a resize in flight is deferred
+// from preemption and a pod marked for preemption can't start a resize.
+func TestResizeInterlock(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	lowPriority, highPriority := int32(0), int32(10)
+	victim := makeBasePod(t, "node-1", "victim", "1500m", "1Ki", "", nil)
+	victim.Spec.Priority = &lowPriority
+	preemptor := makeBasePod(t, "node-1", "preemptor", "1600m", "1Ki", "", nil)
+	preemptor.Spec.Priority = &highPriority
+	key, err := getPodKey(victim)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(victim); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// A pod mid-resize is not eligible as a victim, and marking it for
+	// preemption fails.
+	if err := cache.MarkResizeStarted(key); err != nil {
+		t.Fatalf("MarkResizeStarted failed: %v", err)
+	}
+	if !cache.IsResizing(key) {
+		t.Errorf("expected IsResizing to report the resize in flight")
+	}
+	if _, err := cache.SelectVictims("node-1", preemptor, false); ReasonForError(err) != ReasonInsufficientVictims {
+		t.Errorf("expected an InsufficientVictims error while the victim is resizing, got: %v", err)
+	}
+	if err := cache.MarkPendingPreemption(key); ReasonForError(err) != ReasonResizeConflict {
+		t.Errorf("expected a ResizeConflict error marking a resizing pod, got: %v", err)
+	}
+
+	// Completing the resize releases the pod for preemption.
+	cache.MarkResizeCompleted(key)
+	if cache.IsResizing(key) {
+		t.Errorf("expected the resize to be cleared by MarkResizeCompleted")
+	}
+	if victims, err := cache.SelectVictims("node-1", preemptor, false); err != nil || len(victims) != 1 {
+		t.Errorf("expected one victim after the resize completed, got: %v, %v", victims, err)
+	}
+
+	// In the other direction, a pod marked for preemption can't start a
+	// resize and PlanResize defers.
+	if err := cache.MarkPendingPreemption(key); err != nil {
+		t.Fatalf("MarkPendingPreemption failed: %v", err)
+	}
+	if err := cache.MarkResizeStarted(key); ReasonForError(err) != ReasonResizeConflict {
+		t.Errorf("expected a ResizeConflict error starting a resize on a marked pod, got: %v", err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1600, Memory: 1024})
+	if _, err := cache.PlanResize(key); ReasonForError(err) != ReasonResizeConflict {
+		t.Errorf("expected PlanResize to defer for a marked pod, got: %v", err)
+	}
+	cache.ClearPendingPreemption(key)
+	if err := cache.MarkResizeStarted(key); err != nil {
+		t.Errorf("expected the resize to start once the preemption was abandoned, got: %v", err)
+	}
+
+	// Removing the pod drops its interlock marks.
+	if err := cache.RemovePod(victim); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if cache.IsResizing(key) {
+		t.Errorf("expected the interlock to be cleared when the pod was removed")
+	}
+}
+
+// TestInPlaceResizeCapability tests that PlanResize falls back to
+// rescheduling on nodes whose kubelet didn't advertise in-place resize
+// support.
+func TestInPlaceResizeCapability(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-1",
+			Annotations: map[string]string{InPlaceResizeSupportedAnnotationKey: "false"},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1000, Memory: 1024})
+
+	// The growth fits, but the kubelet would ignore an in-place update.
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected rescheduling on a node without resize support, got: %v, %v", action, err)
+	}
+	if nodeInfoOf(cache, "node-1").InPlaceResizeSupported() {
+		t.Errorf("expected NodeInfo to report in-place resize as unsupported")
+	}
+
+	// An updated advertisement re-enables in-place resizes; absence of the
+	// annotation means supported, for kubelets predating it.
+	supported := node.DeepCopy()
+	supported.Annotations = nil
+	if err := cache.UpdateNode(node, supported); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize after the node advertised support, got: %v, %v", action, err)
+	}
+}
+
+// TestResizeAnnotationGC tests that stale resize annotations are cleared via
+// the injected patch and that bookkeeping of departed pods is dropped.
+func TestResizeAnnotationGC(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	pod.Annotations = map[string]string{ResizeRequestAnnotationKey: "cpu=1"}
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	var patched []*v1.Pod
+	cache.SetResizeAnnotationGC(time.Nanosecond, func(p *v1.Pod, annotations []string) error {
+		patched = append(patched, p)
+		return nil
+	})
+
+	// Before a resize completes there is nothing to sweep.
+	cache.cleanupResizeAnnotations()
+	if len(patched) != 0 {
+		t.Errorf("expected no patches before a completed resize, got: %v", patched)
+	}
+
+	// A long-completed resize has its annotations cleared.
+	cache.MarkResizeCompleted(key)
+	time.Sleep(2 * time.Nanosecond)
+	cache.cleanupResizeAnnotations()
+	if len(patched) != 1 || patched[0].Name != "test" {
+		t.Fatalf("expected the pod to be patched, got: %v", patched)
+	}
+
+	// Bookkeeping of pods no longer in the cache is dropped, so the next
+	// sweep has nothing left to do.
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	patched = nil
+	cache.cleanupResizeAnnotations()
+	if len(patched) != 0 {
+		t.Errorf("expected no patches after the pod left the cache, got: %v", patched)
+	}
+	cache.mu.RLock()
+	_, hasLast := cache.lastResize[key]
+	cache.mu.RUnlock()
+	if hasLast {
+		t.Errorf("expected the pod's resize bookkeeping to be dropped")
+	}
+}
+
+// TestPendingResizes tests that outstanding resizes are reported per node and
+// that their growth counts as occupied in fit checks.
+func TestPendingResizes(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	first := makeBasePod(t, "node-1", "first", "500m", "1Ki", "", nil)
+	second := makeBasePod(t, "node-1", "second", "500m", "1Ki", "", nil)
+	firstKey, err := getPodKey(first)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	secondKey, err := getPodKey(second)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, pod := range []*v1.Pod{first, second} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	if pending := cache.PendingResizes("node-1"); len(pending) != 0 {
+		t.Errorf("expected no pending resizes, got: %v", pending)
+	}
+
+	// Both recommendations show up with their net delta, sorted by key.
+	cache.SetRecommendedResources(firstKey, &Resource{MilliCPU: 1200, Memory: 1024})
+	cache.SetRecommendedResources(secondKey, &Resource{MilliCPU: 400, Memory: 2048})
+	pending := cache.PendingResizes("node-1")
+	if len(pending) != 2 || pending[0].PodKey != firstKey || pending[1].PodKey != secondKey {
+		t.Fatalf("expected both pending resizes sorted by key, got: %v", pending)
+	}
+	if pending[0].Delta.MilliCPU != 700 || pending[1].Delta.MilliCPU != -100 || pending[1].Delta.Memory != 1024 {
+		t.Errorf("expected the net deltas to be reported, got: %v", pending)
+	}
+	if pending := cache.PendingResizes("node-2"); len(pending) != 0 {
+		t.Errorf("expected no pending resizes on other nodes, got: %v", pending)
+	}
+
+	// The first pod's pending growth of 700m leaves 300m free; the second
+	// pod's own shrink doesn't help it grow CPU beyond that.
+	cache.SetRecommendedResources(secondKey, &Resource{MilliCPU: 900, Memory: 1024})
+	if action, err := cache.PlanResize(secondKey); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected the pending growth to block the resize, got: %v, %v", action, err)
+	}
+	// Without the first pod's pending resize the same growth fits.
+	cache.SetRecommendedResources(firstKey, nil)
+	if action, err := cache.PlanResize(secondKey); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected the resize to fit without pending growth, got: %v, %v", action, err)
+	}
+}
+
+// TestResizeRequestDedup tests that resize request annotations are ingested
+// into the recommendation store and that identical re-syncs are skipped.
+func TestResizeRequestDedup(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// An update carrying the annotation feeds the recommendation store.
+	requested := pod.DeepCopy()
+	requested.ResourceVersion = "2"
+	requested.Annotations = map[string]string{ResizeRequestAnnotationKey: "cpu=1500m,memory=2Ki"}
+	if err := cache.UpdatePod(pod, requested); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected the annotation to drive a resize, got: %v, %v", action, err)
+	}
+
+	// A re-sync with identical content is a no-op: state derived from the
+	// request is not reset.
+	cache.SetRecommendedResources(key, nil)
+	resynced := requested.DeepCopy()
+	resynced.ResourceVersion = "3"
+	if err := cache.UpdatePod(requested, resynced); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNone {
+		t.Errorf("expected the identical re-sync to be skipped, got: %v, %v", action, err)
+	}
+
+	// Changed content is ingested again; removing the annotation clears the
+	// request.
+	changed := resynced.DeepCopy()
+	changed.ResourceVersion = "4"
+	changed.Annotations[ResizeRequestAnnotationKey] = "cpu=1800m"
+	if err := cache.UpdatePod(resynced, changed); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected the changed request to be ingested, got: %v, %v", action, err)
+	}
+	cleared := changed.DeepCopy()
+	cleared.ResourceVersion = "5"
+	delete(cleared.Annotations, ResizeRequestAnnotationKey)
+	if err := cache.UpdatePod(changed, cleared); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNone {
+		t.Errorf("expected the cleared request to clear the recommendation, got: %v, %v", action, err)
+	}
+}
+
+// TestResizeCooldown tests that pods resized within the cooldown window are
+// rejected with a distinct reason.
+func TestResizeCooldown(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1000, Memory: 1024})
+
+	// A fresh resize inside the cooldown window is rejected.
+	cache.SetResizeCooldown(time.Hour)
+	cache.MarkResizeCompleted(key)
+	if _, err := cache.PlanResize(key); ReasonForError(err) != ReasonResizeCooldown {
+		t.Errorf("expected a ResizeCooldown error, got: %v", err)
+	}
+
+	// Outside the window, or with the cooldown disabled, planning works.
+	cache.SetResizeCooldown(0)
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize without a cooldown, got: %v, %v", action, err)
+	}
+	cache.SetResizeCooldown(time.Nanosecond)
+	time.Sleep(2 * time.Nanosecond)
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize after the cooldown, got: %v, %v", action, err)
+	}
+
+	// Pods never resized aren't affected by the cooldown.
+	cache.SetResizeCooldown(time.Hour)
+	other := makeBasePod(t, "node-1", "other", "100m", "500", "", nil)
+	if err := cache.AddPod(other); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	otherKey, err := getPodKey(other)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	cache.SetRecommendedResources(otherKey, &Resource{MilliCPU: 200, Memory: 512})
+	if action, err := cache.PlanResize(otherKey); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize for a never-resized pod, got: %v, %v", action, err)
+	}
+}
+
+// TestUpdateNodeUsage tests that measured usage samples decay into the ratios
+// exposed by NodeInfo.UsageRatio.
+func TestUpdateNodeUsage(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	ratios := func() map[v1.ResourceName]float64 {
+		n, ok := cache.store.GetNodeInfo("node-1")
+		if !ok {
+			t.Fatalf("expected a NodeInfo for node-1")
+		}
+		return n.UsageRatio()
+	}
+
+	// No ratios before the first sample.
+	if r := ratios(); r != nil {
+		t.Errorf("expected no usage ratios before a sample, got: %v", r)
+	}
+
+	// The first sample is taken as is.
+	if err := cache.UpdateNodeUsage("node-1", Resource{MilliCPU: 1000, Memory: 1 << 30}); err != nil {
+		t.Fatalf("UpdateNodeUsage failed: %v", err)
+	}
+	if r := ratios(); r[v1.ResourceCPU] != 0.5 || r[v1.ResourceMemory] != 0.25 {
+		t.Errorf("expected ratios of 0.5 CPU and 0.25 memory, got: %v", r)
+	}
+
+	// Later samples are folded in with the configured weight.
+	if err := cache.UpdateNodeUsage("node-1", Resource{MilliCPU: 2000, Memory: 1 << 30}); err != nil {
+		t.Fatalf("UpdateNodeUsage failed: %v", err)
+	}
+	wantCPU := (usageSampleWeight*2000 + (1-usageSampleWeight)*1000) / 2000
+	if r := ratios(); r[v1.ResourceCPU] != wantCPU {
+		t.Errorf("expected a decayed CPU ratio of %v, got: %v", wantCPU, r)
+	}
+
+	if err := cache.UpdateNodeUsage("unknown-node", Resource{}); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+}
+
+// TestUpdatePodUsage tests that per-pod ephemeral storage reports aggregate
+// into the node's requested-vs-used accounting.
+func TestUpdatePodUsage(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	pod.Spec.Containers[0].Resources.Requests[v1.ResourceEphemeralStorage] = resource.MustParse("1Gi")
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	usage := func() (int64, int64) {
+		n, ok := cache.store.GetNodeInfo("node-1")
+		if !ok {
+			t.Fatalf("expected a NodeInfo for node-1")
+		}
+		return n.EphemeralStorageUsage()
+	}
+
+	// Before any report only the requested side is populated.
+	if requested, used := usage(); requested != 1<<30 || used != 0 {
+		t.Errorf("expected 1Gi requested and nothing used, got: %v, %v", requested, used)
+	}
+
+	// Reports replace each other, not accumulate.
+	if err := cache.UpdatePodUsage(key, 600<<20); err != nil {
+		t.Fatalf("UpdatePodUsage failed: %v", err)
+	}
+	if err := cache.UpdatePodUsage(key, 400<<20); err != nil {
+		t.Fatalf("UpdatePodUsage failed: %v", err)
+	}
+	if _, used := usage(); used != 400<<20 {
+		t.Errorf("expected the last report to win, got: %v", used)
+	}
+
+	// A second pod's report adds to the aggregate.
+	other := makeBasePod(t, "node-1", "other", "100m", "500", "", nil)
+	if err := cache.AddPod(other); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	otherKey, err := getPodKey(other)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	if err := cache.UpdatePodUsage(otherKey, 100<<20); err != nil {
+		t.Fatalf("UpdatePodUsage failed: %v", err)
+	}
+	if _, used := usage(); used != 500<<20 {
+		t.Errorf("expected both pods' usage to aggregate, got: %v", used)
+	}
+
+	// Removing a pod subtracts its usage; unknown pods error.
+	if err := cache.RemovePod(pod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if _, used := usage(); used != 100<<20 {
+		t.Errorf("expected the removed pod's usage to be dropped, got: %v", used)
+	}
+	if err := cache.UpdatePodUsage("unknown", 1); ReasonForError(err) != ReasonPodNotFound {
+		t.Errorf("expected a PodNotFound error, got: %v", err)
+	}
+}
+
+// TestEstimatedImagePullTime tests that pull time estimates cover the pod's
+// images known to the cache but missing from the node.
+func TestEstimatedImagePullTime(t *testing.T) {
+	makeNode := func(name string, images ...v1.ContainerImage) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.NodeStatus{Images: images},
+		}
+	}
+	small := v1.ContainerImage{Names: []string{"gcr.io/small:v1"}, SizeBytes: 50 << 20}
+	large := v1.ContainerImage{Names: []string{"gcr.io/large:v1"}, SizeBytes: 200 << 20}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(makeNode("node-1", small, large)); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddNode(makeNode("node-2", small)); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+
+	pod := makeBasePod(t, "", "test", "100m", "500", "", nil)
+	pod.Spec.Containers = []v1.Container{
+		{Name: "a", Image: "gcr.io/small:v1"},
+		{Name: "b", Image: "gcr.io/large:v1"},
+		{Name: "c", Image: "gcr.io/unknown:v1"},
+	}
+
+	// Everything but the unknown image is present on node-1.
+	if d, err := cache.EstimatedImagePullTime(pod, "node-1"); err != nil || d != 0 {
+		t.Errorf("expected no pull time on node-1, got: %v, %v", d, err)
+	}
+	// node-2 is missing the large image; the unknown image has no size and
+	// contributes nothing.
+	want := estimatePullDuration(200<<20, defaultImagePullBandwidth)
+	if d, err := cache.EstimatedImagePullTime(pod, "node-2"); err != nil || d != want {
+		t.Errorf("expected a pull time of %v on node-2, got: %v, %v", want, d, err)
+	}
+
+	// Halving the bandwidth doubles the estimate.
+	cache.SetImagePullBandwidth(defaultImagePullBandwidth / 2)
+	if d, err := cache.EstimatedImagePullTime(pod, "node-2"); err != nil || d != 2*want {
+		t.Errorf("expected a pull time of %v at half bandwidth, got: %v, %v", 2*want, d, err)
+	}
+
+	if _, err := cache.EstimatedImagePullTime(pod, "unknown-node"); ReasonForError(err) != ReasonNodeNotFound {
+		t.Errorf("expected a NodeNotFound error, got: %v", err)
+	}
+}
+
+// TestMatchingAntiAffinityTerms tests that the symmetry index tracks the
+// required anti-affinity terms of cached pods and answers queries for
+// prospective pods.
+func TestMatchingAntiAffinityTerms(t *testing.T) {
+	guard := makeBasePod(t, "node-1", "guard", "100m", "500", "", nil)
+	guard.Spec.Affinity = &v1.Affinity{
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				TopologyKey:   "kubernetes.io/hostname",
+			}},
+		},
+	}
+	guardKey, err := getPodKey(guard)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddPod(guard); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// A prospective pod carrying the repelled label in the same namespace
+	// matches; the term and its node are reported.
+	incoming := makeBasePod(t, "", "incoming", "100m", "500", "", nil)
+	incoming.Labels = map[string]string{"app": "db"}
+	terms := cache.MatchingAntiAffinityTerms(incoming)
+	if len(terms) != 1 || terms[0].PodKey != guardKey || terms[0].NodeName != "node-1" {
+		t.Errorf("expected the guard pod's term to match, got: %v", terms)
+	}
+	if terms[0].Term.TopologyKey != "kubernetes.io/hostname" {
+		t.Errorf("expected the term to be reported, got: %v", terms[0].Term)
+	}
+
+	// Pods with other labels, or in other namespaces, are not repelled: a
+	// term without explicit namespaces applies to its pod's namespace only.
+	other := makeBasePod(t, "", "other", "100m", "500", "", nil)
+	other.Labels = map[string]string{"app": "web"}
+	if terms := cache.MatchingAntiAffinityTerms(other); len(terms) != 0 {
+		t.Errorf("expected no match for an unrelated pod, got: %v", terms)
+	}
+	foreign := incoming.DeepCopy()
+	foreign.Namespace = "elsewhere"
+	if terms := cache.MatchingAntiAffinityTerms(foreign); len(terms) != 0 {
+		t.Errorf("expected no match across namespaces, got: %v", terms)
+	}
+
+	// Moving the guard pod updates the reported node; removing it empties
+	// the index.
+	moved := guard.DeepCopy()
+	moved.Spec.NodeName = "node-2"
+	if err := cache.UpdatePod(guard, moved); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	if terms := cache.MatchingAntiAffinityTerms(incoming); len(terms) != 1 || terms[0].NodeName != "node-2" {
+		t.Errorf("expected the term to follow the pod's node, got: %v", terms)
+	}
+	if err := cache.RemovePod(moved); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if terms := cache.MatchingAntiAffinityTerms(incoming); len(terms) != 0 {
+		t.Errorf("expected no match after removing the pod, got: %v", terms)
+	}
+}
+
+// TestSnapshotIncremental tests that successive snapshots share the NodeInfos
+// of unchanged nodes and re-clone only the changed ones.
+func TestSnapshotIncremental(t *testing.T) {
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, name := range []string{"node-1", "node-2"} {
+		if err := cache.AddNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+
+	first := cache.Snapshot()
+	// Without any churn the next snapshot reuses both nodes.
+	second := cache.Snapshot()
+	if second.Nodes["node-1"] != first.Nodes["node-1"] || second.Nodes["node-2"] != first.Nodes["node-2"] {
+		t.Errorf("expected unchanged nodes to be shared between snapshots")
+	}
+
+	// A pod added to node-1 re-clones it; node-2 is still shared.
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	third := cache.Snapshot()
+	if third.Nodes["node-1"] == second.Nodes["node-1"] {
+		t.Errorf("expected the changed node to be re-cloned")
+	}
+	if len(third.Nodes["node-1"].Pods()) != 1 {
+		t.Errorf("expected the re-cloned node to carry the pod, got: %v", third.Nodes["node-1"].Pods())
+	}
+	if third.Nodes["node-2"] != second.Nodes["node-2"] {
+		t.Errorf("expected the untouched node to be shared")
+	}
+	// The earlier snapshot is unaffected by the mutation.
+	if len(second.Nodes["node-1"].Pods()) != 0 {
+		t.Errorf("expected the earlier snapshot to stay empty, got: %v", second.Nodes["node-1"].Pods())
+	}
+
+	// A removed node drops out of the next snapshot.
+	if err := cache.RemoveNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}}); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	if s := cache.Snapshot(); s.Nodes["node-2"] != nil {
+		t.Errorf("expected the removed node to drop out of the snapshot, got: %v", s.Nodes["node-2"])
+	}
+}
+
+// TestResizeFitPredicate tests that an installed fit predicate gates in-place
+// resizes that pass the resource check.
+func TestResizeFitPredicate(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1000, Memory: 1024})
+
+	// A vetoing predicate turns a fitting growth into a reschedule.
+	var seen *v1.Pod
+	cache.SetResizeFitPredicate(func(p *v1.Pod, n *NodeInfo) (bool, []string) {
+		seen = p
+		return false, []string{"PodFitsHostPorts"}
+	})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected the predicate veto to force a reschedule, got: %v, %v", action, err)
+	}
+	if seen == nil || seen.Name != "test" {
+		t.Errorf("expected the predicate to see the cached pod, got: %v", seen)
+	}
+
+	// An approving predicate keeps the in-place preference.
+	cache.SetResizeFitPredicate(func(p *v1.Pod, n *NodeInfo) (bool, []string) {
+		return true, nil
+	})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize with an approving predicate, got: %v, %v", action, err)
+	}
+
+	// Removing the predicate removes the gate.
+	cache.SetResizeFitPredicate(nil)
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize without a predicate, got: %v, %v", action, err)
+	}
+}
+
+// TestCapacityPools tests that pool reservations are held back from
+// non-member pods in the resize fit checks while member pods see the full
+// node.
+func TestCapacityPools(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	tenant := makeBasePod(t, "node-1", "tenant", "500m", "1Ki", "", nil)
+	tenantKey, err := getPodKey(tenant)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+	system := makeBasePod(t, "node-1", "system", "500m", "1Ki", "", nil)
+	system.Namespace = "kube-system"
+	systemKey, err := getPodKey(system)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	for _, pod := range []*v1.Pod{tenant, system} {
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+	cache.SetCapacityPools([]CapacityPool{{
+		Name:       "system",
+		Namespaces: []string{"kube-system"},
+		Reserved:   Resource{MilliCPU: 800},
+	}})
+
+	// 1000m are free physically but only 200m net of the reservation, so
+	// the tenant pod's growth has to reschedule.
+	cache.SetRecommendedResources(tenantKey, &Resource{MilliCPU: 1000, Memory: 1024})
+	if action, err := cache.PlanResize(tenantKey); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected the reservation to block the tenant resize, got: %v, %v", action, err)
+	}
+	// The pool member grows into its own reservation in place.
+	cache.SetRecommendedResources(systemKey, &Resource{MilliCPU: 1000, Memory: 1024})
+	if action, err := cache.PlanResize(systemKey); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected the system pod to resize in place, got: %v, %v", action, err)
+	}
+
+	// Reservations also shrink the free capacity seen by placement hints.
+	other := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	if err := cache.AddNode(other); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if hint, err := cache.ResizePlacementHint(tenantKey); err != nil || len(hint) != 0 {
+		t.Errorf("expected no hint net of the reservation, got: %v, %v", hint, err)
+	}
+
+	// Dropping the pools restores the full node.
+	cache.SetCapacityPools(nil)
+	if action, err := cache.PlanResize(tenantKey); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize without pools, got: %v, %v", action, err)
+	}
+}
+
+// TestResizePlacementHint tests that pods planned for DeletePodForResizing
+// get a hint naming the nodes their recommended size fits on.
+func TestResizePlacementHint(t *testing.T) {
+	makeNode := func(name, cpu, mem string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse(cpu),
+					v1.ResourceMemory: resource.MustParse(mem),
+				},
+			},
+		}
+	}
+	pod := makeBasePod(t, "node-1", "test", "900m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	for _, node := range []*v1.Node{
+		makeNode("node-1", "1", "4Gi"),
+		makeNode("node-2", "2", "4Gi"),
+		makeNode("node-3", "4", "4Gi"),
+		makeNode("node-4", "1", "4Gi"),
+	} {
+		if err := cache.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// The recommended size doesn't fit on node-1 (or the too-small node-4),
+	// so the plan is a reschedule and the hint names the others, best fit
+	// first.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1500, Memory: 1024})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Fatalf("expected a reschedule, got: %v, %v", action, err)
+	}
+	hint, err := cache.ResizePlacementHint(key)
+	if err != nil {
+		t.Fatalf("ResizePlacementHint failed: %v", err)
+	}
+	if want := []string{"node-3", "node-2"}; !reflect.DeepEqual(hint, want) {
+		t.Errorf("expected hint %v, got: %v", want, hint)
+	}
+
+	// Nodes under maintenance are not hinted.
+	cache.MarkNodeUnschedulableSoon("node-3", time.Now().Add(time.Hour))
+	if hint, err := cache.ResizePlacementHint(key); err != nil || !reflect.DeepEqual(hint, []string{"node-2"}) {
+		t.Errorf("expected only node-2 in the hint, got: %v, %v", hint, err)
+	}
+
+	// The annotation round-trips through the attach/read helpers.
+	hinted := AttachResizePlacementHint(pod, []string{"node-2", "node-3"})
+	if hinted == pod {
+		t.Errorf("expected a copy, got the original pod")
+	}
+	if nodes := ResizePlacementHintOf(hinted); !reflect.DeepEqual(nodes, []string{"node-2", "node-3"}) {
+		t.Errorf("expected the hint to round-trip, got: %v", nodes)
+	}
+	if nodes := ResizePlacementHintOf(pod); nodes != nil {
+		t.Errorf("expected no hint on the original pod, got: %v", nodes)
+	}
+}
+
+// TestNodeMaintenance tests that a registered maintenance intent blocks
+// assumes and in-place resizes on the node and shows up in snapshots.
+func TestNodeMaintenance(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Hour)
+	cache.MarkNodeUnschedulableSoon("node-1", deadline)
+
+	// New assumes are refused while the intent is active.
+	assumed := makeBasePod(t, "node-1", "assumed", "100m", "500", "", nil)
+	if _, err := cache.AssumePod(assumed); ReasonForError(err) != ReasonNodeUnderMaintenance {
+		t.Errorf("expected a NodeUnderMaintenance error, got: %v", err)
+	}
+
+	// So are in-place resizes, even if the growth would fit.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1000, Memory: 1024})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected a reschedule under maintenance, got: %v, %v", action, err)
+	}
+
+	// The intent shows up in snapshots for consumers like the autoscaler.
+	if s := cache.Snapshot(); !s.MaintenanceDeadlines["node-1"].Equal(deadline) {
+		t.Errorf("expected the maintenance deadline in the snapshot, got: %v", s.MaintenanceDeadlines)
+	}
+
+	// Clearing the intent restores normal behavior.
+	cache.ClearNodeMaintenance("node-1")
+	if _, err := cache.AssumePod(assumed); err != nil {
+		t.Errorf("expected the assume to succeed after clearing, got: %v", err)
+	}
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize after clearing, got: %v, %v", action, err)
+	}
+	if s := cache.Snapshot(); len(s.MaintenanceDeadlines) != 0 {
+		t.Errorf("expected no maintenance intents in the snapshot, got: %v", s.MaintenanceDeadlines)
+	}
+
+	// An intent whose deadline already passed is inactive.
+	cache.MarkNodeUnschedulableSoon("node-1", time.Now().Add(-time.Minute))
+	other := makeBasePod(t, "node-1", "other", "100m", "500", "", nil)
+	if _, err := cache.AssumePod(other); err != nil {
+		t.Errorf("expected an expired intent to be ignored, got: %v", err)
+	}
+}
+
+// TestPlanResize tests that recommendations fed in via SetRecommendedResources
+// turn into in-place or reschedule actions depending on the node's free
+// capacity.
+func TestPlanResize(t *testing.T) {
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("4Gi"),
+			},
+		},
+	}
+	pod := makeBasePod(t, "node-1", "test", "500m", "1Ki", "", nil)
+	key, err := getPodKey(pod)
+	if err != nil {
+		t.Fatalf("getPodKey failed: %v", err)
+	}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+
+	// No recommendation, or one at/below the current requests: nothing to do.
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNone {
+		t.Errorf("expected no action without a recommendation, got: %v, %v", action, err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 400, Memory: 1024})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNone {
+		t.Errorf("expected no action for a shrink recommendation, got: %v, %v", action, err)
+	}
+
+	// Growth that fits on the node is resized in place.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 1500, Memory: 1024})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionInPlacePreferred {
+		t.Errorf("expected an in-place resize, got: %v, %v", action, err)
+	}
+
+	// Growth beyond the node's allocatable requires rescheduling.
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 2500, Memory: 1024})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected a reschedule, got: %v, %v", action, err)
+	}
+
+	// Headroom reserved for another pod counts as occupied.
+	greedy := makeBasePod(t, "node-1", "greedy", "1000m", "1Ki", "", nil)
+	greedy.Annotations = map[string]string{ExpectedGrowthFactorAnnotationKey: "1.5"}
+	if err := cache.AddPod(greedy); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	cache.SetRecommendedResources(key, &Resource{MilliCPU: 750, Memory: 1024})
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionDeletePodForResizing {
+		t.Errorf("expected another pod's headroom to block the resize, got: %v, %v", action, err)
+	}
+
+	// Clearing the recommendation clears the action; unknown pods error.
+	cache.SetRecommendedResources(key, nil)
+	if action, err := cache.PlanResize(key); err != nil || action != ResizeActionNone {
+		t.Errorf("expected no action after clearing, got: %v, %v", action, err)
+	}
+	if _, err := cache.PlanResize("unknown"); ReasonForError(err) != ReasonPodNotFound {
+		t.Errorf("expected a PodNotFound error, got: %v", err)
+	}
+}
+
+// TestGrowthHeadroom tests that annotated pods reserve growth headroom on
+// their node and that in-place resizes consume it.
+func TestGrowthHeadroom(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test", "1000m", "1Ki", "", nil)
+	pod.Annotations = map[string]string{ExpectedGrowthFactorAnnotationKey: "1.5"}
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	n := nodeInfoOf(cache, "node-1")
+	if h := n.HeadroomResource(); h.MilliCPU != 500 || h.Memory != 512 {
+		t.Errorf("expected 500m/512 headroom reserved, got: %+v", h)
+	}
+
+	// A resize inside the expected envelope consumes the reservation.
+	resized := makeBasePod(t, "node-1", "test", "1200m", "1Ki", "", nil)
+	resized.Annotations = pod.Annotations
+	if err := cache.UpdatePod(pod, resized); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	n = nodeInfoOf(cache, "node-1")
+	if h := n.HeadroomResource(); h.MilliCPU != 300 || h.Memory != 512 {
+		t.Errorf("expected 300m/512 headroom after resize, got: %+v", h)
+	}
+
+	// A resize beyond the envelope exhausts it without going negative.
+	grown := makeBasePod(t, "node-1", "test", "2000m", "2Ki", "", nil)
+	grown.Annotations = pod.Annotations
+	if err := cache.UpdatePod(resized, grown); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+	n = nodeInfoOf(cache, "node-1")
+	if h := n.HeadroomResource(); h.MilliCPU != 0 || h.Memory != 0 {
+		t.Errorf("expected exhausted headroom, got: %+v", h)
+	}
+
+	// Removing the pod releases anything still reserved; a node with no
+	// growing pods reports zero headroom.
+	if err := cache.RemovePod(grown); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if len(cache.headroomByPod) != 0 {
+		t.Errorf("expected no reservations after removal, got: %v", cache.headroomByPod)
+	}
+
+	// Pods without the annotation reserve nothing.
+	plain := makeBasePod(t, "node-1", "plain", "100m", "500", "", nil)
+	if err := cache.AddPod(plain); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if n := nodeInfoOf(cache, "node-1"); n.headroomResource != nil {
+		t.Errorf("expected no headroom for an unannotated pod, got: %+v", n.headroomResource)
+	}
+}
+
+// recordingTracer collects the spans started through it for assertions.
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	operation  string
+	attributes map[string]string
+	ended      bool
+}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, operation string) (context.Context, Span) {
+	s := &recordedSpan{operation: operation, attributes: make(map[string]string)}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func (s *recordedSpan) SetAttribute(key, value string) { s.attributes[key] = value }
+func (s *recordedSpan) End()                           { s.ended = true }
+
+// TestTracing tests that an installed tracer sees spans for the instrumented
+// cache paths and that attributes identify the object involved.
+func TestTracing(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	tracer := &recordingTracer{}
+	cache.SetTracer(tracer)
+
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	cache.Snapshot()
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans, got: %d", len(tracer.spans))
+	}
+	assume := tracer.spans[0]
+	if assume.operation != "AssumePod" || !assume.ended {
+		t.Errorf("expected a finished AssumePod span, got: %+v", assume)
+	}
+	if assume.attributes["pod"] != "node_info_cache_test/test" || assume.attributes["node"] != "node-1" {
+		t.Errorf("unexpected AssumePod span attributes: %v", assume.attributes)
+	}
+	if snapshot := tracer.spans[1]; snapshot.operation != "Snapshot" || snapshot.attributes["nodes"] != "1" {
+		t.Errorf("unexpected Snapshot span: %+v", snapshot)
+	}
+
+	// Clearing the tracer restores the no-op default.
+	cache.SetTracer(nil)
+	cache.Snapshot()
+	if len(tracer.spans) != 2 {
+		t.Errorf("expected no new spans after clearing the tracer, got: %d", len(tracer.spans))
+	}
+}
+
+// TestWithContext tests that the context-honoring variants skip the operation
+// once the context is cancelled and behave like their plain counterparts
+// otherwise.
+func TestWithContext(t *testing.T) {
+	pod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := cache.AssumePodWithContext(cancelled, pod); err != context.Canceled {
+		t.Errorf("expected context.Canceled from AssumePodWithContext, got: %v", err)
+	}
+	if assumed, _ := cache.IsAssumedPod(pod); assumed {
+		t.Errorf("cancelled assume must not commit the pod")
+	}
+	if _, err := cache.SnapshotWithContext(cancelled); err != context.Canceled {
+		t.Errorf("expected context.Canceled from SnapshotWithContext, got: %v", err)
+	}
+	if _, err := cache.ListWithContext(cancelled, labels.Everything()); err != context.Canceled {
+		t.Errorf("expected context.Canceled from ListWithContext, got: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := cache.AssumePodWithContext(ctx, pod); err != nil {
+		t.Fatalf("AssumePodWithContext failed: %v", err)
+	}
+	if pods, err := cache.ListWithContext(ctx, labels.Everything()); err != nil || len(pods) != 1 {
+		t.Errorf("expected the assumed pod to be listed, got: %v, %v", pods, err)
+	}
+	if s, err := cache.SnapshotWithContext(ctx); err != nil || len(s.AssumedPods) != 1 {
+		t.Errorf("expected the assumed pod in the snapshot, got: %v, %v", s, err)
+	}
+}
+
+// TestSetCleanupPeriod tests runtime adjustment of the cleanup pacing.
+func TestSetCleanupPeriod(t *testing.T) {
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+
+	cache.SetCleanupPeriod(10*time.Second, 0.5)
+	if cache.period != 10*time.Second || cache.periodJitter != 0.5 {
+		t.Errorf("expected period 10s and jitter 0.5, got: %v, %v", cache.period, cache.periodJitter)
+	}
+
+	// Non-positive period and negative jitter leave the settings unchanged.
+	cache.SetCleanupPeriod(0, -1)
+	if cache.period != 10*time.Second || cache.periodJitter != 0.5 {
+		t.Errorf("expected settings to be unchanged, got: %v, %v", cache.period, cache.periodJitter)
+	}
+}
+
+// TestOrphanedPods tests that pods on deleted nodes are detected by the
+// periodic scan and purged once the grace period runs out.
+func TestOrphanedPods(t *testing.T) {
+	nodeName := "node-1"
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+	pod := makeBasePod(t, nodeName, "test", "100m", "500", "", nil)
+	now := time.Now()
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.AddPod(pod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	cache.scanOrphanedPods(now)
+	if orphans := cache.OrphanedPods(); len(orphans) != 0 {
+		t.Errorf("expected no orphans while the node exists, got: %v", orphans)
+	}
+
+	if err := cache.RemoveNode(node); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	cache.scanOrphanedPods(now)
+	if orphans := cache.OrphanedPods(); len(orphans) != 1 {
+		t.Fatalf("expected one orphan after node removal, got: %v", orphans)
+	}
+
+	// Without a grace period the orphan is tracked but never purged.
+	cache.scanOrphanedPods(now.Add(time.Hour))
+	if orphans := cache.OrphanedPods(); len(orphans) != 1 {
+		t.Errorf("expected the orphan to be kept without a grace period, got: %v", orphans)
+	}
+
+	// With a grace period the orphan is purged once it runs out.
+	cache.SetOrphanPurgeGracePeriod(30 * time.Minute)
+	cache.scanOrphanedPods(now.Add(time.Minute))
+	if orphans := cache.OrphanedPods(); len(orphans) != 1 {
+		t.Errorf("expected the orphan to survive inside the grace period, got: %v", orphans)
+	}
+	cache.scanOrphanedPods(now.Add(time.Hour))
+	if orphans := cache.OrphanedPods(); len(orphans) != 0 {
+		t.Errorf("expected the orphan to be purged, got: %v", orphans)
+	}
+	if _, err := cache.GetPod(pod); err == nil {
+		t.Errorf("expected the purged pod to be gone from the cache")
+	}
+	if n := nodeInfoOf(cache, nodeName); n != nil {
+		t.Errorf("expected the node info to be released with its last pod, got: %v", n)
+	}
+}
+
+// TestUpdatePodMoveNode tests that an update changing spec.nodeName moves the
+// pod's accounting from the old node to the new one.
+func TestUpdatePodMoveNode(t *testing.T) {
+	oldPod := makeBasePod(t, "node-1", "test", "100m", "500", "", nil)
+	newPod := makeBasePod(t, "node-2", "test", "100m", "500", "", nil)
+
+	cache := newSchedulerCache(time.Second, time.Second, nil)
+	if err := cache.AddPod(oldPod); err != nil {
+		t.Fatalf("AddPod failed: %v", err)
+	}
+	if err := cache.UpdatePod(oldPod, newPod); err != nil {
+		t.Fatalf("UpdatePod failed: %v", err)
+	}
+
+	// The old node's info was only created for the pod, so it disappears with
+	// the move; the new node carries the pod's accounting.
+	if n := nodeInfoOf(cache, "node-1"); n != nil {
+		t.Errorf("expected node-1 info to be released after the move, got: %v", n)
+	}
+	n := nodeInfoOf(cache, "node-2")
+	if n == nil {
+		t.Fatalf("expected node-2 info after the move")
+	}
+	if n.requestedResource.MilliCPU != 100 || n.requestedResource.Memory != 500 {
+		t.Errorf("expected the pod's requests on node-2, got: %v", n.requestedResource)
+	}
+	if len(n.pods) != 1 || n.pods[0] != newPod {
+		t.Errorf("expected the updated pod on node-2, got: %v", n.pods)
+	}
+}
+
+// TestBindingHandle tests that the handle returned by AssumePod drives the
+// binding transaction: Abort releases the pod, Confirm makes it expirable, and
+// Extend bounds an unconfirmed hold.
+func TestBindingHandle(t *testing.T) {
+	nodeName := "node"
+	ttl := 10 * time.Second
+	now := time.Now()
+
+	// Abort releases the assumed pod immediately.
+	pod := makeBasePod(t, nodeName, "test", "100m", "500", "", nil)
+	cache := newSchedulerCache(ttl, time.Second, nil)
+	handle, err := cache.AssumePod(pod)
+	if err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if err := handle.Abort(); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+	if assumed, err := cache.IsAssumedPod(pod); err != nil || assumed {
+		t.Errorf("pod should not be assumed after Abort, got: %v, %v", assumed, err)
+	}
+
+	// Confirm starts the expiry clock; the pod expires after the TTL.
+	cache = newSchedulerCache(ttl, time.Second, nil)
+	if handle, err = cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	cache.cleanupAssumedPods(now.Add(2 * ttl))
+	if assumed, _ := cache.IsAssumedPod(pod); !assumed {
+		t.Errorf("unconfirmed pod should not expire")
+	}
+	if err := handle.Confirm(); err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	cache.cleanupAssumedPods(time.Now().Add(2 * ttl))
+	if assumed, _ := cache.IsAssumedPod(pod); assumed {
+		t.Errorf("confirmed pod should expire after the TTL")
+	}
+
+	// Extend bounds an unconfirmed hold: the pod survives until the extended
+	// deadline and no longer.
+	cache = newSchedulerCache(ttl, time.Second, nil)
+	if handle, err = cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if err := cache.extendBinding(pod, 5*ttl, now); err != nil {
+		t.Fatalf("Extend failed: %v", err)
+	}
+	cache.cleanupAssumedPods(now.Add(2 * ttl))
+	if assumed, _ := cache.IsAssumedPod(pod); !assumed {
+		t.Errorf("extended pod should survive past the default TTL")
+	}
+	cache.cleanupAssumedPods(now.Add(6 * ttl))
+	if assumed, _ := cache.IsAssumedPod(pod); assumed {
+		t.Errorf("extended pod should expire after the extended deadline")
+	}
+
+	// Extending a pod that was never assumed fails.
+	if err := handle.Extend(ttl); ReasonForError(err) != ReasonPodNotAssumed {
+		t.Errorf("expected a PodNotAssumed error, got: %v", err)
+	}
+}
+
 func BenchmarkList1kNodes30kPods(b *testing.B) {
 	cache := setupCacheOf1kNodes30kPods(b)
 	b.ResetTimer()
@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// CapacityPool reserves per-node capacity for a class of pods, selected by
+// namespace or priority class. The reservation is subtracted from the
+// effective allocatable seen by pods outside the pool in the cache's fit
+// checks — including the resize fit check — so in-place growth of tenant pods
+// can't eat into space reserved for e.g. kube-system.
+type CapacityPool struct {
+	// Name identifies the pool in logs and configuration.
+	Name string
+	// Namespaces and PriorityClassNames select member pods; a pod belongs
+	// to the pool if it matches either list.
+	Namespaces         []string
+	PriorityClassNames []string
+	// Reserved is the capacity set aside on every node for the pool's pods.
+	Reserved Resource
+}
+
+// matches returns true if the pod belongs to the pool.
+func (p *CapacityPool) matches(pod *v1.Pod) bool {
+	for _, ns := range p.Namespaces {
+		if pod.Namespace == ns {
+			return true
+		}
+	}
+	for _, name := range p.PriorityClassNames {
+		if pod.Spec.PriorityClassName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCapacityPools replaces the set of guaranteed capacity pools.
+func (cache *schedulerCache) SetCapacityPools(pools []CapacityPool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.capacityPools = pools
+}
+
+// reservedCapacityFor sums the reservations of every pool the pod is not a
+// member of; that much of each node is off limits to the pod.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) reservedCapacityFor(pod *v1.Pod) Resource {
+	var reserved Resource
+	for i := range cache.capacityPools {
+		pool := &cache.capacityPools[i]
+		if pool.matches(pod) {
+			continue
+		}
+		reserved.MilliCPU += pool.Reserved.MilliCPU
+		reserved.Memory += pool.Reserved.Memory
+		reserved.EphemeralStorage += pool.Reserved.EphemeralStorage
+	}
+	return reserved
+}
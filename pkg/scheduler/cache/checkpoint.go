@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Checkpoint is the part of the cache's state worth surviving a scheduler
+// failover: which pods were assumed and which resize actions were already
+// issued. A standby restoring it avoids re-issuing resize actions the primary
+// sent and can tell fresh state from pre-failover state by generation.
+type Checkpoint struct {
+	// Generation is the highest node generation at checkpoint time.
+	Generation int64 `json:"generation"`
+	// AssumedPods are the keys of the pods assumed but not yet confirmed.
+	AssumedPods []string `json:"assumedPods,omitempty"`
+	// ResizingPods are the keys of the pods with a resize in flight.
+	ResizingPods []string `json:"resizingPods,omitempty"`
+}
+
+// CheckpointWriteFunc persists a checkpoint, e.g. into a ConfigMap or Lease.
+// It is called outside the cache's lock.
+type CheckpointWriteFunc func(cp *Checkpoint) error
+
+// SetCheckpointing enables periodic checkpointing through the injected
+// writer, at most once per interval, piggybacked on the cleanup loop. A
+// non-positive interval or nil writer disables it.
+func (cache *schedulerCache) SetCheckpointing(interval time.Duration, write CheckpointWriteFunc) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.checkpointInterval = interval
+	cache.checkpointWrite = write
+}
+
+// buildCheckpoint assembles the current checkpoint. Assumes that lock is
+// already acquired.
+func (cache *schedulerCache) buildCheckpoint() *Checkpoint {
+	cp := &Checkpoint{}
+	cache.store.RangeNodeInfos(func(name string, info *NodeInfo) bool {
+		if info.generation > cp.Generation {
+			cp.Generation = info.generation
+		}
+		return true
+	})
+	for key := range cache.assumedPods {
+		cp.AssumedPods = append(cp.AssumedPods, key)
+	}
+	for key := range cache.resizingPods {
+		cp.ResizingPods = append(cp.ResizingPods, key)
+	}
+	sort.Strings(cp.AssumedPods)
+	sort.Strings(cp.ResizingPods)
+	return cp
+}
+
+// checkpointIfDue writes a checkpoint when the configured interval has
+// passed. It is run from the cleanup loop; the write happens outside the lock
+// since it hits the API server.
+func (cache *schedulerCache) checkpointIfDue() {
+	now := time.Now()
+
+	cache.mu.RLock()
+	write := cache.checkpointWrite
+	due := cache.checkpointInterval > 0 && write != nil &&
+		now.Sub(cache.lastCheckpoint) >= cache.checkpointInterval
+	var cp *Checkpoint
+	if due {
+		cp = cache.buildCheckpoint()
+	}
+	cache.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	if err := write(cp); err != nil {
+		glog.Errorf("Failed to write scheduler cache checkpoint: %v", err)
+		return
+	}
+	cache.mu.Lock()
+	cache.lastCheckpoint = now
+	cache.mu.Unlock()
+}
+
+// RestoreCheckpoint warms the cache of a standby scheduler from the
+// primary's last checkpoint: pods recorded with a resize in flight keep
+// their mark, so the standby doesn't re-issue resize actions the primary
+// already sent. It is meant to be called once after the standby's informers
+// have synced; marks for pods that never show up are swept with the rest of
+// the resize bookkeeping.
+func (cache *schedulerCache) RestoreCheckpoint(cp *Checkpoint) {
+	if cp == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, key := range cp.ResizingPods {
+		cache.resizingPods[key] = true
+	}
+}
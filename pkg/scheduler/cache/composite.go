@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+)
+
+// CompositeCache multiplexes the caches of several clusters behind one view
+// for a multi-cluster scheduler: the merged snapshot carries cluster-prefixed
+// node names, and assumes are routed to the owning cluster's cache. It is a
+// thin composition layer; each member cache keeps its own lifecycle and
+// event feeds.
+type CompositeCache struct {
+	mu       sync.RWMutex
+	clusters map[string]Cache
+}
+
+// NewCompositeCache returns an empty composite with no member clusters.
+func NewCompositeCache() *CompositeCache {
+	return &CompositeCache{clusters: make(map[string]Cache)}
+}
+
+// ClusterNodeName prefixes a node name with its cluster, as used in the
+// merged snapshot.
+func ClusterNodeName(cluster, node string) string {
+	return cluster + "/" + node
+}
+
+// SplitClusterNodeName splits a cluster-prefixed node name back into its
+// parts. It returns false for names without a cluster prefix.
+func SplitClusterNodeName(name string) (cluster, node string, ok bool) {
+	i := strings.Index(name, "/")
+	if i <= 0 || i == len(name)-1 {
+		return "", "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+// AddCluster registers a member cluster's cache under the given name. Names
+// must be unique and must not contain the "/" separator.
+func (c *CompositeCache) AddCluster(name string, cache Cache) error {
+	if strings.Contains(name, "/") {
+		return fmt.Errorf("cluster name %q must not contain %q", name, "/")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.clusters[name]; ok {
+		return fmt.Errorf("cluster %v is already registered", name)
+	}
+	c.clusters[name] = cache
+	return nil
+}
+
+// RemoveCluster drops a member cluster from the composite. Its cache is left
+// untouched.
+func (c *CompositeCache) RemoveCluster(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clusters, name)
+}
+
+// Cluster returns the member cache registered under the given name.
+func (c *CompositeCache) Cluster(name string) (Cache, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cache, ok := c.clusters[name]
+	return cache, ok
+}
+
+// AssumePod routes the assume to the cluster owning the pod's node: the
+// pod's spec.nodeName must be cluster-prefixed, and the member cache sees a
+// copy carrying the bare node name.
+func (c *CompositeCache) AssumePod(pod *v1.Pod) (BindingHandle, error) {
+	cluster, node, ok := SplitClusterNodeName(pod.Spec.NodeName)
+	if !ok {
+		return nil, fmt.Errorf("pod %v/%v is assigned to node %q without a cluster prefix", pod.Namespace, pod.Name, pod.Spec.NodeName)
+	}
+	member, ok := c.Cluster(cluster)
+	if !ok {
+		return nil, fmt.Errorf("cluster %v of pod %v/%v is not registered", cluster, pod.Namespace, pod.Name)
+	}
+	routed := pod.DeepCopy()
+	routed.Spec.NodeName = node
+	return member.AssumePod(routed)
+}
+
+// Snapshot merges the member snapshots into one view with cluster-prefixed
+// node names. Assumed pod and PDB keys are prefixed the same way, since pods
+// of different clusters may share UIDs. The nodes are shared with the member
+// snapshots and must be treated as read-only.
+func (c *CompositeCache) Snapshot() *Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	merged := &Snapshot{
+		AssumedPods:          make(map[string]bool),
+		Nodes:                make(map[string]*NodeInfo),
+		Pdbs:                 make(map[string]*policy.PodDisruptionBudget),
+		MaintenanceDeadlines: make(map[string]time.Time),
+	}
+	for cluster, member := range c.clusters {
+		s := member.Snapshot()
+		for name, info := range s.Nodes {
+			merged.Nodes[ClusterNodeName(cluster, name)] = info
+		}
+		for key, assumed := range s.AssumedPods {
+			merged.AssumedPods[cluster+"/"+key] = assumed
+		}
+		for key, pdb := range s.Pdbs {
+			merged.Pdbs[cluster+"/"+key] = pdb
+		}
+		for name, deadline := range s.MaintenanceDeadlines {
+			merged.MaintenanceDeadlines[ClusterNodeName(cluster, name)] = deadline
+		}
+	}
+	return merged
+}
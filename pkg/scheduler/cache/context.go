@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"strconv"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// The *WithContext variants let a caller abandon an operation that is stuck
+// behind the cache lock: the context is checked before taking the lock and
+// again once it is held, so an aborted scheduling cycle pays for the lock wait
+// but not for the operation itself. The context-free methods remain the
+// common path; these exist for the spots that already carry a context, e.g.
+// to attach tracing spans.
+
+// AssumePodWithContext is AssumePod honoring ctx cancellation.
+func (cache *schedulerCache) AssumePodWithContext(ctx context.Context, pod *v1.Pod) (BindingHandle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, span := cache.startSpan(ctx, "AssumePod")
+	span.SetAttribute("pod", pod.Namespace+"/"+pod.Name)
+	span.SetAttribute("node", pod.Spec.NodeName)
+	defer span.End()
+
+	defer cache.lockFor("AssumePod")()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return cache.assumePodLocked(pod)
+}
+
+// UpdatePodWithContext is UpdatePod honoring ctx cancellation.
+func (cache *schedulerCache) UpdatePodWithContext(ctx context.Context, oldPod, newPod *v1.Pod) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ctx, span := cache.startSpan(ctx, "UpdatePod")
+	span.SetAttribute("pod", newPod.Namespace+"/"+newPod.Name)
+	span.SetAttribute("node", newPod.Spec.NodeName)
+	if oldPod.Spec.NodeName != newPod.Spec.NodeName {
+		span.SetAttribute("action", "move")
+	} else {
+		span.SetAttribute("action", "update")
+	}
+	defer span.End()
+
+	defer cache.lockFor("UpdatePod")()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return cache.updatePodLocked(oldPod, newPod)
+}
+
+// SnapshotWithContext is Snapshot honoring ctx cancellation.
+func (cache *schedulerCache) SnapshotWithContext(ctx context.Context) (*Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, span := cache.startSpan(ctx, "Snapshot")
+	defer span.End()
+
+	// The build lock comes first so concurrent snapshot calls don't race on
+	// the published snapshot; the cache lock is only held while copying the
+	// changed parts.
+	cache.snapshotMu.Lock()
+	defer cache.snapshotMu.Unlock()
+	defer cache.rlockFor("Snapshot")()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s := cache.snapshotLocked()
+	span.SetAttribute("nodes", strconv.Itoa(len(s.Nodes)))
+	return s, nil
+}
+
+// ListWithContext is List honoring ctx cancellation.
+func (cache *schedulerCache) ListWithContext(ctx context.Context, selector labels.Selector) ([]*v1.Pod, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	alwaysTrue := func(p *v1.Pod) bool { return true }
+	return cache.filteredListLocked(alwaysTrue, selector)
+}
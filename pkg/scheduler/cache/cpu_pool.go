@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// CPUManagerPolicyLabelKey marks nodes whose kubelet runs with the given CPU
+// manager policy. On nodes labeled with the static policy, Guaranteed pods
+// requesting whole CPUs get them exclusively and everything else shares the
+// remainder, so the cache tracks the two sides separately and validates
+// assumes and in-place CPU resizes against the right pool.
+const CPUManagerPolicyLabelKey = "scheduler.alpha.kubernetes.io/cpu-manager-policy"
+
+// CPUManagerPolicyStatic is the label value of the kubelet's static policy.
+const CPUManagerPolicyStatic = "static"
+
+// staticCPUManagerPolicy returns whether the node advertises the static CPU
+// manager policy.
+func staticCPUManagerPolicy(node *v1.Node) bool {
+	return node.Labels[CPUManagerPolicyLabelKey] == CPUManagerPolicyStatic
+}
+
+// exclusiveCPURequest returns the milli-CPUs the static CPU manager would
+// grant the pod exclusively: the pod's CPU request if it is Guaranteed -
+// every container's requests equal its limits - and asks for whole CPUs,
+// zero otherwise.
+func exclusiveCPURequest(pod *v1.Pod) int64 {
+	var total int64
+	for _, container := range pod.Spec.Containers {
+		cpu, ok := container.Resources.Requests[v1.ResourceCPU]
+		if !ok || cpu.MilliValue() <= 0 || cpu.MilliValue()%1000 != 0 {
+			return 0
+		}
+		if limit, ok := container.Resources.Limits[v1.ResourceCPU]; !ok || limit.Cmp(cpu) != 0 {
+			return 0
+		}
+		memory, ok := container.Resources.Requests[v1.ResourceMemory]
+		if !ok {
+			return 0
+		}
+		if limit, ok := container.Resources.Limits[v1.ResourceMemory]; !ok || limit.Cmp(memory) != 0 {
+			return 0
+		}
+		total += cpu.MilliValue()
+	}
+	return total
+}
+
+// ExclusiveMilliCPU returns the milli-CPUs granted exclusively to Guaranteed
+// whole-CPU pods on this node.
+func (n *NodeInfo) ExclusiveMilliCPU() int64 {
+	if n == nil {
+		return 0
+	}
+	return n.exclusiveMilliCPU
+}
+
+// SharedCPUPoolMilli returns the milli-CPUs left to the shared pool after
+// the exclusive grants.
+func (n *NodeInfo) SharedCPUPoolMilli() int64 {
+	if n == nil {
+		return 0
+	}
+	return n.allocatableResource.MilliCPU - n.exclusiveMilliCPU
+}
+
+// validateCPUPool checks the pod against the node's CPU pools: on a static
+// CPU manager node, an exclusive pod must fit in the CPUs not already granted
+// exclusively, and a shared pod's request must fit in the shared pool, or
+// the kubelet's CPU manager would reject what the cache admitted.
+// Assumes that lock is already acquired.
+func (n *NodeInfo) validateCPUPool(pod *v1.Pod) error {
+	if n == nil || n.node == nil || !n.staticCPUPolicy {
+		return nil
+	}
+	if exclusive := exclusiveCPURequest(pod); exclusive > 0 {
+		if n.exclusiveMilliCPU+exclusive > n.allocatableResource.MilliCPU {
+			return newError(ReasonCPUPoolExhausted, "node %v has %vm CPU left for exclusive grants, pod needs %vm", n.node.Name, n.allocatableResource.MilliCPU-n.exclusiveMilliCPU, exclusive)
+		}
+		return nil
+	}
+	res, _, _ := calculateResource(pod)
+	sharedRequested := n.requestedResource.MilliCPU - n.exclusiveMilliCPU
+	if sharedRequested+res.MilliCPU > n.SharedCPUPoolMilli() {
+		return newError(ReasonCPUPoolExhausted, "node %v has %vm CPU free in the shared pool, pod needs %vm", n.node.Name, n.SharedCPUPoolMilli()-sharedRequested, res.MilliCPU)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// PVCDriverResolver maps a PVC to the CSI driver backing its volume. It is
+// injected because resolving claim -> volume -> driver needs the PVC and PV
+// listers, which the cache deliberately doesn't depend on. Claims that can't
+// be resolved (unbound, or backed by an in-tree plugin) return ok false and
+// don't count against any driver's attach slots.
+type PVCDriverResolver func(namespace, claim string) (driver string, ok bool)
+
+// SetPVCDriverResolver installs the resolver used to charge pods' PVC
+// volumes against per-driver attach slots.
+func (cache *schedulerCache) SetPVCDriverResolver(resolve PVCDriverResolver) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.pvcDriverResolver = resolve
+}
+
+// AddCSINode ingests the per-driver allocatable attach counts a node's CSI
+// drivers advertise. Attach slots in use are maintained incrementally as
+// pods with PVCs are added and removed, so RemainingAttachSlots is a map
+// lookup instead of the per-cycle recomputation volume predicates do through
+// TransientInfo.
+func (cache *schedulerCache) AddCSINode(nodeName string, allocatable map[string]int32) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return newError(ReasonNodeNotFound, "node %v is not in the scheduler cache, so its CSI allocatable counts can't be ingested", nodeName)
+	}
+	n.csiAllocatable = make(map[string]int32, len(allocatable))
+	for driver, count := range allocatable {
+		n.csiAllocatable[driver] = count
+	}
+	n.generation = nextGeneration()
+	return nil
+}
+
+// UpdateCSINode replaces the node's per-driver allocatable attach counts,
+// e.g. after a driver restarts with a different limit.
+func (cache *schedulerCache) UpdateCSINode(nodeName string, allocatable map[string]int32) error {
+	return cache.AddCSINode(nodeName, allocatable)
+}
+
+// RemoveCSINode drops the node's per-driver allocatable attach counts, e.g.
+// when the CSINode object is deleted.
+func (cache *schedulerCache) RemoveCSINode(nodeName string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok {
+		return
+	}
+	n.csiAllocatable = nil
+	n.generation = nextGeneration()
+}
+
+// RemainingAttachSlots returns, per CSI driver the node advertised counts
+// for, how many more volumes can be attached. The returned map is a copy and
+// safe to mutate.
+func (cache *schedulerCache) RemainingAttachSlots(nodeName string) (map[string]int32, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return nil, newError(ReasonNodeNotFound, "node %v is not in the scheduler cache, so its attach slots can't be computed", nodeName)
+	}
+	remaining := make(map[string]int32, len(n.csiAllocatable))
+	for driver, count := range n.csiAllocatable {
+		remaining[driver] = count - n.csiAttached[driver]
+	}
+	return remaining, nil
+}
+
+// chargeAttachSlots adjusts the node's per-driver in-use attach counts for a
+// pod being added (delta 1) or removed (delta -1), resolving each PVC volume
+// to its driver. Without a resolver nothing is charged.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) chargeAttachSlots(pod *v1.Pod, n *NodeInfo, delta int32) {
+	if cache.pvcDriverResolver == nil {
+		return
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		driver, ok := cache.pvcDriverResolver(pod.Namespace, volume.PersistentVolumeClaim.ClaimName)
+		if !ok {
+			continue
+		}
+		if n.csiAttached == nil {
+			n.csiAttached = make(map[string]int32)
+		}
+		n.csiAttached[driver] += delta
+		if n.csiAttached[driver] <= 0 {
+			delete(n.csiAttached, driver)
+		}
+	}
+}
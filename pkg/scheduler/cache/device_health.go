@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+)
+
+// UnhealthyResourcesAnnotationKey carries per-node extended resource health
+// as comma-separated unhealthy unit counts, e.g. "nvidia.com/gpu=1". Device
+// plugins report unhealthy devices to the kubelet, but node status keeps
+// advertising them in Allocatable until the plugin re-registers; mirroring
+// the unhealthy counts here lets the cache subtract them up front, so
+// assumes and resizes don't target broken devices.
+const UnhealthyResourcesAnnotationKey = "scheduler.alpha.kubernetes.io/unhealthy-resources"
+
+// parseUnhealthyResources parses the unhealthy-resources annotation value
+// into per-resource unit counts.
+func parseUnhealthyResources(value string) (map[v1.ResourceName]int64, error) {
+	counts := make(map[v1.ResourceName]int64)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed count %q", pair)
+		}
+		count, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("malformed count %q for %q", parts[1], parts[0])
+		}
+		counts[v1.ResourceName(parts[0])] = count
+	}
+	return counts, nil
+}
+
+// subtractUnhealthyResources deducts the node's advertised unhealthy units
+// from the allocatable scalar resources, clamping at zero. It is called from
+// SetNode after the allocatable totals are rebuilt from node status, so
+// updates to either side converge on the healthy count.
+func (n *NodeInfo) subtractUnhealthyResources(node *v1.Node) {
+	value, ok := node.Annotations[UnhealthyResourcesAnnotationKey]
+	if !ok {
+		return
+	}
+	counts, err := parseUnhealthyResources(value)
+	if err != nil {
+		glog.Errorf("Node %v has an invalid unhealthy-resources annotation: %v", node.Name, err)
+		return
+	}
+	for rName, count := range counts {
+		healthy := n.allocatableResource.ScalarResources[rName] - count
+		if healthy < 0 {
+			healthy = 0
+		}
+		n.allocatableResource.SetScalar(rName, healthy)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+
+	"k8s.io/kubernetes/pkg/scheduler/metrics"
+)
+
+// DownsizeSuggestFunc receives a dry-run downsize suggestion: the pod and the
+// requests the analyzer would shrink it to. Implementations typically write a
+// suggested resize annotation or surface the pair to operators; the cache
+// itself never acts on the suggestion.
+type DownsizeSuggestFunc func(pod *v1.Pod, suggested *Resource) error
+
+// SetDownsizeAnalyzer enables the right-sizing analyzer: pods whose requested
+// ephemeral storage stays above factor times their measured usage for the
+// sustained duration are reported once through emit, using the usage data the
+// cache already ingests via UpdatePodUsage. A factor or duration that is not
+// positive disables the analyzer.
+func (cache *schedulerCache) SetDownsizeAnalyzer(factor float64, sustained time.Duration, emit DownsizeSuggestFunc) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.downsizeFactor = factor
+	cache.downsizeWindow = sustained
+	cache.downsizeEmit = emit
+}
+
+// trackOverProvisioning updates the pod's over-provisioning streak from a
+// fresh usage report: the streak starts when requested exceeds factor times
+// used and resets - together with the emitted mark, so a relapse is reported
+// again - as soon as it doesn't.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) trackOverProvisioning(key string, pod *v1.Pod, usedBytes int64) {
+	if cache.downsizeFactor <= 0 || cache.downsizeWindow <= 0 {
+		return
+	}
+	requested, _, _ := calculateResource(pod)
+	if requested.EphemeralStorage > 0 && float64(requested.EphemeralStorage) > cache.downsizeFactor*float64(usedBytes) {
+		if _, ok := cache.overProvisionedSince[key]; !ok {
+			cache.overProvisionedSince[key] = time.Now()
+		}
+		return
+	}
+	delete(cache.overProvisionedSince, key)
+	delete(cache.downsizeEmitted, key)
+}
+
+// analyzeDownsizes is one analyzer round, run from the cleanup loop. Streaks
+// that outlasted the sustained window are emitted, once each, outside the
+// lock; the suggestion is the pod's current requests with ephemeral storage
+// shrunk to its measured usage.
+func (cache *schedulerCache) analyzeDownsizes() {
+	now := time.Now()
+
+	type suggestion struct {
+		key       string
+		pod       *v1.Pod
+		suggested *Resource
+	}
+	var due []suggestion
+	cache.mu.RLock()
+	window := cache.downsizeWindow
+	emit := cache.downsizeEmit
+	if window > 0 {
+		for key, since := range cache.overProvisionedSince {
+			if now.Sub(since) < window || cache.downsizeEmitted[key] {
+				continue
+			}
+			ps, ok := cache.podStates[key]
+			if !ok {
+				continue
+			}
+			suggested, _, _ := calculateResource(ps.pod)
+			suggested.EphemeralStorage = cache.podUsage[key]
+			due = append(due, suggestion{key: key, pod: ps.pod, suggested: &suggested})
+		}
+	}
+	metrics.CacheDownsizeCandidates.Set(float64(len(cache.overProvisionedSince)))
+	cache.mu.RUnlock()
+
+	if emit == nil {
+		return
+	}
+	for _, s := range due {
+		if err := emit(s.pod, s.suggested); err != nil {
+			glog.Errorf("Failed to emit a downsize suggestion for pod %s/%s: %v", s.pod.Namespace, s.pod.Name, err)
+			continue
+		}
+		cache.mu.Lock()
+		cache.downsizeEmitted[s.key] = true
+		cache.mu.Unlock()
+	}
+}
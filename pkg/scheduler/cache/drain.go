@@ -0,0 +1,79 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// IsNodeDrainable reports whether every pod on the node can be evicted right
+// now, so autoscaler and descheduler integrations can ask the cache instead
+// of re-deriving it from raw pod and PDB lists. A node is not drainable if it
+// holds a static pod, a pod without a controller, a pod whose disruption
+// budget is exhausted, or a pod with a resize in flight or outstanding
+// recommendation. DaemonSet pods don't block a drain: they are per-node
+// overhead that a drain leaves behind by design. The returned reasons name
+// the blocking pods; an empty node is drainable.
+func (cache *schedulerCache) IsNodeDrainable(nodeName string) (bool, []string, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return false, nil, newError(ReasonNodeNotFound, "node %v is not in the cache, so its drainability can't be checked", nodeName)
+	}
+
+	budgets := make(map[string][]*victimBudget)
+	var reasons []string
+	for _, pod := range n.pods {
+		switch PodOriginOf(pod) {
+		case PodOriginDaemonSet:
+			continue
+		case PodOriginStatic:
+			reasons = append(reasons, fmt.Sprintf("pod %v/%v is a static pod", pod.Namespace, pod.Name))
+			continue
+		}
+		if metav1.GetControllerOf(pod) == nil {
+			reasons = append(reasons, fmt.Sprintf("pod %v/%v has no controller to recreate it", pod.Namespace, pod.Name))
+			continue
+		}
+		if _, ok := budgets[pod.Namespace]; !ok {
+			budgets[pod.Namespace] = cache.pdbBudgets(pod.Namespace)
+		}
+		for _, budget := range budgets[pod.Namespace] {
+			if !budget.info.selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			if budget.allowed <= 0 {
+				reasons = append(reasons, fmt.Sprintf("pod %v/%v has an exhausted disruption budget", pod.Namespace, pod.Name))
+				break
+			}
+			budget.allowed--
+		}
+		if key, err := getPodKey(pod); err == nil {
+			if cache.resizingPods[key] {
+				reasons = append(reasons, fmt.Sprintf("pod %v/%v has a resize in flight", pod.Namespace, pod.Name))
+			} else if cache.recommendations[key] != nil {
+				reasons = append(reasons, fmt.Sprintf("pod %v/%v has an outstanding resize recommendation", pod.Namespace, pod.Name))
+			}
+		}
+	}
+	return len(reasons) == 0, reasons, nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+)
+
+// ErrorReason classifies the failures of cache operations, so that callers
+// can branch on the failure class instead of matching error strings.
+type ErrorReason string
+
+const (
+	// ReasonPodAlreadyAssumed means the pod is already in the cache and
+	// cannot be assumed again.
+	ReasonPodAlreadyAssumed ErrorReason = "PodAlreadyAssumed"
+	// ReasonPodNotAssumed means the operation requires an assumed pod but
+	// the pod isn't assumed.
+	ReasonPodNotAssumed ErrorReason = "PodNotAssumed"
+	// ReasonPodAlreadyAdded means an add event was delivered for a pod that
+	// is already in added state.
+	ReasonPodAlreadyAdded ErrorReason = "PodAlreadyAdded"
+	// ReasonPodNotFound means the pod isn't known to the cache.
+	ReasonPodNotFound ErrorReason = "PodNotFound"
+	// ReasonPodNotHeld means the pod isn't held by a scheduling gate.
+	ReasonPodNotHeld ErrorReason = "PodNotHeld"
+	// ReasonNodeMismatch means the pod's node doesn't match the node the
+	// cache has accounted the pod on.
+	ReasonNodeMismatch ErrorReason = "NodeMismatch"
+	// ReasonNodeDeleting means the target node is being deleted and doesn't
+	// accept new pods.
+	ReasonNodeDeleting ErrorReason = "NodeDeleting"
+	// ReasonNodeUnderMaintenance means the target node has maintenance
+	// scheduled and doesn't accept new pods.
+	ReasonNodeUnderMaintenance ErrorReason = "NodeUnderMaintenance"
+	// ReasonStaleUpdate means the update carries an older resourceVersion
+	// than the cached object and was rejected.
+	ReasonStaleUpdate ErrorReason = "StaleUpdate"
+	// ReasonAssumeRejected means an assume-time admission hook vetoed the
+	// assume before the cache committed it.
+	ReasonAssumeRejected ErrorReason = "AssumeRejected"
+	// ReasonNodeNotFound means the node isn't known to the cache.
+	ReasonNodeNotFound ErrorReason = "NodeNotFound"
+	// ReasonInsufficientVictims means no eligible victim set frees enough
+	// resources for the preemptor.
+	ReasonInsufficientVictims ErrorReason = "InsufficientVictims"
+	// ReasonResizeCooldown means the pod was resized too recently and new
+	// resize requests are rejected until the cooldown passes.
+	ReasonResizeCooldown ErrorReason = "ResizeCooldown"
+	// ReasonResizeConflict means the action is deferred because the opposite
+	// action - a resize or a preemption - is already in flight for the pod.
+	ReasonResizeConflict ErrorReason = "ResizeConflict"
+	// ReasonNodeHeartbeatStale means the node's lease wasn't renewed within
+	// the configured threshold and the node refuses new pods.
+	ReasonNodeHeartbeatStale ErrorReason = "NodeHeartbeatStale"
+	// ReasonHeartbeatUnknown means no lease was ever ingested for the node.
+	ReasonHeartbeatUnknown ErrorReason = "HeartbeatUnknown"
+	// ReasonResizeRateLimited means the node's resize rate budget for the
+	// current window is spent or reserved for higher-priority resizes.
+	ReasonResizeRateLimited ErrorReason = "ResizeRateLimited"
+	// ReasonCPUPoolExhausted means the pod doesn't fit the exclusive or
+	// shared CPU pool of a static CPU manager node.
+	ReasonCPUPoolExhausted ErrorReason = "CPUPoolExhausted"
+	// ReasonNodeLocalData means the pod uses node-local storage, so
+	// deleting it for resizing would lose its data.
+	ReasonNodeLocalData ErrorReason = "NodeLocalData"
+	// ReasonNamespacePodLimit means the node already carries as many pods
+	// from the pod's namespace as the per-namespace cap allows.
+	ReasonNamespacePodLimit ErrorReason = "NamespacePodLimit"
+	// ReasonNodeCordoned means the target node is marked unschedulable and
+	// strict assume mode refuses to place pods on it.
+	ReasonNodeCordoned ErrorReason = "NodeCordoned"
+	// ReasonGroupExists means a reservation group with the same name is
+	// already assumed.
+	ReasonGroupExists ErrorReason = "GroupExists"
+	// ReasonGroupNotFound means the reservation group isn't known to the
+	// cache.
+	ReasonGroupNotFound ErrorReason = "GroupNotFound"
+)
+
+// Error is the error type returned by cache operations.
+type Error struct {
+	// Reason is the machine-readable failure class.
+	Reason ErrorReason
+	// message is a human-readable description of the failure.
+	message string
+}
+
+var _ error = &Error{}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+func newError(reason ErrorReason, format string, args ...interface{}) *Error {
+	return &Error{
+		Reason:  reason,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// ReasonForError returns the reason of a cache error, or the empty string if
+// the error isn't a cache Error.
+func ReasonForError(err error) ErrorReason {
+	if e, ok := err.(*Error); ok {
+		return e.Reason
+	}
+	return ""
+}
+
+// IsPodNotFound returns true if the error says the pod isn't known to the cache.
+func IsPodNotFound(err error) bool {
+	return ReasonForError(err) == ReasonPodNotFound
+}
+
+// IsPodAlreadyAssumed returns true if the error says the pod is already assumed.
+func IsPodAlreadyAssumed(err error) bool {
+	return ReasonForError(err) == ReasonPodAlreadyAssumed
+}
+
+// IsPodAlreadyAdded returns true if the error says the pod is already in added state.
+func IsPodAlreadyAdded(err error) bool {
+	return ReasonForError(err) == ReasonPodAlreadyAdded
+}
+
+// IsNodeMismatch returns true if the error says the pod's node doesn't match
+// the cache's accounting.
+func IsNodeMismatch(err error) bool {
+	return ReasonForError(err) == ReasonNodeMismatch
+}
+
+// IsStaleUpdate returns true if the error says the update was rejected for
+// carrying an older resourceVersion than the cached object.
+func IsStaleUpdate(err error) bool {
+	return ReasonForError(err) == ReasonStaleUpdate
+}
+
+// IsAssumeRejected returns true if the error says an admission hook vetoed
+// the assume, so the scheduler can retry the pod elsewhere.
+func IsAssumeRejected(err error) bool {
+	return ReasonForError(err) == ReasonAssumeRejected
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"google.golang.org/grpc"
+)
+
+// ExportEventType names the cache mutation an ExportEvent describes.
+type ExportEventType string
+
+const (
+	// ExportEventAssumePod is sent when a pod is assumed onto a node.
+	ExportEventAssumePod ExportEventType = "AssumePod"
+	// ExportEventForgetPod is sent when an assumed pod is forgotten.
+	ExportEventForgetPod ExportEventType = "ForgetPod"
+	// ExportEventAddPod is sent when a pod is added or confirmed.
+	ExportEventAddPod ExportEventType = "AddPod"
+	// ExportEventUpdatePod is sent when a pod is updated.
+	ExportEventUpdatePod ExportEventType = "UpdatePod"
+	// ExportEventMovePod is sent when a pod update changed spec.nodeName and
+	// its accounting moved from the old node to the new one.
+	ExportEventMovePod ExportEventType = "MovePod"
+	// ExportEventRemovePod is sent when a pod is removed.
+	ExportEventRemovePod ExportEventType = "RemovePod"
+	// ExportEventAddNode is sent when a node is added.
+	ExportEventAddNode ExportEventType = "AddNode"
+	// ExportEventUpdateNode is sent when a node is updated.
+	ExportEventUpdateNode ExportEventType = "UpdateNode"
+	// ExportEventRemoveNode is sent when a node is removed.
+	ExportEventRemoveNode ExportEventType = "RemoveNode"
+)
+
+// ExportEvent is one cache mutation as streamed to external consumers such as
+// capacity planners or a secondary scheduler. Seq is drawn from the cache's
+// generation counter and is strictly increasing, so consumers can resume a
+// broken stream with the last sequence number they saw.
+type ExportEvent struct {
+	Seq       int64           `json:"seq"`
+	Type      ExportEventType `json:"type"`
+	Namespace string          `json:"namespace,omitempty"`
+	Name      string          `json:"name"`
+}
+
+// ExportWatchRequest is the request message of the Watch stream.
+type ExportWatchRequest struct {
+	// SinceSeq resumes the stream after the given sequence number. Events
+	// still held in the server's replay buffer are redelivered; older
+	// events are lost and the consumer must relist.
+	SinceSeq int64 `json:"sinceSeq"`
+}
+
+// exportBufferSize bounds the replay buffer and each subscriber's send queue.
+const exportBufferSize = 4096
+
+// exportService is the server-side interface of the schedulercache.Export
+// gRPC service. The service is declared by hand rather than generated from a
+// proto file; messages travel as JSON through the server's codec.
+type exportService interface {
+	watch(req *ExportWatchRequest, stream grpc.ServerStream) error
+}
+
+var exportServiceDesc = grpc.ServiceDesc{
+	ServiceName: "schedulercache.Export",
+	HandlerType: (*exportService)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Watch",
+		Handler:       exportWatchHandler,
+		ServerStreams: true,
+	}},
+}
+
+func exportWatchHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(ExportWatchRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(exportService).watch(req, stream)
+}
+
+// jsonCodec is the gRPC codec of the export service.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) String() string                             { return "json" }
+
+// exportServer fans cache mutation events out to gRPC watch streams, keeping
+// a bounded replay buffer for resumption.
+type exportServer struct {
+	server *grpc.Server
+
+	mu             sync.Mutex
+	buffer         []ExportEvent
+	subscribers    map[int64]chan ExportEvent
+	nextSubscriber int64
+}
+
+func newExportServer() *exportServer {
+	return &exportServer{
+		subscribers: make(map[int64]chan ExportEvent),
+	}
+}
+
+// serve starts the gRPC server on the listener and stops it when the stop
+// channel closes.
+func (s *exportServer) serve(listener net.Listener, stop <-chan struct{}) {
+	s.server = grpc.NewServer(grpc.CustomCodec(jsonCodec{}))
+	s.server.RegisterService(&exportServiceDesc, s)
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			glog.Errorf("scheduler cache export server failed: %v", err)
+		}
+	}()
+	go func() {
+		<-stop
+		s.server.Stop()
+	}()
+}
+
+// record appends the event to the replay buffer and delivers it to every
+// subscriber. Events for subscribers that can't keep up are dropped; such
+// consumers resume via SinceSeq on their next Watch.
+func (s *exportServer) record(event ExportEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buffer = append(s.buffer, event)
+	if len(s.buffer) > exportBufferSize {
+		s.buffer = s.buffer[len(s.buffer)-exportBufferSize:]
+	}
+	for id, ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			glog.V(2).Infof("dropping cache export event %v for slow subscriber %v", event.Seq, id)
+		}
+	}
+}
+
+// eventsAfter returns a copy of the buffered events with a sequence number
+// greater than seq. This function assumes the server's lock has been
+// acquired.
+func (s *exportServer) eventsAfter(seq int64) []ExportEvent {
+	var events []ExportEvent
+	for _, event := range s.buffer {
+		if event.Seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// watch implements the Watch stream: it replays buffered events after
+// req.SinceSeq, then follows live mutations until the consumer goes away.
+func (s *exportServer) watch(req *ExportWatchRequest, stream grpc.ServerStream) error {
+	s.mu.Lock()
+	replay := s.eventsAfter(req.SinceSeq)
+	ch := make(chan ExportEvent, exportBufferSize)
+	id := s.nextSubscriber
+	s.nextSubscriber++
+	s.subscribers[id] = ch
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, id)
+		s.mu.Unlock()
+	}()
+
+	for i := range replay {
+		if err := stream.SendMsg(&replay[i]); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case event := <-ch:
+			if err := stream.SendMsg(&event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// NewWithExport returns a Cache like New and additionally serves the cache
+// mutation stream as the schedulercache.Export gRPC service on the given
+// listener.
+func NewWithExport(ttl time.Duration, stop <-chan struct{}, listener net.Listener) Cache {
+	cache := newSchedulerCache(ttl, cleanAssumedPeriod, stop)
+	cache.export = newExportServer()
+	cache.export.serve(listener, stop)
+	cache.run()
+	return cache
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"sort"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Optional fields of an ExtenderNodeInfo selectable through
+// ExtenderSnapshotFilter.Fields.
+const (
+	ExtenderFieldAllocatable = "allocatable"
+	ExtenderFieldRequested   = "requested"
+	ExtenderFieldTaints      = "taints"
+	ExtenderFieldImages      = "images"
+)
+
+// ExtenderSnapshotFilter controls what SnapshotForExtender serializes.
+type ExtenderSnapshotFilter struct {
+	// Fields restricts which optional fields are included. Empty means all
+	// fields.
+	Fields []string
+	// Gzip compresses the JSON payload. Extenders advertise support via
+	// the Content-Encoding header.
+	Gzip bool
+}
+
+// ExtenderNodeInfo is the compact per-node wire format POSTed to HTTP
+// scheduler extenders instead of full v1.Node objects.
+type ExtenderNodeInfo struct {
+	Name        string          `json:"name"`
+	Allocatable v1.ResourceList `json:"allocatable,omitempty"`
+	Requested   v1.ResourceList `json:"requested,omitempty"`
+	Taints      []v1.Taint      `json:"taints,omitempty"`
+	Images      []string        `json:"images,omitempty"`
+}
+
+// SnapshotForExtender serializes the cached nodes into the compact extender
+// wire format: a JSON array of ExtenderNodeInfo sorted by node name,
+// optionally gzip-compressed. A nil filter includes every field uncompressed.
+func (cache *schedulerCache) SnapshotForExtender(filter *ExtenderSnapshotFilter) ([]byte, error) {
+	if filter == nil {
+		filter = &ExtenderSnapshotFilter{}
+	}
+	fields := sets.NewString(filter.Fields...)
+	all := fields.Len() == 0
+
+	cache.mu.RLock()
+	infos := make([]ExtenderNodeInfo, 0, cache.store.NumNodes())
+	cache.store.RangeNodeInfos(func(name string, n *NodeInfo) bool {
+		if n.node == nil {
+			return true
+		}
+		info := ExtenderNodeInfo{Name: name}
+		if all || fields.Has(ExtenderFieldAllocatable) {
+			info.Allocatable = n.allocatableResource.ResourceList()
+		}
+		if all || fields.Has(ExtenderFieldRequested) {
+			info.Requested = n.requestedResource.ResourceList()
+		}
+		if all || fields.Has(ExtenderFieldTaints) {
+			info.Taints = n.taints
+		}
+		if all || fields.Has(ExtenderFieldImages) {
+			for image := range n.imageStates {
+				info.Images = append(info.Images, image)
+			}
+			sort.Strings(info.Images)
+		}
+		infos = append(infos, info)
+		return true
+	})
+	cache.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	payload, err := json.Marshal(infos)
+	if err != nil {
+		return nil, err
+	}
+	if !filter.Gzip {
+		return payload, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
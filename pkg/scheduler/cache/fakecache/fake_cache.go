@@ -0,0 +1,893 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakecache provides a deterministic fake implementation of the
+// scheduler cache interface for plugin and extender developers: responses can
+// be scripted, errors injected per method, and every call is recorded, so
+// code using the Cache interface can be unit-tested without constructing a
+// real schedulerCache and informers.
+package fakecache
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
+)
+
+// Call records one invocation of a Cache method. Key is the primary object
+// the call was about: "namespace/name" for pods and PDBs, the node name for
+// nodes, empty for calls without an object argument.
+type Call struct {
+	Method string
+	Key    string
+}
+
+// Cache is a fake schedulercache.Cache. The zero value is usable; configure
+// scripted state through the exported fields before handing it to the code
+// under test.
+type Cache struct {
+	mu sync.Mutex
+
+	// Errors maps a method name to the error that method returns. Methods
+	// without an entry succeed.
+	Errors map[string]error
+	// Pods is returned by List and FilteredList (after filtering) and
+	// backs GetPod and GetFullPod lookups.
+	Pods []*v1.Pod
+	// AssumedPods holds the "namespace/name" keys IsAssumedPod reports
+	// true for.
+	AssumedPods map[string]bool
+	// PDBs is returned by ListPDBs and GetPDBsMatchingPod.
+	PDBs []*policy.PodDisruptionBudget
+	// Nodes backs UpdateNodeNameToInfoMap and Snapshot.
+	Nodes map[string]*schedulercache.NodeInfo
+	// TopologyDomains maps "key/value" to the node names
+	// NodesInTopologyDomain returns.
+	TopologyDomains map[string][]string
+	// SpreadCounts is returned by TopologySpreadCounts.
+	SpreadCounts map[string]int32
+	// Capacity is returned by ClusterCapacity.
+	Capacity *schedulercache.ClusterCapacitySummary
+	// ExtenderPayload is returned by SnapshotForExtender.
+	ExtenderPayload []byte
+	// Audit is returned by AuditEntries.
+	Audit []schedulercache.AuditEntry
+	// FitPolicy is returned by FitResourcePolicy.
+	FitPolicy schedulercache.FitResourcePolicy
+	// Victims is returned by SelectVictims.
+	Victims []*v1.Pod
+	// ResizeActions maps pod keys to the action PlanResize returns for
+	// them; pods without an entry get ResizeActionNone.
+	ResizeActions map[string]schedulercache.ResizeAction
+	// PlacementHints maps pod keys to the nodes ResizePlacementHint
+	// returns for them.
+	PlacementHints map[string][]string
+	// Latency is returned by BindingLatency.
+	Latency time.Duration
+	// UpToDate is returned by IsUpToDate.
+	UpToDate bool
+
+	calls []Call
+}
+
+var _ schedulercache.Cache = &Cache{}
+
+// Calls returns a copy of the recorded calls in order.
+func (c *Cache) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	calls := make([]Call, len(c.calls))
+	copy(calls, c.calls)
+	return calls
+}
+
+// record logs the call and returns the injected error for the method, if
+// any.
+func (c *Cache) record(method, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, Call{Method: method, Key: key})
+	return c.Errors[method]
+}
+
+func podKey(pod *v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// AssumePod implements schedulercache.Cache.
+func (c *Cache) AssumePod(pod *v1.Pod) (schedulercache.BindingHandle, error) {
+	if err := c.record("AssumePod", podKey(pod)); err != nil {
+		return nil, err
+	}
+	return fakeBindingHandle{c: c, pod: pod}, nil
+}
+
+// AssumePodWithContext implements schedulercache.Cache.
+func (c *Cache) AssumePodWithContext(ctx context.Context, pod *v1.Pod) (schedulercache.BindingHandle, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.AssumePod(pod)
+}
+
+// fakeBindingHandle records handle calls back into the owning Cache as
+// FinishBinding, ForgetPod, and ExtendBinding calls.
+type fakeBindingHandle struct {
+	c   *Cache
+	pod *v1.Pod
+}
+
+func (h fakeBindingHandle) Confirm() error {
+	return h.c.FinishBinding(h.pod)
+}
+
+func (h fakeBindingHandle) Abort() error {
+	return h.c.ForgetPod(h.pod)
+}
+
+func (h fakeBindingHandle) Extend(ttl time.Duration) error {
+	return h.c.record("ExtendBinding", podKey(h.pod))
+}
+
+// SetAssumeAdmitFunc implements schedulercache.Cache.
+func (c *Cache) SetAssumeAdmitFunc(fn schedulercache.AssumeAdmitFunc) {
+	c.record("SetAssumeAdmitFunc", "")
+}
+
+// FinishBinding implements schedulercache.Cache.
+func (c *Cache) FinishBinding(pod *v1.Pod) error {
+	return c.record("FinishBinding", podKey(pod))
+}
+
+// BindingLatency implements schedulercache.Cache.
+func (c *Cache) BindingLatency(pod *v1.Pod) (time.Duration, error) {
+	return c.Latency, c.record("BindingLatency", podKey(pod))
+}
+
+// ForgetPod implements schedulercache.Cache.
+func (c *Cache) ForgetPod(pod *v1.Pod) error {
+	return c.record("ForgetPod", podKey(pod))
+}
+
+// ReleasePod implements schedulercache.Cache.
+func (c *Cache) ReleasePod(podKey string) error {
+	return c.record("ReleasePod", podKey)
+}
+
+// AddPod implements schedulercache.Cache.
+func (c *Cache) AddPod(pod *v1.Pod) error {
+	return c.record("AddPod", podKey(pod))
+}
+
+// UpdatePod implements schedulercache.Cache.
+func (c *Cache) UpdatePod(oldPod, newPod *v1.Pod) error {
+	return c.record("UpdatePod", podKey(newPod))
+}
+
+// UpdatePodWithContext implements schedulercache.Cache.
+func (c *Cache) UpdatePodWithContext(ctx context.Context, oldPod, newPod *v1.Pod) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.UpdatePod(oldPod, newPod)
+}
+
+// RemovePod implements schedulercache.Cache.
+func (c *Cache) RemovePod(pod *v1.Pod) error {
+	return c.record("RemovePod", podKey(pod))
+}
+
+// GetPod implements schedulercache.Cache.
+func (c *Cache) GetPod(pod *v1.Pod) (*v1.Pod, error) {
+	if err := c.record("GetPod", podKey(pod)); err != nil {
+		return nil, err
+	}
+	for _, p := range c.Pods {
+		if p.Namespace == pod.Namespace && p.Name == pod.Name {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// EnablePodTrimming implements schedulercache.Cache.
+func (c *Cache) EnablePodTrimming(getter schedulercache.PodGetter) {
+	c.record("EnablePodTrimming", "")
+}
+
+// EnablePodDeduplication implements schedulercache.Cache.
+func (c *Cache) EnablePodDeduplication() {
+	c.record("EnablePodDeduplication", "")
+}
+
+// GetFullPod implements schedulercache.Cache.
+func (c *Cache) GetFullPod(pod *v1.Pod) (*v1.Pod, error) {
+	if err := c.record("GetFullPod", podKey(pod)); err != nil {
+		return nil, err
+	}
+	for _, p := range c.Pods {
+		if p.Namespace == pod.Namespace && p.Name == pod.Name {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// IsAssumedPod implements schedulercache.Cache.
+func (c *Cache) IsAssumedPod(pod *v1.Pod) (bool, error) {
+	if err := c.record("IsAssumedPod", podKey(pod)); err != nil {
+		return false, err
+	}
+	return c.AssumedPods[podKey(pod)], nil
+}
+
+// SetProfileTTL implements schedulercache.Cache.
+func (c *Cache) SetProfileTTL(profile string, ttl time.Duration) {
+	c.record("SetProfileTTL", profile)
+}
+
+// AssumedPodsByProfile implements schedulercache.Cache.
+func (c *Cache) AssumedPodsByProfile() map[string]int {
+	c.record("AssumedPodsByProfile", "")
+	return nil
+}
+
+// AddNode implements schedulercache.Cache.
+func (c *Cache) AddNode(node *v1.Node) error {
+	return c.record("AddNode", node.Name)
+}
+
+// UpdateNode implements schedulercache.Cache.
+func (c *Cache) UpdateNode(oldNode, newNode *v1.Node) error {
+	return c.record("UpdateNode", newNode.Name)
+}
+
+// RemoveNode implements schedulercache.Cache.
+func (c *Cache) RemoveNode(node *v1.Node) error {
+	return c.record("RemoveNode", node.Name)
+}
+
+// ApplyBatch implements schedulercache.Cache.
+func (c *Cache) ApplyBatch(events []schedulercache.CacheEvent) error {
+	return c.record("ApplyBatch", "")
+}
+
+// RankNodes implements schedulercache.Cache.
+func (c *Cache) RankNodes(strategy schedulercache.RankStrategy, limit int) ([]schedulercache.NodeRank, error) {
+	if err := c.record("RankNodes", string(strategy)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// DeletedNodes implements schedulercache.Cache.
+func (c *Cache) DeletedNodes() []string {
+	c.record("DeletedNodes", "")
+	return nil
+}
+
+// NodesInTopologyDomain implements schedulercache.Cache.
+func (c *Cache) NodesInTopologyDomain(key, value string) []string {
+	c.record("NodesInTopologyDomain", key+"/"+value)
+	return c.TopologyDomains[key+"/"+value]
+}
+
+// NodeIterator implements schedulercache.Cache.
+func (c *Cache) NodeIterator(spreadKey string) *schedulercache.NodeIterator {
+	c.record("NodeIterator", spreadKey)
+	return &schedulercache.NodeIterator{}
+}
+
+// TopologySpreadCounts implements schedulercache.Cache.
+func (c *Cache) TopologySpreadCounts(constraint schedulercache.TopologySpreadConstraint) map[string]int32 {
+	c.record("TopologySpreadCounts", constraint.Namespace+"/"+constraint.TopologyKey)
+	return c.SpreadCounts
+}
+
+// MatchingAntiAffinityTerms implements schedulercache.Cache.
+func (c *Cache) MatchingAntiAffinityTerms(pod *v1.Pod) []schedulercache.MatchingAntiAffinityTerm {
+	c.record("MatchingAntiAffinityTerms", podKey(pod))
+	return nil
+}
+
+// SetImagePullBandwidth implements schedulercache.Cache.
+func (c *Cache) SetImagePullBandwidth(bytesPerSecond int64) {
+	c.record("SetImagePullBandwidth", "")
+}
+
+// EstimatedImagePullTime implements schedulercache.Cache.
+func (c *Cache) EstimatedImagePullTime(pod *v1.Pod, nodeName string) (time.Duration, error) {
+	c.record("EstimatedImagePullTime", podKey(pod))
+	return 0, nil
+}
+
+// AddInvalidationListener implements schedulercache.Cache.
+func (c *Cache) AddInvalidationListener(listener schedulercache.InvalidationListener) {
+	c.record("AddInvalidationListener", "")
+}
+
+// SnapshotForExtender implements schedulercache.Cache.
+func (c *Cache) SnapshotForExtender(filter *schedulercache.ExtenderSnapshotFilter) ([]byte, error) {
+	if err := c.record("SnapshotForExtender", ""); err != nil {
+		return nil, err
+	}
+	return c.ExtenderPayload, nil
+}
+
+// EnableAuditLog implements schedulercache.Cache.
+func (c *Cache) EnableAuditLog(maxEntries int, maxAge time.Duration, path string) error {
+	return c.record("EnableAuditLog", path)
+}
+
+// AuditEntries implements schedulercache.Cache.
+func (c *Cache) AuditEntries() []schedulercache.AuditEntry {
+	c.record("AuditEntries", "")
+	return c.Audit
+}
+
+// SetScoreDataExport implements schedulercache.Cache.
+func (c *Cache) SetScoreDataExport(interval time.Duration, pending schedulercache.PendingPodsFunc, w io.Writer) {
+	c.record("SetScoreDataExport", "")
+}
+
+// EnableLockProfiling implements schedulercache.Cache.
+func (c *Cache) EnableLockProfiling(sampleRate int) {
+	c.record("EnableLockProfiling", "")
+}
+
+// LockContentionReport implements schedulercache.Cache.
+func (c *Cache) LockContentionReport() []schedulercache.LockOpStats {
+	c.record("LockContentionReport", "")
+	return nil
+}
+
+// SetTracer implements schedulercache.Cache.
+func (c *Cache) SetTracer(t schedulercache.Tracer) {
+	c.record("SetTracer", "")
+}
+
+// SetVerificationMode implements schedulercache.Cache.
+func (c *Cache) SetVerificationMode(mode schedulercache.VerificationMode) {
+	c.record("SetVerificationMode", "")
+}
+
+// SetFitResourcePolicy implements schedulercache.Cache.
+func (c *Cache) SetFitResourcePolicy(policy schedulercache.FitResourcePolicy) {
+	c.record("SetFitResourcePolicy", "")
+	c.FitPolicy = policy
+}
+
+// FitResourcePolicy implements schedulercache.Cache.
+func (c *Cache) FitResourcePolicy() schedulercache.FitResourcePolicy {
+	c.record("FitResourcePolicy", "")
+	return c.FitPolicy
+}
+
+// SetOverCommitRules implements schedulercache.Cache.
+func (c *Cache) SetOverCommitRules(rules []schedulercache.OverCommitRule) {
+	c.record("SetOverCommitRules", "")
+}
+
+// SetCapacityPools implements schedulercache.Cache.
+func (c *Cache) SetCapacityPools(pools []schedulercache.CapacityPool) {
+	c.record("SetCapacityPools", "")
+}
+
+// SelectVictims implements schedulercache.Cache.
+func (c *Cache) SelectVictims(nodeName string, preemptor *v1.Pod, pdbAware bool) ([]*v1.Pod, error) {
+	if err := c.record("SelectVictims", nodeName); err != nil {
+		return nil, err
+	}
+	return c.Victims, nil
+}
+
+// IsNodeDrainable implements schedulercache.Cache.
+func (c *Cache) IsNodeDrainable(nodeName string) (bool, []string, error) {
+	if err := c.record("IsNodeDrainable", nodeName); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+// SetRecommendedResources implements schedulercache.Cache.
+func (c *Cache) SetRecommendedResources(podKey string, recommendation *schedulercache.Resource) {
+	c.record("SetRecommendedResources", podKey)
+}
+
+// PlanResize implements schedulercache.Cache. The returned action can be
+// scripted via the ResizeActions field.
+func (c *Cache) PlanResize(podKey string) (schedulercache.ResizeAction, error) {
+	if err := c.record("PlanResize", podKey); err != nil {
+		return schedulercache.ResizeActionNone, err
+	}
+	if action, ok := c.ResizeActions[podKey]; ok {
+		return action, nil
+	}
+	return schedulercache.ResizeActionNone, nil
+}
+
+// UpdatePodUsage implements schedulercache.Cache.
+func (c *Cache) UpdatePodUsage(podKey string, ephemeralStorageBytes int64) error {
+	return c.record("UpdatePodUsage", podKey)
+}
+
+// SetDownsizeAnalyzer implements schedulercache.Cache.
+func (c *Cache) SetDownsizeAnalyzer(factor float64, sustained time.Duration, emit schedulercache.DownsizeSuggestFunc) {
+	c.record("SetDownsizeAnalyzer", "")
+}
+
+// UpdateNodeUsage implements schedulercache.Cache.
+func (c *Cache) UpdateNodeUsage(nodeName string, usage schedulercache.Resource) error {
+	return c.record("UpdateNodeUsage", nodeName)
+}
+
+// SetResizeFitPredicate implements schedulercache.Cache.
+func (c *Cache) SetResizeFitPredicate(fn schedulercache.FitPredicate) {
+	c.record("SetResizeFitPredicate", "")
+}
+
+// SetResizeCooldown implements schedulercache.Cache.
+func (c *Cache) SetResizeCooldown(d time.Duration) {
+	c.record("SetResizeCooldown", "")
+}
+
+// SetResizeGranularity implements schedulercache.Cache.
+func (c *Cache) SetResizeGranularity(cpuMilli, memoryBytes int64) {
+	c.record("SetResizeGranularity", "")
+}
+
+// SetResizeRateLimit implements schedulercache.Cache.
+func (c *Cache) SetResizeRateLimit(perNode int, window time.Duration) {
+	c.record("SetResizeRateLimit", "")
+}
+
+// MarkResizeStarted implements schedulercache.Cache.
+func (c *Cache) MarkResizeStarted(podKey string) error {
+	c.record("MarkResizeStarted", podKey)
+	return nil
+}
+
+// MarkResizeCompleted implements schedulercache.Cache.
+func (c *Cache) MarkResizeCompleted(podKey string) {
+	c.record("MarkResizeCompleted", podKey)
+}
+
+// IsResizing implements schedulercache.Cache.
+func (c *Cache) IsResizing(podKey string) bool {
+	c.record("IsResizing", podKey)
+	return false
+}
+
+// MarkPendingPreemption implements schedulercache.Cache.
+func (c *Cache) MarkPendingPreemption(podKey string) error {
+	c.record("MarkPendingPreemption", podKey)
+	return nil
+}
+
+// ClearPendingPreemption implements schedulercache.Cache.
+func (c *Cache) ClearPendingPreemption(podKey string) {
+	c.record("ClearPendingPreemption", podKey)
+}
+
+// SetResizeAnnotationGC implements schedulercache.Cache.
+func (c *Cache) SetResizeAnnotationGC(retention time.Duration, patch schedulercache.PodPatchFunc) {
+	c.record("SetResizeAnnotationGC", "")
+}
+
+// SetCheckpointing implements schedulercache.Cache.
+func (c *Cache) SetCheckpointing(interval time.Duration, write schedulercache.CheckpointWriteFunc) {
+	c.record("SetCheckpointing", "")
+}
+
+// RestoreCheckpoint implements schedulercache.Cache.
+func (c *Cache) RestoreCheckpoint(cp *schedulercache.Checkpoint) {
+	c.record("RestoreCheckpoint", "")
+}
+
+// PendingResizes implements schedulercache.Cache.
+func (c *Cache) PendingResizes(nodeName string) []schedulercache.PendingResize {
+	c.record("PendingResizes", nodeName)
+	return nil
+}
+
+// MaxSchedulableUnit implements schedulercache.Cache.
+func (c *Cache) MaxSchedulableUnit(nodeName string) (schedulercache.Resource, error) {
+	c.record("MaxSchedulableUnit", nodeName)
+	return schedulercache.Resource{}, nil
+}
+
+// SetPVCDriverResolver implements schedulercache.Cache.
+func (c *Cache) SetPVCDriverResolver(resolve schedulercache.PVCDriverResolver) {
+	c.record("SetPVCDriverResolver", "")
+}
+
+// AddCSINode implements schedulercache.Cache.
+func (c *Cache) AddCSINode(nodeName string, allocatable map[string]int32) error {
+	return c.record("AddCSINode", nodeName)
+}
+
+// UpdateCSINode implements schedulercache.Cache.
+func (c *Cache) UpdateCSINode(nodeName string, allocatable map[string]int32) error {
+	return c.record("UpdateCSINode", nodeName)
+}
+
+// RemoveCSINode implements schedulercache.Cache.
+func (c *Cache) RemoveCSINode(nodeName string) {
+	c.record("RemoveCSINode", nodeName)
+}
+
+// RemainingAttachSlots implements schedulercache.Cache.
+func (c *Cache) RemainingAttachSlots(nodeName string) (map[string]int32, error) {
+	c.record("RemainingAttachSlots", nodeName)
+	return nil, nil
+}
+
+// AddNodeLocalPVC implements schedulercache.Cache.
+func (c *Cache) AddNodeLocalPVC(namespace, claim string) {
+	c.record("AddNodeLocalPVC", namespace+"/"+claim)
+}
+
+// RemoveNodeLocalPVC implements schedulercache.Cache.
+func (c *Cache) RemoveNodeLocalPVC(namespace, claim string) {
+	c.record("RemoveNodeLocalPVC", namespace+"/"+claim)
+}
+
+// StoreFit implements schedulercache.Cache.
+func (c *Cache) StoreFit(templateHash, nodeName string, fit bool) error {
+	return c.record("StoreFit", nodeName)
+}
+
+// CachedFit implements schedulercache.Cache.
+func (c *Cache) CachedFit(templateHash, nodeName string) (bool, bool) {
+	c.record("CachedFit", nodeName)
+	return false, false
+}
+
+// PodsByOwner implements schedulercache.Cache.
+func (c *Cache) PodsByOwner(uid types.UID) []*v1.Pod {
+	c.record("PodsByOwner", string(uid))
+	return nil
+}
+
+// OwnerSpreadCounts implements schedulercache.Cache.
+func (c *Cache) OwnerSpreadCounts(uid types.UID, topologyKey string) map[string]int32 {
+	c.record("OwnerSpreadCounts", string(uid))
+	return nil
+}
+
+// SetPodUpdater implements schedulercache.Cache.
+func (c *Cache) SetPodUpdater(fn schedulercache.PodUpdateFunc) {
+	c.record("SetPodUpdater", "")
+}
+
+// EnqueuePodMutation implements schedulercache.Cache.
+func (c *Cache) EnqueuePodMutation(podKey string, annotations map[string]string) {
+	c.record("EnqueuePodMutation", podKey)
+}
+
+// ForEachPod implements schedulercache.Cache.
+func (c *Cache) ForEachPod(fn func(pod *v1.Pod) bool) {
+	c.record("ForEachPod", "")
+	for _, pod := range c.Pods {
+		if !fn(pod) {
+			return
+		}
+	}
+}
+
+// GetAssumedPods implements schedulercache.Cache.
+func (c *Cache) GetAssumedPods() []schedulercache.AssumedPodInfo {
+	c.record("GetAssumedPods", "")
+	return nil
+}
+
+// RecentPDBDecisions implements schedulercache.Cache.
+func (c *Cache) RecentPDBDecisions() []schedulercache.PDBDecision {
+	c.record("RecentPDBDecisions", "")
+	return nil
+}
+
+// SetResizeWatchdog implements schedulercache.Cache.
+func (c *Cache) SetResizeWatchdog(threshold time.Duration, handler schedulercache.ResizeStuckFunc) {
+	c.record("SetResizeWatchdog", "")
+}
+
+// SetNamespacePodLimit implements schedulercache.Cache.
+func (c *Cache) SetNamespacePodLimit(limit int) {
+	c.record("SetNamespacePodLimit", "")
+}
+
+// SetStrictAssume implements schedulercache.Cache.
+func (c *Cache) SetStrictAssume(enabled bool) {
+	c.record("SetStrictAssume", "")
+}
+
+// SetOptimisticTerminating implements schedulercache.Cache.
+func (c *Cache) SetOptimisticTerminating(enabled bool) {
+	c.record("SetOptimisticTerminating", "")
+}
+
+// SetZeroRequestDefaults implements schedulercache.Cache.
+func (c *Cache) SetZeroRequestDefaults(defaults *schedulercache.ZeroRequestDefaults) {
+	c.record("SetZeroRequestDefaults", "")
+}
+
+// SetZeroRequestPolicy implements schedulercache.Cache.
+func (c *Cache) SetZeroRequestPolicy(policy schedulercache.ZeroRequestPolicy) {
+	c.record("SetZeroRequestPolicy", "")
+}
+
+// PodAllocatedResources implements schedulercache.Cache.
+func (c *Cache) PodAllocatedResources(podKey string) (map[string]schedulercache.Resource, map[string]schedulercache.Resource, error) {
+	if err := c.record("PodAllocatedResources", podKey); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, nil
+}
+
+// PinPod implements schedulercache.Cache.
+func (c *Cache) PinPod(pod *v1.Pod, nodeName string, ttl time.Duration) error {
+	return c.record("PinPod", podKey(pod))
+}
+
+// AssumeGroup implements schedulercache.Cache.
+func (c *Cache) AssumeGroup(group string, pods []*v1.Pod, ttl time.Duration) error {
+	return c.record("AssumeGroup", group)
+}
+
+// ForgetGroup implements schedulercache.Cache.
+func (c *Cache) ForgetGroup(group string) error {
+	return c.record("ForgetGroup", group)
+}
+
+// GetPodNode implements schedulercache.Cache.
+func (c *Cache) GetPodNode(podKey string) (string, bool, error) {
+	if err := c.record("GetPodNode", podKey); err != nil {
+		return "", false, err
+	}
+	return "", false, nil
+}
+
+// SnapshotMatching implements schedulercache.Cache.
+func (c *Cache) SnapshotMatching(selector labels.Selector) *schedulercache.Snapshot {
+	c.record("SnapshotMatching", selector.String())
+	return &schedulercache.Snapshot{
+		AssumedPods: c.AssumedPods,
+		Nodes:       c.Nodes,
+	}
+}
+
+// UpdateNodeLease implements schedulercache.Cache.
+func (c *Cache) UpdateNodeLease(nodeName string, renewTime time.Time) error {
+	return c.record("UpdateNodeLease", nodeName)
+}
+
+// NodeHeartbeatAge implements schedulercache.Cache.
+func (c *Cache) NodeHeartbeatAge(nodeName string) (time.Duration, error) {
+	if err := c.record("NodeHeartbeatAge", nodeName); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// SetHeartbeatThreshold implements schedulercache.Cache.
+func (c *Cache) SetHeartbeatThreshold(d time.Duration) {
+	c.record("SetHeartbeatThreshold", "")
+}
+
+// MarkNodeUnschedulableSoon implements schedulercache.Cache.
+func (c *Cache) MarkNodeUnschedulableSoon(nodeName string, deadline time.Time) {
+	c.record("MarkNodeUnschedulableSoon", nodeName)
+}
+
+// ClearNodeMaintenance implements schedulercache.Cache.
+func (c *Cache) ClearNodeMaintenance(nodeName string) {
+	c.record("ClearNodeMaintenance", nodeName)
+}
+
+// ResizePlacementHint implements schedulercache.Cache. The returned nodes can
+// be scripted via the PlacementHints field.
+func (c *Cache) ResizePlacementHint(podKey string) ([]string, error) {
+	if err := c.record("ResizePlacementHint", podKey); err != nil {
+		return nil, err
+	}
+	return c.PlacementHints[podKey], nil
+}
+
+// OrphanedPods implements schedulercache.Cache.
+func (c *Cache) OrphanedPods() []*v1.Pod {
+	c.record("OrphanedPods", "")
+	return nil
+}
+
+// SetOrphanPurgeGracePeriod implements schedulercache.Cache.
+func (c *Cache) SetOrphanPurgeGracePeriod(d time.Duration) {
+	c.record("SetOrphanPurgeGracePeriod", "")
+}
+
+// SetCleanupPeriod implements schedulercache.Cache.
+func (c *Cache) SetCleanupPeriod(period time.Duration, jitter float64) {
+	c.record("SetCleanupPeriod", "")
+}
+
+// AddPDB implements schedulercache.Cache.
+func (c *Cache) AddPDB(pdb *policy.PodDisruptionBudget) error {
+	return c.record("AddPDB", pdb.Namespace+"/"+pdb.Name)
+}
+
+// AddPDBData implements schedulercache.Cache.
+func (c *Cache) AddPDBData(data schedulercache.PDBData) error {
+	return c.record("AddPDBData", data.Namespace+"/"+data.Name)
+}
+
+// UpdatePDB implements schedulercache.Cache.
+func (c *Cache) UpdatePDB(oldPDB, newPDB *policy.PodDisruptionBudget) error {
+	return c.record("UpdatePDB", newPDB.Namespace+"/"+newPDB.Name)
+}
+
+// RemovePDB implements schedulercache.Cache.
+func (c *Cache) RemovePDB(pdb *policy.PodDisruptionBudget) error {
+	return c.record("RemovePDB", pdb.Namespace+"/"+pdb.Name)
+}
+
+// ListPDBs implements schedulercache.Cache.
+func (c *Cache) ListPDBs(selector labels.Selector) ([]*policy.PodDisruptionBudget, error) {
+	if err := c.record("ListPDBs", ""); err != nil {
+		return nil, err
+	}
+	var pdbs []*policy.PodDisruptionBudget
+	for _, pdb := range c.PDBs {
+		if selector.Matches(labels.Set(pdb.Labels)) {
+			pdbs = append(pdbs, pdb)
+		}
+	}
+	return pdbs, nil
+}
+
+// GetPDBsMatchingPod implements schedulercache.Cache.
+func (c *Cache) GetPDBsMatchingPod(pod *v1.Pod) []*policy.PodDisruptionBudget {
+	c.record("GetPDBsMatchingPod", podKey(pod))
+	return c.PDBs
+}
+
+// MarkPodDisruption implements schedulercache.Cache.
+func (c *Cache) MarkPodDisruption(pod *v1.Pod) error {
+	return c.record("MarkPodDisruption", podKey(pod))
+}
+
+// PodDisruptionsAllowed implements schedulercache.Cache.
+func (c *Cache) PodDisruptionsAllowed(pdb *policy.PodDisruptionBudget) int32 {
+	c.record("PodDisruptionsAllowed", pdb.Namespace+"/"+pdb.Name)
+	return pdb.Status.PodDisruptionsAllowed
+}
+
+// Resync implements schedulercache.Cache.
+func (c *Cache) Resync(nodes []*v1.Node, pods []*v1.Pod, pdbs []*policy.PodDisruptionBudget) error {
+	return c.record("Resync", "")
+}
+
+// UpdateNodeNameToInfoMap implements schedulercache.Cache.
+func (c *Cache) UpdateNodeNameToInfoMap(infoMap map[string]*schedulercache.NodeInfo) ([]string, error) {
+	if err := c.record("UpdateNodeNameToInfoMap", ""); err != nil {
+		return nil, err
+	}
+	var changed []string
+	for name, info := range c.Nodes {
+		infoMap[name] = info
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// SampleNodes implements schedulercache.Cache.
+func (c *Cache) SampleNodes(n int, filter func(*schedulercache.NodeInfo) bool) []string {
+	c.record("SampleNodes", "")
+	var names []string
+	for name, info := range c.Nodes {
+		if filter != nil && !filter(info) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if n >= 0 && len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+// List implements schedulercache.Cache.
+func (c *Cache) List(selector labels.Selector) ([]*v1.Pod, error) {
+	if err := c.record("List", ""); err != nil {
+		return nil, err
+	}
+	var pods []*v1.Pod
+	for _, pod := range c.Pods {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// ListWithContext implements schedulercache.Cache.
+func (c *Cache) ListWithContext(ctx context.Context, selector labels.Selector) ([]*v1.Pod, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.List(selector)
+}
+
+// FilteredList implements schedulercache.Cache.
+func (c *Cache) FilteredList(filter schedulercache.PodFilter, selector labels.Selector) ([]*v1.Pod, error) {
+	if err := c.record("FilteredList", ""); err != nil {
+		return nil, err
+	}
+	var pods []*v1.Pod
+	for _, pod := range c.Pods {
+		if filter(pod) && selector.Matches(labels.Set(pod.Labels)) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// Snapshot implements schedulercache.Cache.
+func (c *Cache) Snapshot() *schedulercache.Snapshot {
+	c.record("Snapshot", "")
+	return &schedulercache.Snapshot{
+		AssumedPods: c.AssumedPods,
+		Nodes:       c.Nodes,
+	}
+}
+
+// SnapshotWithContext implements schedulercache.Cache.
+func (c *Cache) SnapshotWithContext(ctx context.Context) (*schedulercache.Snapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Snapshot(), nil
+}
+
+// NewReadReplica implements schedulercache.Cache.
+func (c *Cache) NewReadReplica(period time.Duration) *schedulercache.ReadReplica {
+	c.record("NewReadReplica", "")
+	return nil
+}
+
+// ClusterCapacity implements schedulercache.Cache.
+func (c *Cache) ClusterCapacity() *schedulercache.ClusterCapacitySummary {
+	c.record("ClusterCapacity", "")
+	if c.Capacity != nil {
+		return c.Capacity
+	}
+	return &schedulercache.ClusterCapacitySummary{}
+}
+
+// IsUpToDate implements schedulercache.Cache.
+func (c *Cache) IsUpToDate(n *schedulercache.NodeInfo) bool {
+	c.record("IsUpToDate", "")
+	return c.UpToDate
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakecache
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCallRecording(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-1"}}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+
+	cache := &Cache{}
+	if _, err := cache.AssumePod(pod); err != nil {
+		t.Fatalf("AssumePod failed: %v", err)
+	}
+	if err := cache.AddNode(node); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	if err := cache.ForgetPod(pod); err != nil {
+		t.Fatalf("ForgetPod failed: %v", err)
+	}
+
+	expected := []Call{
+		{Method: "AssumePod", Key: "ns/pod-1"},
+		{Method: "AddNode", Key: "node-1"},
+		{Method: "ForgetPod", Key: "ns/pod-1"},
+	}
+	if calls := cache.Calls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls %v, got: %v", expected, calls)
+	}
+}
+
+func TestInjectedErrors(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-1"}}
+	assumeErr := errors.New("assume rejected")
+
+	cache := &Cache{
+		Errors: map[string]error{"AssumePod": assumeErr},
+	}
+	if _, err := cache.AssumePod(pod); err != assumeErr {
+		t.Errorf("expected injected error, got: %v", err)
+	}
+	// Methods without an injected error still succeed.
+	if err := cache.AddPod(pod); err != nil {
+		t.Errorf("expected AddPod to succeed, got: %v", err)
+	}
+}
+
+func TestScriptedResponses(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-1"}}
+
+	cache := &Cache{
+		Pods:        []*v1.Pod{pod},
+		AssumedPods: map[string]bool{"ns/pod-1": true},
+		TopologyDomains: map[string][]string{
+			"zone/zone-a": {"node-1", "node-2"},
+		},
+	}
+	if got, err := cache.GetPod(pod); err != nil || got != pod {
+		t.Errorf("expected the scripted pod, got: %v, %v", got, err)
+	}
+	if assumed, err := cache.IsAssumedPod(pod); err != nil || !assumed {
+		t.Errorf("expected the pod to be assumed, got: %v, %v", assumed, err)
+	}
+	if nodes := cache.NodesInTopologyDomain("zone", "zone-a"); len(nodes) != 2 {
+		t.Errorf("expected 2 nodes in zone-a, got: %v", nodes)
+	}
+}
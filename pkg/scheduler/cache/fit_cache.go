@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// Pods stamped from the same controller template are interchangeable as far
+// as fit predicates are concerned, so during a large scale-up the scheduler
+// evaluates the same (template, node) pair over and over. The fit cache
+// memoizes those results keyed by the caller's template hash and the node's
+// generation: since the generation is bumped on every NodeInfo mutation, a
+// stale entry simply stops matching and is dropped on the next lookup, with
+// no explicit invalidation protocol between the cache and its consumers.
+
+// fitResult is one memoized admission result, valid only while the node's
+// generation is unchanged.
+type fitResult struct {
+	generation int64
+	fit        bool
+}
+
+// StoreFit memoizes the pod template's admission result on the node at its
+// current generation. Callers compute the hash themselves, typically from the
+// pod-template-hash label or a hash of the pod spec.
+func (cache *schedulerCache) StoreFit(templateHash, nodeName string, fit bool) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return newError(ReasonNodeNotFound, "node %v is not in the scheduler cache, so no fit result can be stored for it", nodeName)
+	}
+	results, ok := cache.fitResults[nodeName]
+	if !ok {
+		results = make(map[string]fitResult)
+		cache.fitResults[nodeName] = results
+	}
+	results[templateHash] = fitResult{generation: n.generation, fit: fit}
+	return nil
+}
+
+// CachedFit returns the memoized admission result for the pod template on the
+// node. The second return value reports whether a result is cached; a miss
+// means no result was stored or the node changed since, and the caller has to
+// run the predicates.
+func (cache *schedulerCache) CachedFit(templateHash, nodeName string) (bool, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		delete(cache.fitResults, nodeName)
+		return false, false
+	}
+	result, ok := cache.fitResults[nodeName][templateHash]
+	if !ok {
+		return false, false
+	}
+	if result.generation != n.generation {
+		delete(cache.fitResults[nodeName], templateHash)
+		return false, false
+	}
+	return result.fit, true
+}
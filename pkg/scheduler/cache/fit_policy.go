@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// FitResourcePolicy selects which node resource totals fit checks schedule
+// against. The default is Allocatable; some on-prem clusters deliberately
+// schedule best-effort tiers against Capacity instead.
+type FitResourcePolicy struct {
+	// UseCapacity makes fit checks use node Capacity instead of
+	// Allocatable.
+	UseCapacity bool
+	// Overrides flips the choice for individual resources: a resource
+	// mapped to true uses Capacity regardless of UseCapacity, one mapped
+	// to false uses Allocatable.
+	Overrides map[v1.ResourceName]bool
+}
+
+// useCapacity reports whether fit checks for the given resource should use
+// Capacity.
+func (policy FitResourcePolicy) useCapacity(rName v1.ResourceName) bool {
+	if use, ok := policy.Overrides[rName]; ok {
+		return use
+	}
+	return policy.UseCapacity
+}
+
+// SetFitResourcePolicy sets the policy selecting Capacity or Allocatable for
+// fit checks. It applies to subsequent checks only; pods already assumed are
+// not re-evaluated.
+func (cache *schedulerCache) SetFitResourcePolicy(policy FitResourcePolicy) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.fitPolicy = policy
+}
+
+// FitResourcePolicy returns the current fit resource policy.
+func (cache *schedulerCache) FitResourcePolicy() FitResourcePolicy {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return cache.fitPolicy
+}
+
+// FitResource returns the resource totals fit checks should schedule against
+// on this node under the given policy, blending Allocatable and Capacity per
+// resource. Over-commit ratios apply to resources resolved to Allocatable;
+// Capacity is a physical limit and is never over-committed.
+func (n *NodeInfo) FitResource(policy FitResourcePolicy) Resource {
+	if n == nil {
+		return emptyResource
+	}
+	pick := func(rName v1.ResourceName, allocatable, capacity int64) int64 {
+		if policy.useCapacity(rName) {
+			return capacity
+		}
+		return n.overCommitted(rName, allocatable)
+	}
+	result := Resource{
+		MilliCPU:         pick(v1.ResourceCPU, n.allocatableResource.MilliCPU, n.capacityResource.MilliCPU),
+		Memory:           pick(v1.ResourceMemory, n.allocatableResource.Memory, n.capacityResource.Memory),
+		EphemeralStorage: pick(v1.ResourceEphemeralStorage, n.allocatableResource.EphemeralStorage, n.capacityResource.EphemeralStorage),
+		AllowedPodNumber: int(pick(v1.ResourcePods, int64(n.allocatableResource.AllowedPodNumber), int64(n.capacityResource.AllowedPodNumber))),
+	}
+	for rName := range n.allocatableResource.ScalarResources {
+		result.SetScalar(rName, pick(rName, n.allocatableResource.ScalarResources[rName], n.capacityResource.ScalarResources[rName]))
+	}
+	for rName := range n.capacityResource.ScalarResources {
+		if _, ok := result.ScalarResources[rName]; !ok {
+			result.SetScalar(rName, pick(rName, n.allocatableResource.ScalarResources[rName], n.capacityResource.ScalarResources[rName]))
+		}
+	}
+	return result
+}
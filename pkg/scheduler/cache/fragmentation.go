@@ -0,0 +1,52 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// MaxSchedulableUnit returns the largest pod, by CPU, memory and ephemeral
+// storage request, that could still schedule onto the node: its free capacity
+// after in-flight resizes, growth headroom and the node's reserved overhead
+// are counted as occupied, clamped at zero per component. Bin-packing
+// priorities and resize decisions can compare the unit before and after a
+// candidate action to avoid leaving unusable slivers of capacity behind,
+// e.g. a node with plenty of CPU but too little memory for any real pod.
+// Capacity pool reservations aren't subtracted, since whether they apply
+// depends on the pod asking. The underlying aggregates are maintained
+// incrementally, so the computation is O(1) per node.
+func (cache *schedulerCache) MaxSchedulableUnit(nodeName string) (Resource, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return Resource{}, newError(ReasonNodeNotFound, "node %v is not in the scheduler cache, so its schedulable unit can't be computed", nodeName)
+	}
+	fit := n.FitResource(cache.fitPolicy)
+	headroom := n.HeadroomResource()
+	pending := cache.pendingResizeDelta(nodeName, "")
+	overhead := n.ReservedResource()
+	clamp := func(v int64) int64 {
+		if v < 0 {
+			return 0
+		}
+		return v
+	}
+	return Resource{
+		MilliCPU:         clamp(fit.MilliCPU - pending.MilliCPU - overhead.MilliCPU - n.requestedResource.MilliCPU - headroom.MilliCPU),
+		Memory:           clamp(fit.Memory - pending.Memory - overhead.Memory - n.requestedResource.Memory - headroom.Memory),
+		EphemeralStorage: clamp(fit.EphemeralStorage - pending.EphemeralStorage - overhead.EphemeralStorage - n.requestedResource.EphemeralStorage - headroom.EphemeralStorage),
+	}, nil
+}
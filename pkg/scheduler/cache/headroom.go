@@ -0,0 +1,160 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"strconv"
+
+	"k8s.io/api/core/v1"
+)
+
+// ExpectedGrowthFactorAnnotationKey annotates pods that are expected to grow
+// via in-place resize. The value is a factor over the pod's current requests,
+// e.g. "1.5" reserves an extra 50% of the requests as headroom on the node.
+// The reservation is consumed as resizes arrive, so a resize within the
+// expected envelope doesn't count against the node twice.
+const ExpectedGrowthFactorAnnotationKey = "scheduler.alpha.kubernetes.io/expected-growth-factor"
+
+// podGrowthHeadroom returns the extra capacity to reserve for pod, computed
+// from its expected growth factor annotation, or nil if the pod doesn't ask
+// for headroom.
+func podGrowthHeadroom(pod *v1.Pod) *Resource {
+	value, ok := pod.Annotations[ExpectedGrowthFactorAnnotationKey]
+	if !ok {
+		return nil
+	}
+	factor, err := strconv.ParseFloat(value, 64)
+	if err != nil || factor <= 1 {
+		return nil
+	}
+	res, _, _ := calculateResource(pod)
+	h := &Resource{
+		MilliCPU:         int64(float64(res.MilliCPU) * (factor - 1)),
+		Memory:           int64(float64(res.Memory) * (factor - 1)),
+		EphemeralStorage: int64(float64(res.EphemeralStorage) * (factor - 1)),
+	}
+	if h.isZero() {
+		return nil
+	}
+	return h
+}
+
+// adjustHeadroom adds (sign=1) or releases (sign=-1) reserved growth headroom
+// on the node. The field stays nil while no headroom is reserved, so nodes
+// without growing pods are unaffected.
+func (n *NodeInfo) adjustHeadroom(h *Resource, sign int64) {
+	if h == nil || h.isZero() {
+		return
+	}
+	if n.headroomResource == nil {
+		n.headroomResource = &Resource{}
+	}
+	n.headroomResource.MilliCPU += sign * h.MilliCPU
+	n.headroomResource.Memory += sign * h.Memory
+	n.headroomResource.EphemeralStorage += sign * h.EphemeralStorage
+	if n.headroomResource.isZero() {
+		n.headroomResource = nil
+	}
+	n.generation = nextGeneration()
+}
+
+// HeadroomResource returns the growth headroom currently reserved on the node.
+func (n *NodeInfo) HeadroomResource() Resource {
+	if n.headroomResource == nil {
+		return Resource{}
+	}
+	return *n.headroomResource.Clone()
+}
+
+// reserveHeadroom records the pod's growth headroom on its node.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) reserveHeadroom(pod *v1.Pod, n *NodeInfo) {
+	h := podGrowthHeadroom(pod)
+	if h == nil {
+		return
+	}
+	key, err := getPodKey(pod)
+	if err != nil {
+		return
+	}
+	cache.headroomByPod[key] = h
+	n.adjustHeadroom(h, 1)
+}
+
+// releaseHeadroom drops whatever headroom is still reserved for the pod.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) releaseHeadroom(pod *v1.Pod, n *NodeInfo) {
+	key, err := getPodKey(pod)
+	if err != nil {
+		return
+	}
+	if h, ok := cache.headroomByPod[key]; ok {
+		n.adjustHeadroom(h, -1)
+		delete(cache.headroomByPod, key)
+	}
+}
+
+// settleHeadroom fixes up a pod's reservation after an in-place update. The
+// remove/add pair inside updatePod re-reserved headroom from the new spec;
+// the reservation model instead wants the old reservation minus the growth
+// the resize just consumed, so the two are swapped here.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) settleHeadroom(key string, oldReserved *Resource, oldPod, newPod *v1.Pod) {
+	n, ok := cache.store.GetNodeInfo(newPod.Spec.NodeName)
+	if !ok {
+		return
+	}
+	oldRes, _, _ := calculateResource(oldPod)
+	newRes, _, _ := calculateResource(newPod)
+	desired := remainingHeadroom(oldReserved, &oldRes, &newRes)
+
+	if fresh, ok := cache.headroomByPod[key]; ok {
+		n.adjustHeadroom(fresh, -1)
+		delete(cache.headroomByPod, key)
+	}
+	if desired != nil {
+		cache.headroomByPod[key] = desired
+		n.adjustHeadroom(desired, 1)
+	}
+}
+
+// remainingHeadroom returns what is left of a reservation after the request
+// growth from oldRes to newRes is consumed, or nil if nothing is left.
+func remainingHeadroom(reserved, oldRes, newRes *Resource) *Resource {
+	if reserved == nil {
+		return nil
+	}
+	left := &Resource{
+		MilliCPU:         consumeReserved(reserved.MilliCPU, newRes.MilliCPU-oldRes.MilliCPU),
+		Memory:           consumeReserved(reserved.Memory, newRes.Memory-oldRes.Memory),
+		EphemeralStorage: consumeReserved(reserved.EphemeralStorage, newRes.EphemeralStorage-oldRes.EphemeralStorage),
+	}
+	if left.isZero() {
+		return nil
+	}
+	return left
+}
+
+func consumeReserved(reserved, growth int64) int64 {
+	if growth <= 0 {
+		return reserved
+	}
+	if growth >= reserved {
+		return 0
+	}
+	return reserved - growth
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+)
+
+// UpdateNodeLease ingests the renew time of a node's heartbeat lease.
+// Assuming pods onto nodes whose kubelet stopped renewing wastes binding
+// cycles, so with SetHeartbeatThreshold set, assumes and in-place resizes
+// refuse nodes whose last renewal is older than the threshold.
+func (cache *schedulerCache) UpdateNodeLease(nodeName string, renewTime time.Time) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return newError(ReasonNodeNotFound, "node %v is not in the cache, so its lease can't be recorded", nodeName)
+	}
+	if renewTime.After(n.lastHeartbeat) {
+		n.lastHeartbeat = renewTime
+		n.generation = nextGeneration()
+	}
+	return nil
+}
+
+// NodeHeartbeatAge returns how long ago the node's lease was last renewed.
+// Nodes whose lease was never ingested report an unknown heartbeat.
+func (cache *schedulerCache) NodeHeartbeatAge(nodeName string) (time.Duration, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return 0, newError(ReasonNodeNotFound, "node %v is not in the cache, so its heartbeat age is unknown", nodeName)
+	}
+	if n.lastHeartbeat.IsZero() {
+		return 0, newError(ReasonHeartbeatUnknown, "no lease was ingested for node %v", nodeName)
+	}
+	return time.Since(n.lastHeartbeat), nil
+}
+
+// SetHeartbeatThreshold configures the maximum heartbeat age up to which
+// nodes accept new assumes and in-place resizes. Nodes without an ingested
+// lease are not gated, so clusters that don't feed leases into the cache are
+// unaffected. Zero disables the gate.
+func (cache *schedulerCache) SetHeartbeatThreshold(d time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.heartbeatThreshold = d
+}
+
+// nodeHeartbeatStale reports whether the node's heartbeat is older than the
+// configured threshold. Assumes that lock is already acquired.
+func (cache *schedulerCache) nodeHeartbeatStale(n *NodeInfo, now time.Time) bool {
+	if cache.heartbeatThreshold <= 0 || n == nil || n.lastHeartbeat.IsZero() {
+		return false
+	}
+	return now.Sub(n.lastHeartbeat) > cache.heartbeatThreshold
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// defaultImagePullBandwidth is the assumed registry bandwidth when none has
+// been configured, in bytes per second.
+const defaultImagePullBandwidth int64 = 100 << 20
+
+// SetImagePullBandwidth configures the registry bandwidth, in bytes per
+// second, used to estimate image pull durations. Non-positive values restore
+// the default.
+func (cache *schedulerCache) SetImagePullBandwidth(bytesPerSecond int64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.pullBandwidth = bytesPerSecond
+}
+
+// imagePullBandwidth returns the effective pull bandwidth.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) imagePullBandwidth() int64 {
+	if cache.pullBandwidth > 0 {
+		return cache.pullBandwidth
+	}
+	return defaultImagePullBandwidth
+}
+
+// estimatePullDuration converts an image size into an estimated pull time at
+// the given bandwidth.
+func estimatePullDuration(sizeBytes, bandwidth int64) time.Duration {
+	if sizeBytes <= 0 || bandwidth <= 0 {
+		return 0
+	}
+	return time.Duration(float64(sizeBytes) / float64(bandwidth) * float64(time.Second))
+}
+
+// EstimatedImagePullTime estimates how long starting the pod on the node
+// would spend pulling images: the summed pull time of the pod's images that
+// are known to the cache but not present on the node. Images the cache has
+// never seen contribute nothing, since their size is unknown. The estimate
+// feeds pull-time-aware scoring and resize decisions that weigh restart cost.
+func (cache *schedulerCache) EstimatedImagePullTime(pod *v1.Pod, nodeName string) (time.Duration, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return 0, newError(ReasonNodeNotFound, "node %v is not in the cache, so image pull time can't be estimated", nodeName)
+	}
+	bandwidth := cache.imagePullBandwidth()
+	var total time.Duration
+	for _, container := range pod.Spec.Containers {
+		if _, present := n.imageStates[container.Image]; present {
+			continue
+		}
+		state, known := cache.imageStates[container.Image]
+		if !known {
+			continue
+		}
+		total += estimatePullDuration(state.size, bandwidth)
+	}
+	return total, nil
+}
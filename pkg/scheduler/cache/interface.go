@@ -17,9 +17,14 @@ limitations under the License.
 package cache
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // PodFilter is a function to filter a pod. If pod passed return true else return false.
@@ -63,14 +68,33 @@ type Cache interface {
 	// AssumePod assumes a pod scheduled and aggregates the pod's information into its node.
 	// The implementation also decides the policy to expire pod before being confirmed (receiving Add event).
 	// After expiration, its information would be subtracted.
-	AssumePod(pod *v1.Pod) error
+	// The returned BindingHandle controls the binding transaction for the
+	// assumed pod: Confirm, Abort, or Extend its hold on the node.
+	AssumePod(pod *v1.Pod) (BindingHandle, error)
+
+	// AssumePodWithContext is AssumePod honoring ctx cancellation: the
+	// operation is skipped if ctx is done before or while waiting for the
+	// cache lock.
+	AssumePodWithContext(ctx context.Context, pod *v1.Pod) (BindingHandle, error)
+
+	// SetAssumeAdmitFunc installs a hook that is called inside AssumePod and
+	// can veto the assume before the cache commits it.
+	SetAssumeAdmitFunc(fn AssumeAdmitFunc)
 
 	// FinishBinding signals that cache for assumed pod can be expired
 	FinishBinding(pod *v1.Pod) error
 
+	// BindingLatency returns the time between the pod being assumed and its
+	// add event confirming the binding.
+	BindingLatency(pod *v1.Pod) (time.Duration, error)
+
 	// ForgetPod removes an assumed pod from cache.
 	ForgetPod(pod *v1.Pod) error
 
+	// ReleasePod clears the hold on a pod that was assumed while carrying a
+	// scheduling-gate annotation, committing its resources to its node.
+	ReleasePod(podKey string) error
+
 	// AddPod either confirms a pod if it's assumed, or adds it back if it's expired.
 	// If added back, the pod's information would be added again.
 	AddPod(pod *v1.Pod) error
@@ -78,6 +102,9 @@ type Cache interface {
 	// UpdatePod removes oldPod's information and adds newPod's information.
 	UpdatePod(oldPod, newPod *v1.Pod) error
 
+	// UpdatePodWithContext is UpdatePod honoring ctx cancellation.
+	UpdatePodWithContext(ctx context.Context, oldPod, newPod *v1.Pod) error
+
 	// RemovePod removes a pod. The pod's information would be subtracted from assigned node.
 	RemovePod(pod *v1.Pod) error
 
@@ -85,9 +112,29 @@ type Cache interface {
 	// same name of the specified pod.
 	GetPod(pod *v1.Pod) (*v1.Pod, error)
 
+	// EnablePodTrimming makes the cache store trimmed pod copies to bound
+	// its memory usage. The getter is used to reconstruct full pods.
+	EnablePodTrimming(getter PodGetter)
+
+	// GetFullPod returns the untrimmed pod object, using the configured pod
+	// getter when trimming is enabled.
+	GetFullPod(pod *v1.Pod) (*v1.Pod, error)
+
+	// EnablePodDeduplication makes pods of the same controller share one
+	// canonical container spec slice to bound cache memory.
+	EnablePodDeduplication()
+
 	// IsAssumedPod returns true if the pod is assumed and not expired.
 	IsAssumedPod(pod *v1.Pod) (bool, error)
 
+	// SetProfileTTL overrides the assume TTL for pods of the given scheduler
+	// profile (scheduler name).
+	SetProfileTTL(profile string, ttl time.Duration)
+
+	// AssumedPodsByProfile returns the number of assumed pods per scheduler
+	// profile (scheduler name).
+	AssumedPodsByProfile() map[string]int
+
 	// AddNode adds overall information about node.
 	AddNode(node *v1.Node) error
 
@@ -97,9 +144,341 @@ type Cache interface {
 	// RemoveNode removes overall information about node.
 	RemoveNode(node *v1.Node) error
 
+	// ApplyBatch applies a batch of pod and node events under one lock
+	// acquisition and returns the errors of the events that failed,
+	// aggregated.
+	ApplyBatch(events []CacheEvent) error
+
+	// DeletedNodes returns the names of nodes that are deleted but still
+	// have pods accounted in the cache.
+	DeletedNodes() []string
+
+	// RankNodes orders the cached nodes by the given strategy over the
+	// cached aggregates and returns the best limit of them.
+	RankNodes(strategy RankStrategy, limit int) ([]NodeRank, error)
+
+	// NodesInTopologyDomain returns the names of the nodes labeled with the
+	// given topology key and value.
+	NodesInTopologyDomain(key, value string) []string
+
+	// NodeIterator returns an iterator over the cached nodes that
+	// round-robins across the domains of the given topology key, so
+	// evaluating only a prefix of the nodes still covers every domain.
+	NodeIterator(spreadKey string) *NodeIterator
+
+	// TopologySpreadCounts returns the number of pods matching the
+	// constraint per topology domain, keyed by the domain's label value.
+	TopologySpreadCounts(constraint TopologySpreadConstraint) map[string]int32
+
+	// MatchingAntiAffinityTerms returns the required anti-affinity terms of
+	// cached pods that select the given prospective pod, for the symmetry
+	// half of the anti-affinity predicate.
+	MatchingAntiAffinityTerms(pod *v1.Pod) []MatchingAntiAffinityTerm
+
+	// SetImagePullBandwidth configures the registry bandwidth, in bytes per
+	// second, used to estimate image pull times.
+	SetImagePullBandwidth(bytesPerSecond int64)
+
+	// EstimatedImagePullTime estimates the time starting the pod on the
+	// node would spend pulling images not already present there.
+	EstimatedImagePullTime(pod *v1.Pod, nodeName string) (time.Duration, error)
+
+	// AddInvalidationListener subscribes the listener to NodeInfo field
+	// change events.
+	AddInvalidationListener(listener InvalidationListener)
+
+	// SnapshotForExtender serializes the cached nodes into the compact
+	// wire format POSTed to HTTP scheduler extenders.
+	SnapshotForExtender(filter *ExtenderSnapshotFilter) ([]byte, error)
+
+	// EnableAuditLog turns on the append-only log of cache mutations used
+	// for post-incident analysis.
+	EnableAuditLog(maxEntries int, maxAge time.Duration, path string) error
+
+	// AuditEntries returns a copy of the retained audit entries in order.
+	AuditEntries() []AuditEntry
+
+	// SetScoreDataExport enables the periodic CSV export of feature
+	// vectors per (pending pod, candidate node) pair for offline
+	// placement-model training.
+	SetScoreDataExport(interval time.Duration, pending PendingPodsFunc, w io.Writer)
+
+	// SetVerificationMode controls shadow verification of pod accounting.
+	// Verification is slow and intended for soak testing only.
+	SetVerificationMode(mode VerificationMode)
+
+	// SetTracer installs the tracer used for cache operation spans. A nil
+	// tracer restores the no-op default.
+	SetTracer(t Tracer)
+
+	// EnableLockProfiling samples the lock wait and hold duration of one in
+	// sampleRate acquisitions per operation; non-positive disables.
+	EnableLockProfiling(sampleRate int)
+
+	// LockContentionReport returns the lock samples collected per operation
+	// since profiling was enabled, worst total wait first.
+	LockContentionReport() []LockOpStats
+
+	// SetFitResourcePolicy selects Capacity or Allocatable, per resource,
+	// for fit checks.
+	SetFitResourcePolicy(policy FitResourcePolicy)
+
+	// FitResourcePolicy returns the current fit resource policy.
+	FitResourcePolicy() FitResourcePolicy
+
+	// SetOverCommitRules replaces the over-commit rules and reapplies them
+	// to all cached nodes.
+	SetOverCommitRules(rules []OverCommitRule)
+
+	// SetCapacityPools replaces the guaranteed capacity pools whose
+	// reservations are held back from non-member pods in fit checks.
+	SetCapacityPools(pools []CapacityPool)
+
+	// SelectVictims computes a minimal set of lower-priority pods whose
+	// removal from the node frees enough resources for the preemptor.
+	SelectVictims(nodeName string, preemptor *v1.Pod, pdbAware bool) ([]*v1.Pod, error)
+
+	// IsNodeDrainable reports whether every pod on the node can be evicted
+	// right now, with the reasons naming the blocking pods otherwise.
+	IsNodeDrainable(nodeName string) (bool, []string, error)
+
+	// SetRecommendedResources feeds a target size for a pod into the cache,
+	// e.g. from a VPA recommender. Nil clears the recommendation.
+	SetRecommendedResources(podKey string, recommendation *Resource)
+
+	// UpdatePodUsage records the pod's measured ephemeral storage usage, in
+	// bytes, fed from the node status summary or a metrics pipeline.
+	UpdatePodUsage(podKey string, ephemeralStorageBytes int64) error
+
+	// SetDownsizeAnalyzer enables the right-sizing analyzer: pods whose
+	// requests stay above factor times their measured usage for the
+	// sustained duration are reported once through emit as dry-run
+	// downsize suggestions.
+	SetDownsizeAnalyzer(factor float64, sustained time.Duration, emit DownsizeSuggestFunc)
+
+	// UpdateNodeUsage feeds a measured CPU/memory usage sample for the
+	// node, folded into a decayed average exposed via NodeInfo.UsageRatio.
+	UpdateNodeUsage(nodeName string, usage Resource) error
+
+	// PlanResize decides how to act on the pod's recommended resources:
+	// not at all, in place, or by rescheduling.
+	PlanResize(podKey string) (ResizeAction, error)
+
+	// SetResizeFitPredicate installs the predicate consulted by PlanResize
+	// before it prefers an in-place resize, so scheduler predicates gate
+	// resizes consistently.
+	SetResizeFitPredicate(fn FitPredicate)
+
+	// SetResizeCooldown configures the minimum time between resizes of the
+	// same pod; inside the window PlanResize fails with ResizeCooldown.
+	SetResizeCooldown(d time.Duration)
+
+	// SetResizeGranularity configures the granularity recommendations are
+	// rounded to as they are ingested, so byte-level deltas become no-ops.
+	SetResizeGranularity(cpuMilli, memoryBytes int64)
+
+	// SetResizeRateLimit caps how many resizes MarkResizeStarted admits
+	// per node within a sliding window; contended budget is reserved for
+	// the node's highest-priority pending resizes.
+	SetResizeRateLimit(perNode int, window time.Duration)
+
+	// MarkResizeStarted records that an in-place resize of the pod is being
+	// carried out, deferring its eviction until MarkResizeCompleted; it
+	// fails if the pod is already marked for preemption.
+	MarkResizeStarted(podKey string) error
+
+	// MarkResizeCompleted records that the pod's resize was carried out,
+	// starting its cooldown window.
+	MarkResizeCompleted(podKey string)
+
+	// IsResizing returns whether the pod has a resize in flight.
+	IsResizing(podKey string) bool
+
+	// MarkPendingPreemption records that the pod is about to be preempted,
+	// rejecting new resizes of it; it fails if the pod has a resize in
+	// flight.
+	MarkPendingPreemption(podKey string) error
+
+	// ClearPendingPreemption releases the pod's preemption mark for callers
+	// that abandon a planned preemption.
+	ClearPendingPreemption(podKey string)
+
+	// SetResizeAnnotationGC enables the background sweep clearing stale
+	// resize annotations from long-completed resizes via the injected patch.
+	SetResizeAnnotationGC(retention time.Duration, patch PodPatchFunc)
+
+	// SetCheckpointing enables periodic checkpointing of failover-relevant
+	// state through the injected writer, at most once per interval.
+	SetCheckpointing(interval time.Duration, write CheckpointWriteFunc)
+
+	// RestoreCheckpoint warms a standby scheduler's cache from the
+	// primary's last checkpoint after the informers have synced.
+	RestoreCheckpoint(cp *Checkpoint)
+
+	// ResizePlacementHint returns nodes with enough free capacity for the
+	// pod's recommended size, best fit first, for pods being deleted for
+	// resizing.
+	ResizePlacementHint(podKey string) ([]string, error)
+
+	// PendingResizes returns the pods on the node with an outstanding
+	// resize and their net resource delta.
+	PendingResizes(nodeName string) []PendingResize
+
+	// MaxSchedulableUnit returns the largest pod, by resource request,
+	// that could still schedule onto the node, for bin-packing priorities
+	// and fragmentation-aware resize decisions.
+	MaxSchedulableUnit(nodeName string) (Resource, error)
+
+	// SetPVCDriverResolver installs the resolver mapping PVCs to their
+	// CSI driver, used to charge pods' volumes against attach slots.
+	SetPVCDriverResolver(resolve PVCDriverResolver)
+
+	// AddCSINode ingests the per-driver allocatable volume attach counts
+	// a node's CSI drivers advertise.
+	AddCSINode(nodeName string, allocatable map[string]int32) error
+
+	// UpdateCSINode replaces the node's per-driver allocatable volume
+	// attach counts.
+	UpdateCSINode(nodeName string, allocatable map[string]int32) error
+
+	// RemoveCSINode drops the node's per-driver allocatable volume attach
+	// counts.
+	RemoveCSINode(nodeName string)
+
+	// RemainingAttachSlots returns how many more volumes each advertised
+	// CSI driver can attach on the node.
+	RemainingAttachSlots(nodeName string) (map[string]int32, error)
+
+	// AddNodeLocalPVC records that the claim is bound to a node-local PV,
+	// so pods using it are never deleted for resizing.
+	AddNodeLocalPVC(namespace, claim string)
+
+	// RemoveNodeLocalPVC drops the claim from the node-local volume index.
+	RemoveNodeLocalPVC(namespace, claim string)
+
+	// StoreFit memoizes the pod template's admission result on the node
+	// at its current generation.
+	StoreFit(templateHash, nodeName string, fit bool) error
+
+	// CachedFit returns the memoized admission result for the pod
+	// template on the node; the second return value reports whether a
+	// current result is cached.
+	CachedFit(templateHash, nodeName string) (bool, bool)
+
+	// PodsByOwner returns the cached pods controlled by the given owner
+	// UID.
+	PodsByOwner(uid types.UID) []*v1.Pod
+
+	// OwnerSpreadCounts returns how many pods of the given owner sit in
+	// each domain of the topology key.
+	OwnerSpreadCounts(uid types.UID, topologyKey string) map[string]int32
+
+	// SetPodUpdater installs the function queued pod mutations are
+	// written through.
+	SetPodUpdater(fn PodUpdateFunc)
+
+	// EnqueuePodMutation queues the annotations to be written to the pod
+	// asynchronously, with retries.
+	EnqueuePodMutation(podKey string, annotations map[string]string)
+
+	// ForEachPod calls fn for every pod in the cache until fn returns
+	// false, without copying the pod set; fn runs under the read lock and
+	// must not call back into the cache.
+	ForEachPod(fn func(pod *v1.Pod) bool)
+
+	// GetAssumedPods returns every assumed pod with its source, assume
+	// time and expiry deadline, sorted by pod key.
+	GetAssumedPods() []AssumedPodInfo
+
+	// RecentPDBDecisions returns the journaled disruption-budget
+	// decisions, oldest first.
+	RecentPDBDecisions() []PDBDecision
+
+	// SnapshotMatching takes a snapshot restricted to the nodes whose
+	// labels match the selector, including only pods on those nodes.
+	SnapshotMatching(selector labels.Selector) *Snapshot
+
+	// GetPodNode resolves the node the pod is placed on and whether the
+	// placement is still assumed rather than confirmed.
+	GetPodNode(podKey string) (nodeName string, assumed bool, err error)
+
+	// SetResizeWatchdog enables the stuck-resize scan: resizes still in
+	// flight after threshold are reported to the handler.
+	SetResizeWatchdog(threshold time.Duration, handler ResizeStuckFunc)
+
+	// SetNamespacePodLimit caps how many pods from a single namespace may
+	// land on one node; non-positive disables the cap.
+	SetNamespacePodLimit(limit int)
+
+	// SetStrictAssume toggles rejecting assumes on unknown or cordoned
+	// nodes instead of tolerating them.
+	SetStrictAssume(enabled bool)
+
+	// SetOptimisticTerminating makes resize fit checks count the requests
+	// of terminating pods as free capacity.
+	SetOptimisticTerminating(enabled bool)
+
+	// SetZeroRequestDefaults replaces the compiled-in requests assumed for
+	// zero-request containers in the defaulted aggregate.
+	SetZeroRequestDefaults(defaults *ZeroRequestDefaults)
+
+	// SetZeroRequestPolicy installs a per-namespace override of the
+	// zero-request defaults.
+	SetZeroRequestPolicy(policy ZeroRequestPolicy)
+
+	// PodAllocatedResources returns the pod's spec-requested and
+	// kubelet-reported allocated resources per container.
+	PodAllocatedResources(podKey string) (requested, allocated map[string]Resource, err error)
+
+	// PinPod reserves the pod's resources on the named node like
+	// AssumePod, for pods bound by an external controller.
+	PinPod(pod *v1.Pod, nodeName string, ttl time.Duration) error
+
+	// AssumeGroup assumes a set of pods atomically with all-or-nothing
+	// semantics and a shared TTL, for gang scheduling.
+	AssumeGroup(group string, pods []*v1.Pod, ttl time.Duration) error
+
+	// ForgetGroup rolls back every still-assumed member of the group.
+	ForgetGroup(group string) error
+
+	// UpdateNodeLease ingests the renew time of a node's heartbeat lease.
+	UpdateNodeLease(nodeName string, renewTime time.Time) error
+
+	// NodeHeartbeatAge returns how long ago the node's lease was last
+	// renewed.
+	NodeHeartbeatAge(nodeName string) (time.Duration, error)
+
+	// SetHeartbeatThreshold configures the maximum heartbeat age up to
+	// which nodes accept new assumes and in-place resizes; zero disables
+	// the gate.
+	SetHeartbeatThreshold(d time.Duration)
+
+	// MarkNodeUnschedulableSoon registers upcoming maintenance for the
+	// node: until the deadline passes, new assumes and in-place resizes on
+	// it are refused.
+	MarkNodeUnschedulableSoon(nodeName string, deadline time.Time)
+
+	// ClearNodeMaintenance drops the node's maintenance intent.
+	ClearNodeMaintenance(nodeName string)
+
+	// OrphanedPods returns the cached pods whose node no longer exists.
+	OrphanedPods() []*v1.Pod
+
+	// SetOrphanPurgeGracePeriod enables auto-purging of pods that stay
+	// orphaned for the given duration. Zero disables purging.
+	SetOrphanPurgeGracePeriod(d time.Duration)
+
+	// SetCleanupPeriod adjusts, at runtime, the interval between expiry
+	// scans and the jitter factor applied to it.
+	SetCleanupPeriod(period time.Duration, jitter float64)
+
 	// AddPDB adds a PodDisruptionBudget object to the cache.
 	AddPDB(pdb *policy.PodDisruptionBudget) error
 
+	// AddPDBData adds or updates a PDB from its version-neutral fields, for
+	// adapters of policy API versions not vendored into this tree.
+	AddPDBData(data PDBData) error
+
 	// UpdatePDB updates a PodDisruptionBudget object in the cache.
 	UpdatePDB(oldPDB, newPDB *policy.PodDisruptionBudget) error
 
@@ -109,20 +488,59 @@ type Cache interface {
 	// List lists all cached PDBs matching the selector.
 	ListPDBs(selector labels.Selector) ([]*policy.PodDisruptionBudget, error)
 
+	// GetPDBsMatchingPod returns all cached PDBs in the pod's namespace whose
+	// spec selector matches the pod's labels.
+	GetPDBsMatchingPod(pod *v1.Pod) []*policy.PodDisruptionBudget
+
+	// MarkPodDisruption records a locally decided disruption (e.g. preemption)
+	// of the pod against all matching PDBs, until their status catches up.
+	MarkPodDisruption(pod *v1.Pod) error
+
+	// PodDisruptionsAllowed returns the number of disruptions the given PDB
+	// still allows, taking locally marked in-flight disruptions into account.
+	PodDisruptionsAllowed(pdb *policy.PodDisruptionBudget) int32
+
+	// Resync atomically replaces the cache's nodes, pods, and PDBs with the
+	// given authoritative lists, preserving pending assumed pods. It is meant
+	// for recovering from detected cache drift without a scheduler restart.
+	Resync(nodes []*v1.Node, pods []*v1.Pod, pdbs []*policy.PodDisruptionBudget) error
+
 	// UpdateNodeNameToInfoMap updates the passed infoMap to the current contents of Cache.
 	// The node info contains aggregated information of pods scheduled (including assumed to be)
-	// on this node.
-	UpdateNodeNameToInfoMap(infoMap map[string]*NodeInfo) error
+	// on this node. It returns the sorted names of the nodes whose entries were refreshed or
+	// removed, so callers can limit per-node recomputation to the changed nodes.
+	UpdateNodeNameToInfoMap(infoMap map[string]*NodeInfo) ([]string, error)
+
+	// SampleNodes returns the names of up to n nodes drawn uniformly at
+	// random from the cache, restricted to nodes the filter accepts; a nil
+	// filter accepts every node. The filter runs with the cache's lock held
+	// and must neither mutate the NodeInfo nor retain it.
+	SampleNodes(n int, filter func(*NodeInfo) bool) []string
 
 	// List lists all cached pods (including assumed ones).
 	List(labels.Selector) ([]*v1.Pod, error)
 
+	// ListWithContext is List honoring ctx cancellation.
+	ListWithContext(ctx context.Context, selector labels.Selector) ([]*v1.Pod, error)
+
 	// FilteredList returns all cached pods that pass the filter.
 	FilteredList(filter PodFilter, selector labels.Selector) ([]*v1.Pod, error)
 
-	// Snapshot takes a snapshot on current cache
+	// Snapshot takes a snapshot on current cache. Unchanged nodes are
+	// shared between successive snapshots, so the result is read-only.
 	Snapshot() *Snapshot
 
+	// SnapshotWithContext is Snapshot honoring ctx cancellation.
+	SnapshotWithContext(ctx context.Context) (*Snapshot, error)
+
+	// NewReadReplica returns an eventually consistent, lock-free view of
+	// the cache, refreshed every period, for expensive read-only consumers.
+	NewReadReplica(period time.Duration) *ReadReplica
+
+	// ClusterCapacity returns a summary of total allocatable versus
+	// requested resources and node counts by readiness.
+	ClusterCapacity() *ClusterCapacitySummary
+
 	// IsUpToDate returns true if the given NodeInfo matches the current data in the cache.
 	IsUpToDate(n *NodeInfo) bool
 }
@@ -132,4 +550,7 @@ type Snapshot struct {
 	AssumedPods map[string]bool
 	Nodes       map[string]*NodeInfo
 	Pdbs        map[string]*policy.PodDisruptionBudget
+	// MaintenanceDeadlines maps node names with an active maintenance
+	// intent to the registered deadline, for consumers like the autoscaler.
+	MaintenanceDeadlines map[string]time.Time
 }
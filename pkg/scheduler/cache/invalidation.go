@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+
+	"k8s.io/api/core/v1"
+)
+
+// InvalidationField names the part of a NodeInfo that changed. Consumers such
+// as the equivalence cache map fields to the predicates that read them, so a
+// pod event only invalidates port- and resource-related predicates instead of
+// flushing every cached result for the node.
+type InvalidationField string
+
+const (
+	// InvalidationAllocatable signals a change of the node's allocatable
+	// resources.
+	InvalidationAllocatable InvalidationField = "allocatable"
+	// InvalidationTaints signals a change of the node's taints.
+	InvalidationTaints InvalidationField = "taints"
+	// InvalidationPods signals a pod being added to or removed from the
+	// node.
+	InvalidationPods InvalidationField = "pods"
+	// InvalidationUsedPorts signals a change of the host ports in use on
+	// the node.
+	InvalidationUsedPorts InvalidationField = "usedPorts"
+)
+
+// InvalidationEvent describes one NodeInfo field change on one node.
+type InvalidationEvent struct {
+	NodeName string
+	Field    InvalidationField
+}
+
+// InvalidationListener receives NodeInfo field change events. Listeners are
+// called synchronously with the cache lock held: they must return quickly and
+// must not call back into the cache.
+type InvalidationListener func(event InvalidationEvent)
+
+// AddInvalidationListener subscribes the listener to NodeInfo field change
+// events. Listeners cannot be removed; they live as long as the cache.
+func (cache *schedulerCache) AddInvalidationListener(listener InvalidationListener) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.invalidationListeners = append(cache.invalidationListeners, listener)
+}
+
+// publishInvalidation delivers one event per changed field to every listener.
+// This function assumes the lock to the scheduler cache has been acquired.
+func (cache *schedulerCache) publishInvalidation(nodeName string, fields ...InvalidationField) {
+	if len(cache.invalidationListeners) == 0 {
+		return
+	}
+	for _, field := range fields {
+		event := InvalidationEvent{NodeName: nodeName, Field: field}
+		for _, listener := range cache.invalidationListeners {
+			listener(event)
+		}
+	}
+}
+
+// nodeChangedFields returns the invalidation fields that differ between the
+// old and new node objects. A nil old node reports all fields changed.
+func nodeChangedFields(oldNode, newNode *v1.Node) []InvalidationField {
+	if oldNode == nil {
+		return []InvalidationField{InvalidationAllocatable, InvalidationTaints}
+	}
+	var fields []InvalidationField
+	if !reflect.DeepEqual(oldNode.Status.Allocatable, newNode.Status.Allocatable) {
+		fields = append(fields, InvalidationAllocatable)
+	}
+	if !reflect.DeepEqual(oldNode.Spec.Taints, newNode.Spec.Taints) {
+		fields = append(fields, InvalidationTaints)
+	}
+	return fields
+}
+
+// podUsesHostPorts returns true if any container of the pod requests a host
+// port, i.e. the pod affects the node's usedPorts.
+func podUsesHostPorts(pod *v1.Pod) bool {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.HostPort != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
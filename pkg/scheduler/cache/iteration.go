@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// ForEachPod calls fn for every pod in the cache until fn returns false. It
+// exists for read-heavy consumers (usage exporters, cache comparers) that
+// would otherwise List a six-figure pod count into one slice: iteration goes
+// node by node, holding the read lock only per node, so writers interleave
+// and no copy of the pod set is ever materialized.
+//
+// fn runs under the read lock and must return quickly and not call back into
+// the cache. Because the lock is released between nodes, the traversal is not
+// a snapshot: pods added or removed while iterating may or may not be seen.
+func (cache *schedulerCache) ForEachPod(fn func(pod *v1.Pod) bool) {
+	cache.mu.RLock()
+	nodeNames := make([]string, 0, cache.store.NumNodes())
+	cache.store.RangeNodeInfos(func(name string, _ *NodeInfo) bool {
+		nodeNames = append(nodeNames, name)
+		return true
+	})
+	cache.mu.RUnlock()
+
+	for _, name := range nodeNames {
+		cache.mu.RLock()
+		n, ok := cache.store.GetNodeInfo(name)
+		if !ok {
+			// The node left the cache between chunks.
+			cache.mu.RUnlock()
+			continue
+		}
+		for _, pod := range n.pods {
+			if !fn(pod) {
+				cache.mu.RUnlock()
+				return
+			}
+		}
+		cache.mu.RUnlock()
+	}
+}
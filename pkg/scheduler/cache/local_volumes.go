@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// The node-local volume index records which PVCs are bound to node-local PVs
+// (local volumes, hostPath-backed PVs). A pod using one can't be rescheduled
+// onto another node without losing its data, so PlanResize refuses the
+// DeletePodForResizing fallback for such pods instead of planning a delete
+// the replacement pod could never recover from. The index is fed by whoever
+// watches PVs, since the cache deliberately doesn't depend on the PV listers.
+
+// AddNodeLocalPVC records that the claim is bound to a node-local PV.
+func (cache *schedulerCache) AddNodeLocalPVC(namespace, claim string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.nodeLocalPVCs[namespace+"/"+claim] = true
+}
+
+// RemoveNodeLocalPVC drops the claim from the node-local volume index, e.g.
+// when the claim is deleted or rebound to a portable PV.
+func (cache *schedulerCache) RemoveNodeLocalPVC(namespace, claim string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.nodeLocalPVCs, namespace+"/"+claim)
+}
+
+// podUsesNodeLocalStorage returns whether the pod mounts a hostPath volume
+// directly or a PVC from the node-local volume index.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) podUsesNodeLocalStorage(pod *v1.Pod) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.HostPath != nil {
+			return true
+		}
+		if volume.PersistentVolumeClaim != nil && cache.nodeLocalPVCs[pod.Namespace+"/"+volume.PersistentVolumeClaim.ClaimName] {
+			return true
+		}
+	}
+	return false
+}
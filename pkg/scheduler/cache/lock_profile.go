@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// LockOpStats is the sampled lock contention of one operation type: how long
+// its acquisitions waited for the cache's lock and how long they held it.
+type LockOpStats struct {
+	Op        string        `json:"op"`
+	Samples   int64         `json:"samples"`
+	TotalWait time.Duration `json:"totalWait"`
+	MaxWait   time.Duration `json:"maxWait"`
+	TotalHold time.Duration `json:"totalHold"`
+	MaxHold   time.Duration `json:"maxHold"`
+}
+
+// lockOpProfile accumulates the samples of one operation type. Guarded by
+// the cache's lockProfileMu.
+type lockOpProfile struct {
+	samples   int64
+	totalWait time.Duration
+	maxWait   time.Duration
+	totalHold time.Duration
+	maxHold   time.Duration
+}
+
+// EnableLockProfiling makes one in sampleRate lock acquisitions of the
+// instrumented operations record its wait and hold duration, and clears any
+// previously collected samples. A non-positive rate disables profiling. The
+// unsampled path costs two atomic reads per acquisition, so a modest rate is
+// safe to leave on in production while quantifying whether mutation storms
+// or snapshot builds starve the scheduling goroutine.
+func (cache *schedulerCache) EnableLockProfiling(sampleRate int) {
+	cache.lockProfileMu.Lock()
+	cache.lockProfiles = make(map[string]*lockOpProfile)
+	cache.lockProfileMu.Unlock()
+	atomic.StoreInt64(&cache.lockProfileRate, int64(sampleRate))
+}
+
+// LockContentionReport returns the samples collected per operation type
+// since profiling was enabled, ordered by total wait so the operations
+// starving the others come first. Serve it from a debug endpoint; it is
+// collected without touching the cache's lock.
+func (cache *schedulerCache) LockContentionReport() []LockOpStats {
+	cache.lockProfileMu.Lock()
+	defer cache.lockProfileMu.Unlock()
+
+	var report []LockOpStats
+	for op, profile := range cache.lockProfiles {
+		report = append(report, LockOpStats{
+			Op:        op,
+			Samples:   profile.samples,
+			TotalWait: profile.totalWait,
+			MaxWait:   profile.maxWait,
+			TotalHold: profile.totalHold,
+			MaxHold:   profile.maxHold,
+		})
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].TotalWait != report[j].TotalWait {
+			return report[i].TotalWait > report[j].TotalWait
+		}
+		return report[i].Op < report[j].Op
+	})
+	return report
+}
+
+// lockFor acquires the cache's write lock attributed to op and returns the
+// function releasing it. When the acquisition is sampled, the wait and hold
+// durations are recorded after the release so the bookkeeping never extends
+// the hold it measures.
+func (cache *schedulerCache) lockFor(op string) func() {
+	rate := atomic.LoadInt64(&cache.lockProfileRate)
+	if rate <= 0 || atomic.AddInt64(&cache.lockProfileSeq, 1)%rate != 0 {
+		cache.mu.Lock()
+		return cache.mu.Unlock
+	}
+	start := time.Now()
+	cache.mu.Lock()
+	acquired := time.Now()
+	return func() {
+		held := time.Since(acquired)
+		cache.mu.Unlock()
+		cache.recordLockSample(op, acquired.Sub(start), held)
+	}
+}
+
+// rlockFor is lockFor for the read lock.
+func (cache *schedulerCache) rlockFor(op string) func() {
+	rate := atomic.LoadInt64(&cache.lockProfileRate)
+	if rate <= 0 || atomic.AddInt64(&cache.lockProfileSeq, 1)%rate != 0 {
+		cache.mu.RLock()
+		return cache.mu.RUnlock
+	}
+	start := time.Now()
+	cache.mu.RLock()
+	acquired := time.Now()
+	return func() {
+		held := time.Since(acquired)
+		cache.mu.RUnlock()
+		cache.recordLockSample(op, acquired.Sub(start), held)
+	}
+}
+
+func (cache *schedulerCache) recordLockSample(op string, wait, hold time.Duration) {
+	cache.lockProfileMu.Lock()
+	defer cache.lockProfileMu.Unlock()
+
+	if cache.lockProfiles == nil {
+		return
+	}
+	profile, ok := cache.lockProfiles[op]
+	if !ok {
+		profile = &lockOpProfile{}
+		cache.lockProfiles[op] = profile
+	}
+	profile.samples++
+	profile.totalWait += wait
+	if wait > profile.maxWait {
+		profile.maxWait = wait
+	}
+	profile.totalHold += hold
+	if hold > profile.maxHold {
+		profile.maxHold = hold
+	}
+}
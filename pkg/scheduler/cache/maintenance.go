@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+)
+
+// Maintenance intent is registered by drain tooling before a node is actually
+// cordoned: from registration until the deadline passes (or the intent is
+// cleared) the cache stops approving new assumes and in-place resizes on the
+// node, so the drain doesn't race new placements. The intent is advisory
+// only; it doesn't touch the node object.
+
+// MarkNodeUnschedulableSoon registers upcoming maintenance for the node. The
+// intent is active immediately and expires once the deadline passes, by which
+// time the node is expected to be cordoned for real.
+func (cache *schedulerCache) MarkNodeUnschedulableSoon(nodeName string, deadline time.Time) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.maintenance[nodeName] = deadline
+}
+
+// ClearNodeMaintenance drops the maintenance intent for the node, e.g. when
+// a drain is cancelled.
+func (cache *schedulerCache) ClearNodeMaintenance(nodeName string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.maintenance, nodeName)
+}
+
+// nodeUnderMaintenance returns true if the node has an active maintenance
+// intent. Assumes that lock is already acquired.
+func (cache *schedulerCache) nodeUnderMaintenance(nodeName string, now time.Time) bool {
+	deadline, ok := cache.maintenance[nodeName]
+	return ok && now.Before(deadline)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+)
+
+// The mutation queue decouples pod annotation writes from the paths that
+// decide them. Resize handling runs in informer callbacks with the cache lock
+// held; mutating the pod on the API server from there would stall every other
+// cache operation on an API round trip. Instead the mutation is enqueued and
+// applied write-behind from the cleanup loop, outside the lock, with retries.
+
+// maxMutationRetries is how many rounds a failing mutation is retried before
+// it is dropped. A conflict retry re-reads the latest cached pod, so repeated
+// failures mean the API server keeps rejecting the write, not that the pod is
+// merely stale.
+const maxMutationRetries = 5
+
+// PodUpdateFunc applies the annotations to the pod on the API server. It is
+// injected so the cache itself stays free of client-go dependencies; the
+// implementation is expected to fetch-and-update or patch, returning an error
+// on conflict so the queue retries with the then-current cached pod.
+type PodUpdateFunc func(pod *v1.Pod, annotations map[string]string) error
+
+// podMutation is one queued annotation write, merged across enqueues for the
+// same pod.
+type podMutation struct {
+	annotations map[string]string
+	retries     int
+}
+
+// SetPodUpdater installs the function the mutation queue writes pods through.
+// A nil updater leaves mutations queued until one is installed.
+func (cache *schedulerCache) SetPodUpdater(fn PodUpdateFunc) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.podUpdate = fn
+}
+
+// EnqueuePodMutation queues the annotations to be written to the pod
+// asynchronously. Later values for the same annotation key overwrite earlier
+// ones still in the queue, so only the newest decision reaches the API
+// server.
+func (cache *schedulerCache) EnqueuePodMutation(podKey string, annotations map[string]string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.enqueuePodMutation(podKey, annotations)
+}
+
+// enqueuePodMutation is the lock-held part of EnqueuePodMutation, for callers
+// already inside a cache operation. Assumes that lock is already acquired.
+func (cache *schedulerCache) enqueuePodMutation(podKey string, annotations map[string]string) {
+	mutation, ok := cache.podMutations[podKey]
+	if !ok {
+		mutation = &podMutation{annotations: make(map[string]string)}
+		cache.podMutations[podKey] = mutation
+	}
+	for key, value := range annotations {
+		mutation.annotations[key] = value
+	}
+	mutation.retries = 0
+}
+
+// processPodMutations applies one round of queued mutations, run from the
+// cleanup loop. The updater calls happen outside the lock since they hit the
+// API server; failed mutations are requeued unless their pod left the cache
+// or their retries are spent.
+func (cache *schedulerCache) processPodMutations() {
+	type pendingMutation struct {
+		podKey   string
+		pod      *v1.Pod
+		mutation *podMutation
+	}
+	var pending []pendingMutation
+
+	cache.mu.Lock()
+	update := cache.podUpdate
+	if update == nil || len(cache.podMutations) == 0 {
+		cache.mu.Unlock()
+		return
+	}
+	for podKey, mutation := range cache.podMutations {
+		ps, ok := cache.podStates[podKey]
+		if !ok {
+			// The pod left the cache; its mutation can never apply.
+			delete(cache.podMutations, podKey)
+			continue
+		}
+		pending = append(pending, pendingMutation{podKey: podKey, pod: ps.pod, mutation: mutation})
+		delete(cache.podMutations, podKey)
+	}
+	cache.mu.Unlock()
+
+	var failed []pendingMutation
+	for _, p := range pending {
+		if err := update(p.pod, p.mutation.annotations); err != nil {
+			p.mutation.retries++
+			if p.mutation.retries >= maxMutationRetries {
+				glog.Errorf("Dropping mutation of pod %v after %d attempts: %v", p.podKey, p.mutation.retries, err)
+				continue
+			}
+			failed = append(failed, p)
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, p := range failed {
+		// A newer mutation enqueued meanwhile already carries the
+		// latest values; don't clobber it with the failed one.
+		if _, ok := cache.podMutations[p.podKey]; ok {
+			continue
+		}
+		if _, ok := cache.podStates[p.podKey]; !ok {
+			continue
+		}
+		cache.podMutations[p.podKey] = p.mutation
+	}
+}
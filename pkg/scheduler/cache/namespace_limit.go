@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"strconv"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+)
+
+// NamespacePodLimitAnnotationKey overrides, per node (annotation or label),
+// how many pods from a single namespace may land on that node. It takes
+// precedence over the cache-wide limit set via SetNamespacePodLimit; a
+// non-positive or malformed value disables the limit for the node.
+const NamespacePodLimitAnnotationKey = "scheduler.alpha.kubernetes.io/max-pods-per-namespace"
+
+// SetNamespacePodLimit caps how many pods from a single namespace may land on
+// one node, bounding the blast radius of a tenant in multi-tenant clusters.
+// The cap is enforced at assume time and consulted by resize placement hints;
+// nodes can override it via NamespacePodLimitAnnotationKey. A non-positive
+// limit disables the cache-wide cap.
+func (cache *schedulerCache) SetNamespacePodLimit(limit int) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.namespacePodLimit = limit
+}
+
+// nodeNamespacePodLimit reads the node's per-namespace pod cap advertisement,
+// or false if the node doesn't carry one.
+func nodeNamespacePodLimit(node *v1.Node) (int, bool) {
+	value, ok := node.Annotations[NamespacePodLimitAnnotationKey]
+	if !ok {
+		value, ok = node.Labels[NamespacePodLimitAnnotationKey]
+	}
+	if !ok {
+		return 0, false
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		glog.Errorf("Node %v has an invalid %v advertisement %q: %v", node.Name, NamespacePodLimitAnnotationKey, value, err)
+		return 0, false
+	}
+	return limit, true
+}
+
+// namespaceAtPodLimit reports whether assuming the pod on the node would
+// exceed the applicable per-namespace pod cap, along with the cap and the
+// namespace's current count for error reporting.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) namespaceAtPodLimit(pod *v1.Pod, n *NodeInfo) (limit, count int, exceeded bool) {
+	limit = cache.namespacePodLimit
+	if n.node != nil {
+		if nodeLimit, ok := nodeNamespacePodLimit(n.node); ok {
+			limit = nodeLimit
+		}
+	}
+	if limit <= 0 {
+		return limit, 0, false
+	}
+	for _, p := range n.pods {
+		if p.Namespace == pod.Namespace {
+			count++
+		}
+	}
+	return limit, count, count >= limit
+}
@@ -17,10 +17,14 @@ limitations under the License.
 package cache
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/golang/glog"
 
@@ -36,11 +40,30 @@ var (
 	generation    int64
 )
 
+// NodeState is the lifecycle state of a NodeInfo in the cache.
+type NodeState int
+
+const (
+	// NodeActive is a node that is present in the cluster.
+	NodeActive NodeState = iota
+	// NodeDeleting is a node whose node object has been deleted, but whose
+	// NodeInfo is kept around because pod removal events for it are still
+	// outstanding. New pods must not be assumed on such nodes.
+	NodeDeleting
+	// NodeGone is a node whose NodeInfo has been dropped from the cache. It
+	// is never observed on a NodeInfo and exists to complete the state
+	// machine: Active -> Deleting -> Gone.
+	NodeGone
+)
+
 // NodeInfo is node level aggregated information.
 type NodeInfo struct {
 	// Overall node information.
 	node *v1.Node
 
+	// The lifecycle state of this node in the cache.
+	state NodeState
+
 	pods             []*v1.Pod
 	podsWithAffinity []*v1.Pod
 	usedPorts        util.HostPortInfo
@@ -53,6 +76,64 @@ type NodeInfo struct {
 	// We store allocatedResources (which is Node.Status.Allocatable.*) explicitly
 	// as int64, to avoid conversions and accessing map.
 	allocatableResource *Resource
+	// capacityResource is Node.Status.Capacity in the same representation.
+	// Fit checks use one or the other depending on the cache's
+	// FitResourcePolicy.
+	capacityResource *Resource
+	// reservedResource is the node's advertised kube-reserved plus
+	// system-reserved overhead, subtracted by the resize fit checks (see
+	// node_reserved.go); nil while none is advertised.
+	reservedResource *Resource
+	// staticCPUPolicy is whether the node's kubelet runs the static CPU
+	// manager policy, and exclusiveMilliCPU the milli-CPUs it has granted
+	// exclusively to Guaranteed whole-CPU pods (see cpu_pool.go).
+	staticCPUPolicy   bool
+	exclusiveMilliCPU int64
+	// csiAllocatable is the per-driver allocatable volume attach count the
+	// node's CSI drivers advertise, and csiAttached how many of them the
+	// cached pods' PVC volumes use (see csi_node.go); both nil while
+	// nothing is ingested.
+	csiAllocatable map[string]int32
+	csiAttached    map[string]int32
+	// overCommitRatios multiplies effective allocatable per resource; nil
+	// when no over-commit rule matches this node.
+	overCommitRatios map[v1.ResourceName]float64
+	// requestedByOrigin splits requestedResource by pod origin
+	// (daemonset/static/normal).
+	requestedByOrigin map[PodOrigin]*Resource
+	// headroomResource is capacity reserved for the expected growth of pods
+	// annotated with a growth factor; nil while nothing is reserved.
+	headroomResource *Resource
+	// terminatingResource sums the requests of pods carrying a deletion
+	// timestamp; nil while no pod on the node is terminating.
+	terminatingResource *Resource
+	// allocatedDelta sums the kubelet-reported allocation minus the spec
+	// requests across the node's pods; nil while they agree
+	// (see allocated.go).
+	allocatedDelta *Resource
+	// usedEphemeralStorage sums the last reported ephemeral storage usage,
+	// in bytes, of the pods on this node (see Cache.UpdatePodUsage).
+	usedEphemeralStorage int64
+	// Exponentially decayed CPU (milli) and memory (bytes) usage fed by
+	// Cache.UpdateNodeUsage; meaningless until hasUsageSample is set.
+	decayedCPUUsage    float64
+	decayedMemoryUsage float64
+	hasUsageSample     bool
+	// inPlaceResizeSupported mirrors the node's kubelet capability
+	// advertisement for in-place pod resizes (see resize_capability.go).
+	inPlaceResizeSupported bool
+	// unschedulable mirrors node.Spec.Unschedulable, consulted by strict
+	// assumes to catch races between cordon and bind.
+	unschedulable bool
+	// zeroRequestDefaults and zeroRequestPolicy carry the cache's
+	// configuration for the requests assumed for zero-request containers in
+	// nonzeroRequest; both nil means the compiled-in defaults
+	// (see zero_request.go).
+	zeroRequestDefaults *ZeroRequestDefaults
+	zeroRequestPolicy   ZeroRequestPolicy
+	// lastHeartbeat is the last ingested renew time of the node's lease;
+	// the zero value means no lease was ever ingested (see heartbeat.go).
+	lastHeartbeat time.Time
 
 	// Cached taints of the node for faster lookup.
 	taints    []v1.Taint
@@ -76,6 +157,16 @@ type NodeInfo struct {
 	// Whenever NodeInfo changes, generation is bumped.
 	// This is used to avoid cloning it if the object didn't change.
 	generation int64
+
+	// Memoized requested/allocatable fractions, valid for ratiosGeneration.
+	// See UtilizationRatios.
+	utilizationRatios map[v1.ResourceName]float64
+	ratiosGeneration  int64
+
+	// Memoized per-priority-band requests, valid for bandsGeneration.
+	// See RequestedByBand.
+	priorityBandRequests *[numPriorityBands]Resource
+	bandsGeneration      int64
 }
 
 //initializeNodeTransientInfo initializes transient information pertaining to node.
@@ -206,6 +297,40 @@ func (r *Resource) Clone() *Resource {
 	return res
 }
 
+// addResource adds another Resource into this one field by field.
+func (r *Resource) addResource(other *Resource) {
+	r.MilliCPU += other.MilliCPU
+	r.Memory += other.Memory
+	r.EphemeralStorage += other.EphemeralStorage
+	r.AllowedPodNumber += other.AllowedPodNumber
+	for rName, rQuant := range other.ScalarResources {
+		r.AddScalar(rName, rQuant)
+	}
+}
+
+// subResource subtracts another Resource from this one field by field,
+// clamping each value at zero.
+func (r *Resource) subResource(other *Resource) {
+	r.MilliCPU = nonNegative(r.MilliCPU - other.MilliCPU)
+	r.Memory = nonNegative(r.Memory - other.Memory)
+	r.EphemeralStorage = nonNegative(r.EphemeralStorage - other.EphemeralStorage)
+	if r.AllowedPodNumber < other.AllowedPodNumber {
+		r.AllowedPodNumber = 0
+	} else {
+		r.AllowedPodNumber -= other.AllowedPodNumber
+	}
+	for rName, rQuant := range other.ScalarResources {
+		r.SetScalar(rName, nonNegative(r.ScalarResources[rName]-rQuant))
+	}
+}
+
+func nonNegative(value int64) int64 {
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
 // AddScalar adds a resource by a scalar value of this resource.
 func (r *Resource) AddScalar(name v1.ResourceName, quantity int64) {
 	r.SetScalar(name, r.ScalarResources[name]+quantity)
@@ -259,6 +384,7 @@ func NewNodeInfo(pods ...*v1.Pod) *NodeInfo {
 		requestedResource:   &Resource{},
 		nonzeroRequest:      &Resource{},
 		allocatableResource: &Resource{},
+		capacityResource:    &Resource{},
 		TransientInfo:       newTransientSchedulerInfo(),
 		generation:          nextGeneration(),
 		usedPorts:           make(util.HostPortInfo),
@@ -278,6 +404,14 @@ func (n *NodeInfo) Node() *v1.Node {
 	return n.node
 }
 
+// State returns the lifecycle state of this node in the cache.
+func (n *NodeInfo) State() NodeState {
+	if n == nil {
+		return NodeGone
+	}
+	return n.state
+}
+
 // Pods return all pods scheduled (including assumed to be) on this node.
 func (n *NodeInfo) Pods() []*v1.Pod {
 	if n == nil {
@@ -380,13 +514,69 @@ func (n *NodeInfo) SetAllocatableResource(allocatableResource *Resource) {
 	n.generation = nextGeneration()
 }
 
+// CapacityResource returns the capacity resources on a given node.
+func (n *NodeInfo) CapacityResource() Resource {
+	if n == nil {
+		return emptyResource
+	}
+	return *n.capacityResource
+}
+
+// UtilizationRatios returns the requested/allocatable fraction per resource,
+// covering CPU, memory, ephemeral storage, and all allocatable scalar
+// resources. Resources with no allocatable amount report zero. The result is
+// memoized per generation, so priorities evaluating many pods against the
+// same NodeInfo don't redo the divisions; like the rest of NodeInfo, it isn't
+// safe for concurrent use.
+func (n *NodeInfo) UtilizationRatios() map[v1.ResourceName]float64 {
+	if n == nil {
+		return nil
+	}
+	if n.utilizationRatios != nil && n.ratiosGeneration == n.generation {
+		return n.utilizationRatios
+	}
+
+	ratio := func(requested, allocatable int64) float64 {
+		if allocatable <= 0 {
+			return 0
+		}
+		return float64(requested) / float64(allocatable)
+	}
+	ratios := make(map[v1.ResourceName]float64, 3+len(n.allocatableResource.ScalarResources))
+	ratios[v1.ResourceCPU] = ratio(n.requestedResource.MilliCPU, n.allocatableResource.MilliCPU)
+	ratios[v1.ResourceMemory] = ratio(n.requestedResource.Memory, n.allocatableResource.Memory)
+	ratios[v1.ResourceEphemeralStorage] = ratio(n.requestedResource.EphemeralStorage, n.allocatableResource.EphemeralStorage)
+	for rName, rQuant := range n.allocatableResource.ScalarResources {
+		ratios[rName] = ratio(n.requestedResource.ScalarResources[rName], rQuant)
+	}
+	n.utilizationRatios = ratios
+	n.ratiosGeneration = n.generation
+	return ratios
+}
+
 // Clone returns a copy of this node.
 func (n *NodeInfo) Clone() *NodeInfo {
 	clone := &NodeInfo{
-		node:                    n.node,
-		requestedResource:       n.requestedResource.Clone(),
-		nonzeroRequest:          n.nonzeroRequest.Clone(),
-		allocatableResource:     n.allocatableResource.Clone(),
+		node:                n.node,
+		state:               n.state,
+		requestedResource:   n.requestedResource.Clone(),
+		nonzeroRequest:      n.nonzeroRequest.Clone(),
+		allocatableResource: n.allocatableResource.Clone(),
+		capacityResource:    n.capacityResource.Clone(),
+		// Ratios are replaced wholesale by SetOverCommitRules, never
+		// mutated in place, so sharing the map is safe.
+		overCommitRatios:        n.overCommitRatios,
+		usedEphemeralStorage:    n.usedEphemeralStorage,
+		decayedCPUUsage:         n.decayedCPUUsage,
+		decayedMemoryUsage:      n.decayedMemoryUsage,
+		hasUsageSample:          n.hasUsageSample,
+		inPlaceResizeSupported:  n.inPlaceResizeSupported,
+		unschedulable:           n.unschedulable,
+		zeroRequestDefaults:     n.zeroRequestDefaults,
+		zeroRequestPolicy:       n.zeroRequestPolicy,
+		lastHeartbeat:           n.lastHeartbeat,
+		staticCPUPolicy:         n.staticCPUPolicy,
+		exclusiveMilliCPU:       n.exclusiveMilliCPU,
 		taintsErr:               n.taintsErr,
 		TransientInfo:           n.TransientInfo,
 		memoryPressureCondition: n.memoryPressureCondition,
@@ -395,13 +585,25 @@ func (n *NodeInfo) Clone() *NodeInfo {
 		usedPorts:               make(util.HostPortInfo),
 		imageStates:             make(map[string]*ImageStateSummary),
 		generation:              n.generation,
+		// The memoized ratios and band requests are safe to share: a
+		// fresh map or array is built on every recomputation.
+		utilizationRatios:    n.utilizationRatios,
+		ratiosGeneration:     n.ratiosGeneration,
+		priorityBandRequests: n.priorityBandRequests,
+		bandsGeneration:      n.bandsGeneration,
 	}
 	if len(n.pods) > 0 {
 		clone.pods = append([]*v1.Pod(nil), n.pods...)
 	}
 	if len(n.usedPorts) > 0 {
-		for k, v := range n.usedPorts {
-			clone.usedPorts[k] = v
+		// Deep-copy the per-IP port maps: sharing them would let RemovePod
+		// on a what-if clone release host ports on the original.
+		for ip, ports := range n.usedPorts {
+			portsCopy := make(map[util.ProtocolPort]struct{}, len(ports))
+			for port := range ports {
+				portsCopy[port] = struct{}{}
+			}
+			clone.usedPorts[ip] = portsCopy
 		}
 	}
 	if len(n.podsWithAffinity) > 0 {
@@ -410,6 +612,59 @@ func (n *NodeInfo) Clone() *NodeInfo {
 	if len(n.taints) > 0 {
 		clone.taints = append([]v1.Taint(nil), n.taints...)
 	}
+	if len(n.requestedByOrigin) > 0 {
+		clone.requestedByOrigin = make(map[PodOrigin]*Resource, len(n.requestedByOrigin))
+		for origin, res := range n.requestedByOrigin {
+			clone.requestedByOrigin[origin] = res.Clone()
+		}
+	}
+	if n.headroomResource != nil {
+		clone.headroomResource = n.headroomResource.Clone()
+	}
+	if n.terminatingResource != nil {
+		clone.terminatingResource = n.terminatingResource.Clone()
+	}
+	if n.allocatedDelta != nil {
+		clone.allocatedDelta = n.allocatedDelta.Clone()
+	}
+	if n.reservedResource != nil {
+		clone.reservedResource = n.reservedResource.Clone()
+	}
+	if len(n.csiAllocatable) > 0 {
+		clone.csiAllocatable = make(map[string]int32, len(n.csiAllocatable))
+		for driver, count := range n.csiAllocatable {
+			clone.csiAllocatable[driver] = count
+		}
+	}
+	if len(n.csiAttached) > 0 {
+		clone.csiAttached = make(map[string]int32, len(n.csiAttached))
+		for driver, count := range n.csiAttached {
+			clone.csiAttached[driver] = count
+		}
+	}
+	return clone
+}
+
+// CloneWithPodsRemoved returns a copy of this node with the given pods
+// removed, for preemption and resize what-if analysis. The receiver is not
+// modified; all derived state (requested resources, used ports, scalars) is
+// adjusted on the copy. Pods not present on the node are ignored.
+func (n *NodeInfo) CloneWithPodsRemoved(pods []*v1.Pod) *NodeInfo {
+	clone := n.Clone()
+	for _, pod := range pods {
+		if err := clone.RemovePod(pod); err != nil {
+			glog.V(4).Infof("Pod %s/%s is not on node %s, ignoring for what-if removal: %v",
+				pod.Namespace, pod.Name, clone.node.Name, err)
+		}
+	}
+	return clone
+}
+
+// CloneWithPodAdded returns a copy of this node with the pod added, for
+// what-if analysis. The receiver is not modified.
+func (n *NodeInfo) CloneWithPodAdded(pod *v1.Pod) *NodeInfo {
+	clone := n.Clone()
+	clone.AddPod(pod)
 	return clone
 }
 
@@ -424,14 +679,88 @@ func (n *NodeInfo) VolumeLimits() map[v1.ResourceName]int64 {
 	return volumeLimits
 }
 
-// String returns representation of human readable format of this NodeInfo.
+// formatMilliCPU renders a milli-CPU quantity as cores.
+func formatMilliCPU(milli int64) string {
+	return fmt.Sprintf("%gcores", float64(milli)/1000)
+}
+
+// formatBytes renders a byte quantity in Ki or Mi.
+func formatBytes(bytes int64) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1fMi", float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%.1fKi", float64(bytes)/1024)
+	}
+	return fmt.Sprintf("%d", bytes)
+}
+
+// podsByOwner summarizes the pods by their first owner reference, sorted by
+// owner. Pods without an owner are counted as "standalone".
+func (n *NodeInfo) podsByOwner() []string {
+	counts := make(map[string]int)
+	for _, pod := range n.pods {
+		owner := "standalone"
+		if refs := pod.OwnerReferences; len(refs) > 0 {
+			owner = refs[0].Kind + "/" + refs[0].Name
+		}
+		counts[owner]++
+	}
+	owners := make([]string, 0, len(counts))
+	for owner, count := range counts {
+		owners = append(owners, fmt.Sprintf("%s x%d", owner, count))
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// String returns a human readable one-line summary of this NodeInfo with
+// quantities in cores and Ki/Mi and pods grouped by owner. Annotations are
+// redacted: they routinely hold multi-KB blobs and occasionally secrets.
 func (n *NodeInfo) String() string {
-	podKeys := make([]string, len(n.pods))
-	for i, pod := range n.pods {
-		podKeys[i] = pod.Name
+	nodeName := "<unknown>"
+	redacted := ""
+	if n.node != nil {
+		nodeName = n.node.Name
+		if len(n.node.Annotations) > 0 {
+			redacted = fmt.Sprintf(", %d annotations redacted", len(n.node.Annotations))
+		}
+	}
+	return fmt.Sprintf("&NodeInfo{Node: %s, Requested: %s/%s of %s/%s allocatable, UsedPorts: %d, Pods: [%s]%s}",
+		nodeName,
+		formatMilliCPU(n.requestedResource.MilliCPU), formatBytes(n.requestedResource.Memory),
+		formatMilliCPU(n.allocatableResource.MilliCPU), formatBytes(n.allocatableResource.Memory),
+		len(n.usedPorts), strings.Join(n.podsByOwner(), ", "), redacted)
+}
+
+// nodeInfoSummary is the JSON shape of a NodeInfo. It carries the same
+// summary as String: formatted quantities, pods by owner, no annotations.
+type nodeInfoSummary struct {
+	Node           string   `json:"node,omitempty"`
+	RequestedCPU   string   `json:"requestedCPU"`
+	RequestedMem   string   `json:"requestedMemory"`
+	AllocatableCPU string   `json:"allocatableCPU"`
+	AllocatableMem string   `json:"allocatableMemory"`
+	UsedPorts      int      `json:"usedPorts"`
+	Pods           []string `json:"pods,omitempty"`
+	Generation     int64    `json:"generation"`
+}
+
+// MarshalJSON serializes the NodeInfo summary for structured log sinks.
+func (n *NodeInfo) MarshalJSON() ([]byte, error) {
+	summary := nodeInfoSummary{
+		RequestedCPU:   formatMilliCPU(n.requestedResource.MilliCPU),
+		RequestedMem:   formatBytes(n.requestedResource.Memory),
+		AllocatableCPU: formatMilliCPU(n.allocatableResource.MilliCPU),
+		AllocatableMem: formatBytes(n.allocatableResource.Memory),
+		UsedPorts:      len(n.usedPorts),
+		Pods:           n.podsByOwner(),
+		Generation:     n.generation,
+	}
+	if n.node != nil {
+		summary.Node = n.node.Name
 	}
-	return fmt.Sprintf("&NodeInfo{Pods:%v, RequestedResource:%#v, NonZeroRequest: %#v, UsedPort: %#v, AllocatableResource:%#v}",
-		podKeys, n.requestedResource, n.nonzeroRequest, n.usedPorts, n.allocatableResource)
+	return json.Marshal(summary)
 }
 
 func hasPodAffinityConstraints(pod *v1.Pod) bool {
@@ -441,7 +770,7 @@ func hasPodAffinityConstraints(pod *v1.Pod) bool {
 
 // AddPod adds pod information to this NodeInfo.
 func (n *NodeInfo) AddPod(pod *v1.Pod) {
-	res, non0CPU, non0Mem := calculateResource(pod)
+	res, non0CPU, non0Mem := n.calculatePodResource(pod)
 	n.requestedResource.MilliCPU += res.MilliCPU
 	n.requestedResource.Memory += res.Memory
 	n.requestedResource.EphemeralStorage += res.EphemeralStorage
@@ -453,6 +782,10 @@ func (n *NodeInfo) AddPod(pod *v1.Pod) {
 	}
 	n.nonzeroRequest.MilliCPU += non0CPU
 	n.nonzeroRequest.Memory += non0Mem
+	n.exclusiveMilliCPU += exclusiveCPURequest(pod)
+	n.accountOrigin(pod, &res, 1)
+	n.accountTerminating(pod, &res, 1)
+	n.accountAllocated(pod, 1)
 	n.pods = append(n.pods, pod)
 	if hasPodAffinityConstraints(pod) {
 		n.podsWithAffinity = append(n.podsWithAffinity, pod)
@@ -495,7 +828,7 @@ func (n *NodeInfo) RemovePod(pod *v1.Pod) error {
 			n.pods[i] = n.pods[len(n.pods)-1]
 			n.pods = n.pods[:len(n.pods)-1]
 			// reduce the resource data
-			res, non0CPU, non0Mem := calculateResource(pod)
+			res, non0CPU, non0Mem := n.calculatePodResource(pod)
 
 			n.requestedResource.MilliCPU -= res.MilliCPU
 			n.requestedResource.Memory -= res.Memory
@@ -508,6 +841,10 @@ func (n *NodeInfo) RemovePod(pod *v1.Pod) error {
 			}
 			n.nonzeroRequest.MilliCPU -= non0CPU
 			n.nonzeroRequest.Memory -= non0Mem
+			n.exclusiveMilliCPU -= exclusiveCPURequest(pod)
+			n.accountOrigin(pod, &res, -1)
+			n.accountTerminating(pod, &res, -1)
+			n.accountAllocated(pod, -1)
 
 			// Release ports when remove Pods.
 			n.updateUsedPorts(pod, false)
@@ -551,8 +888,11 @@ func (n *NodeInfo) updateUsedPorts(pod *v1.Pod, add bool) {
 // SetNode sets the overall node information.
 func (n *NodeInfo) SetNode(node *v1.Node) error {
 	n.node = node
+	n.state = NodeActive
 
 	n.allocatableResource = NewResource(node.Status.Allocatable)
+	n.capacityResource = NewResource(node.Status.Capacity)
+	n.subtractUnhealthyResources(node)
 
 	n.taints = node.Spec.Taints
 	for i := range node.Status.Conditions {
@@ -568,6 +908,10 @@ func (n *NodeInfo) SetNode(node *v1.Node) error {
 			// We ignore other conditions.
 		}
 	}
+	n.inPlaceResizeSupported = inPlaceResizeSupported(node)
+	n.unschedulable = node.Spec.Unschedulable
+	n.reservedResource = reservedResourcesOf(node)
+	n.staticCPUPolicy = staticCPUManagerPolicy(node)
 	n.TransientInfo = newTransientSchedulerInfo()
 	n.generation = nextGeneration()
 	return nil
@@ -580,12 +924,18 @@ func (n *NodeInfo) RemoveNode(node *v1.Node) error {
 	// and thus can potentially be observed later, even though they happened before
 	// node removal. This is handled correctly in cache.go file.
 	n.node = nil
+	n.state = NodeDeleting
 	n.allocatableResource = &Resource{}
+	n.capacityResource = &Resource{}
+	n.reservedResource = nil
+	n.staticCPUPolicy = false
 	n.taints, n.taintsErr = nil, nil
 	n.memoryPressureCondition = v1.ConditionUnknown
 	n.diskPressureCondition = v1.ConditionUnknown
 	n.pidPressureCondition = v1.ConditionUnknown
 	n.imageStates = make(map[string]*ImageStateSummary)
+	n.inPlaceResizeSupported = false
+	n.unschedulable = false
 	n.generation = nextGeneration()
 	return nil
 }
@@ -17,8 +17,10 @@ limitations under the License.
 package cache
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"k8s.io/api/core/v1"
@@ -240,6 +242,121 @@ func TestSetMaxResource(t *testing.T) {
 	}
 }
 
+func TestAddSubResource(t *testing.T) {
+	tests := []struct {
+		resource *Resource
+		other    *Resource
+		expected *Resource
+	}{
+		{
+			resource: &Resource{},
+			other:    nil,
+			expected: &Resource{},
+		},
+		{
+			resource: &Resource{
+				MilliCPU:         4,
+				Memory:           2000,
+				EphemeralStorage: 5000,
+				AllowedPodNumber: 80,
+				ScalarResources:  map[v1.ResourceName]int64{"scalar.test/scalar1": 1},
+			},
+			other: &Resource{
+				MilliCPU:         2,
+				Memory:           1000,
+				EphemeralStorage: 3000,
+				AllowedPodNumber: 10,
+				ScalarResources:  map[v1.ResourceName]int64{"scalar.test/scalar1": 2, "hugepages-test": 2},
+			},
+			expected: &Resource{
+				MilliCPU:         6,
+				Memory:           3000,
+				EphemeralStorage: 8000,
+				AllowedPodNumber: 90,
+				ScalarResources:  map[v1.ResourceName]int64{"scalar.test/scalar1": 3, "hugepages-test": 2},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		sum := test.resource.Clone()
+		sum.AddResource(test.other)
+		if !reflect.DeepEqual(test.expected, sum) {
+			t.Errorf("expected: %#v, got: %#v", test.expected, sum)
+		}
+		// Subtracting the addend again restores the original values.
+		if test.other != nil {
+			sum.SubResource(test.other)
+			if sum.MilliCPU != test.resource.MilliCPU || sum.Memory != test.resource.Memory ||
+				sum.EphemeralStorage != test.resource.EphemeralStorage {
+				t.Errorf("expected: %#v, got: %#v", test.resource, sum)
+			}
+		}
+	}
+
+	// Subtraction clamps at zero rather than going negative.
+	small := &Resource{MilliCPU: 1, Memory: 100}
+	small.SubResource(&Resource{MilliCPU: 4, Memory: 2000, EphemeralStorage: 5000})
+	if !reflect.DeepEqual(&Resource{}, small) {
+		t.Errorf("expected an empty resource, got: %#v", small)
+	}
+}
+
+func TestResourceFits(t *testing.T) {
+	allocatable := &Resource{
+		MilliCPU:         4000,
+		Memory:           8000,
+		EphemeralStorage: 5000,
+		ScalarResources:  map[v1.ResourceName]int64{"scalar.test/scalar1": 2},
+	}
+	tests := []struct {
+		resource *Resource
+		other    *Resource
+		expected bool
+	}{
+		{
+			resource: &Resource{},
+			other:    nil,
+			expected: true,
+		},
+		{
+			resource: &Resource{MilliCPU: 2000, Memory: 4000},
+			other:    &Resource{MilliCPU: 2000, Memory: 4000},
+			expected: true,
+		},
+		{
+			resource: &Resource{MilliCPU: 2000, Memory: 4000},
+			other:    &Resource{MilliCPU: 2001},
+			expected: false,
+		},
+		{
+			resource: &Resource{EphemeralStorage: 6000},
+			other:    nil,
+			expected: false,
+		},
+		{
+			resource: &Resource{ScalarResources: map[v1.ResourceName]int64{"scalar.test/scalar1": 2}},
+			other:    &Resource{ScalarResources: map[v1.ResourceName]int64{"scalar.test/scalar1": 1}},
+			expected: false,
+		},
+		{
+			resource: &Resource{ScalarResources: map[v1.ResourceName]int64{"scalar.test/scalar2": 1}},
+			other:    nil,
+			expected: false,
+		},
+	}
+
+	for i, test := range tests {
+		if fits := test.resource.Fits(test.other, allocatable); fits != test.expected {
+			t.Errorf("case %d: expected fits=%v, got: %v", i, test.expected, fits)
+		}
+	}
+
+	if (&Resource{}).Fits(nil, nil) {
+		t.Errorf("expected nothing to fit a nil allocatable")
+	}
+}
+
 func TestNewNodeInfo(t *testing.T) {
 	nodeName := "test-node"
 	pods := []*v1.Pod{
@@ -262,8 +379,18 @@ func TestNewNodeInfo(t *testing.T) {
 			AllowedPodNumber: 0,
 			ScalarResources:  map[v1.ResourceName]int64(nil),
 		},
+		requestedByOrigin: map[PodOrigin]*Resource{
+			PodOriginNormal: {
+				MilliCPU:         300,
+				Memory:           1524,
+				EphemeralStorage: 0,
+				AllowedPodNumber: 0,
+				ScalarResources:  map[v1.ResourceName]int64(nil),
+			},
+		},
 		TransientInfo:       newTransientSchedulerInfo(),
 		allocatableResource: &Resource{},
+		capacityResource:    &Resource{},
 		generation:          2,
 		usedPorts: util.HostPortInfo{
 			"127.0.0.1": map[util.ProtocolPort]struct{}{
@@ -353,6 +480,7 @@ func TestNodeInfoClone(t *testing.T) {
 				nonzeroRequest:      &Resource{},
 				TransientInfo:       newTransientSchedulerInfo(),
 				allocatableResource: &Resource{},
+				capacityResource:    &Resource{},
 				generation:          2,
 				usedPorts: util.HostPortInfo{
 					"127.0.0.1": map[util.ProtocolPort]struct{}{
@@ -423,6 +551,7 @@ func TestNodeInfoClone(t *testing.T) {
 				nonzeroRequest:      &Resource{},
 				TransientInfo:       newTransientSchedulerInfo(),
 				allocatableResource: &Resource{},
+				capacityResource:    &Resource{},
 				generation:          2,
 				usedPorts: util.HostPortInfo{
 					"127.0.0.1": map[util.ProtocolPort]struct{}{
@@ -579,8 +708,18 @@ func TestNodeInfoAddPod(t *testing.T) {
 			AllowedPodNumber: 0,
 			ScalarResources:  map[v1.ResourceName]int64(nil),
 		},
+		requestedByOrigin: map[PodOrigin]*Resource{
+			PodOriginNormal: {
+				MilliCPU:         300,
+				Memory:           1524,
+				EphemeralStorage: 0,
+				AllowedPodNumber: 0,
+				ScalarResources:  map[v1.ResourceName]int64(nil),
+			},
+		},
 		TransientInfo:       newTransientSchedulerInfo(),
 		allocatableResource: &Resource{},
+		capacityResource:    &Resource{},
 		generation:          2,
 		usedPorts: util.HostPortInfo{
 			"127.0.0.1": map[util.ProtocolPort]struct{}{
@@ -698,8 +837,18 @@ func TestNodeInfoRemovePod(t *testing.T) {
 					AllowedPodNumber: 0,
 					ScalarResources:  map[v1.ResourceName]int64(nil),
 				},
+				requestedByOrigin: map[PodOrigin]*Resource{
+					PodOriginNormal: {
+						MilliCPU:         300,
+						Memory:           1524,
+						EphemeralStorage: 0,
+						AllowedPodNumber: 0,
+						ScalarResources:  map[v1.ResourceName]int64(nil),
+					},
+				},
 				TransientInfo:       newTransientSchedulerInfo(),
 				allocatableResource: &Resource{},
+				capacityResource:    &Resource{},
 				generation:          2,
 				usedPorts: util.HostPortInfo{
 					"127.0.0.1": map[util.ProtocolPort]struct{}{
@@ -815,8 +964,18 @@ func TestNodeInfoRemovePod(t *testing.T) {
 					AllowedPodNumber: 0,
 					ScalarResources:  map[v1.ResourceName]int64(nil),
 				},
+				requestedByOrigin: map[PodOrigin]*Resource{
+					PodOriginNormal: {
+						MilliCPU:         200,
+						Memory:           1024,
+						EphemeralStorage: 0,
+						AllowedPodNumber: 0,
+						ScalarResources:  map[v1.ResourceName]int64(nil),
+					},
+				},
 				TransientInfo:       newTransientSchedulerInfo(),
 				allocatableResource: &Resource{},
+				capacityResource:    &Resource{},
 				generation:          3,
 				usedPorts: util.HostPortInfo{
 					"127.0.0.1": map[util.ProtocolPort]struct{}{
@@ -893,3 +1052,248 @@ func fakeNodeInfo(pods ...*v1.Pod) *NodeInfo {
 	}
 	return ni
 }
+
+func TestUtilizationRatios(t *testing.T) {
+	ni := NewNodeInfo()
+	ni.SetNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	})
+	pod := makeBasePod(t, "test-node", "test-pod", "500m", "512Mi", "", nil)
+	ni.AddPod(pod)
+
+	ratios := ni.UtilizationRatios()
+	if ratios[v1.ResourceCPU] != 0.25 {
+		t.Errorf("expected CPU ratio 0.25, got %v", ratios[v1.ResourceCPU])
+	}
+	if ratios[v1.ResourceMemory] != 0.5 {
+		t.Errorf("expected memory ratio 0.5, got %v", ratios[v1.ResourceMemory])
+	}
+	if again := ni.UtilizationRatios(); reflect.ValueOf(again).Pointer() != reflect.ValueOf(ratios).Pointer() {
+		t.Error("expected memoized ratios while the NodeInfo is unchanged")
+	}
+
+	ni.RemovePod(pod)
+	if ratios = ni.UtilizationRatios(); ratios[v1.ResourceCPU] != 0 {
+		t.Errorf("expected CPU ratio 0 after pod removal, got %v", ratios[v1.ResourceCPU])
+	}
+}
+
+func TestNodeInfoString(t *testing.T) {
+	ni := NewNodeInfo()
+	ni.SetNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-node",
+			Annotations: map[string]string{"big/blob": "redact-me"},
+		},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	})
+	owned := makeBasePod(t, "test-node", "owned-pod", "500m", "512Mi", "", nil)
+	owned.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "rs-1"}}
+	ni.AddPod(owned)
+	ni.AddPod(makeBasePod(t, "test-node", "standalone-pod", "100m", "1Ki", "", nil))
+
+	got := ni.String()
+	for _, want := range []string{
+		"test-node",
+		"0.6cores",
+		"512.0Mi",
+		"2cores",
+		"1024.0Mi",
+		"ReplicaSet/rs-1 x1",
+		"standalone x1",
+		"1 annotations redacted",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected String to contain %q, got: %s", want, got)
+		}
+	}
+	if strings.Contains(got, "redact-me") {
+		t.Errorf("expected annotation values to be redacted, got: %s", got)
+	}
+}
+
+func TestNodeInfoMarshalJSON(t *testing.T) {
+	ni := NewNodeInfo()
+	ni.SetNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	})
+	ni.AddPod(makeBasePod(t, "test-node", "test-pod", "500m", "512Mi", "", nil))
+
+	data, err := json.Marshal(ni)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	var summary map[string]interface{}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("unmarshaling the summary failed: %v", err)
+	}
+	if summary["node"] != "test-node" {
+		t.Errorf("expected node %q, got: %v", "test-node", summary["node"])
+	}
+	if summary["requestedCPU"] != "0.5cores" {
+		t.Errorf("expected requested CPU %q, got: %v", "0.5cores", summary["requestedCPU"])
+	}
+	if summary["allocatableMemory"] != "1024.0Mi" {
+		t.Errorf("expected allocatable memory %q, got: %v", "1024.0Mi", summary["allocatableMemory"])
+	}
+}
+
+func TestFitResource(t *testing.T) {
+	ni := NewNodeInfo()
+	ni.SetNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+		Status: v1.NodeStatus{
+			Capacity: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("3500m"),
+				v1.ResourceMemory: resource.MustParse("7Gi"),
+			},
+		},
+	})
+
+	tests := []struct {
+		name        string
+		policy      FitResourcePolicy
+		expectedCPU int64
+		expectedMem int64
+	}{
+		{
+			name:        "default uses allocatable",
+			policy:      FitResourcePolicy{},
+			expectedCPU: 3500,
+			expectedMem: 7 * 1024 * 1024 * 1024,
+		},
+		{
+			name:        "capacity policy uses capacity",
+			policy:      FitResourcePolicy{UseCapacity: true},
+			expectedCPU: 4000,
+			expectedMem: 8 * 1024 * 1024 * 1024,
+		},
+		{
+			name: "per-resource override",
+			policy: FitResourcePolicy{
+				Overrides: map[v1.ResourceName]bool{v1.ResourceCPU: true},
+			},
+			expectedCPU: 4000,
+			expectedMem: 7 * 1024 * 1024 * 1024,
+		},
+		{
+			name: "override back to allocatable",
+			policy: FitResourcePolicy{
+				UseCapacity: true,
+				Overrides:   map[v1.ResourceName]bool{v1.ResourceMemory: false},
+			},
+			expectedCPU: 4000,
+			expectedMem: 7 * 1024 * 1024 * 1024,
+		},
+	}
+	for _, test := range tests {
+		fit := ni.FitResource(test.policy)
+		if fit.MilliCPU != test.expectedCPU {
+			t.Errorf("%s: expected CPU %d, got %d", test.name, test.expectedCPU, fit.MilliCPU)
+		}
+		if fit.Memory != test.expectedMem {
+			t.Errorf("%s: expected memory %d, got %d", test.name, test.expectedMem, fit.Memory)
+		}
+	}
+}
+
+func TestRequestedByOrigin(t *testing.T) {
+	ni := fakeNodeInfo()
+
+	daemonPod := makeBasePod(t, "test-node", "daemon-pod", "100m", "500", "", nil)
+	daemonPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds-1"}}
+	staticPod := makeBasePod(t, "test-node", "static-pod", "200m", "1Ki", "", nil)
+	staticPod.Annotations = map[string]string{v1.MirrorPodAnnotationKey: "mirror"}
+	normalPod := makeBasePod(t, "test-node", "normal-pod", "300m", "2Ki", "", nil)
+
+	for _, pod := range []*v1.Pod{daemonPod, staticPod, normalPod} {
+		ni.AddPod(pod)
+	}
+
+	if requested := ni.RequestedByOrigin(PodOriginDaemonSet); requested.MilliCPU != 100 || requested.Memory != 500 {
+		t.Errorf("unexpected daemonset requests: %+v", requested)
+	}
+	if requested := ni.RequestedByOrigin(PodOriginStatic); requested.MilliCPU != 200 || requested.Memory != 1024 {
+		t.Errorf("unexpected static requests: %+v", requested)
+	}
+	if requested := ni.RequestedByOrigin(PodOriginNormal); requested.MilliCPU != 300 || requested.Memory != 2048 {
+		t.Errorf("unexpected normal requests: %+v", requested)
+	}
+
+	if err := ni.RemovePod(daemonPod); err != nil {
+		t.Fatalf("RemovePod failed: %v", err)
+	}
+	if requested := ni.RequestedByOrigin(PodOriginDaemonSet); requested.MilliCPU != 0 {
+		t.Errorf("expected empty daemonset bucket after removal, got: %+v", requested)
+	}
+	if requested := ni.RequestedByOrigin(PodOriginNormal); requested.MilliCPU != 300 {
+		t.Errorf("expected normal requests unchanged, got: %+v", requested)
+	}
+}
+
+func TestCloneWithPodsRemovedAndAdded(t *testing.T) {
+	pods := []*v1.Pod{
+		makeBasePod(t, "test-node", "test-1", "100m", "500", "example.com/foo:3", []v1.ContainerPort{{HostIP: "127.0.0.1", HostPort: 80, Protocol: "TCP"}}),
+		makeBasePod(t, "test-node", "test-2", "200m", "1Ki", "", []v1.ContainerPort{{HostIP: "127.0.0.1", HostPort: 8080, Protocol: "TCP"}}),
+	}
+	ni := fakeNodeInfo(pods...)
+
+	removed := ni.CloneWithPodsRemoved(pods[:1])
+	if len(removed.pods) != 1 || removed.pods[0] != pods[1] {
+		t.Errorf("expected only the second pod to remain, got: %v", removed.pods)
+	}
+	if removed.requestedResource.MilliCPU != 200 {
+		t.Errorf("expected requested CPU 200m after removal, got %d", removed.requestedResource.MilliCPU)
+	}
+	if removed.requestedResource.ScalarResources["example.com/foo"] != 0 {
+		t.Errorf("expected scalar resource released, got: %v", removed.requestedResource.ScalarResources)
+	}
+	if len(removed.usedPorts["127.0.0.1"]) != 1 {
+		t.Errorf("expected port 80 released, got: %v", removed.usedPorts)
+	}
+	// Removing a pod that is not on the node is ignored.
+	stranger := makeBasePod(t, "test-node", "stranger", "100m", "500", "", nil)
+	if clone := ni.CloneWithPodsRemoved([]*v1.Pod{stranger}); len(clone.pods) != 2 {
+		t.Errorf("expected the clone unchanged, got: %v", clone.pods)
+	}
+
+	added := ni.CloneWithPodAdded(makeBasePod(t, "test-node", "test-3", "300m", "2Ki", "", []v1.ContainerPort{{HostIP: "127.0.0.1", HostPort: 443, Protocol: "TCP"}}))
+	if len(added.pods) != 3 {
+		t.Errorf("expected 3 pods on the clone, got: %v", added.pods)
+	}
+	if added.requestedResource.MilliCPU != 600 {
+		t.Errorf("expected requested CPU 600m after addition, got %d", added.requestedResource.MilliCPU)
+	}
+	if len(added.usedPorts["127.0.0.1"]) != 3 {
+		t.Errorf("expected port 443 consumed, got: %v", added.usedPorts)
+	}
+
+	// The receiver is never modified.
+	if len(ni.pods) != 2 || ni.requestedResource.MilliCPU != 300 {
+		t.Errorf("expected the original NodeInfo untouched, got %d pods, CPU %d", len(ni.pods), ni.requestedResource.MilliCPU)
+	}
+	if len(ni.usedPorts["127.0.0.1"]) != 2 {
+		t.Errorf("expected the original used ports untouched, got: %v", ni.usedPorts)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// NodeReservedAnnotationKey advertises the node's kube-reserved plus
+// system-reserved overhead in the kubelet's flag syntax, e.g.
+// "cpu=500m,memory=1Gi,ephemeral-storage=2Gi". The kubelet already subtracts
+// these from Allocatable, but fit checks resolved to Capacity by the
+// FitResourcePolicy don't see that subtraction, so an in-place grow could
+// push total requests past what the kubelet will actually admit. Modeling
+// the overhead explicitly lets the resize fit check subtract it regardless
+// of which total it schedules against.
+const NodeReservedAnnotationKey = "scheduler.alpha.kubernetes.io/reserved-resources"
+
+// parseReservedResources parses the reserved-resources annotation value.
+// Unknown resource names are ignored, since the resize fit check only covers
+// CPU, memory and ephemeral storage.
+func parseReservedResources(value string) (*Resource, error) {
+	reserved := &Resource{}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed reservation %q", pair)
+		}
+		quantity, err := resource.ParseQuantity(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed quantity %q for %q: %v", parts[1], parts[0], err)
+		}
+		switch v1.ResourceName(parts[0]) {
+		case v1.ResourceCPU:
+			reserved.MilliCPU = quantity.MilliValue()
+		case v1.ResourceMemory:
+			reserved.Memory = quantity.Value()
+		case v1.ResourceEphemeralStorage:
+			reserved.EphemeralStorage = quantity.Value()
+		}
+	}
+	return reserved, nil
+}
+
+// reservedResourcesOf returns the node's advertised reserved overhead, or
+// nil if the annotation is absent or malformed.
+func reservedResourcesOf(node *v1.Node) *Resource {
+	value, ok := node.Annotations[NodeReservedAnnotationKey]
+	if !ok {
+		return nil
+	}
+	reserved, err := parseReservedResources(value)
+	if err != nil {
+		glog.Errorf("Node %v has an invalid reserved-resources annotation: %v", node.Name, err)
+		return nil
+	}
+	return reserved
+}
+
+// ReservedResource returns the node's advertised kube-reserved plus
+// system-reserved overhead.
+func (n *NodeInfo) ReservedResource() Resource {
+	if n == nil || n.reservedResource == nil {
+		return emptyResource
+	}
+	return *n.reservedResource
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// usageSampleWeight is the weight of a new usage sample in the exponentially
+// decayed average; older samples fade accordingly. A moderate weight smooths
+// over short load spikes without lagging far behind sustained shifts.
+const usageSampleWeight = 0.5
+
+// UpdateNodeUsage feeds a measured CPU/memory usage sample for the node, e.g.
+// from metrics-server or Prometheus. Samples are folded into an exponentially
+// decayed average stored on the NodeInfo and exposed via UsageRatio, for
+// load-aware priorities and for deciding between in-place resize and
+// reschedule. Only the MilliCPU and Memory fields of usage are consumed.
+func (cache *schedulerCache) UpdateNodeUsage(nodeName string, usage Resource) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return newError(ReasonNodeNotFound, "node %v is not in the cache, so its usage can't be recorded", nodeName)
+	}
+	if n.hasUsageSample {
+		n.decayedCPUUsage = usageSampleWeight*float64(usage.MilliCPU) + (1-usageSampleWeight)*n.decayedCPUUsage
+		n.decayedMemoryUsage = usageSampleWeight*float64(usage.Memory) + (1-usageSampleWeight)*n.decayedMemoryUsage
+	} else {
+		n.decayedCPUUsage = float64(usage.MilliCPU)
+		n.decayedMemoryUsage = float64(usage.Memory)
+		n.hasUsageSample = true
+	}
+	n.generation = nextGeneration()
+	return nil
+}
+
+// UsageRatio returns the node's decayed measured usage as a fraction of its
+// allocatable, per resource, or nil if no usage sample has arrived yet.
+// Unlike UtilizationRatios, which is based on requests, this reflects what
+// the pods actually consume.
+func (n *NodeInfo) UsageRatio() map[v1.ResourceName]float64 {
+	if !n.hasUsageSample {
+		return nil
+	}
+	ratios := make(map[v1.ResourceName]float64, 2)
+	if n.allocatableResource.MilliCPU > 0 {
+		ratios[v1.ResourceCPU] = n.decayedCPUUsage / float64(n.allocatableResource.MilliCPU)
+	}
+	if n.allocatableResource.Memory > 0 {
+		ratios[v1.ResourceMemory] = n.decayedMemoryUsage / float64(n.allocatableResource.Memory)
+	}
+	return ratios
+}
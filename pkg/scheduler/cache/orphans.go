@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/metrics"
+)
+
+// A pod is orphaned when its node's NodeInfo is gone or the node object was
+// deleted. Orphans are normal transiently — an assumed pod can land before its
+// node's add event — but a pod that stays orphaned holds cache memory for a
+// node that will never come back. The periodic scan tracks when each orphan
+// was first seen and, if a purge grace period is set, removes pods that stay
+// orphaned longer than it.
+
+// SetOrphanPurgeGracePeriod enables auto-purging of orphaned pods: a pod that
+// stays orphaned for the given duration is removed from the cache. A zero
+// duration (the default) disables purging; orphans are only tracked and
+// reported.
+func (cache *schedulerCache) SetOrphanPurgeGracePeriod(d time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.orphanGracePeriod = d
+}
+
+// OrphanedPods returns the pods currently considered orphaned, i.e. seen as
+// such by at least one scan and not reclaimed since.
+func (cache *schedulerCache) OrphanedPods() []*v1.Pod {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	var pods []*v1.Pod
+	for key := range cache.orphanedPods {
+		if ps, ok := cache.podStates[key]; ok {
+			pods = append(pods, ps.pod)
+		}
+	}
+	return pods
+}
+
+func (cache *schedulerCache) cleanupOrphanedPods() {
+	cache.scanOrphanedPods(time.Now())
+}
+
+// scanOrphanedPods refreshes the orphan set and purges pods whose grace
+// period has run out.
+func (cache *schedulerCache) scanOrphanedPods(now time.Time) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for key, ps := range cache.podStates {
+		// Held pods never committed resources to a node, so they can't leak
+		// node accounting.
+		if ps.held || ps.pod.Spec.NodeName == "" {
+			delete(cache.orphanedPods, key)
+			continue
+		}
+		if n, ok := cache.store.GetNodeInfo(ps.pod.Spec.NodeName); ok && n.node != nil {
+			delete(cache.orphanedPods, key)
+			continue
+		}
+		firstSeen, seen := cache.orphanedPods[key]
+		if !seen {
+			cache.orphanedPods[key] = now
+			continue
+		}
+		if cache.orphanGracePeriod > 0 && now.Sub(firstSeen) >= cache.orphanGracePeriod {
+			glog.Warningf("Purging pod %v: orphaned on deleted node %v since %v", key, ps.pod.Spec.NodeName, firstSeen)
+			cache.purgeOrphanedPod(key, ps)
+		}
+	}
+	// Drop tracking for pods that left the cache through the normal paths.
+	for key := range cache.orphanedPods {
+		if _, ok := cache.podStates[key]; !ok {
+			delete(cache.orphanedPods, key)
+		}
+	}
+	metrics.CacheOrphanedPods.Set(float64(len(cache.orphanedPods)))
+}
+
+// purgeOrphanedPod removes an orphaned pod from the cache entirely.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) purgeOrphanedPod(key string, ps *podState) {
+	if _, ok := cache.store.GetNodeInfo(ps.pod.Spec.NodeName); ok {
+		if err := cache.removePod(ps.pod); err != nil {
+			glog.Errorf("Failed to subtract purged pod %v from its node: %v", key, err)
+		}
+	}
+	delete(cache.assumedPods, key)
+	delete(cache.podStates, key)
+	cache.store.DeletePod(key)
+	delete(cache.orphanedPods, key)
+	metrics.CacheOrphanedPodsPurged.Inc()
+}
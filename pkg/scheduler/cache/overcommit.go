@@ -0,0 +1,93 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// OverCommitRule multiplies the effective allocatable of nodes matching the
+// selector, e.g. CPU 1.5x for a burstable node pool. The raw allocatable
+// values are unchanged and stay available for reporting. The first matching
+// rule wins.
+type OverCommitRule struct {
+	// Selector matches node labels, typically a node pool label.
+	Selector labels.Selector
+	// Ratios maps a resource to its over-commit multiplier. Resources not
+	// listed keep their allocatable value. Ratios below 1 under-commit.
+	Ratios map[v1.ResourceName]float64
+}
+
+// SetOverCommitRules replaces the over-commit rules and reapplies them to all
+// cached nodes. Fit checks against effective allocatable pick up the new
+// ratios immediately; pods already assumed are not re-evaluated.
+func (cache *schedulerCache) SetOverCommitRules(rules []OverCommitRule) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.overCommitRules = rules
+	cache.store.RangeNodeInfos(func(_ string, n *NodeInfo) bool {
+		cache.applyOverCommit(n)
+		return true
+	})
+}
+
+// applyOverCommit sets the node's over-commit ratios from the first rule
+// matching its labels. It assumes the cache's lock is acquired.
+func (cache *schedulerCache) applyOverCommit(n *NodeInfo) {
+	if n.node == nil {
+		return
+	}
+	n.overCommitRatios = nil
+	for _, rule := range cache.overCommitRules {
+		if rule.Selector.Matches(labels.Set(n.node.Labels)) {
+			n.overCommitRatios = rule.Ratios
+			break
+		}
+	}
+	// Bump the generation so incremental snapshots re-clone the node and
+	// memoized fit results are invalidated under the new ratios.
+	n.generation = nextGeneration()
+}
+
+// overCommitted scales the given amount by the node's ratio for the resource,
+// if any.
+func (n *NodeInfo) overCommitted(rName v1.ResourceName, amount int64) int64 {
+	if ratio, ok := n.overCommitRatios[rName]; ok {
+		return int64(float64(amount) * ratio)
+	}
+	return amount
+}
+
+// EffectiveAllocatable returns the node's allocatable with over-commit ratios
+// applied. Without matching rules it equals AllocatableResource.
+func (n *NodeInfo) EffectiveAllocatable() Resource {
+	if n == nil {
+		return emptyResource
+	}
+	result := Resource{
+		MilliCPU:         n.overCommitted(v1.ResourceCPU, n.allocatableResource.MilliCPU),
+		Memory:           n.overCommitted(v1.ResourceMemory, n.allocatableResource.Memory),
+		EphemeralStorage: n.overCommitted(v1.ResourceEphemeralStorage, n.allocatableResource.EphemeralStorage),
+		AllowedPodNumber: int(n.overCommitted(v1.ResourcePods, int64(n.allocatableResource.AllowedPodNumber))),
+	}
+	for rName, rQuant := range n.allocatableResource.ScalarResources {
+		result.SetScalar(rName, n.overCommitted(rName, rQuant))
+	}
+	return result
+}
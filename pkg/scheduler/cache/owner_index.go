@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// The owner index groups cached pods by their controller UID so per-owner
+// queries (selector spread, resize batching over a ReplicaSet) don't have to
+// filter a full List. It is maintained incrementally as pods are added and
+// removed; pods without a controller aren't indexed.
+
+// indexPodOwner adds the pod to its controller's entry in the owner index.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) indexPodOwner(pod *v1.Pod) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return
+	}
+	key, err := getPodKey(pod)
+	if err != nil {
+		return
+	}
+	pods, ok := cache.podsByOwner[owner.UID]
+	if !ok {
+		pods = make(map[string]*v1.Pod)
+		cache.podsByOwner[owner.UID] = pods
+	}
+	pods[key] = pod
+}
+
+// unindexPodOwner removes the pod from its controller's entry in the owner
+// index, dropping the entry once it is empty.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) unindexPodOwner(pod *v1.Pod) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return
+	}
+	key, err := getPodKey(pod)
+	if err != nil {
+		return
+	}
+	pods, ok := cache.podsByOwner[owner.UID]
+	if !ok {
+		return
+	}
+	delete(pods, key)
+	if len(pods) == 0 {
+		delete(cache.podsByOwner, owner.UID)
+	}
+}
+
+// PodsByOwner returns the cached pods controlled by the given owner UID.
+func (cache *schedulerCache) PodsByOwner(uid types.UID) []*v1.Pod {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	indexed := cache.podsByOwner[uid]
+	if len(indexed) == 0 {
+		return nil
+	}
+	pods := make([]*v1.Pod, 0, len(indexed))
+	for _, pod := range indexed {
+		pods = append(pods, pod)
+	}
+	return pods
+}
+
+// OwnerSpreadCounts returns how many pods of the given owner sit in each
+// domain of the topology key, resolved through the nodes' labels. Pods on
+// nodes the cache doesn't know, or whose node lacks the label, aren't
+// counted.
+func (cache *schedulerCache) OwnerSpreadCounts(uid types.UID, topologyKey string) map[string]int32 {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	counts := make(map[string]int32)
+	for _, pod := range cache.podsByOwner[uid] {
+		n, ok := cache.store.GetNodeInfo(pod.Spec.NodeName)
+		if !ok || n.node == nil {
+			continue
+		}
+		if value, ok := n.node.Labels[topologyKey]; ok {
+			counts[value]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// pdbInfo is a version-independent representation of a PodDisruptionBudget.
+// The cache operates on pdbInfo only, so that PDBs can be ingested from
+// different policy API versions as clusters migrate: policy/v1beta1 objects
+// through AddPDB, and any other version through AddPDBData once an adapter
+// has pulled out the version-neutral fields.
+type pdbInfo struct {
+	namespace string
+	name      string
+	uid       string
+	// selector is parsed from the PDB's spec selector. It never matches
+	// anything if the spec selector is absent or invalid.
+	selector              labels.Selector
+	minAvailable          *intstr.IntOrString
+	maxUnavailable        *intstr.IntOrString
+	generation            int64
+	observedGeneration    int64
+	podDisruptionsAllowed int32
+	// disruptionsInFlight counts pod disruptions that have been decided
+	// locally (e.g. by preemption) but are not yet reflected in the PDB
+	// status reported by the API server. It is reset when a PDB update
+	// carrying a newer status arrives.
+	disruptionsInFlight int32
+	// v1beta1 holds the object the PDB was ingested from, for compatibility
+	// with consumers of ListPDBs and Snapshot.
+	v1beta1 *policy.PodDisruptionBudget
+}
+
+// PDBData holds the version-neutral fields of a PodDisruptionBudget, the
+// subset the cache operates on. Adapters for policy API versions not vendored
+// into this tree (such as policy/v1) populate it from their own types and
+// hand it to AddPDBData; no field references a versioned policy type.
+type PDBData struct {
+	Namespace string
+	Name      string
+	UID       string
+	// Selector is the PDB's spec selector. A nil or invalid selector
+	// matches no pods.
+	Selector              *metav1.LabelSelector
+	MinAvailable          *intstr.IntOrString
+	MaxUnavailable        *intstr.IntOrString
+	Generation            int64
+	ObservedGeneration    int64
+	PodDisruptionsAllowed int32
+}
+
+// newPDBInfo converts version-neutral PDB fields into the cache's internal
+// representation.
+func newPDBInfo(data PDBData) *pdbInfo {
+	selector := labels.Nothing()
+	if data.Selector != nil {
+		if s, err := metav1.LabelSelectorAsSelector(data.Selector); err == nil {
+			selector = s
+		} else {
+			glog.Errorf("PDB %s/%s has invalid selector: %v", data.Namespace, data.Name, err)
+		}
+	}
+	return &pdbInfo{
+		namespace:             data.Namespace,
+		name:                  data.Name,
+		uid:                   data.UID,
+		selector:              selector,
+		minAvailable:          data.MinAvailable,
+		maxUnavailable:        data.MaxUnavailable,
+		generation:            data.Generation,
+		observedGeneration:    data.ObservedGeneration,
+		podDisruptionsAllowed: data.PodDisruptionsAllowed,
+	}
+}
+
+// newPDBInfoFromV1beta1 converts a policy/v1beta1 PodDisruptionBudget into the
+// cache's internal representation.
+func newPDBInfoFromV1beta1(pdb *policy.PodDisruptionBudget) *pdbInfo {
+	info := newPDBInfo(PDBData{
+		Namespace:             pdb.Namespace,
+		Name:                  pdb.Name,
+		UID:                   string(pdb.UID),
+		Selector:              pdb.Spec.Selector,
+		MinAvailable:          pdb.Spec.MinAvailable,
+		MaxUnavailable:        pdb.Spec.MaxUnavailable,
+		Generation:            pdb.Generation,
+		ObservedGeneration:    pdb.Status.ObservedGeneration,
+		PodDisruptionsAllowed: pdb.Status.PodDisruptionsAllowed,
+	})
+	info.v1beta1 = pdb
+	return info
+}
+
+// statusCaughtUp returns true if other carries a status update relative to
+// this PDB, meaning locally tracked in-flight disruptions are now accounted
+// for in the PDB status.
+func (p *pdbInfo) statusCaughtUp(other *pdbInfo) bool {
+	return p.observedGeneration != other.observedGeneration ||
+		p.podDisruptionsAllowed != other.podDisruptionsAllowed
+}
+
+// statusFresh returns true if the PDB status has been computed for the
+// current spec generation.
+func (p *pdbInfo) statusFresh() bool {
+	return p.observedGeneration > 0 && p.observedGeneration == p.generation
+}
+
+// addPDBInfo adds or updates the internal representation of a PDB.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) addPDBInfo(info *pdbInfo) {
+	nsPDBs, ok := cache.pdbs[info.namespace]
+	if !ok {
+		nsPDBs = make(map[string]*pdbInfo)
+		cache.pdbs[info.namespace] = nsPDBs
+	}
+	if old, ok := nsPDBs[info.uid]; ok && !old.statusCaughtUp(info) {
+		// The API server hasn't observed the disruptions we decided locally
+		// yet, so keep counting them against the budget.
+		info.disruptionsInFlight = old.disruptionsInFlight
+	}
+	nsPDBs[info.uid] = info
+}
+
+func (cache *schedulerCache) AddPDB(pdb *policy.PodDisruptionBudget) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Unconditionally update cache.
+	cache.addPDBInfo(newPDBInfoFromV1beta1(pdb))
+	return nil
+}
+
+// AddPDBData adds or updates a PDB from its version-neutral fields. PDBs
+// ingested this way carry no v1beta1 object, so ListPDBs and
+// GetPDBsMatchingPod skip them; they still count against the budget in
+// MarkPodDisruption and PodDisruptionsAllowed.
+func (cache *schedulerCache) AddPDBData(data PDBData) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.addPDBInfo(newPDBInfo(data))
+	return nil
+}
+
+func (cache *schedulerCache) UpdatePDB(oldPDB, newPDB *policy.PodDisruptionBudget) error {
+	return cache.AddPDB(newPDB)
+}
+
+func (cache *schedulerCache) RemovePDB(pdb *policy.PodDisruptionBudget) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	nsPDBs, ok := cache.pdbs[pdb.Namespace]
+	if !ok {
+		return nil
+	}
+	delete(nsPDBs, string(pdb.UID))
+	if len(nsPDBs) == 0 {
+		delete(cache.pdbs, pdb.Namespace)
+	}
+	return nil
+}
+
+func (cache *schedulerCache) ListPDBs(selector labels.Selector) ([]*policy.PodDisruptionBudget, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	var pdbs []*policy.PodDisruptionBudget
+	for _, nsPDBs := range cache.pdbs {
+		for _, info := range nsPDBs {
+			if info.v1beta1 != nil && selector.Matches(labels.Set(info.v1beta1.Labels)) {
+				pdbs = append(pdbs, info.v1beta1)
+			}
+		}
+	}
+	return pdbs, nil
+}
+
+func (cache *schedulerCache) GetPDBsMatchingPod(pod *v1.Pod) []*policy.PodDisruptionBudget {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	var pdbs []*policy.PodDisruptionBudget
+	for _, info := range cache.pdbs[pod.Namespace] {
+		if info.v1beta1 != nil && info.selector.Matches(labels.Set(pod.Labels)) {
+			pdbs = append(pdbs, info.v1beta1)
+		}
+	}
+	return pdbs
+}
+
+func (cache *schedulerCache) MarkPodDisruption(pod *v1.Pod) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for _, info := range cache.pdbs[pod.Namespace] {
+		if info.selector.Matches(labels.Set(pod.Labels)) {
+			info.disruptionsInFlight++
+		}
+	}
+	return nil
+}
+
+func (cache *schedulerCache) PodDisruptionsAllowed(pdb *policy.PodDisruptionBudget) int32 {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	info, ok := cache.pdbs[pdb.Namespace][string(pdb.UID)]
+	if !ok {
+		return pdb.Status.PodDisruptionsAllowed
+	}
+	allowed := info.podDisruptionsAllowed
+	if !info.statusFresh() {
+		// The disruption controller hasn't observed the current spec yet;
+		// fall back to computing the budget from the spec against the pods
+		// currently known to the cache.
+		allowed = cache.disruptionsAllowedFromSpec(info)
+	}
+	allowed -= info.disruptionsInFlight
+	if allowed < 0 {
+		allowed = 0
+	}
+	return allowed
+}
+
+// disruptionsAllowedFromSpec computes the number of allowed disruptions from
+// the PDB spec, resolving percentage values against the number of matching
+// pods currently in the cache. It assumes all matching pods are available,
+// which makes it an upper bound modulo in-flight disruptions.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) disruptionsAllowedFromSpec(info *pdbInfo) int32 {
+	matching := 0
+	cache.store.RangeNodeInfos(func(_ string, n *NodeInfo) bool {
+		for _, pod := range n.pods {
+			if pod.Namespace == info.namespace && info.selector.Matches(labels.Set(pod.Labels)) {
+				matching++
+			}
+		}
+		return true
+	})
+
+	var allowed int
+	switch {
+	case info.maxUnavailable != nil:
+		// Round down so a percentage never allows more disruptions than the
+		// controller would.
+		maxUnavailable, err := intstr.GetValueFromIntOrPercent(info.maxUnavailable, matching, false)
+		if err != nil {
+			glog.Errorf("PDB %s/%s has invalid maxUnavailable: %v", info.namespace, info.name, err)
+			return 0
+		}
+		allowed = maxUnavailable
+	case info.minAvailable != nil:
+		// Round up for the same reason: keep the fallback conservative.
+		minAvailable, err := intstr.GetValueFromIntOrPercent(info.minAvailable, matching, true)
+		if err != nil {
+			glog.Errorf("PDB %s/%s has invalid minAvailable: %v", info.namespace, info.name, err)
+			return 0
+		}
+		allowed = matching - minAvailable
+	}
+	if allowed < 0 {
+		allowed = 0
+	}
+	return int32(allowed)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// maxPDBDecisions bounds the disruption-budget decision journal. The journal
+// is a receipt for disputes ("the scheduler wouldn't evict my pod because of
+// a PDB"), not an audit log, so a few hundred recent entries suffice.
+const maxPDBDecisions = 256
+
+// PDBDecision records one disruption-budget check outcome for a candidate
+// victim: which PDBs matched the pod, the smallest remaining budget seen
+// among them, and whether the disruption was permitted.
+type PDBDecision struct {
+	Time      time.Time
+	Namespace string
+	Name      string
+	// PDBs are the names of the PDBs matching the pod.
+	PDBs []string
+	// Allowed is the smallest remaining disruption budget among the
+	// matching PDBs at decision time.
+	Allowed int32
+	// Permitted reports whether the pod was eligible as a victim.
+	Permitted bool
+}
+
+// pdbJournal is a bounded, self-locked journal of disruption-budget
+// decisions. It carries its own mutex because decisions are recorded from
+// read-locked paths like SelectVictims, which can't promote the cache lock.
+type pdbJournal struct {
+	mu        sync.Mutex
+	decisions []PDBDecision
+}
+
+// record appends the decision, evicting the oldest entries past the bound.
+func (journal *pdbJournal) record(decision PDBDecision) {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	journal.decisions = append(journal.decisions, decision)
+	if len(journal.decisions) > maxPDBDecisions {
+		journal.decisions = journal.decisions[len(journal.decisions)-maxPDBDecisions:]
+	}
+}
+
+// recordPDBDecision journals one disruption-budget check outcome.
+func (cache *schedulerCache) recordPDBDecision(decision PDBDecision) {
+	decision.Time = time.Now()
+	cache.pdbJournal.record(decision)
+}
+
+// RecentPDBDecisions returns the journaled disruption-budget decisions,
+// oldest first.
+func (cache *schedulerCache) RecentPDBDecisions() []PDBDecision {
+	cache.pdbJournal.mu.Lock()
+	defer cache.pdbJournal.mu.Unlock()
+	decisions := make([]PDBDecision, len(cache.pdbJournal.decisions))
+	copy(decisions, cache.pdbJournal.decisions)
+	return decisions
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func makeLabeledPod(namespace, name string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:       types.UID(name),
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+	}
+}
+
+// TestGetPDBsMatchingPod tests that only PDBs in the pod's namespace whose
+// selector matches the pod's labels are returned.
+func TestGetPDBsMatchingPod(t *testing.T) {
+	testPDBs := []*v1beta1.PodDisruptionBudget{
+		makePDB("pdb0", "ns1", "uid0", map[string]string{"tkey1": "tval1"}, 3),
+		makePDB("pdb1", "ns1", "uid1", map[string]string{"tkey1": "tval1", "tkey2": "tval2"}, 1),
+		makePDB("pdb2", "ns2", "uid2", map[string]string{"tkey1": "tval1"}, 10),
+	}
+
+	tests := []struct {
+		pod          *v1.Pod
+		expectedUIDs []types.UID
+	}{
+		{
+			pod:          makeLabeledPod("ns1", "pod0", map[string]string{"tkey1": "tval1"}),
+			expectedUIDs: []types.UID{"uid0"},
+		},
+		{
+			pod:          makeLabeledPod("ns1", "pod1", map[string]string{"tkey1": "tval1", "tkey2": "tval2"}),
+			expectedUIDs: []types.UID{"uid0", "uid1"},
+		},
+		{
+			pod:          makeLabeledPod("ns2", "pod2", map[string]string{"tkey1": "tval1"}),
+			expectedUIDs: []types.UID{"uid2"},
+		},
+		{
+			pod:          makeLabeledPod("ns3", "pod3", map[string]string{"tkey1": "tval1"}),
+			expectedUIDs: nil,
+		},
+		{
+			pod:          makeLabeledPod("ns1", "pod4", nil),
+			expectedUIDs: nil,
+		},
+	}
+
+	cache := newSchedulerCache(10*time.Second, time.Second, nil)
+	for _, pdb := range testPDBs {
+		if err := cache.AddPDB(pdb); err != nil {
+			t.Fatalf("AddPDB failed: %v", err)
+		}
+	}
+
+	for i, test := range tests {
+		pdbs := cache.GetPDBsMatchingPod(test.pod)
+		verifyMatchingPDBs(t, i, pdbs, test.expectedUIDs)
+	}
+}
+
+func verifyMatchingPDBs(t *testing.T, i int, pdbs []*v1beta1.PodDisruptionBudget, expectedUIDs []types.UID) {
+	if len(pdbs) != len(expectedUIDs) {
+		t.Errorf("#%d: expected %d PDBs, got %d", i, len(expectedUIDs), len(pdbs))
+		return
+	}
+	for _, uid := range expectedUIDs {
+		found := false
+		for _, pdb := range pdbs {
+			if pdb.UID == uid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("#%d: PDB with uid '%v' was not returned", i, uid)
+		}
+	}
+}
+
+// TestMarkPodDisruption tests that locally marked disruptions are counted
+// against the budget until a PDB status update catches up.
+func TestMarkPodDisruption(t *testing.T) {
+	pdb := makePDB("pdb0", "ns1", "uid0", map[string]string{"tkey1": "tval1"}, 3)
+	pdb.Generation = 1
+	pdb.Status.ObservedGeneration = 1
+	pdb.Status.PodDisruptionsAllowed = 2
+	pod := makeLabeledPod("ns1", "pod0", map[string]string{"tkey1": "tval1"})
+
+	cache := newSchedulerCache(10*time.Second, time.Second, nil)
+	if err := cache.AddPDB(pdb); err != nil {
+		t.Fatalf("AddPDB failed: %v", err)
+	}
+
+	if allowed := cache.PodDisruptionsAllowed(pdb); allowed != 2 {
+		t.Errorf("expected 2 disruptions allowed, got %d", allowed)
+	}
+	if err := cache.MarkPodDisruption(pod); err != nil {
+		t.Fatalf("MarkPodDisruption failed: %v", err)
+	}
+	if allowed := cache.PodDisruptionsAllowed(pdb); allowed != 1 {
+		t.Errorf("expected 1 disruption allowed, got %d", allowed)
+	}
+	if err := cache.MarkPodDisruption(pod); err != nil {
+		t.Fatalf("MarkPodDisruption failed: %v", err)
+	}
+	if err := cache.MarkPodDisruption(pod); err != nil {
+		t.Fatalf("MarkPodDisruption failed: %v", err)
+	}
+	// The count of allowed disruptions never goes negative.
+	if allowed := cache.PodDisruptionsAllowed(pdb); allowed != 0 {
+		t.Errorf("expected 0 disruptions allowed, got %d", allowed)
+	}
+
+	// An update that doesn't change the status keeps in-flight disruptions.
+	updatedPDB := pdb.DeepCopy()
+	if err := cache.UpdatePDB(pdb, updatedPDB); err != nil {
+		t.Fatalf("UpdatePDB failed: %v", err)
+	}
+	if allowed := cache.PodDisruptionsAllowed(pdb); allowed != 0 {
+		t.Errorf("expected 0 disruptions allowed, got %d", allowed)
+	}
+
+	// A status update resets the in-flight counter.
+	caughtUpPDB := pdb.DeepCopy()
+	caughtUpPDB.Status.PodDisruptionsAllowed = 1
+	if err := cache.UpdatePDB(updatedPDB, caughtUpPDB); err != nil {
+		t.Fatalf("UpdatePDB failed: %v", err)
+	}
+	if allowed := cache.PodDisruptionsAllowed(pdb); allowed != 1 {
+		t.Errorf("expected 1 disruption allowed, got %d", allowed)
+	}
+}
+
+// TestPodDisruptionsAllowedFallback tests computing allowed disruptions from
+// the PDB spec when the status hasn't been observed yet.
+func TestPodDisruptionsAllowedFallback(t *testing.T) {
+	podLabels := map[string]string{"tkey1": "tval1"}
+	halfAvailable := intstr.FromString("50%")
+	maxOneUnavailable := intstr.FromInt(1)
+
+	minAvailablePDB := makePDB("pdb0", "ns1", "uid0", podLabels, 2)
+	percentagePDB := makePDB("pdb1", "ns1", "uid1", podLabels, 0)
+	percentagePDB.Spec.MinAvailable = &halfAvailable
+	maxUnavailablePDB := makePDB("pdb2", "ns1", "uid2", podLabels, 0)
+	maxUnavailablePDB.Spec.MinAvailable = nil
+	maxUnavailablePDB.Spec.MaxUnavailable = &maxOneUnavailable
+
+	tests := []struct {
+		pdb             *v1beta1.PodDisruptionBudget
+		expectedAllowed int32
+	}{
+		{pdb: minAvailablePDB, expectedAllowed: 1},   // 3 matching - 2 minAvailable
+		{pdb: percentagePDB, expectedAllowed: 1},     // 3 matching - ceil(50% of 3)
+		{pdb: maxUnavailablePDB, expectedAllowed: 1}, // maxUnavailable of 1
+	}
+
+	cache := newSchedulerCache(10*time.Second, time.Second, nil)
+	for i := 0; i < 3; i++ {
+		pod := makeLabeledPod("ns1", fmt.Sprintf("pod%d", i), podLabels)
+		pod.Spec.NodeName = "node1"
+		if err := cache.AddPod(pod); err != nil {
+			t.Fatalf("AddPod failed: %v", err)
+		}
+	}
+
+	for i, test := range tests {
+		if err := cache.AddPDB(test.pdb); err != nil {
+			t.Fatalf("AddPDB failed: %v", err)
+		}
+		if allowed := cache.PodDisruptionsAllowed(test.pdb); allowed != test.expectedAllowed {
+			t.Errorf("#%d: expected %d disruptions allowed, got %d", i, test.expectedAllowed, allowed)
+		}
+	}
+}
+
+// TestAddPDBData tests that PDBs ingested from version-neutral fields count
+// against the budget like PDBs ingested from policy/v1beta1 objects.
+func TestAddPDBData(t *testing.T) {
+	podLabels := map[string]string{"tkey1": "tval1"}
+	pod := makeLabeledPod("ns1", "pod0", podLabels)
+
+	cache := newSchedulerCache(10*time.Second, time.Second, nil)
+	if err := cache.AddPDBData(PDBData{
+		Namespace:             "ns1",
+		Name:                  "pdb0",
+		UID:                   "uid0",
+		Selector:              &metav1.LabelSelector{MatchLabels: podLabels},
+		Generation:            1,
+		ObservedGeneration:    1,
+		PodDisruptionsAllowed: 2,
+	}); err != nil {
+		t.Fatalf("AddPDBData failed: %v", err)
+	}
+
+	// Budget queries go through the same internal representation, keyed by
+	// namespace and UID.
+	lookup := makePDB("pdb0", "ns1", "uid0", podLabels, 0)
+	if allowed := cache.PodDisruptionsAllowed(lookup); allowed != 2 {
+		t.Errorf("expected 2 disruptions allowed, got %d", allowed)
+	}
+	if err := cache.MarkPodDisruption(pod); err != nil {
+		t.Fatalf("MarkPodDisruption failed: %v", err)
+	}
+	if allowed := cache.PodDisruptionsAllowed(lookup); allowed != 1 {
+		t.Errorf("expected 1 disruption allowed, got %d", allowed)
+	}
+
+	// With no v1beta1 object to return, listing skips data-ingested PDBs.
+	if pdbs, err := cache.ListPDBs(labels.Everything()); err != nil || len(pdbs) != 0 {
+		t.Errorf("expected ListPDBs to skip data-ingested PDBs, got %v, %v", pdbs, err)
+	}
+}
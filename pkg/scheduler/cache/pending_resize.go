@@ -0,0 +1,98 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+)
+
+// PendingResize describes one pod with an outstanding resize on a node: a
+// recommendation that differs from the pod's current requests.
+type PendingResize struct {
+	// PodKey identifies the pod.
+	PodKey string
+	// Delta is the net difference between the recommended and the current
+	// requests; components can be negative for shrinking resizes.
+	Delta Resource
+	// Priority is the resize's priority (see resize_priority.go).
+	Priority int32
+}
+
+// PendingResizes returns the pods on the node with an outstanding resize and
+// their net resource delta, highest priority first so an executor draining
+// the queue in order serves critical workloads before best-effort ones. It
+// also lets operators and controllers see how much capacity is already
+// spoken for mid-resize.
+func (cache *schedulerCache) PendingResizes(nodeName string) []PendingResize {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	var pending []PendingResize
+	for key, rec := range cache.recommendations {
+		ps, ok := cache.podStates[key]
+		if !ok || ps.pod.Spec.NodeName != nodeName {
+			continue
+		}
+		current, _, _ := calculateResource(ps.pod)
+		delta := Resource{
+			MilliCPU:         rec.MilliCPU - current.MilliCPU,
+			Memory:           rec.Memory - current.Memory,
+			EphemeralStorage: rec.EphemeralStorage - current.EphemeralStorage,
+		}
+		if delta.MilliCPU == 0 && delta.Memory == 0 && delta.EphemeralStorage == 0 {
+			continue
+		}
+		pending = append(pending, PendingResize{PodKey: key, Delta: delta, Priority: resizePriorityOf(ps.pod)})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Priority != pending[j].Priority {
+			return pending[i].Priority > pending[j].Priority
+		}
+		return pending[i].PodKey < pending[j].PodKey
+	})
+	return pending
+}
+
+// pendingResizeDelta sums the growing components of the pending resizes on
+// the node, excluding excludeKey, so fit checks count capacity already
+// promised to in-flight resizes as occupied and concurrent decisions don't
+// double-book it. Shrinking components are ignored: that capacity isn't free
+// until the resize actually happens.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) pendingResizeDelta(nodeName, excludeKey string) Resource {
+	var sum Resource
+	for key, rec := range cache.recommendations {
+		if key == excludeKey {
+			continue
+		}
+		ps, ok := cache.podStates[key]
+		if !ok || ps.pod.Spec.NodeName != nodeName {
+			continue
+		}
+		current, _, _ := calculateResource(ps.pod)
+		if d := rec.MilliCPU - current.MilliCPU; d > 0 {
+			sum.MilliCPU += d
+		}
+		if d := rec.Memory - current.Memory; d > 0 {
+			sum.Memory += d
+		}
+		if d := rec.EphemeralStorage - current.EphemeralStorage; d > 0 {
+			sum.EphemeralStorage += d
+		}
+	}
+	return sum
+}
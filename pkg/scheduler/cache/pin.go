@@ -0,0 +1,63 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// PinPod reserves the pod's resources on the named node exactly like
+// AssumePod, on behalf of an external binding controller - e.g. a batch gang
+// scheduler - that bypasses the default binding path. Without the pin such
+// controllers and this scheduler double-book nodes: the external bind is
+// invisible here until the pod's add event arrives. The pin expires after
+// ttl like any assumed pod (the cache-wide TTL if non-positive) and is
+// confirmed by the pod's add event or rolled back with ForgetPod.
+func (cache *schedulerCache) PinPod(pod *v1.Pod, nodeName string, ttl time.Duration) error {
+	key, err := getPodKey(pod)
+	if err != nil {
+		return err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	pinned := pod
+	if pinned.Spec.NodeName != nodeName {
+		pinned = pod.DeepCopy()
+		pinned.Spec.NodeName = nodeName
+	}
+	if _, err := cache.assumePodLocked(pinned); err != nil {
+		return err
+	}
+	// Unlike a bind in flight, the external controller already bound the
+	// pod, so the binding counts as finished and the expiry clock starts
+	// immediately.
+	if ttl <= 0 {
+		ttl = cache.ttl
+	}
+	now := time.Now()
+	deadline := now.Add(ttl)
+	ps := cache.podStates[key]
+	ps.bindingFinished = true
+	ps.bindingFinishedAt = now
+	ps.deadline = &deadline
+	cache.pinnedPods[key] = true
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podTemplate is the canonical container spec shared by the pods of one
+// controller (see EnablePodDeduplication).
+type podTemplate struct {
+	containers     []v1.Container
+	initContainers []v1.Container
+}
+
+// EnablePodDeduplication makes the cache share one canonical container spec
+// slice across the pods of the same controller. In a cluster of many nearly
+// identical pods, most cache memory goes to duplicated container specs while
+// only metadata, IP and status differ per pod; deduplicated pods keep those
+// per-pod fields in their own shallow copy and point at the owner's template
+// for the rest. The shared slices are read-only by the same convention that
+// already applies to cached pods.
+func (cache *schedulerCache) EnablePodDeduplication() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.dedupPodSpecs = true
+}
+
+// dedupPodSpec returns a shallow copy of the pod pointing at its owner's
+// canonical container slices, or the pod unchanged if it has no controller or
+// its containers diverge from the template. The first pod seen for an owner
+// donates the template.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) dedupPodSpec(pod *v1.Pod) *v1.Pod {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return pod
+	}
+	tmpl, ok := cache.dedupTemplates[owner.UID]
+	if !ok {
+		cache.dedupTemplates[owner.UID] = &podTemplate{
+			containers:     pod.Spec.Containers,
+			initContainers: pod.Spec.InitContainers,
+		}
+		return pod
+	}
+	if !reflect.DeepEqual(pod.Spec.Containers, tmpl.containers) ||
+		!reflect.DeepEqual(pod.Spec.InitContainers, tmpl.initContainers) {
+		return pod
+	}
+	deduped := *pod
+	deduped.Spec.Containers = tmpl.containers
+	deduped.Spec.InitContainers = tmpl.initContainers
+	return &deduped
+}
+
+// cleanupDedupTemplates drops the templates of owners that no longer have any
+// pod in the cache, using the owner index. Called from the cleanup loop.
+func (cache *schedulerCache) cleanupDedupTemplates() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if len(cache.dedupTemplates) == 0 {
+		return
+	}
+	var orphaned []types.UID
+	for uid := range cache.dedupTemplates {
+		if len(cache.podsByOwner[uid]) == 0 {
+			orphaned = append(orphaned, uid)
+		}
+	}
+	for _, uid := range orphaned {
+		delete(cache.dedupTemplates, uid)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// PodOrigin classifies how a pod came to run on a node. Autoscaler
+// integrations and the resize planner use it to tell movable load apart from
+// fixed per-node overhead.
+type PodOrigin string
+
+const (
+	// PodOriginDaemonSet marks pods owned by a DaemonSet.
+	PodOriginDaemonSet PodOrigin = "daemonset"
+	// PodOriginStatic marks mirror pods of static pods.
+	PodOriginStatic PodOrigin = "static"
+	// PodOriginNormal marks all other pods.
+	PodOriginNormal PodOrigin = "normal"
+)
+
+// PodOriginOf derives the pod's origin from its owner references and the
+// mirror-pod annotation.
+func PodOriginOf(pod *v1.Pod) PodOrigin {
+	if _, ok := pod.Annotations[v1.MirrorPodAnnotationKey]; ok {
+		return PodOriginStatic
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return PodOriginDaemonSet
+		}
+	}
+	return PodOriginNormal
+}
+
+// isZero reports whether the resource has no amounts at all.
+func (r *Resource) isZero() bool {
+	if r.MilliCPU != 0 || r.Memory != 0 || r.EphemeralStorage != 0 || r.AllowedPodNumber != 0 {
+		return false
+	}
+	for _, rQuant := range r.ScalarResources {
+		if rQuant != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// accountOrigin adds (sign +1) or subtracts (sign -1) the pod's requests in
+// its origin bucket. Buckets that would be all zero are omitted, so a
+// NodeInfo whose pods request nothing looks the same as one never touched.
+func (n *NodeInfo) accountOrigin(pod *v1.Pod, res *Resource, sign int64) {
+	if res.isZero() {
+		return
+	}
+	origin := PodOriginOf(pod)
+	if n.requestedByOrigin == nil {
+		n.requestedByOrigin = make(map[PodOrigin]*Resource)
+	}
+	bucket, ok := n.requestedByOrigin[origin]
+	if !ok {
+		bucket = &Resource{}
+		n.requestedByOrigin[origin] = bucket
+	}
+	bucket.MilliCPU += sign * res.MilliCPU
+	bucket.Memory += sign * res.Memory
+	bucket.EphemeralStorage += sign * res.EphemeralStorage
+	if bucket.ScalarResources == nil && len(res.ScalarResources) > 0 {
+		bucket.ScalarResources = map[v1.ResourceName]int64{}
+	}
+	for rName, rQuant := range res.ScalarResources {
+		bucket.ScalarResources[rName] += sign * rQuant
+	}
+	if bucket.isZero() {
+		delete(n.requestedByOrigin, origin)
+		if len(n.requestedByOrigin) == 0 {
+			n.requestedByOrigin = nil
+		}
+	}
+}
+
+// RequestedByOrigin returns the aggregated resource requests of the pods on
+// this node with the given origin.
+func (n *NodeInfo) RequestedByOrigin(origin PodOrigin) Resource {
+	if n == nil || n.requestedByOrigin[origin] == nil {
+		return emptyResource
+	}
+	return *n.requestedByOrigin[origin]
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// UpdatePodUsage records the pod's measured ephemeral storage usage, in
+// bytes, fed from the node status summary or a metrics pipeline. The node's
+// aggregate usage is adjusted by the delta from the previous report, so
+// downscale resize recommendations and disk-pressure-aware scoring can
+// compare requested against actually used storage. Usage is dropped when the
+// pod leaves its node and repopulated by the next report.
+func (cache *schedulerCache) UpdatePodUsage(podKey string, ephemeralStorageBytes int64) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	ps, ok := cache.podStates[podKey]
+	if !ok {
+		return newError(ReasonPodNotFound, "pod %v is not in the scheduler cache, so its usage can't be recorded", podKey)
+	}
+	previous := cache.podUsage[podKey]
+	cache.podUsage[podKey] = ephemeralStorageBytes
+	if n, ok := cache.store.GetNodeInfo(ps.pod.Spec.NodeName); ok {
+		n.usedEphemeralStorage += ephemeralStorageBytes - previous
+		n.generation = nextGeneration()
+	}
+	cache.trackOverProvisioning(podKey, ps.pod, ephemeralStorageBytes)
+	return nil
+}
+
+// dropPodUsage subtracts the pod's recorded usage from its node and forgets
+// it. Assumes that lock is already acquired.
+func (cache *schedulerCache) dropPodUsage(pod *v1.Pod, n *NodeInfo) {
+	key, err := getPodKey(pod)
+	if err != nil {
+		return
+	}
+	usage, ok := cache.podUsage[key]
+	if !ok {
+		return
+	}
+	delete(cache.podUsage, key)
+	delete(cache.overProvisionedSince, key)
+	delete(cache.downsizeEmitted, key)
+	if n != nil {
+		n.usedEphemeralStorage -= usage
+		n.generation = nextGeneration()
+	}
+}
+
+// EphemeralStorageUsage returns the requested and the measured ephemeral
+// storage of the pods on this node, in bytes. The measured side only covers
+// pods with a usage report (see Cache.UpdatePodUsage).
+func (n *NodeInfo) EphemeralStorageUsage() (requested, used int64) {
+	return n.requestedResource.EphemeralStorage, n.usedEphemeralStorage
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/kubernetes/pkg/apis/scheduling"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// PriorityBand buckets pod priorities into the coarse classes capacity
+// reporting cares about. Bands are ordered: a numerically larger band
+// outranks a smaller one.
+type PriorityBand int
+
+const (
+	// PriorityBandLow holds pods with a negative priority.
+	PriorityBandLow PriorityBand = iota
+	// PriorityBandDefault holds pods with priority zero, including pods
+	// without a priority class.
+	PriorityBandDefault
+	// PriorityBandHigh holds pods with a positive user-definable
+	// priority.
+	PriorityBandHigh
+	// PriorityBandSystem holds pods in the system-reserved priority range.
+	PriorityBandSystem
+)
+
+// numPriorityBands is the number of bands, for sizing per-band arrays.
+const numPriorityBands = 4
+
+// priorityBandOf returns the band the priority falls into.
+func priorityBandOf(priority int32) PriorityBand {
+	switch {
+	case priority > scheduling.HighestUserDefinablePriority:
+		return PriorityBandSystem
+	case priority > 0:
+		return PriorityBandHigh
+	case priority == 0:
+		return PriorityBandDefault
+	default:
+		return PriorityBandLow
+	}
+}
+
+// bandRequests returns the requested resources per priority band. The result
+// is memoized per generation like UtilizationRatios, so repeated queries
+// against an unchanged node don't rescan its pods.
+func (n *NodeInfo) bandRequests() *[numPriorityBands]Resource {
+	if n.priorityBandRequests != nil && n.bandsGeneration == n.generation {
+		return n.priorityBandRequests
+	}
+
+	var requests [numPriorityBands]Resource
+	for _, pod := range n.pods {
+		res, _, _ := calculateResource(pod)
+		band := priorityBandOf(util.GetPodPriority(pod))
+		requests[band].MilliCPU += res.MilliCPU
+		requests[band].Memory += res.Memory
+		requests[band].EphemeralStorage += res.EphemeralStorage
+	}
+	n.priorityBandRequests = &requests
+	n.bandsGeneration = n.generation
+	return n.priorityBandRequests
+}
+
+// RequestedByBand returns the requested resources of the node's pods in the
+// given priority band. Like the rest of NodeInfo, it isn't safe for
+// concurrent use.
+func (n *NodeInfo) RequestedByBand(band PriorityBand) Resource {
+	if n == nil || band < 0 || band >= numPriorityBands {
+		return emptyResource
+	}
+	return n.bandRequests()[band]
+}
+
+// ReclaimableBelow returns the resources requested by pods in bands strictly
+// below the given priority's band, i.e. what preempting at that priority
+// could reclaim at band granularity. Pods inside the same band are not
+// counted, since preemption within a band depends on the exact priorities.
+func (n *NodeInfo) ReclaimableBelow(priority int32) Resource {
+	if n == nil {
+		return emptyResource
+	}
+	requests := n.bandRequests()
+	var reclaimable Resource
+	for band := PriorityBandLow; band < priorityBandOf(priority); band++ {
+		reclaimable.MilliCPU += requests[band].MilliCPU
+		reclaimable.Memory += requests[band].Memory
+		reclaimable.EphemeralStorage += requests[band].EphemeralStorage
+	}
+	return reclaimable
+}
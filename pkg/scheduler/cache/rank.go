@@ -0,0 +1,118 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RankStrategy names a node ranking order.
+type RankStrategy string
+
+const (
+	// RankLeastRequested prefers the nodes with the lowest requested
+	// fraction of CPU and memory, spreading load.
+	RankLeastRequested RankStrategy = "LeastRequested"
+	// RankMostRequested prefers the nodes with the highest requested
+	// fraction of CPU and memory, packing load.
+	RankMostRequested RankStrategy = "MostRequested"
+	// RankBalanced prefers the nodes whose CPU and memory requested
+	// fractions are closest to each other, avoiding lopsided nodes.
+	RankBalanced RankStrategy = "Balanced"
+)
+
+// NodeRank is one ranked node: its name and the strategy's score for it,
+// higher being preferred.
+type NodeRank struct {
+	Name  string
+	Score float64
+}
+
+// RankNodes orders the cached nodes by the given strategy over the cache's
+// requested and allocatable aggregates and returns the best limit of them
+// (all of them if limit is non-positive). It is a cheap read of the cached
+// accounting, not the priority framework: no pod is involved, deleted and
+// cordoned nodes are skipped, and ties break by node name for determinism.
+func (cache *schedulerCache) RankNodes(strategy RankStrategy, limit int) ([]NodeRank, error) {
+	score, err := rankScoreFunc(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.RLock()
+	var ranks []NodeRank
+	cache.store.RangeNodeInfos(func(name string, n *NodeInfo) bool {
+		if n.node == nil || n.state == NodeDeleting || n.unschedulable {
+			return true
+		}
+		cpuFraction := requestedFraction(n.requestedResource.MilliCPU, n.allocatableResource.MilliCPU)
+		memFraction := requestedFraction(n.requestedResource.Memory, n.allocatableResource.Memory)
+		ranks = append(ranks, NodeRank{Name: name, Score: score(cpuFraction, memFraction)})
+		return true
+	})
+	cache.mu.RUnlock()
+
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Score != ranks[j].Score {
+			return ranks[i].Score > ranks[j].Score
+		}
+		return ranks[i].Name < ranks[j].Name
+	})
+	if limit > 0 && len(ranks) > limit {
+		ranks = ranks[:limit]
+	}
+	return ranks, nil
+}
+
+// rankScoreFunc maps a strategy to its score over the CPU and memory
+// requested fractions.
+func rankScoreFunc(strategy RankStrategy) (func(cpuFraction, memFraction float64) float64, error) {
+	switch strategy {
+	case RankLeastRequested:
+		return func(cpuFraction, memFraction float64) float64 {
+			return 1 - (cpuFraction+memFraction)/2
+		}, nil
+	case RankMostRequested:
+		return func(cpuFraction, memFraction float64) float64 {
+			return (cpuFraction + memFraction) / 2
+		}, nil
+	case RankBalanced:
+		return func(cpuFraction, memFraction float64) float64 {
+			diff := cpuFraction - memFraction
+			if diff < 0 {
+				diff = -diff
+			}
+			return 1 - diff
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown rank strategy %q", strategy)
+	}
+}
+
+// requestedFraction is the requested/allocatable fraction, capped at 1 so an
+// overcommitted node can't score better than a merely full one.
+func requestedFraction(requested, allocatable int64) float64 {
+	if allocatable == 0 {
+		return 0
+	}
+	fraction := float64(requested) / float64(allocatable)
+	if fraction > 1 {
+		return 1
+	}
+	return fraction
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ReadReplica is an eventually consistent, lock-free view of the cache for
+// expensive read-only consumers - debug dumps, exporters, extenders - that
+// must never hold the primary cache's lock. A background loop rebuilds the
+// published snapshot once per period, so the primary's lock is touched a
+// bounded number of times no matter how many consumers read the replica.
+type ReadReplica struct {
+	cache *schedulerCache
+	// state holds the latest *replicaState; readers load it atomically
+	// and are never blocked by, nor ever block, the primary.
+	state atomic.Value
+}
+
+// replicaState is one published snapshot together with its sync time.
+type replicaState struct {
+	snapshot *Snapshot
+	syncedAt time.Time
+}
+
+// NewReadReplica returns a replica synced every period until the cache's stop
+// channel closes. The first sync happens before returning, so the replica is
+// never empty. Consumers must treat the returned snapshots as read-only, like
+// any cache snapshot.
+func (cache *schedulerCache) NewReadReplica(period time.Duration) *ReadReplica {
+	replica := &ReadReplica{cache: cache}
+	replica.sync()
+	go replica.syncLoop(period)
+	return replica
+}
+
+func (r *ReadReplica) syncLoop(period time.Duration) {
+	for {
+		select {
+		case <-r.cache.stop:
+			return
+		case <-time.After(period):
+		}
+		r.sync()
+	}
+}
+
+// sync publishes a fresh snapshot. It is the only place the primary's lock is
+// touched on behalf of the replica's consumers.
+func (r *ReadReplica) sync() {
+	r.state.Store(&replicaState{
+		snapshot: r.cache.Snapshot(),
+		syncedAt: time.Now(),
+	})
+}
+
+// Snapshot returns the most recently published snapshot without taking any
+// lock. The view is at most one sync period stale.
+func (r *ReadReplica) Snapshot() *Snapshot {
+	return r.state.Load().(*replicaState).snapshot
+}
+
+// SyncedAt returns when the published snapshot was taken, so consumers can
+// judge its staleness.
+func (r *ReadReplica) SyncedAt() time.Time {
+	return r.state.Load().(*replicaState).syncedAt
+}
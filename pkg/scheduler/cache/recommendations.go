@@ -0,0 +1,361 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+)
+
+// ResizePlacementHintAnnotationKey carries a comma-separated list of node
+// names with enough free capacity for the pod's recommended size. It is
+// attached to pods about to be deleted for resizing so the next scheduling
+// cycle can prioritize those nodes.
+const ResizePlacementHintAnnotationKey = "scheduler.alpha.kubernetes.io/resize-placement-hint"
+
+// placementHintMaxNodes caps the hint so the annotation stays small even in
+// large clusters.
+const placementHintMaxNodes = 5
+
+// FitPredicate reports whether the pod fits on the node, with the reasons it
+// doesn't. It lets the scheduler inject the same predicate logic (ports,
+// volumes, taints) it uses for placement, so in-place resizes are gated
+// consistently instead of duplicating a subset of it here.
+type FitPredicate func(pod *v1.Pod, nodeInfo *NodeInfo) (bool, []string)
+
+// SetResizeFitPredicate installs the predicate consulted by PlanResize before
+// it prefers an in-place resize. A nil predicate removes the gate.
+func (cache *schedulerCache) SetResizeFitPredicate(fn FitPredicate) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.resizeFitPredicate = fn
+}
+
+// ResizeAction is what the cache recommends doing about a pod whose
+// recommended resources differ from its current requests.
+type ResizeAction string
+
+const (
+	// ResizeActionNone means the pod needs no resize: there is no
+	// recommendation, or the recommendation doesn't exceed the current
+	// requests.
+	ResizeActionNone ResizeAction = "None"
+	// ResizeActionInPlacePreferred means the growth fits on the pod's
+	// current node, so the pod should be resized in place.
+	ResizeActionInPlacePreferred ResizeAction = "InPlacePreferred"
+	// ResizeActionDeletePodForResizing means the growth doesn't fit on the
+	// node; the pod has to be deleted and rescheduled at the new size.
+	ResizeActionDeletePodForResizing ResizeAction = "DeletePodForResizing"
+	// ResizeActionNoop means the recommendation equals the pod's current
+	// requests after rounding; the resize is complete without touching the
+	// node or the kubelet.
+	ResizeActionNoop ResizeAction = "Noop"
+)
+
+// SetResizeCooldown configures the minimum time between resizes of the same
+// pod. While a pod is inside the window, PlanResize rejects new requests with
+// ReasonResizeCooldown, which breaks feedback loops between a flapping
+// recommender and the kubelet. Zero disables the cooldown.
+func (cache *schedulerCache) SetResizeCooldown(d time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.resizeCooldown = d
+}
+
+// MarkResizeCompleted records that the pod's resize was carried out, starting
+// its cooldown window. The caller that executes resize actions is expected to
+// report back here.
+func (cache *schedulerCache) MarkResizeCompleted(podKey string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.lastResize[podKey] = time.Now()
+	delete(cache.resizingPods, podKey)
+	delete(cache.resizeStartedAt, podKey)
+	delete(cache.resizeTimeouts, podKey)
+}
+
+// SetResizeGranularity configures the granularity recommendations are
+// rounded to as they are ingested, e.g. 10m CPU and 1Mi memory. Raw
+// recommendations occasionally differ from the current requests by
+// sub-millicore or byte-level amounts, and issuing such deltas churns the
+// kubelet for no gain; rounding to the nearest multiple turns them into
+// no-ops instead. Non-positive granularities disable rounding for that
+// resource. Already-ingested recommendations are left as they are.
+func (cache *schedulerCache) SetResizeGranularity(cpuMilli, memoryBytes int64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.resizeCPUGranularity = cpuMilli
+	cache.resizeMemoryGranularity = memoryBytes
+}
+
+// roundRecommendation rounds the recommendation to the configured
+// granularity, in place. Assumes that lock is already acquired.
+func (cache *schedulerCache) roundRecommendation(r *Resource) {
+	r.MilliCPU = roundToNearest(r.MilliCPU, cache.resizeCPUGranularity)
+	r.Memory = roundToNearest(r.Memory, cache.resizeMemoryGranularity)
+}
+
+// roundToNearest rounds value to the nearest multiple of granularity, ties
+// up. A non-positive granularity returns the value unchanged.
+func roundToNearest(value, granularity int64) int64 {
+	if granularity <= 0 {
+		return value
+	}
+	return (value + granularity/2) / granularity * granularity
+}
+
+// SetRecommendedResources feeds a target size for the pod into the cache,
+// e.g. from a VPA recommender. The cache derives the resize action itself
+// (see PlanResize) instead of round-tripping through pod annotations written
+// by another controller. The recommendation is rounded to the configured
+// granularity; a nil recommendation clears a previous one.
+func (cache *schedulerCache) SetRecommendedResources(podKey string, recommendation *Resource) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if recommendation == nil {
+		delete(cache.recommendations, podKey)
+		return
+	}
+	rounded := recommendation.Clone()
+	cache.roundRecommendation(rounded)
+	cache.recommendations[podKey] = rounded
+}
+
+// PlanResize decides how to act on the pod's recommended resources: not at
+// all, in place, or by rescheduling. The growth is checked against the node's
+// free capacity, counting headroom reserved for other pods as occupied but
+// letting the pod consume its own reservation.
+func (cache *schedulerCache) PlanResize(podKey string) (ResizeAction, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	ps, ok := cache.podStates[podKey]
+	if !ok {
+		return ResizeActionNone, newError(ReasonPodNotFound, "pod %v is not in the scheduler cache, so its resize can't be planned", podKey)
+	}
+	if cache.pendingPreemptions[podKey] {
+		return ResizeActionNone, newError(ReasonResizeConflict, "pod %v is marked for preemption, so its resize is deferred", podKey)
+	}
+	rec := cache.recommendations[podKey]
+	if rec == nil {
+		return ResizeActionNone, nil
+	}
+	current, _, _ := calculateResource(ps.pod)
+	if resizeIsNoop(rec, &current) {
+		return ResizeActionNoop, nil
+	}
+	growth := Resource{
+		MilliCPU:         rec.MilliCPU - current.MilliCPU,
+		Memory:           rec.Memory - current.Memory,
+		EphemeralStorage: rec.EphemeralStorage - current.EphemeralStorage,
+	}
+	if growth.MilliCPU <= 0 && growth.Memory <= 0 && growth.EphemeralStorage <= 0 {
+		return ResizeActionNone, nil
+	}
+	if cache.resizeCooldown > 0 {
+		if last, ok := cache.lastResize[podKey]; ok {
+			if elapsed := time.Since(last); elapsed < cache.resizeCooldown {
+				return ResizeActionNone, newError(ReasonResizeCooldown, "pod %v was resized %v ago, within the %v cooldown", podKey, elapsed, cache.resizeCooldown)
+			}
+		}
+	}
+
+	// Deleting for resizing loses node-local data with the pod; refuse the
+	// fallback for pods that couldn't come back anywhere else.
+	deleteForResizing := func() (ResizeAction, error) {
+		if cache.podUsesNodeLocalStorage(ps.pod) {
+			return ResizeActionNone, newError(ReasonNodeLocalData, "pod %v uses node-local storage, so deleting it for resizing would lose data", podKey)
+		}
+		return ResizeActionDeletePodForResizing, nil
+	}
+
+	n, ok := cache.store.GetNodeInfo(ps.pod.Spec.NodeName)
+	if !ok || n.node == nil {
+		return deleteForResizing()
+	}
+	// Nodes about to be drained don't grow their pods in place.
+	if cache.nodeUnderMaintenance(ps.pod.Spec.NodeName, time.Now()) {
+		return deleteForResizing()
+	}
+	// If the node's kubelet hasn't advertised in-place resize support, an
+	// in-place update would be silently ignored; reschedule instead.
+	if !n.inPlaceResizeSupported {
+		return deleteForResizing()
+	}
+	// A node that stopped renewing its lease can't apply the update either.
+	if cache.nodeHeartbeatStale(n, time.Now()) {
+		return deleteForResizing()
+	}
+	// On static CPU manager nodes, exclusive CPU grants are pinned at
+	// admission: the kubelet rejects changing an exclusive pod in place,
+	// and shared-pool growth is bounded by the pool, not the node.
+	if n.staticCPUPolicy {
+		if exclusiveCPURequest(ps.pod) > 0 {
+			return deleteForResizing()
+		}
+		sharedRequested := n.requestedResource.MilliCPU - n.exclusiveMilliCPU
+		if growth.MilliCPU > 0 && sharedRequested+growth.MilliCPU > n.SharedCPUPoolMilli() {
+			return deleteForResizing()
+		}
+	}
+	fit := n.FitResource(cache.fitPolicy)
+	headroom := n.HeadroomResource()
+	reserved := cache.reservedCapacityFor(ps.pod)
+	pending := cache.pendingResizeDelta(ps.pod.Spec.NodeName, podKey)
+	overhead := n.ReservedResource()
+	requested := *n.requestedResource
+	if cache.optimisticTerminating {
+		requested = n.RequestedExcludingTerminating()
+	}
+	if n.allocatedDelta != nil {
+		// Mid-resize the kubelet's allocation, not the spec, is what
+		// occupies the node.
+		requested.MilliCPU += n.allocatedDelta.MilliCPU
+		requested.Memory += n.allocatedDelta.Memory
+		requested.EphemeralStorage += n.allocatedDelta.EphemeralStorage
+	}
+	own := cache.headroomByPod[podKey]
+	if own == nil {
+		own = &Resource{}
+	}
+	if growth.MilliCPU > fit.MilliCPU-reserved.MilliCPU-pending.MilliCPU-overhead.MilliCPU-requested.MilliCPU-headroom.MilliCPU+own.MilliCPU ||
+		growth.Memory > fit.Memory-reserved.Memory-pending.Memory-overhead.Memory-requested.Memory-headroom.Memory+own.Memory ||
+		growth.EphemeralStorage > fit.EphemeralStorage-reserved.EphemeralStorage-pending.EphemeralStorage-overhead.EphemeralStorage-requested.EphemeralStorage-headroom.EphemeralStorage+own.EphemeralStorage {
+		return deleteForResizing()
+	}
+	// The formula above compares requests; growing ephemeral storage in
+	// place additionally needs the disk itself to have room, since pods can
+	// fill it beyond what they requested (see NodeInfo.FreeLocalStorage).
+	if growth.EphemeralStorage > 0 && growth.EphemeralStorage > n.FreeLocalStorage() {
+		return deleteForResizing()
+	}
+	if cache.resizeFitPredicate != nil {
+		if fits, reasons := cache.resizeFitPredicate(ps.pod, n); !fits {
+			glog.V(4).Infof("In-place resize of pod %v vetoed by fit predicate: %v", podKey, strings.Join(reasons, ", "))
+			return deleteForResizing()
+		}
+	}
+	return ResizeActionInPlacePreferred, nil
+}
+
+// ResizePlacementHint returns up to placementHintMaxNodes nodes, best fit
+// first, with enough free capacity for the pod's recommended size. It is
+// meant for pods whose resize was planned as DeletePodForResizing: attaching
+// the result via AttachResizePlacementHint lets the next scheduling cycle
+// prioritize those nodes and cuts resize-triggered rescheduling latency.
+func (cache *schedulerCache) ResizePlacementHint(podKey string) ([]string, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	ps, ok := cache.podStates[podKey]
+	if !ok {
+		return nil, newError(ReasonPodNotFound, "pod %v is not in the scheduler cache, so no placement hint can be computed", podKey)
+	}
+	rec := cache.recommendations[podKey]
+	if rec == nil {
+		current, _, _ := calculateResource(ps.pod)
+		rec = &current
+	}
+
+	now := time.Now()
+	reserved := cache.reservedCapacityFor(ps.pod)
+	type candidate struct {
+		name    string
+		freeCPU int64
+	}
+	var candidates []candidate
+	cache.store.RangeNodeInfos(func(name string, n *NodeInfo) bool {
+		if name == ps.pod.Spec.NodeName || n.node == nil || n.state == NodeDeleting {
+			return true
+		}
+		if cache.nodeUnderMaintenance(name, now) {
+			return true
+		}
+		if cache.nodeHeartbeatStale(n, now) {
+			return true
+		}
+		if _, _, exceeded := cache.namespaceAtPodLimit(ps.pod, n); exceeded {
+			return true
+		}
+		fit := n.FitResource(cache.fitPolicy)
+		headroom := n.HeadroomResource()
+		pending := cache.pendingResizeDelta(name, podKey)
+		overhead := n.ReservedResource()
+		requested := *n.requestedResource
+		if cache.optimisticTerminating {
+			requested = n.RequestedExcludingTerminating()
+		}
+		if n.allocatedDelta != nil {
+			// Mid-resize the kubelet's allocation, not the spec, is what
+			// occupies the node.
+			requested.MilliCPU += n.allocatedDelta.MilliCPU
+			requested.Memory += n.allocatedDelta.Memory
+			requested.EphemeralStorage += n.allocatedDelta.EphemeralStorage
+		}
+		freeCPU := fit.MilliCPU - reserved.MilliCPU - pending.MilliCPU - overhead.MilliCPU - requested.MilliCPU - headroom.MilliCPU
+		if rec.MilliCPU > freeCPU ||
+			rec.Memory > fit.Memory-reserved.Memory-pending.Memory-overhead.Memory-requested.Memory-headroom.Memory ||
+			rec.EphemeralStorage > fit.EphemeralStorage-reserved.EphemeralStorage-pending.EphemeralStorage-overhead.EphemeralStorage-requested.EphemeralStorage-headroom.EphemeralStorage {
+			return true
+		}
+		candidates = append(candidates, candidate{name: name, freeCPU: freeCPU})
+		return true
+	})
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].freeCPU != candidates[j].freeCPU {
+			return candidates[i].freeCPU > candidates[j].freeCPU
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	if len(candidates) > placementHintMaxNodes {
+		candidates = candidates[:placementHintMaxNodes]
+	}
+	var names []string
+	for _, c := range candidates {
+		names = append(names, c.name)
+	}
+	return names, nil
+}
+
+// AttachResizePlacementHint returns a copy of pod carrying the placement hint
+// annotation, or the pod unchanged if there are no nodes to hint.
+func AttachResizePlacementHint(pod *v1.Pod, nodes []string) *v1.Pod {
+	if len(nodes) == 0 {
+		return pod
+	}
+	hinted := pod.DeepCopy()
+	if hinted.Annotations == nil {
+		hinted.Annotations = make(map[string]string)
+	}
+	hinted.Annotations[ResizePlacementHintAnnotationKey] = strings.Join(nodes, ",")
+	return hinted
+}
+
+// ResizePlacementHintOf returns the node names from the pod's placement hint
+// annotation, or nil if the pod doesn't carry one.
+func ResizePlacementHintOf(pod *v1.Pod) []string {
+	value, ok := pod.Annotations[ResizePlacementHintAnnotationKey]
+	if !ok || value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// CacheEvent is one recorded cache mutation: the operation and its input
+// object, the replayable subset of an AuditEntry. Exactly one of Pod and
+// Node is set.
+type CacheEvent struct {
+	Op   ExportEventType `json:"op"`
+	Pod  *v1.Pod         `json:"pod,omitempty"`
+	Node *v1.Node        `json:"node,omitempty"`
+}
+
+// EventsOf extracts the replayable events from audit entries, e.g. the
+// retained entries of a production cache or entries decoded from its audit
+// log file.
+func EventsOf(entries []AuditEntry) []CacheEvent {
+	var events []CacheEvent
+	for _, entry := range entries {
+		events = append(events, CacheEvent{Op: entry.Op, Pod: entry.Pod, Node: entry.Node})
+	}
+	return events
+}
+
+// Replay reconstructs cache state from a recorded event stream and returns
+// the final snapshot, so production scheduling anomalies can be reproduced
+// offline in unit tests. Events that fail to apply - as they can against a
+// log truncated by the audit retention - are skipped, and the errors come
+// back aggregated alongside the snapshot of whatever state the remaining
+// events produced.
+func Replay(events []CacheEvent) (*Snapshot, error) {
+	// The replayed cache never starts its cleanup loop; the generous TTL
+	// just keeps assumed pods from being reported as expired mid-replay.
+	replayed := newSchedulerCache(24*time.Hour, time.Second, nil)
+	err := replayed.ApplyBatch(events)
+	return replayed.Snapshot(), err
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+)
+
+// AssumeGroup assumes a set of pods atomically across their nodes with
+// all-or-nothing semantics: if any member can't be assumed, the ones already
+// assumed are rolled back and the member's error is returned. The members
+// share one TTL (the cache-wide one if non-positive), and the expiry of any
+// unconfirmed member expires the whole group, so gang workloads - MPI, Spark -
+// never end up with a partial reservation deadlocking against another gang.
+func (cache *schedulerCache) AssumeGroup(group string, pods []*v1.Pod, ttl time.Duration) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if _, ok := cache.reservationGroups[group]; ok {
+		return newError(ReasonGroupExists, "reservation group %v already exists, so can't be assumed again", group)
+	}
+	var keys []string
+	for _, pod := range pods {
+		key, err := getPodKey(pod)
+		if err == nil {
+			_, err = cache.assumePodLocked(pod)
+		}
+		if err != nil {
+			// All or nothing: undo the members assumed so far.
+			for _, assumed := range keys {
+				cache.rollbackGroupMember(assumed)
+			}
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	if ttl <= 0 {
+		ttl = cache.ttl
+	}
+	now := time.Now()
+	deadline := now.Add(ttl)
+	for _, key := range keys {
+		ps := cache.podStates[key]
+		ps.bindingFinished = true
+		ps.bindingFinishedAt = now
+		ps.deadline = &deadline
+		cache.podGroups[key] = group
+	}
+	cache.reservationGroups[group] = keys
+	return nil
+}
+
+// ForgetGroup rolls back every member of the group that is still assumed and
+// drops the group. Members already confirmed by their add event are real pods
+// and stay.
+func (cache *schedulerCache) ForgetGroup(group string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	members, ok := cache.reservationGroups[group]
+	if !ok {
+		return newError(ReasonGroupNotFound, "reservation group %v is not in the scheduler cache, so can't be forgotten", group)
+	}
+	delete(cache.reservationGroups, group)
+	for _, member := range members {
+		delete(cache.podGroups, member)
+		cache.rollbackGroupMember(member)
+	}
+	return nil
+}
+
+// rollbackGroupMember removes one assumed member from the cache, mirroring
+// the assumed branch of ForgetPod. Members that were confirmed or already
+// expired are left alone.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) rollbackGroupMember(key string) {
+	ps, ok := cache.podStates[key]
+	if !ok {
+		return
+	}
+	switch {
+	case cache.assumedPods[key]:
+		if err := cache.removePod(ps.pod); err != nil {
+			glog.Errorf("Rolling back reservation group member %v failed: %v", key, err)
+			return
+		}
+		delete(cache.assumedPods, key)
+	case ps.held:
+		// Held members never committed resources.
+	default:
+		return
+	}
+	delete(cache.podStates, key)
+	cache.store.DeletePod(key)
+}
+
+// expireGroup expires every remaining member of the group. The group mapping
+// is dropped up front so the nested expiries don't cascade again.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) expireGroup(group string) {
+	members := cache.reservationGroups[group]
+	delete(cache.reservationGroups, group)
+	for _, member := range members {
+		delete(cache.podGroups, member)
+	}
+	for _, member := range members {
+		ps, ok := cache.podStates[member]
+		if !ok || !cache.assumedPods[member] {
+			continue
+		}
+		glog.Warningf("Pod %s/%s expired with its reservation group %v", ps.pod.Namespace, ps.pod.Name, group)
+		if err := cache.expirePod(member, ps); err != nil {
+			glog.Errorf("ExpirePod failed for group member %s: %v", member, err)
+		}
+	}
+}
+
+// dropFromGroup detaches the pod from its reservation group, e.g. once its
+// add event confirmed the bind. The group is kept for the members still
+// assumed and dropped with the last one.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) dropFromGroup(key string) {
+	group, ok := cache.podGroups[key]
+	if !ok {
+		return
+	}
+	delete(cache.podGroups, key)
+	members := cache.reservationGroups[group]
+	for i := range members {
+		if members[i] == key {
+			members = append(members[:i], members[i+1:]...)
+			break
+		}
+	}
+	if len(members) == 0 {
+		delete(cache.reservationGroups, group)
+		return
+	}
+	cache.reservationGroups[group] = members
+}
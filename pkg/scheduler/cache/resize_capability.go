@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// InPlaceResizeSupportedAnnotationKey advertises, as "true" or "false" on the
+// node object (annotation or label), whether the node's kubelet can apply
+// in-place pod resizes. Absence means supported, for compatibility with
+// kubelets predating the advertisement.
+const InPlaceResizeSupportedAnnotationKey = "scheduler.alpha.kubernetes.io/in-place-resize-supported"
+
+// inPlaceResizeSupported reads the node's capability advertisement.
+func inPlaceResizeSupported(node *v1.Node) bool {
+	if value, ok := node.Annotations[InPlaceResizeSupportedAnnotationKey]; ok {
+		return value != "false"
+	}
+	if value, ok := node.Labels[InPlaceResizeSupportedAnnotationKey]; ok {
+		return value != "false"
+	}
+	return true
+}
+
+// InPlaceResizeSupported returns whether the node's kubelet advertised
+// support for in-place pod resizes. PlanResize falls back to
+// DeletePodForResizing on nodes that didn't, instead of preferring updates
+// the kubelet would ignore.
+func (n *NodeInfo) InPlaceResizeSupported() bool {
+	return n.inPlaceResizeSupported
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+)
+
+// PodPatchFunc removes the named annotations from the pod on the API server.
+// It is injected so the cache itself stays free of client-go dependencies.
+type PodPatchFunc func(pod *v1.Pod, annotations []string) error
+
+// SetResizeAnnotationGC enables the background sweep of stale resize
+// annotations. Pods whose last resize completed more than retention ago have
+// their resize request and placement hint annotations cleared via patch, and
+// resize bookkeeping for pods no longer in the cache (e.g. recreated ones) is
+// dropped. A non-positive retention disables the sweep.
+func (cache *schedulerCache) SetResizeAnnotationGC(retention time.Duration, patch PodPatchFunc) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.resizeGCRetention = retention
+	cache.podPatch = patch
+}
+
+// cleanupResizeAnnotations is one sweep round, run from the cleanup loop. The
+// patch calls happen outside the lock since they hit the API server.
+func (cache *schedulerCache) cleanupResizeAnnotations() {
+	now := time.Now()
+
+	cache.mu.Lock()
+	// Bookkeeping for pods that left the cache can never match again.
+	for key := range cache.lastResize {
+		if _, ok := cache.podStates[key]; !ok {
+			delete(cache.lastResize, key)
+			delete(cache.resizeRequestHashes, key)
+			delete(cache.resizeStatusHashes, key)
+			delete(cache.recommendations, key)
+		}
+	}
+	// The same goes for interlock marks, including ones restored from a
+	// checkpoint whose pod never showed up.
+	for key := range cache.resizingPods {
+		if _, ok := cache.podStates[key]; !ok {
+			delete(cache.resizingPods, key)
+		}
+	}
+	for key := range cache.pendingPreemptions {
+		if _, ok := cache.podStates[key]; !ok {
+			delete(cache.pendingPreemptions, key)
+		}
+	}
+	// Rate budget charges for deleted nodes, and expired ones elsewhere,
+	// so idle nodes don't pin their start times forever.
+	for nodeName := range cache.resizeStarts {
+		if _, ok := cache.store.GetNodeInfo(nodeName); !ok {
+			delete(cache.resizeStarts, nodeName)
+			continue
+		}
+		cache.pruneResizeStarts(nodeName, now)
+	}
+	retention := cache.resizeGCRetention
+	patch := cache.podPatch
+	var stale []*v1.Pod
+	if retention > 0 && patch != nil {
+		for key, last := range cache.lastResize {
+			if now.Sub(last) < retention {
+				continue
+			}
+			pod := cache.podStates[key].pod
+			if _, ok := pod.Annotations[ResizeRequestAnnotationKey]; !ok {
+				if _, ok := pod.Annotations[ResizePlacementHintAnnotationKey]; !ok {
+					continue
+				}
+			}
+			stale = append(stale, pod)
+		}
+	}
+	cache.mu.Unlock()
+
+	for _, pod := range stale {
+		err := patch(pod, []string{ResizeRequestAnnotationKey, ResizePlacementHintAnnotationKey})
+		if err != nil {
+			glog.Errorf("Failed to clear stale resize annotations from pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+}
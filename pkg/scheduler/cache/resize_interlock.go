@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// The resize interlock keeps vertical and horizontal actions on the same pod
+// from racing: without it a preemption can delete a pod while the kubelet is
+// applying an in-place resize, producing a resized-then-deleted pod. Pods
+// marked as resizing are deferred by SelectVictims, and pods marked for
+// preemption are rejected by PlanResize and MarkResizeStarted, until the
+// opposite action completes.
+
+// MarkResizeStarted records that an in-place resize of the pod is being
+// carried out, deferring its eviction until MarkResizeCompleted. It fails
+// with ReasonResizeConflict if the pod is already marked for preemption, and
+// with ReasonResizeRateLimited if the node's resize rate budget is spent or
+// reserved for higher-priority resizes (see resize_priority.go).
+func (cache *schedulerCache) MarkResizeStarted(podKey string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	ps, ok := cache.podStates[podKey]
+	if !ok {
+		return newError(ReasonPodNotFound, "pod %v is not in the scheduler cache, so its resize can't be started", podKey)
+	}
+	if cache.pendingPreemptions[podKey] {
+		return newError(ReasonResizeConflict, "pod %v is marked for preemption, so its resize is deferred", podKey)
+	}
+	if err := cache.takeResizeBudget(podKey, ps.pod); err != nil {
+		return err
+	}
+	cache.resizingPods[podKey] = true
+	cache.resizeStartedAt[podKey] = time.Now()
+	return nil
+}
+
+// IsResizing returns whether the pod has a resize in flight, i.e. between
+// MarkResizeStarted and MarkResizeCompleted.
+func (cache *schedulerCache) IsResizing(podKey string) bool {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return cache.resizingPods[podKey]
+}
+
+// MarkPendingPreemption records that the pod was chosen as a preemption
+// victim and is about to be deleted, so new resizes of it are rejected. It
+// fails with ReasonResizeConflict if the pod has a resize in flight. The mark
+// is released when the pod is removed from the cache, or explicitly via
+// ClearPendingPreemption if the preemption is abandoned.
+func (cache *schedulerCache) MarkPendingPreemption(podKey string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if _, ok := cache.podStates[podKey]; !ok {
+		return newError(ReasonPodNotFound, "pod %v is not in the scheduler cache, so it can't be marked for preemption", podKey)
+	}
+	if cache.resizingPods[podKey] {
+		return newError(ReasonResizeConflict, "pod %v has a resize in flight, so its preemption is deferred", podKey)
+	}
+	cache.pendingPreemptions[podKey] = true
+	return nil
+}
+
+// ClearPendingPreemption releases the pod's preemption mark without deleting
+// the pod, for callers that abandon a planned preemption.
+func (cache *schedulerCache) ClearPendingPreemption(podKey string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	delete(cache.pendingPreemptions, podKey)
+}
+
+// clearResizeInterlock drops both interlock marks of a pod leaving the cache.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) clearResizeInterlock(pod *v1.Pod) {
+	key, err := getPodKey(pod)
+	if err != nil {
+		return
+	}
+	delete(cache.resizingPods, key)
+	delete(cache.pendingPreemptions, key)
+	delete(cache.resizeStartedAt, key)
+	delete(cache.resizeTimeouts, key)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"strconv"
+	"time"
+
+	"k8s.io/api/core/v1"
+
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// ResizePriorityAnnotationKey overrides the priority of a pod's resizes,
+// as a decimal integer. Without it the pod's scheduling priority is used,
+// so critical workloads' resizes win contended capacity or rate budget by
+// default and the annotation only covers pods whose resize urgency differs
+// from their scheduling urgency.
+const ResizePriorityAnnotationKey = "scheduler.alpha.kubernetes.io/resize-priority"
+
+// resizePriorityOf returns the priority of the pod's resizes: the override
+// annotation if present and well-formed, the pod's scheduling priority
+// otherwise.
+func resizePriorityOf(pod *v1.Pod) int32 {
+	if value, ok := pod.Annotations[ResizePriorityAnnotationKey]; ok {
+		if priority, err := strconv.ParseInt(value, 10, 32); err == nil {
+			return int32(priority)
+		}
+	}
+	return util.GetPodPriority(pod)
+}
+
+// SetResizeRateLimit caps how many resizes MarkResizeStarted admits per node
+// within a sliding window. While the budget is contended the remaining slots
+// are reserved for the node's highest-priority pending resizes, so an
+// executor draining PendingResizes in order starves best-effort resizes, not
+// critical ones. A non-positive limit disables the cap.
+func (cache *schedulerCache) SetResizeRateLimit(perNode int, window time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.resizeRateLimit = perNode
+	cache.resizeRateWindow = window
+}
+
+// takeResizeBudget charges one resize of the pod against its node's rate
+// budget, failing with ResizeRateLimited if the window's budget is spent or
+// what is left of it is reserved for higher-priority pending resizes.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) takeResizeBudget(podKey string, pod *v1.Pod) error {
+	if cache.resizeRateLimit <= 0 {
+		return nil
+	}
+	nodeName := pod.Spec.NodeName
+	starts := cache.pruneResizeStarts(nodeName, time.Now())
+	free := cache.resizeRateLimit - len(starts)
+	if free <= 0 {
+		return newError(ReasonResizeRateLimited, "node %v spent its resize budget of %v per %v", nodeName, cache.resizeRateLimit, cache.resizeRateWindow)
+	}
+	if higher := cache.pendingHigherPriorityResizes(nodeName, podKey, resizePriorityOf(pod)); higher >= free {
+		return newError(ReasonResizeRateLimited, "node %v has %v higher-priority resizes pending against %v remaining budget", nodeName, higher, free)
+	}
+	cache.resizeStarts[nodeName] = append(starts, time.Now())
+	return nil
+}
+
+// pruneResizeStarts drops the node's resize starts that fell out of the rate
+// window and returns the ones still in it.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) pruneResizeStarts(nodeName string, now time.Time) []time.Time {
+	var kept []time.Time
+	for _, start := range cache.resizeStarts[nodeName] {
+		if cache.resizeRateWindow <= 0 || now.Sub(start) < cache.resizeRateWindow {
+			kept = append(kept, start)
+		}
+	}
+	if len(kept) == 0 {
+		delete(cache.resizeStarts, nodeName)
+	} else {
+		cache.resizeStarts[nodeName] = kept
+	}
+	return kept
+}
+
+// pendingHigherPriorityResizes counts the node's pending resizes of other
+// pods whose priority is strictly above the given one and that aren't in
+// flight yet, i.e. the resizes a budget slot should be held back for.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) pendingHigherPriorityResizes(nodeName, excludeKey string, priority int32) int {
+	count := 0
+	for key := range cache.recommendations {
+		if key == excludeKey || cache.resizingPods[key] {
+			continue
+		}
+		ps, ok := cache.podStates[key]
+		if !ok || ps.pod.Spec.NodeName != nodeName {
+			continue
+		}
+		if resizePriorityOf(ps.pod) > priority {
+			count++
+		}
+	}
+	return count
+}
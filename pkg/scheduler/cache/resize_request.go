@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResizeRequestAnnotationKey carries a requested pod size as comma-separated
+// resource=quantity pairs, e.g. "cpu=1500m,memory=2Gi,ephemeral-storage=4Gi".
+// Controllers write it on the pod; the cache ingests it on pod updates as if
+// the size had been fed via SetRecommendedResources.
+const ResizeRequestAnnotationKey = "scheduler.alpha.kubernetes.io/resize-request"
+
+// parseResizeRequest parses the resize request annotation value into a
+// Resource.
+func parseResizeRequest(value string) (*Resource, error) {
+	requested := &Resource{}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed resource pair %q", pair)
+		}
+		quantity, err := resource.ParseQuantity(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %q for resource %q: %v", parts[1], parts[0], err)
+		}
+		switch v1.ResourceName(parts[0]) {
+		case v1.ResourceCPU:
+			requested.MilliCPU = quantity.MilliValue()
+		case v1.ResourceMemory:
+			requested.Memory = quantity.Value()
+		case v1.ResourceEphemeralStorage:
+			requested.EphemeralStorage = quantity.Value()
+		default:
+			return nil, fmt.Errorf("unsupported resource %q", parts[0])
+		}
+	}
+	return requested, nil
+}
+
+// ingestResizeRequest reads the pod's resize request annotation into the
+// recommendation store. Identical annotation content is detected via a
+// content hash and skipped, so controllers re-syncing the same request don't
+// needlessly reset resize state derived from it.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) ingestResizeRequest(key string, pod *v1.Pod) {
+	value, ok := pod.Annotations[ResizeRequestAnnotationKey]
+	if !ok {
+		if _, had := cache.resizeRequestHashes[key]; had {
+			delete(cache.resizeRequestHashes, key)
+			delete(cache.recommendations, key)
+		}
+		return
+	}
+	hash := fnv.New64()
+	hash.Write([]byte(value))
+	sum := hash.Sum64()
+	if cache.resizeRequestHashes[key] == sum {
+		return
+	}
+	requested, err := parseResizeRequest(value)
+	if err != nil {
+		glog.Errorf("Pod %v has an invalid resize request annotation: %v", key, err)
+		return
+	}
+	cache.roundRecommendation(requested)
+	cache.resizeRequestHashes[key] = sum
+	// A request that already matches the pod's requests is complete the
+	// moment it arrives: nothing is stored, so neither the NodeInfo nor the
+	// kubelet sees a pointless update, and an in-flight mark is released.
+	current, _, _ := calculateResource(pod)
+	if resizeIsNoop(requested, &current) {
+		delete(cache.recommendations, key)
+		delete(cache.resizingPods, key)
+		return
+	}
+	cache.recommendations[key] = requested
+}
+
+// resizeIsNoop reports whether the requested size matches the current one on
+// every resource the request specifies. Resources requested as zero are
+// treated as unspecified, mirroring how a partial resize annotation omits
+// them.
+func resizeIsNoop(requested, current *Resource) bool {
+	specified := false
+	for _, pair := range [][2]int64{
+		{requested.MilliCPU, current.MilliCPU},
+		{requested.Memory, current.Memory},
+		{requested.EphemeralStorage, current.EphemeralStorage},
+	} {
+		if pair[0] == 0 {
+			continue
+		}
+		if pair[0] != pair[1] {
+			return false
+		}
+		specified = true
+	}
+	return specified
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+)
+
+// ResizeStatusAnnotationKey carries the kubelet's per-container outcome of an
+// in-place resize as comma-separated pairs, e.g. "c1=ok,c2=failed:oom killed
+// during restart". The kubelet restores the resources of failed containers
+// itself; the cache only has to keep the resize pending for them instead of
+// treating the whole pod as rolled back.
+const ResizeStatusAnnotationKey = "scheduler.alpha.kubernetes.io/resize-status"
+
+// ContainerResizeResult is one container's outcome of an in-place resize.
+type ContainerResizeResult struct {
+	// Container is the container's name.
+	Container string
+	// Succeeded is whether the container's resources were applied.
+	Succeeded bool
+	// Message carries the kubelet's failure message, if any.
+	Message string
+}
+
+// parseResizeStatus parses the resize status annotation value.
+func parseResizeStatus(value string) ([]ContainerResizeResult, error) {
+	var results []ContainerResizeResult
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed container result %q", pair)
+		}
+		result := ContainerResizeResult{Container: parts[0]}
+		switch {
+		case parts[1] == "ok":
+			result.Succeeded = true
+		case parts[1] == "failed":
+		case strings.HasPrefix(parts[1], "failed:"):
+			result.Message = strings.TrimPrefix(parts[1], "failed:")
+		default:
+			return nil, fmt.Errorf("unknown result %q for container %q", parts[1], parts[0])
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// processResizeStatus reads the kubelet's per-container resize outcome from
+// the pod's annotations. Either way the attempt is over: the in-flight mark
+// is released and the cooldown starts. If every container succeeded the
+// recommendation is dropped; after a partial failure it is kept, and since
+// the updated pod spec already carries the succeeded containers' new sizes,
+// the remaining delta the planner sees covers exactly the failed ones.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) processResizeStatus(key string, pod *v1.Pod) {
+	value, ok := pod.Annotations[ResizeStatusAnnotationKey]
+	if !ok {
+		delete(cache.resizeStatusHashes, key)
+		return
+	}
+	hash := fnv.New64()
+	hash.Write([]byte(value))
+	sum := hash.Sum64()
+	if cache.resizeStatusHashes[key] == sum {
+		return
+	}
+	results, err := parseResizeStatus(value)
+	if err != nil {
+		glog.Errorf("Pod %v has an invalid resize status annotation: %v", key, err)
+		return
+	}
+	cache.resizeStatusHashes[key] = sum
+
+	var failed []string
+	for _, result := range results {
+		if !result.Succeeded {
+			failed = append(failed, fmt.Sprintf("%v: %v", result.Container, result.Message))
+		}
+	}
+	delete(cache.resizingPods, key)
+	cache.lastResize[key] = time.Now()
+	if len(failed) == 0 {
+		delete(cache.recommendations, key)
+		return
+	}
+	glog.V(4).Infof("Resize of pod %v partially failed, keeping it pending for the failed containers: %v", key, strings.Join(failed, ", "))
+}
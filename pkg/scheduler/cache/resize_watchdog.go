@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// The resize watchdog catches resizes the kubelet never finished: a pod stays
+// between MarkResizeStarted and MarkResizeCompleted indefinitely when the
+// node's kubelet is stuck, which pins the pod's eviction deferral and resize
+// budget forever. The watchdog first asks the handler to re-emit the update,
+// and after repeated timeouts escalates to deleting the pod for resizing —
+// unless the pod uses node-local storage, in which case deleting would lose
+// data and the watchdog keeps re-emitting instead.
+
+// resizeWatchdogEscalateAfter is after how many consecutive timeouts a stuck
+// resize is escalated from re-emitting to deletion.
+const resizeWatchdogEscalateAfter = 3
+
+// ResizeWatchdogAction is what the watchdog asks the handler to do about a
+// stuck resize.
+type ResizeWatchdogAction string
+
+const (
+	// ResizeWatchdogReemit asks the handler to re-issue the in-place
+	// update to the kubelet.
+	ResizeWatchdogReemit ResizeWatchdogAction = "Reemit"
+	// ResizeWatchdogDelete asks the handler to delete the pod for
+	// resizing, abandoning the stuck in-place path.
+	ResizeWatchdogDelete ResizeWatchdogAction = "Delete"
+)
+
+// ResizeStuckFunc handles one stuck resize, e.g. by re-patching the pod,
+// deleting it, or raising an event. It is called without the cache lock held.
+type ResizeStuckFunc func(podKey string, pod *v1.Pod, action ResizeWatchdogAction)
+
+// SetResizeWatchdog enables the stuck-resize scan: resizes still in flight
+// after threshold are reported to the handler. A non-positive threshold or
+// nil handler disables the watchdog.
+func (cache *schedulerCache) SetResizeWatchdog(threshold time.Duration, handler ResizeStuckFunc) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.resizeWatchdogThreshold = threshold
+	cache.resizeStuck = handler
+}
+
+// watchResizes is one watchdog round, run from the cleanup loop. The handler
+// calls happen outside the lock; each timed-out resize has its clock reset so
+// it is reported again one threshold later, escalating after repeated
+// timeouts.
+func (cache *schedulerCache) watchResizes() {
+	now := time.Now()
+
+	type stuckResize struct {
+		podKey string
+		pod    *v1.Pod
+		action ResizeWatchdogAction
+	}
+	var stuck []stuckResize
+
+	cache.mu.Lock()
+	handler := cache.resizeStuck
+	if handler == nil || cache.resizeWatchdogThreshold <= 0 {
+		cache.mu.Unlock()
+		return
+	}
+	for podKey := range cache.resizingPods {
+		startedAt, ok := cache.resizeStartedAt[podKey]
+		if !ok || now.Sub(startedAt) < cache.resizeWatchdogThreshold {
+			continue
+		}
+		ps, ok := cache.podStates[podKey]
+		if !ok {
+			continue
+		}
+		cache.resizeTimeouts[podKey]++
+		cache.resizeStartedAt[podKey] = now
+		action := ResizeWatchdogReemit
+		if cache.resizeTimeouts[podKey] >= resizeWatchdogEscalateAfter && !cache.podUsesNodeLocalStorage(ps.pod) {
+			action = ResizeWatchdogDelete
+		}
+		stuck = append(stuck, stuckResize{podKey: podKey, pod: ps.pod, action: action})
+	}
+	cache.mu.Unlock()
+
+	for _, s := range stuck {
+		handler(s.podKey, s.pod, s.action)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// Resource math for extenders and out-of-tree consumers. Together with Add,
+// SetMaxResource, Clone and ResourceList these make Resource a self-contained
+// quantity type, so callers don't reimplement the arithmetic the cache uses
+// for its own accounting.
+
+// AddResource adds another Resource into this one field by field.
+func (r *Resource) AddResource(other *Resource) {
+	if r == nil || other == nil {
+		return
+	}
+	r.addResource(other)
+}
+
+// SubResource subtracts another Resource from this one field by field,
+// clamping each value at zero.
+func (r *Resource) SubResource(other *Resource) {
+	if r == nil || other == nil {
+		return
+	}
+	r.subResource(other)
+}
+
+// Fits returns whether this resource combined with other still fits within
+// allocatable, using the same per-dimension comparison as the cache's fit
+// checks. A nil other compares this resource alone. AllowedPodNumber is a
+// capacity, not a request, and is not compared.
+func (r *Resource) Fits(other, allocatable *Resource) bool {
+	if allocatable == nil {
+		return false
+	}
+	combined := &Resource{}
+	combined.AddResource(r)
+	combined.AddResource(other)
+	if combined.MilliCPU > allocatable.MilliCPU ||
+		combined.Memory > allocatable.Memory ||
+		combined.EphemeralStorage > allocatable.EphemeralStorage {
+		return false
+	}
+	for rName, rQuant := range combined.ScalarResources {
+		if rQuant > allocatable.ScalarResources[rName] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// SampleNodes returns the names of up to n nodes drawn uniformly at random
+// from the cache, restricted to nodes the filter accepts. A nil filter
+// accepts every node. Nodes being deleted are never sampled. The sample is
+// built in one pass over the cache's internal structures (reservoir
+// sampling), so percentage-of-nodes-to-score scheduling doesn't have to
+// materialize the full node list each cycle.
+//
+// The filter runs with the cache's lock held and must neither mutate the
+// NodeInfo nor retain it after returning.
+func (cache *schedulerCache) SampleNodes(n int, filter func(*NodeInfo) bool) []string {
+	if n <= 0 {
+		return nil
+	}
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	var reservoir []string
+	seen := 0
+	cache.store.RangeNodeInfos(func(name string, info *NodeInfo) bool {
+		if info.node == nil || info.state == NodeDeleting {
+			return true
+		}
+		if filter != nil && !filter(info) {
+			return true
+		}
+		seen++
+		if len(reservoir) < n {
+			reservoir = append(reservoir, name)
+			return true
+		}
+		if j := rand.Intn(seen); j < n {
+			reservoir[j] = name
+		}
+		return true
+	})
+	// Map iteration order already varies run to run; sorting makes the
+	// sample stable for callers that diff or log it.
+	sort.Strings(reservoir)
+	return reservoir
+}
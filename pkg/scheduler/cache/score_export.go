@@ -0,0 +1,175 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// scoreDataHeader names the columns of one exported feature vector: the
+// (pending pod, candidate node) pair followed by its features.
+var scoreDataHeader = []string{
+	"pod",
+	"node",
+	"cpu_utilization",
+	"memory_utilization",
+	"free_milli_cpu",
+	"free_memory_bytes",
+	"image_present",
+	"port_conflict",
+	"colocated_controller_pods",
+}
+
+// PendingPodsFunc supplies the pods awaiting placement, e.g. from the
+// scheduling queue. It is injected so the cache itself stays free of queue
+// dependencies.
+type PendingPodsFunc func() []*v1.Pod
+
+// scoreDataExporter periodically writes one feature vector per (pending pod,
+// candidate node) pair as CSV, so placement models can be trained on real
+// cache data.
+type scoreDataExporter struct {
+	interval    time.Duration
+	pending     PendingPodsFunc
+	w           io.Writer
+	last        time.Time
+	wroteHeader bool
+}
+
+// SetScoreDataExport enables the periodic feature vector export: every
+// interval, one CSV row per (pending pod, candidate node) pair is appended
+// to w, with the header written once up front. A non-positive interval or a
+// nil writer disables the export.
+func (cache *schedulerCache) SetScoreDataExport(interval time.Duration, pending PendingPodsFunc, w io.Writer) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if interval <= 0 || w == nil {
+		cache.scoreExport = nil
+		return
+	}
+	cache.scoreExport = &scoreDataExporter{interval: interval, pending: pending, w: w}
+}
+
+// exportScoreDataIfDue is one export round, run from the cleanup loop. The
+// pending pods are fetched outside the lock, since the supplier may block on
+// other stores.
+func (cache *schedulerCache) exportScoreDataIfDue() {
+	cache.mu.RLock()
+	exporter := cache.scoreExport
+	due := exporter != nil && time.Since(exporter.last) >= exporter.interval
+	cache.mu.RUnlock()
+	if !due || exporter.pending == nil {
+		return
+	}
+
+	pods := exporter.pending()
+
+	cache.mu.Lock()
+	if cache.scoreExport != exporter {
+		cache.mu.Unlock()
+		return
+	}
+	exporter.last = time.Now()
+	err := cache.writeScoreData(exporter, pods)
+	cache.mu.Unlock()
+	if err != nil {
+		glog.Errorf("Failed to export score data: %v", err)
+	}
+}
+
+// writeScoreData appends the feature vectors of the given pending pods
+// against every candidate node. Assumes that lock is already acquired.
+func (cache *schedulerCache) writeScoreData(exporter *scoreDataExporter, pods []*v1.Pod) error {
+	out := csv.NewWriter(exporter.w)
+	if !exporter.wroteHeader {
+		if err := out.Write(scoreDataHeader); err != nil {
+			return err
+		}
+		exporter.wroteHeader = true
+	}
+	for _, pod := range pods {
+		podName := pod.Namespace + "/" + pod.Name
+		var rangeErr error
+		cache.store.RangeNodeInfos(func(name string, n *NodeInfo) bool {
+			if n.node == nil || n.state == NodeDeleting {
+				return true
+			}
+			if err := out.Write(append([]string{podName, name}, scoreFeatures(pod, n, cache.fitPolicy)...)); err != nil {
+				rangeErr = err
+				return false
+			}
+			return true
+		})
+		if rangeErr != nil {
+			return rangeErr
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// scoreFeatures computes the feature columns of one (pending pod, candidate
+// node) pair.
+func scoreFeatures(pod *v1.Pod, n *NodeInfo, policy FitResourcePolicy) []string {
+	fit := n.FitResource(policy)
+	utilization := func(requested, total int64) string {
+		if total <= 0 {
+			return "1"
+		}
+		return strconv.FormatFloat(float64(requested)/float64(total), 'f', 4, 64)
+	}
+
+	imagePresent := "0"
+	portConflict := "0"
+	for _, container := range pod.Spec.Containers {
+		if _, ok := n.imageStates[container.Image]; ok {
+			imagePresent = "1"
+		}
+		for _, port := range container.Ports {
+			if port.HostPort > 0 && n.usedPorts.CheckConflict(port.HostIP, string(port.Protocol), port.HostPort) {
+				portConflict = "1"
+			}
+		}
+	}
+
+	colocated := 0
+	if controller := metav1.GetControllerOf(pod); controller != nil {
+		for _, other := range n.pods {
+			if sibling := metav1.GetControllerOf(other); sibling != nil && sibling.UID == controller.UID {
+				colocated++
+			}
+		}
+	}
+
+	return []string{
+		utilization(n.requestedResource.MilliCPU, fit.MilliCPU),
+		utilization(n.requestedResource.Memory, fit.Memory),
+		strconv.FormatInt(fit.MilliCPU-n.requestedResource.MilliCPU, 10),
+		strconv.FormatInt(fit.Memory-n.requestedResource.Memory, 10),
+		imagePresent,
+		portConflict,
+		strconv.Itoa(colocated),
+	}
+}
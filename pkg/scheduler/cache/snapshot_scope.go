@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	policy "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// SnapshotMatching takes a snapshot restricted to the nodes whose labels
+// match the selector, e.g. one node pool. Pods appear only through the
+// NodeInfos of matching nodes, and assumed-pod and maintenance bookkeeping is
+// filtered the same way, so node-pool-scoped autoscalers and extenders don't
+// pay for the whole cluster. PDBs are namespace-scoped, not node-scoped, and
+// are included in full.
+//
+// Unlike Snapshot, the scoped variant doesn't build incrementally: every
+// matching node is cloned each call. It is meant for scoped consumers whose
+// subset is small, not as a drop-in for the cluster-wide snapshot.
+func (cache *schedulerCache) SnapshotMatching(selector labels.Selector) *Snapshot {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	nodes := make(map[string]*NodeInfo)
+	cache.store.RangeNodeInfos(func(name string, n *NodeInfo) bool {
+		if n.node == nil || !selector.Matches(labels.Set(n.node.Labels)) {
+			return true
+		}
+		nodes[name] = n.Clone()
+		return true
+	})
+
+	assumedPods := make(map[string]bool)
+	for key := range cache.assumedPods {
+		ps, ok := cache.podStates[key]
+		if !ok {
+			continue
+		}
+		if _, ok := nodes[ps.pod.Spec.NodeName]; ok {
+			assumedPods[key] = true
+		}
+	}
+
+	pdbs := make(map[string]*policy.PodDisruptionBudget)
+	for _, nsPDBs := range cache.pdbs {
+		for k, v := range nsPDBs {
+			if v.v1beta1 != nil {
+				pdbs[k] = v.v1beta1.DeepCopy()
+			}
+		}
+	}
+
+	var maintenance map[string]time.Time
+	if len(cache.maintenance) > 0 {
+		now := time.Now()
+		for nodeName, deadline := range cache.maintenance {
+			if _, ok := nodes[nodeName]; !ok || !now.Before(deadline) {
+				continue
+			}
+			if maintenance == nil {
+				maintenance = make(map[string]time.Time)
+			}
+			maintenance[nodeName] = deadline
+		}
+	}
+
+	return &Snapshot{
+		Nodes:                nodes,
+		AssumedPods:          assumedPods,
+		Pdbs:                 pdbs,
+		MaintenanceDeadlines: maintenance,
+	}
+}
@@ -0,0 +1,31 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+// FreeLocalStorage returns the node's allocatable ephemeral storage minus the
+// measured usage of its pods, in bytes, floored at zero. Unlike the
+// requests-based fit accounting, this reflects what is actually left on the
+// disk: pods may write beyond their requests, so an ephemeral-storage growth
+// that fits on paper can still be unsatisfiable in place. Pods without a
+// usage report (see Cache.UpdatePodUsage) count as using nothing.
+func (n *NodeInfo) FreeLocalStorage() int64 {
+	free := n.allocatableResource.EphemeralStorage - n.usedEphemeralStorage
+	if free < 0 {
+		return 0
+	}
+	return free
+}
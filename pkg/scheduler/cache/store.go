@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"k8s.io/api/core/v1"
+)
+
+// Store abstracts the node and pod storage of the scheduler cache so that an
+// alternative backend — e.g. a shared memory segment or an embedded KV store
+// shared by multiple schedulers — can be swapped in for HA experiments.
+// schedulerCache keeps its semantics (assume handling, TTLs, generations) on
+// top of whichever Store it is given: the Store only holds objects, it never
+// interprets them. Implementations don't need to be thread safe; the cache
+// serializes access through its own lock.
+type Store interface {
+	// GetNodeInfo returns the NodeInfo stored under the node name.
+	GetNodeInfo(name string) (*NodeInfo, bool)
+	// SetNodeInfo stores the NodeInfo under the node name.
+	SetNodeInfo(name string, info *NodeInfo)
+	// DeleteNodeInfo removes the NodeInfo stored under the node name.
+	DeleteNodeInfo(name string)
+	// RangeNodeInfos calls fn for every stored NodeInfo until fn returns
+	// false.
+	RangeNodeInfos(fn func(name string, info *NodeInfo) bool)
+	// NumNodes returns the number of stored NodeInfos.
+	NumNodes() int
+
+	// GetPod returns the pod stored under the pod key.
+	GetPod(key string) (*v1.Pod, bool)
+	// SetPod stores the pod under the pod key.
+	SetPod(key string, pod *v1.Pod)
+	// DeletePod removes the pod stored under the pod key.
+	DeletePod(key string)
+	// RangePods calls fn for every stored pod until fn returns false.
+	RangePods(fn func(key string, pod *v1.Pod) bool)
+	// NumPods returns the number of stored pods.
+	NumPods() int
+}
+
+// mapStore is the default Store, backed by plain in-memory maps.
+type mapStore struct {
+	nodes map[string]*NodeInfo
+	pods  map[string]*v1.Pod
+}
+
+// NewMapStore returns the default in-memory Store implementation.
+func NewMapStore() Store {
+	return &mapStore{
+		nodes: make(map[string]*NodeInfo),
+		pods:  make(map[string]*v1.Pod),
+	}
+}
+
+func (s *mapStore) GetNodeInfo(name string) (*NodeInfo, bool) {
+	info, ok := s.nodes[name]
+	return info, ok
+}
+
+func (s *mapStore) SetNodeInfo(name string, info *NodeInfo) {
+	s.nodes[name] = info
+}
+
+func (s *mapStore) DeleteNodeInfo(name string) {
+	delete(s.nodes, name)
+}
+
+func (s *mapStore) RangeNodeInfos(fn func(name string, info *NodeInfo) bool) {
+	for name, info := range s.nodes {
+		if !fn(name, info) {
+			return
+		}
+	}
+}
+
+func (s *mapStore) NumNodes() int {
+	return len(s.nodes)
+}
+
+func (s *mapStore) GetPod(key string) (*v1.Pod, bool) {
+	pod, ok := s.pods[key]
+	return pod, ok
+}
+
+func (s *mapStore) SetPod(key string, pod *v1.Pod) {
+	s.pods[key] = pod
+}
+
+func (s *mapStore) DeletePod(key string) {
+	delete(s.pods, key)
+}
+
+func (s *mapStore) RangePods(fn func(key string, pod *v1.Pod) bool) {
+	for key, pod := range s.pods {
+		if !fn(key, pod) {
+			return
+		}
+	}
+}
+
+func (s *mapStore) NumPods() int {
+	return len(s.pods)
+}
+
+// NewWithStore returns a Cache like New, but backed by the given Store
+// instead of the default in-memory maps.
+func NewWithStore(ttl time.Duration, stop <-chan struct{}, store Store) Cache {
+	cache := newSchedulerCache(ttl, cleanAssumedPeriod, stop)
+	cache.store = store
+	cache.run()
+	return cache
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// SetStrictAssume toggles strict node checks at assume time. AssumePod
+// normally tolerates nodes the cache hasn't seen yet, because pod and node
+// watches are delivered independently; under strict mode it instead rejects
+// assumes on unknown or cordoned nodes with a typed error, catching races
+// between cordon and bind at the cost of requiring the node watch to be
+// warmed up first.
+func (cache *schedulerCache) SetStrictAssume(enabled bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.strictAssume = enabled
+}
+
+// Unschedulable returns whether the node was cordoned, as of the last
+// ingested node object.
+func (n *NodeInfo) Unschedulable() bool {
+	return n.unschedulable
+}
+
+// checkStrictAssume rejects the assume if strict mode is on and the pod's
+// node is unknown or cordoned.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) checkStrictAssume(key string, pod *v1.Pod) error {
+	if !cache.strictAssume {
+		return nil
+	}
+	n, ok := cache.store.GetNodeInfo(pod.Spec.NodeName)
+	if !ok || n.node == nil {
+		return newError(ReasonNodeNotFound, "node %v is not yet known to the cache, so pod %v can't be assumed on it under strict assume", pod.Spec.NodeName, key)
+	}
+	if n.unschedulable {
+		return newError(ReasonNodeCordoned, "node %v is cordoned, so pod %v can't be assumed on it", pod.Spec.NodeName, key)
+	}
+	return nil
+}
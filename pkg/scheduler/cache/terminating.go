@@ -0,0 +1,71 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+)
+
+// SetOptimisticTerminating makes the resize fit checks count the requests of
+// pods already being deleted as free: their capacity comes back as soon as
+// they finish terminating, so waiting for the removal event just delays
+// resizes that would fit. The accounting itself is always maintained; the
+// flag only selects which view PlanResize and ResizePlacementHint use.
+func (cache *schedulerCache) SetOptimisticTerminating(enabled bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.optimisticTerminating = enabled
+}
+
+// accountTerminating adds (sign 1) or subtracts (sign -1) the pod's requests
+// from the node's terminating aggregate if the pod carries a deletion
+// timestamp. The aggregate is nil while no terminating pod is on the node.
+func (n *NodeInfo) accountTerminating(pod *v1.Pod, res *Resource, sign int64) {
+	if pod.DeletionTimestamp == nil {
+		return
+	}
+	if n.terminatingResource == nil {
+		n.terminatingResource = &Resource{}
+	}
+	n.terminatingResource.MilliCPU += sign * res.MilliCPU
+	n.terminatingResource.Memory += sign * res.Memory
+	n.terminatingResource.EphemeralStorage += sign * res.EphemeralStorage
+	if n.terminatingResource.MilliCPU == 0 && n.terminatingResource.Memory == 0 && n.terminatingResource.EphemeralStorage == 0 {
+		n.terminatingResource = nil
+	}
+}
+
+// TerminatingResource returns the summed requests of the node's pods that
+// carry a deletion timestamp, i.e. capacity about to come free.
+func (n *NodeInfo) TerminatingResource() Resource {
+	if n.terminatingResource == nil {
+		return Resource{}
+	}
+	return *n.terminatingResource
+}
+
+// RequestedExcludingTerminating returns the node's requested resources with
+// terminating pods subtracted, the optimistic view of soon-to-free capacity.
+func (n *NodeInfo) RequestedExcludingTerminating() Resource {
+	requested := *n.requestedResource
+	if n.terminatingResource != nil {
+		requested.MilliCPU -= n.terminatingResource.MilliCPU
+		requested.Memory -= n.terminatingResource.Memory
+		requested.EphemeralStorage -= n.terminatingResource.EphemeralStorage
+	}
+	return requested
+}
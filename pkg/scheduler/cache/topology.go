@@ -0,0 +1,170 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// addNodeTopology indexes the node under each of its labels. Every label is
+// treated as a topology key so that custom domains work the same way as the
+// well-known zone/region/hostname labels. This function assumes the lock to
+// the scheduler cache has been acquired.
+func (cache *schedulerCache) addNodeTopology(node *v1.Node) {
+	if node == nil {
+		return
+	}
+	for key, value := range node.Labels {
+		domains, ok := cache.topology[key]
+		if !ok {
+			domains = make(map[string]sets.String)
+			cache.topology[key] = domains
+		}
+		nodes, ok := domains[value]
+		if !ok {
+			nodes = sets.NewString()
+			domains[value] = nodes
+		}
+		nodes.Insert(node.Name)
+	}
+}
+
+// removeNodeTopology removes the node from the index entries of each of its
+// labels, dropping entries that become empty. This function assumes the lock
+// to the scheduler cache has been acquired.
+func (cache *schedulerCache) removeNodeTopology(node *v1.Node) {
+	if node == nil {
+		return
+	}
+	for key, value := range node.Labels {
+		domains, ok := cache.topology[key]
+		if !ok {
+			continue
+		}
+		nodes, ok := domains[value]
+		if !ok {
+			continue
+		}
+		nodes.Delete(node.Name)
+		if nodes.Len() == 0 {
+			delete(domains, value)
+		}
+		if len(domains) == 0 {
+			delete(cache.topology, key)
+		}
+	}
+}
+
+// NodesInTopologyDomain returns the names of the nodes labeled with the given
+// topology key and value, e.g. the nodes of one zone. The result is sorted
+// and safe to mutate.
+func (cache *schedulerCache) NodesInTopologyDomain(key, value string) []string {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	domains, ok := cache.topology[key]
+	if !ok {
+		return nil
+	}
+	nodes, ok := domains[value]
+	if !ok {
+		return nil
+	}
+	return nodes.List()
+}
+
+// NodeIterator walks a fixed ordering of node names produced by
+// Cache.NodeIterator. The zero value is an exhausted iterator.
+type NodeIterator struct {
+	names []string
+	next  int
+}
+
+// Next returns the next node name, or false once the iterator is exhausted.
+func (it *NodeIterator) Next() (string, bool) {
+	if it.next >= len(it.names) {
+		return "", false
+	}
+	name := it.names[it.next]
+	it.next++
+	return name, true
+}
+
+// Len returns the total number of nodes the iterator covers.
+func (it *NodeIterator) Len() int {
+	return len(it.names)
+}
+
+// NodeIterator returns an iterator over the cached nodes that round-robins
+// across the domains of the given topology key (e.g. zones), sorted domain
+// and node names first. A scheduler that only evaluates a prefix of the nodes
+// therefore sees every domain represented instead of skewing into the first
+// one alphabetically. Nodes without the label form one trailing domain of
+// their own, and nodes being deleted are skipped.
+func (cache *schedulerCache) NodeIterator(spreadKey string) *NodeIterator {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	eligible := sets.NewString()
+	cache.store.RangeNodeInfos(func(name string, info *NodeInfo) bool {
+		if info.node != nil && info.state != NodeDeleting {
+			eligible.Insert(name)
+		}
+		return true
+	})
+
+	var buckets [][]string
+	indexed := sets.NewString()
+	domains := cache.topology[spreadKey]
+	for _, value := range sortedDomainValues(domains) {
+		var bucket []string
+		for _, name := range domains[value].List() {
+			if eligible.Has(name) {
+				bucket = append(bucket, name)
+				indexed.Insert(name)
+			}
+		}
+		if len(bucket) > 0 {
+			buckets = append(buckets, bucket)
+		}
+	}
+	if unlabeled := eligible.Difference(indexed); unlabeled.Len() > 0 {
+		buckets = append(buckets, unlabeled.List())
+	}
+
+	names := make([]string, 0, eligible.Len())
+	for round := 0; len(names) < eligible.Len(); round++ {
+		for _, bucket := range buckets {
+			if round < len(bucket) {
+				names = append(names, bucket[round])
+			}
+		}
+	}
+	return &NodeIterator{names: names}
+}
+
+func sortedDomainValues(domains map[string]sets.String) []string {
+	var values []string
+	for value := range domains {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// TopologySpreadConstraint identifies a set of pods whose spread over a
+// topology key is tracked by the cache: the pods of the given namespace
+// matching the selector, grouped by the value of the topology label on the
+// node each pod is assigned to.
+type TopologySpreadConstraint struct {
+	Namespace   string
+	TopologyKey string
+	Selector    labels.Selector
+}
+
+// key returns the identity of the constraint in the cache's count index.
+func (c *TopologySpreadConstraint) key() string {
+	return c.Namespace + "/" + c.TopologyKey + "/" + c.Selector.String()
+}
+
+// matches returns true if the pod belongs to the set of pods the constraint
+// counts.
+func (c *TopologySpreadConstraint) matches(pod *v1.Pod) bool {
+	return pod.Namespace == c.Namespace && c.Selector.Matches(labels.Set(pod.Labels))
+}
+
+// spreadCounts holds the number of matching pods per topology domain value
+// for one tracked constraint.
+type spreadCounts struct {
+	constraint TopologySpreadConstraint
+	domains    map[string]int32
+}
+
+// TopologySpreadCounts returns the number of pods matching the constraint in
+// each topology domain, keyed by the domain's label value. The first query
+// for a constraint scans the cached pods once; afterwards the counts are
+// maintained incrementally as pods are added and removed. The returned map is
+// a copy and safe to mutate.
+func (cache *schedulerCache) TopologySpreadCounts(constraint TopologySpreadConstraint) map[string]int32 {
+	key := constraint.key()
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	sc, ok := cache.spreadCounts[key]
+	if !ok {
+		sc = &spreadCounts{
+			constraint: constraint,
+			domains:    cache.countSpread(&constraint),
+		}
+		cache.spreadCounts[key] = sc
+	}
+
+	counts := make(map[string]int32, len(sc.domains))
+	for domain, count := range sc.domains {
+		counts[domain] = count
+	}
+	return counts
+}
+
+// countSpread scans the cached pods and returns the matching pod count per
+// topology domain. This function assumes the lock to the scheduler cache has
+// been acquired.
+func (cache *schedulerCache) countSpread(constraint *TopologySpreadConstraint) map[string]int32 {
+	domains := make(map[string]int32)
+	cache.store.RangeNodeInfos(func(_ string, n *NodeInfo) bool {
+		if n.node == nil {
+			return true
+		}
+		value, ok := n.node.Labels[constraint.TopologyKey]
+		if !ok {
+			return true
+		}
+		for _, pod := range n.pods {
+			if constraint.matches(pod) {
+				domains[value]++
+			}
+		}
+		return true
+	})
+	return domains
+}
+
+// updateSpreadCounts adjusts the tracked per-domain counts for a pod being
+// added to (delta 1) or removed from (delta -1) its node. Pods on nodes the
+// cache doesn't know yet aren't counted; their counts are picked up when the
+// counts are rebuilt on the next node update. This function assumes the lock
+// to the scheduler cache has been acquired.
+func (cache *schedulerCache) updateSpreadCounts(pod *v1.Pod, delta int32) {
+	if len(cache.spreadCounts) == 0 {
+		return
+	}
+	n, ok := cache.store.GetNodeInfo(pod.Spec.NodeName)
+	if !ok || n.node == nil {
+		return
+	}
+	for _, sc := range cache.spreadCounts {
+		if !sc.constraint.matches(pod) {
+			continue
+		}
+		value, ok := n.node.Labels[sc.constraint.TopologyKey]
+		if !ok {
+			continue
+		}
+		sc.domains[value] += delta
+		if sc.domains[value] <= 0 {
+			delete(sc.domains, value)
+		}
+	}
+}
+
+// rebuildSpreadCounts recomputes the counts of every tracked constraint from
+// scratch. It is called when node topology labels change, which is rare
+// enough that a full recount is acceptable. This function assumes the lock to
+// the scheduler cache has been acquired.
+func (cache *schedulerCache) rebuildSpreadCounts() {
+	for _, sc := range cache.spreadCounts {
+		sc.domains = cache.countSpread(&sc.constraint)
+	}
+}
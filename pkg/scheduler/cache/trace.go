@@ -0,0 +1,66 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+)
+
+// Span is one traced cache operation. It follows the OpenTelemetry span shape
+// so an adapter to a real tracing backend is a thin wrapper; the cache itself
+// doesn't depend on any tracing library.
+type Span interface {
+	// SetAttribute attaches a key/value pair to the span.
+	SetAttribute(key, value string)
+
+	// End finishes the span.
+	End()
+}
+
+// Tracer starts spans for cache operations. The default is a no-op; install a
+// backend adapter with SetTracer to include cache time in end-to-end
+// scheduling latency traces.
+type Tracer interface {
+	// StartSpan starts a span for the named operation, parented to any span
+	// already carried by ctx.
+	StartSpan(ctx context.Context, operation string) (context.Context, Span)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key, value string) {}
+func (noopSpan) End()                           {}
+
+// SetTracer installs the tracer used for cache operation spans. A nil tracer
+// restores the no-op default.
+func (cache *schedulerCache) SetTracer(t Tracer) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.tracer = t
+}
+
+// startSpan starts a span for operation via the installed tracer, or returns
+// a no-op span if none is installed.
+func (cache *schedulerCache) startSpan(ctx context.Context, operation string) (context.Context, Span) {
+	cache.mu.RLock()
+	tracer := cache.tracer
+	cache.mu.RUnlock()
+	if tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.StartSpan(ctx, operation)
+}
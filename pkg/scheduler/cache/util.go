@@ -52,8 +52,9 @@ func getNodeImageStates(node *v1.Node, imageExistenceMap map[string]sets.String)
 	for _, image := range node.Status.Images {
 		for _, name := range image.Names {
 			imageStates[name] = &ImageStateSummary{
-				Size:     image.SizeBytes,
-				NumNodes: len(imageExistenceMap[name]),
+				Size:              image.SizeBytes,
+				NumNodes:          len(imageExistenceMap[name]),
+				EstimatedPullTime: estimatePullDuration(image.SizeBytes, defaultImagePullBandwidth),
 			}
 		}
 	}
@@ -59,12 +59,14 @@ func TestGetNodeImageStates(t *testing.T) {
 			},
 			expected: map[string]*ImageStateSummary{
 				"gcr.io/10:v1": {
-					Size:     int64(10 * mb),
-					NumNodes: 2,
+					Size:              int64(10 * mb),
+					NumNodes:          2,
+					EstimatedPullTime: estimatePullDuration(int64(10*mb), defaultImagePullBandwidth),
 				},
 				"gcr.io/200:v1": {
-					Size:     int64(200 * mb),
-					NumNodes: 1,
+					Size:              int64(200 * mb),
+					NumNodes:          1,
+					EstimatedPullTime: estimatePullDuration(int64(200*mb), defaultImagePullBandwidth),
 				},
 			},
 		},
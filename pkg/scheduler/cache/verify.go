@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/golang/glog"
+)
+
+// VerificationMode controls the cache's shadow verification.
+type VerificationMode int
+
+const (
+	// VerificationOff disables verification. This is the default.
+	VerificationOff VerificationMode = iota
+	// VerificationLog recomputes node accounting after every mutation and
+	// logs divergence from the incremental state.
+	VerificationLog
+	// VerificationPanic is like VerificationLog but panics on divergence.
+	// Intended for soak clusters where a crash with a precise message is
+	// more useful than a drifting cache.
+	VerificationPanic
+)
+
+// SetVerificationMode enables or disables shadow verification. In a
+// verification mode every pod mutation also rebuilds the affected node's
+// accounting from scratch and compares it against the incrementally
+// maintained state. This is slow (linear in the node's pods per mutation)
+// and meant for soak testing, not production scheduling.
+func (cache *schedulerCache) SetVerificationMode(mode VerificationMode) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.verification = mode
+}
+
+// referenceNodeInfo rebuilds the accounting of n from its pod list alone,
+// the way buildNodeInfo in the tests does. It deliberately avoids the
+// incremental add/remove paths so that it can catch their bugs.
+func referenceNodeInfo(n *NodeInfo) *NodeInfo {
+	expected := NewNodeInfo()
+	expected.zeroRequestDefaults = n.zeroRequestDefaults
+	expected.zeroRequestPolicy = n.zeroRequestPolicy
+	for _, pod := range n.pods {
+		expected.pods = append(expected.pods, pod)
+		defaultMilliCPU, defaultMemory := n.zeroRequestFor(pod)
+		for i := range pod.Spec.Containers {
+			requests := pod.Spec.Containers[i].Resources.Requests
+			expected.requestedResource.Add(requests)
+			cpu, mem := nonzeroRequests(&requests, defaultMilliCPU, defaultMemory)
+			expected.nonzeroRequest.MilliCPU += cpu
+			expected.nonzeroRequest.Memory += mem
+		}
+		expected.updateUsedPorts(pod, true)
+	}
+	return expected
+}
+
+// resourcesDiverge compares two Resources treating absent scalar resources
+// as zero, so that a map left empty by the decrement path does not count as
+// divergence against a nil map.
+func resourcesDiverge(got, want *Resource) bool {
+	if got.MilliCPU != want.MilliCPU || got.Memory != want.Memory || got.EphemeralStorage != want.EphemeralStorage {
+		return true
+	}
+	for rName, rQuant := range got.ScalarResources {
+		if rQuant != want.ScalarResources[rName] {
+			return true
+		}
+	}
+	for rName, rQuant := range want.ScalarResources {
+		if rQuant != got.ScalarResources[rName] {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyNodeInfo compares the incrementally maintained accounting of the
+// named node against a from-scratch rebuild. It assumes the cache's lock is
+// acquired and must be called after the mutation has fully applied.
+func (cache *schedulerCache) verifyNodeInfo(nodeName string) {
+	if cache.verification == VerificationOff {
+		return
+	}
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok {
+		return
+	}
+	expected := referenceNodeInfo(n)
+
+	var divergence string
+	switch {
+	case len(n.pods) != len(expected.pods):
+		divergence = fmt.Sprintf("pod count %d, expected %d", len(n.pods), len(expected.pods))
+	case resourcesDiverge(n.requestedResource, expected.requestedResource):
+		divergence = fmt.Sprintf("requested resource %+v, expected %+v", n.requestedResource, expected.requestedResource)
+	case resourcesDiverge(n.nonzeroRequest, expected.nonzeroRequest):
+		divergence = fmt.Sprintf("nonzero request %+v, expected %+v", n.nonzeroRequest, expected.nonzeroRequest)
+	case !reflect.DeepEqual(n.usedPorts, expected.usedPorts):
+		divergence = fmt.Sprintf("used ports %+v, expected %+v", n.usedPorts, expected.usedPorts)
+	default:
+		return
+	}
+
+	message := fmt.Sprintf("scheduler cache verification failed for node %q: %s", nodeName, divergence)
+	if cache.verification == VerificationPanic {
+		panic(message)
+	}
+	glog.Error(message)
+}
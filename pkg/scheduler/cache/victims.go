@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/kubernetes/pkg/scheduler/util"
+)
+
+// victimBudget tracks how many more disruptions a PDB tolerates while a
+// victim set is being built.
+type victimBudget struct {
+	info    *pdbInfo
+	allowed int32
+}
+
+// pdbBudgets returns the remaining disruption budgets of the PDBs in the
+// pod's namespace. It assumes the cache's lock is acquired.
+func (cache *schedulerCache) pdbBudgets(namespace string) []*victimBudget {
+	var budgets []*victimBudget
+	for _, info := range cache.pdbs[namespace] {
+		allowed := info.podDisruptionsAllowed
+		if !info.statusFresh() {
+			allowed = cache.disruptionsAllowedFromSpec(info)
+		}
+		allowed -= info.disruptionsInFlight
+		if allowed < 0 {
+			allowed = 0
+		}
+		budgets = append(budgets, &victimBudget{info: info, allowed: allowed})
+	}
+	return budgets
+}
+
+// SelectVictims computes a minimal set of pods whose removal from the node
+// frees enough resources for the preemptor. Only pods with a lower priority
+// than the preemptor are considered, lowest priority first. With pdbAware
+// set, pods whose disruption budget is exhausted are not eligible. The
+// returned set is empty if the preemptor already fits, and an error is
+// returned if no eligible victim set frees enough resources.
+//
+// The victim set is advisory: the cache is not modified, and the budget of
+// chosen victims is only consumed once the caller disrupts them (via
+// MarkPodDisruption).
+func (cache *schedulerCache) SelectVictims(nodeName string, preemptor *v1.Pod, pdbAware bool) ([]*v1.Pod, error) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	n, ok := cache.store.GetNodeInfo(nodeName)
+	if !ok || n.node == nil {
+		return nil, newError(ReasonNodeNotFound, "node %v is not in the cache, so victims can't be selected on it", nodeName)
+	}
+
+	need, _, _ := calculateResource(preemptor)
+	total := n.FitResource(cache.fitPolicy)
+	reserved := cache.reservedCapacityFor(preemptor)
+	pending := cache.pendingResizeDelta(nodeName, "")
+	freeCPU := total.MilliCPU - reserved.MilliCPU - pending.MilliCPU - n.requestedResource.MilliCPU
+	freeMem := total.Memory - reserved.Memory - pending.Memory - n.requestedResource.Memory
+	freeStorage := total.EphemeralStorage - reserved.EphemeralStorage - pending.EphemeralStorage - n.requestedResource.EphemeralStorage
+	fits := func() bool {
+		return freeCPU >= need.MilliCPU && freeMem >= need.Memory && freeStorage >= need.EphemeralStorage
+	}
+	if fits() {
+		return nil, nil
+	}
+
+	preemptorPriority := util.GetPodPriority(preemptor)
+	var candidates []*v1.Pod
+	for _, pod := range n.pods {
+		if util.GetPodPriority(pod) >= preemptorPriority {
+			continue
+		}
+		// Pods with a resize in flight are deferred from eviction until the
+		// resize completes (see resize_interlock.go).
+		if key, err := getPodKey(pod); err == nil && cache.resizingPods[key] {
+			continue
+		}
+		candidates = append(candidates, pod)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return util.GetPodPriority(candidates[i]) < util.GetPodPriority(candidates[j])
+	})
+
+	budgets := make(map[string][]*victimBudget)
+	matching := func(pod *v1.Pod) []*victimBudget {
+		if _, ok := budgets[pod.Namespace]; !ok {
+			budgets[pod.Namespace] = cache.pdbBudgets(pod.Namespace)
+		}
+		var matched []*victimBudget
+		for _, budget := range budgets[pod.Namespace] {
+			if budget.info.selector.Matches(labels.Set(pod.Labels)) {
+				matched = append(matched, budget)
+			}
+		}
+		return matched
+	}
+
+	var victims []*v1.Pod
+	for _, pod := range candidates {
+		if pdbAware {
+			matched := matching(pod)
+			blocked := false
+			for _, budget := range matched {
+				if budget.allowed <= 0 {
+					blocked = true
+					break
+				}
+			}
+			if len(matched) > 0 {
+				decision := PDBDecision{
+					Namespace: pod.Namespace,
+					Name:      pod.Name,
+					Permitted: !blocked,
+				}
+				for i, budget := range matched {
+					decision.PDBs = append(decision.PDBs, budget.info.name)
+					if i == 0 || budget.allowed < decision.Allowed {
+						decision.Allowed = budget.allowed
+					}
+				}
+				cache.recordPDBDecision(decision)
+			}
+			if blocked {
+				continue
+			}
+			for _, budget := range matched {
+				budget.allowed--
+			}
+		}
+		res, _, _ := calculateResource(pod)
+		freeCPU += res.MilliCPU
+		freeMem += res.Memory
+		freeStorage += res.EphemeralStorage
+		victims = append(victims, pod)
+		if fits() {
+			break
+		}
+	}
+	if !fits() {
+		if pdbAware {
+			return nil, newError(ReasonInsufficientVictims, "removing all eligible pods from node %v without violating disruption budgets doesn't fit pod %v/%v", nodeName, preemptor.Namespace, preemptor.Name)
+		}
+		return nil, newError(ReasonInsufficientVictims, "removing all lower-priority pods from node %v doesn't fit pod %v/%v", nodeName, preemptor.Namespace, preemptor.Name)
+	}
+
+	// Reprieve pass: earlier low-priority victims may have become redundant
+	// once a larger pod was added to the set.
+	kept := victims[:0]
+	for _, pod := range victims {
+		res, _, _ := calculateResource(pod)
+		if freeCPU-res.MilliCPU >= need.MilliCPU &&
+			freeMem-res.Memory >= need.Memory &&
+			freeStorage-res.EphemeralStorage >= need.EphemeralStorage {
+			freeCPU -= res.MilliCPU
+			freeMem -= res.Memory
+			freeStorage -= res.EphemeralStorage
+			continue
+		}
+		kept = append(kept, pod)
+	}
+	return kept, nil
+}
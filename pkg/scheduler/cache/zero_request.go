@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"k8s.io/api/core/v1"
+
+	priorityutil "k8s.io/kubernetes/pkg/scheduler/algorithm/priorities/util"
+)
+
+// ZeroRequestDefaults is the CPU and memory request assumed for containers
+// that specify none, feeding the defaulted (NonZeroRequest) aggregate. The
+// raw aggregate, RequestedResource, never applies defaults.
+type ZeroRequestDefaults struct {
+	MilliCPU int64
+	Memory   int64
+}
+
+// ZeroRequestPolicy returns the defaults to assume for zero-request
+// containers of pods in the given namespace. Returning nil falls back to the
+// cache-wide defaults.
+type ZeroRequestPolicy func(namespace string) *ZeroRequestDefaults
+
+// SetZeroRequestDefaults replaces the compiled-in 100m/200Mi assumption for
+// zero-request containers with the given one and recomputes the defaulted
+// aggregate of every cached node. A nil argument restores the compiled-in
+// defaults.
+func (cache *schedulerCache) SetZeroRequestDefaults(defaults *ZeroRequestDefaults) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.zeroRequestDefaults = defaults
+	cache.applyZeroRequestConfig()
+}
+
+// SetZeroRequestPolicy installs a per-namespace override of the zero-request
+// defaults and recomputes the defaulted aggregate of every cached node. The
+// policy must be pure and stable for the lifetime of the cache - add and
+// remove both consult it, so a policy that changes its answers corrupts the
+// incremental accounting. A nil policy removes the override.
+func (cache *schedulerCache) SetZeroRequestPolicy(policy ZeroRequestPolicy) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.zeroRequestPolicy = policy
+	cache.applyZeroRequestConfig()
+}
+
+// applyZeroRequestConfig pushes the cache's zero-request configuration to
+// every cached node and rebuilds their defaulted aggregates under it.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) applyZeroRequestConfig() {
+	cache.store.RangeNodeInfos(func(name string, n *NodeInfo) bool {
+		n.zeroRequestDefaults = cache.zeroRequestDefaults
+		n.zeroRequestPolicy = cache.zeroRequestPolicy
+		n.recomputeNonzeroRequest()
+		return true
+	})
+}
+
+// newNodeInfo returns a NodeInfo carrying the cache's zero-request
+// configuration, so aggregates of nodes created later match those of nodes
+// that saw the configuration applied.
+// Assumes that lock is already acquired.
+func (cache *schedulerCache) newNodeInfo() *NodeInfo {
+	n := NewNodeInfo()
+	n.zeroRequestDefaults = cache.zeroRequestDefaults
+	n.zeroRequestPolicy = cache.zeroRequestPolicy
+	return n
+}
+
+// zeroRequestFor resolves the defaults to assume for the pod's zero-request
+// containers: the namespace policy's answer if one is installed, then the
+// cache-wide defaults, then the compiled-in ones.
+func (n *NodeInfo) zeroRequestFor(pod *v1.Pod) (milliCPU, memory int64) {
+	if n != nil {
+		if n.zeroRequestPolicy != nil {
+			if d := n.zeroRequestPolicy(pod.Namespace); d != nil {
+				return d.MilliCPU, d.Memory
+			}
+		}
+		if n.zeroRequestDefaults != nil {
+			return n.zeroRequestDefaults.MilliCPU, n.zeroRequestDefaults.Memory
+		}
+	}
+	return priorityutil.DefaultMilliCPURequest, priorityutil.DefaultMemoryRequest
+}
+
+// calculatePodResource is calculateResource with this node's zero-request
+// defaulting applied to the nonzero pair.
+func (n *NodeInfo) calculatePodResource(pod *v1.Pod) (res Resource, non0CPU int64, non0Mem int64) {
+	defaultMilliCPU, defaultMemory := n.zeroRequestFor(pod)
+	resPtr := &res
+	for _, c := range pod.Spec.Containers {
+		resPtr.Add(c.Resources.Requests)
+
+		non0CPUReq, non0MemReq := nonzeroRequests(&c.Resources.Requests, defaultMilliCPU, defaultMemory)
+		non0CPU += non0CPUReq
+		non0Mem += non0MemReq
+	}
+
+	return
+}
+
+// recomputeNonzeroRequest rebuilds the defaulted aggregate from the node's
+// pod list, e.g. after the zero-request configuration changed under it.
+func (n *NodeInfo) recomputeNonzeroRequest() {
+	total := &Resource{}
+	for _, pod := range n.pods {
+		_, non0CPU, non0Mem := n.calculatePodResource(pod)
+		total.MilliCPU += non0CPU
+		total.Memory += non0Mem
+	}
+	n.nonzeroRequest = total
+	n.generation = nextGeneration()
+}
+
+// nonzeroRequests is priorityutil.GetNonzeroRequests with the defaults
+// parameterized.
+func nonzeroRequests(requests *v1.ResourceList, defaultMilliCPU, defaultMemory int64) (int64, int64) {
+	outMilliCPU := defaultMilliCPU
+	if _, found := (*requests)[v1.ResourceCPU]; found {
+		outMilliCPU = requests.Cpu().MilliValue()
+	}
+	outMemory := defaultMemory
+	if _, found := (*requests)[v1.ResourceMemory]; found {
+		outMemory = requests.Memory().Value()
+	}
+	return outMilliCPU, outMemory
+}
@@ -1417,13 +1417,13 @@ type syncingMockCache struct {
 //
 // Since UpdateNodeNameToInfoMap is one of the first steps of (*genericScheduler).Schedule, we use
 // this point to signal to the test that a scheduling cycle has started.
-func (c *syncingMockCache) UpdateNodeNameToInfoMap(infoMap map[string]*schedulercache.NodeInfo) error {
-	err := c.Cache.UpdateNodeNameToInfoMap(infoMap)
+func (c *syncingMockCache) UpdateNodeNameToInfoMap(infoMap map[string]*schedulercache.NodeInfo) ([]string, error) {
+	changed, err := c.Cache.UpdateNodeNameToInfoMap(infoMap)
 	c.once.Do(func() {
 		c.cycleStart <- struct{}{}
 		<-c.cacheInvalidated
 	})
-	return err
+	return changed, err
 }
 
 // TestCacheInvalidationRace tests that equivalence cache invalidation is correctly
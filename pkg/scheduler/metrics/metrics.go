@@ -105,6 +105,22 @@ var (
 			Buckets:   prometheus.ExponentialBuckets(1000, 2, 15),
 		},
 	)
+	BindingFinishLatency = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Subsystem: SchedulerSubsystem,
+			Name:      "binding_finish_latency_microseconds",
+			Help:      "Latency between a pod being assumed and its binding finishing",
+			Buckets:   prometheus.ExponentialBuckets(1000, 2, 15),
+		},
+	)
+	BindingConfirmationLatency = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Subsystem: SchedulerSubsystem,
+			Name:      "binding_confirmation_latency_microseconds",
+			Help:      "Latency between a pod being assumed and its add event confirming the binding",
+			Buckets:   prometheus.ExponentialBuckets(1000, 2, 15),
+		},
+	)
 	PreemptionVictims = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Subsystem: SchedulerSubsystem,
@@ -117,6 +133,38 @@ var (
 			Name:      "total_preemption_attempts",
 			Help:      "Total preemption attempts in the cluster till now",
 		})
+	CacheCompactionReclaimedItems = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: SchedulerSubsystem,
+			Name:      "cache_compaction_reclaimed_items",
+			Help:      "Total number of stale cache entries reclaimed by background compaction",
+		})
+	CacheOrphanedPods = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: SchedulerSubsystem,
+			Name:      "cache_orphaned_pods",
+			Help:      "Number of cached pods whose node no longer exists",
+		})
+	CacheOrphanedPodsPurged = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: SchedulerSubsystem,
+			Name:      "cache_orphaned_pods_purged",
+			Help:      "Total number of orphaned pods purged from the cache after the grace period",
+		})
+	CacheDownsizeCandidates = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: SchedulerSubsystem,
+			Name:      "cache_downsize_candidates",
+			Help:      "Number of cached pods whose requests currently exceed their measured usage by the configured factor",
+		})
+	CacheAssumedPods = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: SchedulerSubsystem,
+			Name:      "cache_assumed_pods",
+			Help:      "Number of assumed pods in the scheduler cache, by assume source and age bucket",
+		},
+		[]string{"source", "age_bucket"},
+	)
 	metricsList = []prometheus.Collector{
 		SchedulingLatency,
 		E2eSchedulingLatency,
@@ -125,8 +173,15 @@ var (
 		SchedulingAlgorithmPredicateEvaluationDuration,
 		SchedulingAlgorithmPriorityEvaluationDuration,
 		SchedulingAlgorithmPremptionEvaluationDuration,
+		BindingFinishLatency,
+		BindingConfirmationLatency,
 		PreemptionVictims,
 		PreemptionAttempts,
+		CacheCompactionReclaimedItems,
+		CacheOrphanedPods,
+		CacheOrphanedPodsPurged,
+		CacheDownsizeCandidates,
+		CacheAssumedPods,
 	}
 )
 
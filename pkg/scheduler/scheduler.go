@@ -366,8 +366,9 @@ func (sched *Scheduler) bindVolumesWorker() {
 }
 
 // assume signals to the cache that a pod is already in the cache, so that binding can be asynchronous.
-// assume modifies `assumed`.
-func (sched *Scheduler) assume(assumed *v1.Pod, host string) error {
+// assume modifies `assumed`. The returned handle controls the binding
+// transaction for the assumed pod.
+func (sched *Scheduler) assume(assumed *v1.Pod, host string) (schedulercache.BindingHandle, error) {
 	// Optimistically assume that the binding will succeed and send it to apiserver
 	// in the background.
 	// If the binding fails, scheduler will release resources allocated to assumed pod
@@ -376,7 +377,8 @@ func (sched *Scheduler) assume(assumed *v1.Pod, host string) error {
 	// NOTE: Because the scheduler uses snapshots of SchedulerCache and the live
 	// version of Ecache, updates must be written to SchedulerCache before
 	// invalidating Ecache.
-	if err := sched.config.SchedulerCache.AssumePod(assumed); err != nil {
+	handle, err := sched.config.SchedulerCache.AssumePod(assumed)
+	if err != nil {
 		glog.Errorf("scheduler cache AssumePod failed: %v", err)
 
 		// This is most probably result of a BUG in retrying logic.
@@ -392,7 +394,7 @@ func (sched *Scheduler) assume(assumed *v1.Pod, host string) error {
 			Reason:  "SchedulerError",
 			Message: err.Error(),
 		})
-		return err
+		return nil, err
 	}
 
 	// Optimistically assume that the binding will succeed, so we need to invalidate affected
@@ -401,23 +403,23 @@ func (sched *Scheduler) assume(assumed *v1.Pod, host string) error {
 	if sched.config.Ecache != nil {
 		sched.config.Ecache.InvalidateCachedPredicateItemForPodAdd(assumed, host)
 	}
-	return nil
+	return handle, nil
 }
 
 // bind binds a pod to a given node defined in a binding object.  We expect this to run asynchronously, so we
 // handle binding metrics internally.
-func (sched *Scheduler) bind(assumed *v1.Pod, b *v1.Binding) error {
+func (sched *Scheduler) bind(assumed *v1.Pod, handle schedulercache.BindingHandle, b *v1.Binding) error {
 	bindingStart := time.Now()
 	// If binding succeeded then PodScheduled condition will be updated in apiserver so that
 	// it's atomic with setting host.
 	err := sched.config.GetBinder(assumed).Bind(b)
-	if err := sched.config.SchedulerCache.FinishBinding(assumed); err != nil {
-		glog.Errorf("scheduler cache FinishBinding failed: %v", err)
+	if err := handle.Confirm(); err != nil {
+		glog.Errorf("scheduler cache Confirm failed: %v", err)
 	}
 	if err != nil {
 		glog.V(1).Infof("Failed to bind pod: %v/%v", assumed.Namespace, assumed.Name)
-		if err := sched.config.SchedulerCache.ForgetPod(assumed); err != nil {
-			glog.Errorf("scheduler cache ForgetPod failed: %v", err)
+		if err := handle.Abort(); err != nil {
+			glog.Errorf("scheduler cache Abort failed: %v", err)
 		}
 		sched.config.Error(assumed, err)
 		sched.config.Recorder.Eventf(assumed, v1.EventTypeWarning, "FailedScheduling", "Binding rejected: %v", err)
@@ -485,13 +487,13 @@ func (sched *Scheduler) scheduleOne() {
 	}
 
 	// assume modifies `assumedPod` by setting NodeName=suggestedHost
-	err = sched.assume(assumedPod, suggestedHost)
+	handle, err := sched.assume(assumedPod, suggestedHost)
 	if err != nil {
 		return
 	}
 	// bind the pod to its host asynchronously (we can do this b/c of the assumption step above).
 	go func() {
-		err := sched.bind(assumedPod, &v1.Binding{
+		err := sched.bind(assumedPod, handle, &v1.Binding{
 			ObjectMeta: metav1.ObjectMeta{Namespace: assumedPod.Namespace, Name: assumedPod.Name, UID: assumedPod.UID},
 			Target: v1.ObjectReference{
 				Kind: "Node",
@@ -17,9 +17,14 @@ limitations under the License.
 package testing
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1beta1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
 )
 
@@ -32,26 +37,57 @@ type FakeCache struct {
 }
 
 // AssumePod is a fake method for testing.
-func (f *FakeCache) AssumePod(pod *v1.Pod) error {
+func (f *FakeCache) AssumePod(pod *v1.Pod) (schedulercache.BindingHandle, error) {
 	f.AssumeFunc(pod)
-	return nil
+	return fakeBindingHandle{cache: f, pod: pod}, nil
+}
+
+// AssumePodWithContext is a fake method for testing.
+func (f *FakeCache) AssumePodWithContext(ctx context.Context, pod *v1.Pod) (schedulercache.BindingHandle, error) {
+	return f.AssumePod(pod)
+}
+
+// fakeBindingHandle is the BindingHandle returned by FakeCache.AssumePod. It
+// delegates to the fake cache like the real handle delegates to the scheduler
+// cache, so tests observe aborted and confirmed bindings.
+type fakeBindingHandle struct {
+	cache *FakeCache
+	pod   *v1.Pod
 }
 
+func (h fakeBindingHandle) Confirm() error                 { return h.cache.FinishBinding(h.pod) }
+func (h fakeBindingHandle) Abort() error                   { return h.cache.ForgetPod(h.pod) }
+func (h fakeBindingHandle) Extend(ttl time.Duration) error { return nil }
+
+// SetAssumeAdmitFunc is a fake method for testing.
+func (f *FakeCache) SetAssumeAdmitFunc(fn schedulercache.AssumeAdmitFunc) {}
+
 // FinishBinding is a fake method for testing.
 func (f *FakeCache) FinishBinding(pod *v1.Pod) error { return nil }
 
+// BindingLatency is a fake method for testing.
+func (f *FakeCache) BindingLatency(pod *v1.Pod) (time.Duration, error) { return 0, nil }
+
 // ForgetPod is a fake method for testing.
 func (f *FakeCache) ForgetPod(pod *v1.Pod) error {
 	f.ForgetFunc(pod)
 	return nil
 }
 
+// ReleasePod is a fake method for testing.
+func (f *FakeCache) ReleasePod(podKey string) error { return nil }
+
 // AddPod is a fake method for testing.
 func (f *FakeCache) AddPod(pod *v1.Pod) error { return nil }
 
 // UpdatePod is a fake method for testing.
 func (f *FakeCache) UpdatePod(oldPod, newPod *v1.Pod) error { return nil }
 
+// UpdatePodWithContext is a fake method for testing.
+func (f *FakeCache) UpdatePodWithContext(ctx context.Context, oldPod, newPod *v1.Pod) error {
+	return nil
+}
+
 // RemovePod is a fake method for testing.
 func (f *FakeCache) RemovePod(pod *v1.Pod) error { return nil }
 
@@ -60,11 +96,28 @@ func (f *FakeCache) IsAssumedPod(pod *v1.Pod) (bool, error) {
 	return f.IsAssumedPodFunc(pod), nil
 }
 
+// SetProfileTTL is a fake method for testing.
+func (f *FakeCache) SetProfileTTL(profile string, ttl time.Duration) {}
+
+// AssumedPodsByProfile is a fake method for testing.
+func (f *FakeCache) AssumedPodsByProfile() map[string]int { return nil }
+
 // GetPod is a fake method for testing.
 func (f *FakeCache) GetPod(pod *v1.Pod) (*v1.Pod, error) {
 	return f.GetPodFunc(pod), nil
 }
 
+// EnablePodTrimming is a fake method for testing.
+func (f *FakeCache) EnablePodTrimming(getter schedulercache.PodGetter) {}
+
+// EnablePodDeduplication is a fake method for testing.
+func (f *FakeCache) EnablePodDeduplication() {}
+
+// GetFullPod is a fake method for testing.
+func (f *FakeCache) GetFullPod(pod *v1.Pod) (*v1.Pod, error) {
+	return f.GetPodFunc(pod), nil
+}
+
 // AddNode is a fake method for testing.
 func (f *FakeCache) AddNode(node *v1.Node) error { return nil }
 
@@ -74,20 +127,328 @@ func (f *FakeCache) UpdateNode(oldNode, newNode *v1.Node) error { return nil }
 // RemoveNode is a fake method for testing.
 func (f *FakeCache) RemoveNode(node *v1.Node) error { return nil }
 
+// ApplyBatch is a fake method for testing.
+func (f *FakeCache) ApplyBatch(events []schedulercache.CacheEvent) error { return nil }
+
+// RankNodes is a fake method for testing.
+func (f *FakeCache) RankNodes(strategy schedulercache.RankStrategy, limit int) ([]schedulercache.NodeRank, error) {
+	return nil, nil
+}
+
+// DeletedNodes is a fake method for testing.
+func (f *FakeCache) DeletedNodes() []string { return nil }
+
+// NodesInTopologyDomain is a fake method for testing.
+func (f *FakeCache) NodesInTopologyDomain(key, value string) []string { return nil }
+
+// NodeIterator is a fake method for testing.
+func (f *FakeCache) NodeIterator(spreadKey string) *schedulercache.NodeIterator {
+	return &schedulercache.NodeIterator{}
+}
+
+// TopologySpreadCounts is a fake method for testing.
+func (f *FakeCache) TopologySpreadCounts(constraint schedulercache.TopologySpreadConstraint) map[string]int32 {
+	return nil
+}
+
+// MatchingAntiAffinityTerms is a fake method for testing.
+func (f *FakeCache) MatchingAntiAffinityTerms(pod *v1.Pod) []schedulercache.MatchingAntiAffinityTerm {
+	return nil
+}
+
+// SetImagePullBandwidth is a fake method for testing.
+func (f *FakeCache) SetImagePullBandwidth(bytesPerSecond int64) {}
+
+// EstimatedImagePullTime is a fake method for testing.
+func (f *FakeCache) EstimatedImagePullTime(pod *v1.Pod, nodeName string) (time.Duration, error) {
+	return 0, nil
+}
+
+// AddInvalidationListener is a fake method for testing.
+func (f *FakeCache) AddInvalidationListener(listener schedulercache.InvalidationListener) {}
+
+// SnapshotForExtender is a fake method for testing.
+func (f *FakeCache) SnapshotForExtender(filter *schedulercache.ExtenderSnapshotFilter) ([]byte, error) {
+	return nil, nil
+}
+
+// EnableAuditLog is a fake method for testing.
+func (f *FakeCache) EnableAuditLog(maxEntries int, maxAge time.Duration, path string) error {
+	return nil
+}
+
+// AuditEntries is a fake method for testing.
+func (f *FakeCache) AuditEntries() []schedulercache.AuditEntry { return nil }
+
+// SetScoreDataExport is a fake method for testing.
+func (f *FakeCache) SetScoreDataExport(interval time.Duration, pending schedulercache.PendingPodsFunc, w io.Writer) {
+}
+
+// SetVerificationMode is a fake method for testing.
+func (f *FakeCache) SetVerificationMode(mode schedulercache.VerificationMode) {}
+
+// SetTracer is a fake method for testing.
+func (f *FakeCache) SetTracer(t schedulercache.Tracer) {}
+
+// EnableLockProfiling is a fake method for testing.
+func (f *FakeCache) EnableLockProfiling(sampleRate int) {}
+
+// LockContentionReport is a fake method for testing.
+func (f *FakeCache) LockContentionReport() []schedulercache.LockOpStats { return nil }
+
+// SetFitResourcePolicy is a fake method for testing.
+func (f *FakeCache) SetFitResourcePolicy(policy schedulercache.FitResourcePolicy) {}
+
+// FitResourcePolicy is a fake method for testing.
+func (f *FakeCache) FitResourcePolicy() schedulercache.FitResourcePolicy {
+	return schedulercache.FitResourcePolicy{}
+}
+
+// SetOverCommitRules is a fake method for testing.
+func (f *FakeCache) SetOverCommitRules(rules []schedulercache.OverCommitRule) {}
+
+// SetCapacityPools is a fake method for testing.
+func (f *FakeCache) SetCapacityPools(pools []schedulercache.CapacityPool) {}
+
+// SelectVictims is a fake method for testing.
+func (f *FakeCache) SelectVictims(nodeName string, preemptor *v1.Pod, pdbAware bool) ([]*v1.Pod, error) {
+	return nil, nil
+}
+
+// IsNodeDrainable is a fake method for testing.
+func (f *FakeCache) IsNodeDrainable(nodeName string) (bool, []string, error) {
+	return true, nil, nil
+}
+
+// SetRecommendedResources is a fake method for testing.
+func (f *FakeCache) SetRecommendedResources(podKey string, recommendation *schedulercache.Resource) {
+}
+
+// UpdatePodUsage is a fake method for testing.
+func (f *FakeCache) UpdatePodUsage(podKey string, ephemeralStorageBytes int64) error { return nil }
+
+// SetDownsizeAnalyzer is a fake method for testing.
+func (f *FakeCache) SetDownsizeAnalyzer(factor float64, sustained time.Duration, emit schedulercache.DownsizeSuggestFunc) {
+}
+
+// UpdateNodeUsage is a fake method for testing.
+func (f *FakeCache) UpdateNodeUsage(nodeName string, usage schedulercache.Resource) error {
+	return nil
+}
+
+// PlanResize is a fake method for testing.
+func (f *FakeCache) PlanResize(podKey string) (schedulercache.ResizeAction, error) {
+	return schedulercache.ResizeActionNone, nil
+}
+
+// SetResizeFitPredicate is a fake method for testing.
+func (f *FakeCache) SetResizeFitPredicate(fn schedulercache.FitPredicate) {}
+
+// SetResizeCooldown is a fake method for testing.
+func (f *FakeCache) SetResizeCooldown(d time.Duration) {}
+
+// SetResizeGranularity is a fake method for testing.
+func (f *FakeCache) SetResizeGranularity(cpuMilli, memoryBytes int64) {}
+
+// SetResizeRateLimit is a fake method for testing.
+func (f *FakeCache) SetResizeRateLimit(perNode int, window time.Duration) {}
+
+// MarkResizeStarted is a fake method for testing.
+func (f *FakeCache) MarkResizeStarted(podKey string) error { return nil }
+
+// MarkResizeCompleted is a fake method for testing.
+func (f *FakeCache) MarkResizeCompleted(podKey string) {}
+
+// IsResizing is a fake method for testing.
+func (f *FakeCache) IsResizing(podKey string) bool { return false }
+
+// MarkPendingPreemption is a fake method for testing.
+func (f *FakeCache) MarkPendingPreemption(podKey string) error { return nil }
+
+// ClearPendingPreemption is a fake method for testing.
+func (f *FakeCache) ClearPendingPreemption(podKey string) {}
+
+// SetCheckpointing is a fake method for testing.
+func (f *FakeCache) SetCheckpointing(interval time.Duration, write schedulercache.CheckpointWriteFunc) {
+}
+
+// RestoreCheckpoint is a fake method for testing.
+func (f *FakeCache) RestoreCheckpoint(cp *schedulercache.Checkpoint) {}
+
+// SetResizeAnnotationGC is a fake method for testing.
+func (f *FakeCache) SetResizeAnnotationGC(retention time.Duration, patch schedulercache.PodPatchFunc) {
+}
+
+// ResizePlacementHint is a fake method for testing.
+func (f *FakeCache) ResizePlacementHint(podKey string) ([]string, error) {
+	return nil, nil
+}
+
+// PendingResizes is a fake method for testing.
+func (f *FakeCache) PendingResizes(nodeName string) []schedulercache.PendingResize { return nil }
+
+// MaxSchedulableUnit is a fake method for testing.
+func (f *FakeCache) MaxSchedulableUnit(nodeName string) (schedulercache.Resource, error) {
+	return schedulercache.Resource{}, nil
+}
+
+// SetPVCDriverResolver is a fake method for testing.
+func (f *FakeCache) SetPVCDriverResolver(resolve schedulercache.PVCDriverResolver) {}
+
+// AddCSINode is a fake method for testing.
+func (f *FakeCache) AddCSINode(nodeName string, allocatable map[string]int32) error { return nil }
+
+// UpdateCSINode is a fake method for testing.
+func (f *FakeCache) UpdateCSINode(nodeName string, allocatable map[string]int32) error { return nil }
+
+// RemoveCSINode is a fake method for testing.
+func (f *FakeCache) RemoveCSINode(nodeName string) {}
+
+// RemainingAttachSlots is a fake method for testing.
+func (f *FakeCache) RemainingAttachSlots(nodeName string) (map[string]int32, error) {
+	return nil, nil
+}
+
+// AddNodeLocalPVC is a fake method for testing.
+func (f *FakeCache) AddNodeLocalPVC(namespace, claim string) {}
+
+// RemoveNodeLocalPVC is a fake method for testing.
+func (f *FakeCache) RemoveNodeLocalPVC(namespace, claim string) {}
+
+// StoreFit is a fake method for testing.
+func (f *FakeCache) StoreFit(templateHash, nodeName string, fit bool) error { return nil }
+
+// CachedFit is a fake method for testing.
+func (f *FakeCache) CachedFit(templateHash, nodeName string) (bool, bool) { return false, false }
+
+// PodsByOwner is a fake method for testing.
+func (f *FakeCache) PodsByOwner(uid types.UID) []*v1.Pod { return nil }
+
+// OwnerSpreadCounts is a fake method for testing.
+func (f *FakeCache) OwnerSpreadCounts(uid types.UID, topologyKey string) map[string]int32 {
+	return nil
+}
+
+// SetPodUpdater is a fake method for testing.
+func (f *FakeCache) SetPodUpdater(fn schedulercache.PodUpdateFunc) {}
+
+// EnqueuePodMutation is a fake method for testing.
+func (f *FakeCache) EnqueuePodMutation(podKey string, annotations map[string]string) {}
+
+// ForEachPod is a fake method for testing.
+func (f *FakeCache) ForEachPod(fn func(pod *v1.Pod) bool) {}
+
+// GetAssumedPods is a fake method for testing.
+func (f *FakeCache) GetAssumedPods() []schedulercache.AssumedPodInfo { return nil }
+
+// RecentPDBDecisions is a fake method for testing.
+func (f *FakeCache) RecentPDBDecisions() []schedulercache.PDBDecision { return nil }
+
+// SnapshotMatching is a fake method for testing.
+func (f *FakeCache) SnapshotMatching(selector labels.Selector) *schedulercache.Snapshot {
+	return &schedulercache.Snapshot{}
+}
+
+// GetPodNode is a fake method for testing.
+func (f *FakeCache) GetPodNode(podKey string) (string, bool, error) { return "", false, nil }
+
+// SetResizeWatchdog is a fake method for testing.
+func (f *FakeCache) SetResizeWatchdog(threshold time.Duration, handler schedulercache.ResizeStuckFunc) {
+}
+
+// SetNamespacePodLimit is a fake method for testing.
+func (f *FakeCache) SetNamespacePodLimit(limit int) {}
+
+// SetStrictAssume is a fake method for testing.
+func (f *FakeCache) SetStrictAssume(enabled bool) {}
+
+// SetOptimisticTerminating is a fake method for testing.
+func (f *FakeCache) SetOptimisticTerminating(enabled bool) {}
+
+// SetZeroRequestDefaults is a fake method for testing.
+func (f *FakeCache) SetZeroRequestDefaults(defaults *schedulercache.ZeroRequestDefaults) {}
+
+// SetZeroRequestPolicy is a fake method for testing.
+func (f *FakeCache) SetZeroRequestPolicy(policy schedulercache.ZeroRequestPolicy) {}
+
+// PodAllocatedResources is a fake method for testing.
+func (f *FakeCache) PodAllocatedResources(podKey string) (map[string]schedulercache.Resource, map[string]schedulercache.Resource, error) {
+	return nil, nil, nil
+}
+
+// PinPod is a fake method for testing.
+func (f *FakeCache) PinPod(pod *v1.Pod, nodeName string, ttl time.Duration) error { return nil }
+
+// AssumeGroup is a fake method for testing.
+func (f *FakeCache) AssumeGroup(group string, pods []*v1.Pod, ttl time.Duration) error { return nil }
+
+// ForgetGroup is a fake method for testing.
+func (f *FakeCache) ForgetGroup(group string) error { return nil }
+
+// UpdateNodeLease is a fake method for testing.
+func (f *FakeCache) UpdateNodeLease(nodeName string, renewTime time.Time) error { return nil }
+
+// NodeHeartbeatAge is a fake method for testing.
+func (f *FakeCache) NodeHeartbeatAge(nodeName string) (time.Duration, error) { return 0, nil }
+
+// SetHeartbeatThreshold is a fake method for testing.
+func (f *FakeCache) SetHeartbeatThreshold(d time.Duration) {}
+
+// MarkNodeUnschedulableSoon is a fake method for testing.
+func (f *FakeCache) MarkNodeUnschedulableSoon(nodeName string, deadline time.Time) {}
+
+// ClearNodeMaintenance is a fake method for testing.
+func (f *FakeCache) ClearNodeMaintenance(nodeName string) {}
+
+// OrphanedPods is a fake method for testing.
+func (f *FakeCache) OrphanedPods() []*v1.Pod { return nil }
+
+// SetOrphanPurgeGracePeriod is a fake method for testing.
+func (f *FakeCache) SetOrphanPurgeGracePeriod(d time.Duration) {}
+
+// SetCleanupPeriod is a fake method for testing.
+func (f *FakeCache) SetCleanupPeriod(period time.Duration, jitter float64) {}
+
+// Resync is a fake method for testing.
+func (f *FakeCache) Resync(nodes []*v1.Node, pods []*v1.Pod, pdbs []*policy.PodDisruptionBudget) error {
+	return nil
+}
+
 // UpdateNodeNameToInfoMap is a fake method for testing.
-func (f *FakeCache) UpdateNodeNameToInfoMap(infoMap map[string]*schedulercache.NodeInfo) error {
+func (f *FakeCache) UpdateNodeNameToInfoMap(infoMap map[string]*schedulercache.NodeInfo) ([]string, error) {
+	return nil, nil
+}
+
+// SampleNodes is a fake method for testing.
+func (f *FakeCache) SampleNodes(n int, filter func(*schedulercache.NodeInfo) bool) []string {
 	return nil
 }
 
 // AddPDB is a fake method for testing.
 func (f *FakeCache) AddPDB(pdb *policy.PodDisruptionBudget) error { return nil }
 
+// AddPDBData is a fake method for testing.
+func (f *FakeCache) AddPDBData(data schedulercache.PDBData) error { return nil }
+
 // UpdatePDB is a fake method for testing.
 func (f *FakeCache) UpdatePDB(oldPDB, newPDB *policy.PodDisruptionBudget) error { return nil }
 
 // RemovePDB is a fake method for testing.
 func (f *FakeCache) RemovePDB(pdb *policy.PodDisruptionBudget) error { return nil }
 
+// GetPDBsMatchingPod is a fake method for testing.
+func (f *FakeCache) GetPDBsMatchingPod(pod *v1.Pod) []*policy.PodDisruptionBudget {
+	return nil
+}
+
+// MarkPodDisruption is a fake method for testing.
+func (f *FakeCache) MarkPodDisruption(pod *v1.Pod) error { return nil }
+
+// PodDisruptionsAllowed is a fake method for testing.
+func (f *FakeCache) PodDisruptionsAllowed(pdb *policy.PodDisruptionBudget) int32 {
+	return pdb.Status.PodDisruptionsAllowed
+}
+
 // ListPDBs is a fake method for testing.
 func (f *FakeCache) ListPDBs(selector labels.Selector) ([]*policy.PodDisruptionBudget, error) {
 	return nil, nil
@@ -96,6 +457,11 @@ func (f *FakeCache) ListPDBs(selector labels.Selector) ([]*policy.PodDisruptionB
 // List is a fake method for testing.
 func (f *FakeCache) List(s labels.Selector) ([]*v1.Pod, error) { return nil, nil }
 
+// ListWithContext is a fake method for testing.
+func (f *FakeCache) ListWithContext(ctx context.Context, s labels.Selector) ([]*v1.Pod, error) {
+	return nil, nil
+}
+
 // FilteredList is a fake method for testing.
 func (f *FakeCache) FilteredList(filter schedulercache.PodFilter, selector labels.Selector) ([]*v1.Pod, error) {
 	return nil, nil
@@ -106,5 +472,18 @@ func (f *FakeCache) Snapshot() *schedulercache.Snapshot {
 	return &schedulercache.Snapshot{}
 }
 
+// SnapshotWithContext is a fake method for testing.
+func (f *FakeCache) SnapshotWithContext(ctx context.Context) (*schedulercache.Snapshot, error) {
+	return f.Snapshot(), nil
+}
+
+// NewReadReplica is a fake method for testing.
+func (f *FakeCache) NewReadReplica(period time.Duration) *schedulercache.ReadReplica { return nil }
+
+// ClusterCapacity is a fake method for testing.
+func (f *FakeCache) ClusterCapacity() *schedulercache.ClusterCapacitySummary {
+	return &schedulercache.ClusterCapacitySummary{}
+}
+
 // IsUpToDate is a fake mthod for testing
 func (f *FakeCache) IsUpToDate(*schedulercache.NodeInfo) bool { return true }
@@ -17,6 +17,8 @@ limitations under the License.
 package testing
 
 import (
+	"time"
+
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	schedulercache "k8s.io/kubernetes/pkg/scheduler/cache"
@@ -25,8 +27,19 @@ import (
 // PodsToCache is used for testing
 type PodsToCache []*v1.Pod
 
-// AssumePod returns nil.
-func (p PodsToCache) AssumePod(pod *v1.Pod) error { return nil }
+// inertBindingHandle is the BindingHandle returned by PodsToCache.AssumePod.
+// PodsToCache records nothing, so unlike FakeCache's delegating handle all
+// methods are no-ops.
+type inertBindingHandle struct{}
+
+func (inertBindingHandle) Confirm() error                 { return nil }
+func (inertBindingHandle) Abort() error                   { return nil }
+func (inertBindingHandle) Extend(ttl time.Duration) error { return nil }
+
+// AssumePod returns a no-op handle.
+func (p PodsToCache) AssumePod(pod *v1.Pod) (schedulercache.BindingHandle, error) {
+	return inertBindingHandle{}, nil
+}
 
 // ForgetPod returns nil.
 func (p PodsToCache) ForgetPod(pod *v1.Pod) error { return nil }
@@ -50,8 +63,8 @@ func (p PodsToCache) UpdateNode(oldNode, newNode *v1.Node) error { return nil }
 func (p PodsToCache) RemoveNode(node *v1.Node) error { return nil }
 
 // UpdateNodeNameToInfoMap returns nil.
-func (p PodsToCache) UpdateNodeNameToInfoMap(infoMap map[string]*schedulercache.NodeInfo) error {
-	return nil
+func (p PodsToCache) UpdateNodeNameToInfoMap(infoMap map[string]*schedulercache.NodeInfo) ([]string, error) {
+	return nil, nil
 }
 
 // List returns pods matching the label selector.